@@ -7,6 +7,7 @@ require (
 	github.com/jackc/pgx/v5 v5.9.2
 	github.com/robfig/cron/v3 v3.0.1
 	github.com/yuin/goldmark v1.7.16
+	golang.org/x/image v0.25.0
 )
 
 require (
@@ -11,16 +11,70 @@ import (
 	"strings"
 	"time"
 
+	"github.com/onion/audio-share-backend/middleware"
 	"github.com/onion/audio-share-backend/services"
 )
 
+type searchInsightsReporter interface {
+	Insights(since time.Time, limit int) (*services.SearchInsights, error)
+}
+
+type removalRequestModerator interface {
+	List(status string) ([]services.RemovalRequest, error)
+	Get(id int64) (*services.RemovalRequest, error)
+	UpdateNotes(id int64, notes string) error
+	Transition(id int64, status, note string) error
+}
+
+type adminAuditLog interface {
+	Record(actor, method, endpoint, targetType, targetID string, before, after any) error
+	List(filter services.AuditFilter) (*services.AuditPage, error)
+}
+
+type AdminHandlerOptions struct {
+	SearchInsights  searchInsightsReporter
+	RemovalRequests removalRequestModerator
+	AuditLog        adminAuditLog
+	AdminKeys       adminKeyManager
+	AccessKeys      keyIssuanceReporter
+	Bans            banManager
+	// BanList is refreshed after a ban is created or lifted.
+	BanList banRefresher
+	Scraper scraperReporter
+	// Jobs are background jobs admins with the jobs scope can start, by name.
+	Jobs map[string]func() error
+}
+
 type AdminHandler struct {
-	db       *sql.DB
-	requests *services.RequestsService
+	db              *sql.DB
+	requests        *services.RequestsService
+	searchInsights  searchInsightsReporter
+	removalRequests removalRequestModerator
+	auditLog        adminAuditLog
+	adminKeys       adminKeyManager
+	accessKeys      keyIssuanceReporter
+	bans            banManager
+	banList         banRefresher
+	scraper         scraperReporter
+	jobs            map[string]*adminJob
 }
 
-func NewAdminHandler(db *sql.DB, requests *services.RequestsService) *AdminHandler {
-	return &AdminHandler{db: db, requests: requests}
+func NewAdminHandler(db *sql.DB, requests *services.RequestsService, options ...AdminHandlerOptions) *AdminHandler {
+	handler := &AdminHandler{db: db, requests: requests, jobs: map[string]*adminJob{}}
+	if len(options) > 0 {
+		handler.searchInsights = options[0].SearchInsights
+		handler.removalRequests = options[0].RemovalRequests
+		handler.auditLog = options[0].AuditLog
+		handler.adminKeys = options[0].AdminKeys
+		handler.accessKeys = options[0].AccessKeys
+		handler.bans = options[0].Bans
+		handler.banList = options[0].BanList
+		handler.scraper = options[0].Scraper
+		for name, run := range options[0].Jobs {
+			handler.jobs[name] = &adminJob{run: run}
+		}
+	}
+	return handler
 }
 
 func (h *AdminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -35,6 +89,10 @@ func (h *AdminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		key := strings.TrimPrefix(path, "audio/")
 		key = strings.TrimSuffix(key, "/unavailable")
 		h.handleAudioUnavailable(w, r, key)
+	case strings.HasPrefix(path, "audio/") && strings.HasSuffix(path, "/availability") && r.Method == http.MethodGet:
+		key := strings.TrimPrefix(path, "audio/")
+		key = strings.TrimSuffix(key, "/availability")
+		h.handleAudioAvailability(w, r, key)
 	case strings.HasPrefix(path, "audio/") && strings.HasSuffix(path, "/removal-request") && r.Method == http.MethodPatch:
 		key := strings.TrimPrefix(path, "audio/")
 		key = strings.TrimSuffix(key, "/removal-request")
@@ -45,11 +103,71 @@ func (h *AdminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 		h.handleAudioRemovalRequest(w, r, handleKey)
 
+	// Folders
+	case strings.HasPrefix(path, "folders/") && strings.HasSuffix(path, "/unavailable") && r.Method == http.MethodPatch:
+		key := strings.TrimPrefix(path, "folders/")
+		key = strings.TrimSuffix(key, "/unavailable")
+		h.handleFolderUnavailable(w, r, key)
+	case strings.HasPrefix(path, "folders/") && strings.HasSuffix(path, "/removal-request") && r.Method == http.MethodPatch:
+		key := strings.TrimPrefix(path, "folders/")
+		key = strings.TrimSuffix(key, "/removal-request")
+		h.handleFolderRemovalRequest(w, r, key)
+
+	// Removal requests
+	case path == "removal-requests" && r.Method == http.MethodGet:
+		h.handleRemovalRequestList(w, r)
+	case strings.HasPrefix(path, "removal-requests/") && r.Method == http.MethodGet:
+		h.handleRemovalRequestGet(w, r, strings.TrimPrefix(path, "removal-requests/"))
+	case strings.HasPrefix(path, "removal-requests/") && r.Method == http.MethodPatch:
+		h.handleRemovalRequestUpdate(w, r, strings.TrimPrefix(path, "removal-requests/"))
+
+	// Audit log
+	case path == "audit" && r.Method == http.MethodGet:
+		h.handleAuditList(w, r)
+
+	// Session inspector
+	case path == "sessions" && r.Method == http.MethodGet:
+		h.handleSessionInspect(w, r)
+
+	// Bans
+	case path == "bans" && r.Method == http.MethodGet:
+		h.handleBanList(w, r)
+	case path == "bans" && r.Method == http.MethodPost:
+		h.handleBanCreate(w, r)
+	case strings.HasPrefix(path, "bans/") && r.Method == http.MethodDelete:
+		h.handleBanLift(w, r, strings.TrimPrefix(path, "bans/"))
+
+	// Scraper detection
+	case path == "scraper-decisions" && r.Method == http.MethodGet:
+		h.handleScraperDecisions(w, r)
+
+	// API keys
+	case path == "keys" && r.Method == http.MethodGet:
+		h.handleAdminKeyList(w, r)
+	case path == "keys" && r.Method == http.MethodPost:
+		h.handleAdminKeyCreate(w, r)
+	case strings.HasPrefix(path, "keys/") && r.Method == http.MethodDelete:
+		h.handleAdminKeyRevoke(w, r, strings.TrimPrefix(path, "keys/"))
+
+	// Jobs
+	case path == "jobs" && r.Method == http.MethodGet:
+		h.handleJobList(w, r)
+	case strings.HasPrefix(path, "jobs/") && r.Method == http.MethodPost:
+		h.handleJobStart(w, r, strings.TrimPrefix(path, "jobs/"))
+
+	// Search insights
+	case path == "search-insights" && r.Method == http.MethodGet:
+		h.handleSearchInsights(w, r)
+	case path == "search-insights/requests" && r.Method == http.MethodPost:
+		h.handleSearchInsightRequestCreate(w, r)
+
 	// Targeted messages
 	case path == "targeted-messages" && r.Method == http.MethodPost:
 		h.handleTargetedMessageCreate(w, r)
 
 	// Requests
+	case path == "requests" && r.Method == http.MethodGet:
+		h.handleRequestList(w, r)
 	case path == "requests" && r.Method == http.MethodPost:
 		h.handleRequestCreate(w, r)
 	case strings.HasSuffix(path, "/status") && r.Method == http.MethodPatch:
@@ -76,12 +194,13 @@ type audioSourceItem struct {
 	Filename           string  `json:"filename"`
 	UnavailableAt      *string `json:"unavailableAt"`
 	RemovalRequestedAt *string `json:"removalRequestedAt"`
+	CheckedAt          *string `json:"availabilityCheckedAt"`
 }
 
 func (h *AdminHandler) handleAudioSources(w http.ResponseWriter, r *http.Request) {
 	rows, err := h.db.Query(`
 		SELECT share_key, webpage_url, COALESCE(title, ''), filename, unavailable_at,
-		       removal_requested_at
+		       removal_requested_at, availability_checked_at
 		FROM audio_files
 		WHERE deleted = 0 AND webpage_url IS NOT NULL AND webpage_url != ''
 		ORDER BY indexed_at DESC
@@ -98,8 +217,9 @@ func (h *AdminHandler) handleAudioSources(w http.ResponseWriter, r *http.Request
 		var item audioSourceItem
 		var unavailableAt sql.NullTime
 		var removalRequestedAt sql.NullTime
+		var checkedAt sql.NullTime
 		if err := rows.Scan(&item.ShareKey, &item.WebpageURL, &item.Title, &item.Filename,
-			&unavailableAt, &removalRequestedAt); err != nil {
+			&unavailableAt, &removalRequestedAt, &checkedAt); err != nil {
 			log.Printf("admin: audio sources scan failed: %v", err)
 			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Server error"})
 			return
@@ -112,6 +232,10 @@ func (h *AdminHandler) handleAudioSources(w http.ResponseWriter, r *http.Request
 			s := removalRequestedAt.Time.UTC().Format(time.RFC3339)
 			item.RemovalRequestedAt = &s
 		}
+		if checkedAt.Valid {
+			s := checkedAt.Time.UTC().Format(time.RFC3339)
+			item.CheckedAt = &s
+		}
 		items = append(items, item)
 	}
 
@@ -132,6 +256,7 @@ func (h *AdminHandler) handleAudioUnavailable(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	before := h.auditAudioTime(key, "unavailable_at")
 	var unavailableAt interface{}
 	if body.Unavailable {
 		unavailableAt = time.Now().UTC()
@@ -150,9 +275,42 @@ func (h *AdminHandler) handleAudioUnavailable(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	h.recordAudit(r, "audio", key,
+		map[string]any{"unavailableAt": before},
+		map[string]any{"unavailableAt": unavailableAt})
 	writeJSON(w, http.StatusOK, map[string]bool{"success": true})
 }
 
+const (
+	defaultAvailabilityHistoryLimit = 20
+	maxAvailabilityHistoryLimit     = 200
+)
+
+func (h *AdminHandler) handleAudioAvailability(w http.ResponseWriter, r *http.Request, key string) {
+	if key == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Key required"})
+		return
+	}
+	limit := defaultAvailabilityHistoryLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid limit"})
+			return
+		}
+		limit = min(parsed, maxAvailabilityHistoryLimit)
+	}
+
+	checks, err := services.AvailabilityHistory(h.db, key, limit)
+	if err != nil {
+		log.Printf("admin: availability history failed for key=%s: %v", key, err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Server error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, checks)
+}
+
 func (h *AdminHandler) handleAudioRemovalRequest(w http.ResponseWriter, r *http.Request, key string) {
 	if key == "" {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Key required"})
@@ -167,6 +325,7 @@ func (h *AdminHandler) handleAudioRemovalRequest(w http.ResponseWriter, r *http.
 		return
 	}
 
+	before := h.auditAudioTime(key, "removal_requested_at")
 	var requestedAt interface{}
 	if body.RemovalRequested {
 		requestedAt = time.Now().UTC()
@@ -185,11 +344,306 @@ func (h *AdminHandler) handleAudioRemovalRequest(w http.ResponseWriter, r *http.
 		return
 	}
 
+	h.recordAudit(r, "audio", key,
+		map[string]any{"removalRequestedAt": before},
+		map[string]any{"removalRequestedAt": requestedAt})
 	writeJSON(w, http.StatusOK, map[string]bool{"success": true})
 }
 
+// Folder handlers
+
+func (h *AdminHandler) handleFolderUnavailable(w http.ResponseWriter, r *http.Request, key string) {
+	if key == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Key required"})
+		return
+	}
+
+	var body struct {
+		Unavailable bool `json:"unavailable"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	result, err := services.SetFolderUnavailable(h.db, key, body.Unavailable)
+	h.writeFolderActionResult(w, r, key, map[string]any{"unavailable": body.Unavailable}, result, err)
+}
+
+func (h *AdminHandler) handleFolderRemovalRequest(w http.ResponseWriter, r *http.Request, key string) {
+	if key == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Key required"})
+		return
+	}
+
+	var body struct {
+		RemovalRequested bool `json:"removalRequested"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	result, err := services.SetFolderRemovalRequested(h.db, key, body.RemovalRequested)
+	h.writeFolderActionResult(w, r, key, map[string]any{"removalRequested": body.RemovalRequested}, result, err)
+}
+
+// writeFolderActionResult responds to a folder action. The audit entry
+// records the requested state with what changed; the previous state of each
+// track is not kept.
+func (h *AdminHandler) writeFolderActionResult(w http.ResponseWriter, r *http.Request, key string, change map[string]any, result *services.FolderActionResult, err error) {
+	if errors.Is(err, services.ErrNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("admin: folder action failed for key=%s: %v", key, err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Server error"})
+		return
+	}
+
+	change["tracks"] = result.Tracks
+	change["folders"] = result.Folders
+	change["urlBroken"] = result.URLBroken
+	h.recordAudit(r, "folder", key, nil, change)
+	writeJSON(w, http.StatusOK, result)
+}
+
+// Removal request handlers
+
+func (h *AdminHandler) handleRemovalRequestList(w http.ResponseWriter, r *http.Request) {
+	if h.removalRequests == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Not found"})
+		return
+	}
+	status := r.URL.Query().Get("status")
+	if status != "" && !services.IsValidRemovalStatus(status) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid status"})
+		return
+	}
+
+	requests, err := h.removalRequests.List(status)
+	if err != nil {
+		log.Printf("admin: removal request list failed: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Server error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, requests)
+}
+
+func (h *AdminHandler) handleRemovalRequestGet(w http.ResponseWriter, r *http.Request, idStr string) {
+	if h.removalRequests == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Not found"})
+		return
+	}
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request ID"})
+		return
+	}
+
+	request, err := h.removalRequests.Get(id)
+	if errors.Is(err, services.ErrNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Request not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("admin: removal request lookup failed for id=%d: %v", id, err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Server error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, request)
+}
+
+// handleRemovalRequestUpdate changes a request's status, its admin notes, or
+// both. The optional note is kept with the status change in the history.
+func (h *AdminHandler) handleRemovalRequestUpdate(w http.ResponseWriter, r *http.Request, idStr string) {
+	if h.removalRequests == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Not found"})
+		return
+	}
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request ID"})
+		return
+	}
+
+	var body struct {
+		Status     string  `json:"status"`
+		Note       string  `json:"note"`
+		AdminNotes *string `json:"adminNotes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+	if body.Status == "" && body.AdminNotes == nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Status or admin notes required"})
+		return
+	}
+	if body.Status != "" && !services.IsValidRemovalStatus(body.Status) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid status"})
+		return
+	}
+
+	before, err := h.removalRequests.Get(id)
+	if err == nil && body.AdminNotes != nil {
+		err = h.removalRequests.UpdateNotes(id, strings.TrimSpace(*body.AdminNotes))
+	}
+	if err == nil && body.Status != "" {
+		err = h.removalRequests.Transition(id, body.Status, strings.TrimSpace(body.Note))
+	}
+	switch {
+	case errors.Is(err, services.ErrNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Request not found"})
+		return
+	case errors.Is(err, services.ErrInvalidRemovalTransition):
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		return
+	case err != nil:
+		log.Printf("admin: removal request update failed for id=%d: %v", id, err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Server error"})
+		return
+	}
+
+	after, err := h.removalRequests.Get(id)
+	if err != nil {
+		log.Printf("admin: removal request lookup failed for id=%d: %v", id, err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Server error"})
+		return
+	}
+	h.recordAudit(r, "removal_request", idStr, removalRequestAuditState(before), removalRequestAuditState(after))
+	writeJSON(w, http.StatusOK, after)
+}
+
+func removalRequestAuditState(request *services.RemovalRequest) map[string]string {
+	return map[string]string{"status": request.Status, "adminNotes": request.AdminNotes}
+}
+
+// Search insights handlers
+
+const (
+	defaultSearchInsightDays  = 30
+	maxSearchInsightDays      = 365
+	defaultSearchInsightLimit = 20
+	maxSearchInsightLimit     = 100
+)
+
+func (h *AdminHandler) handleSearchInsights(w http.ResponseWriter, r *http.Request) {
+	if h.searchInsights == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Not found"})
+		return
+	}
+
+	days := defaultSearchInsightDays
+	if value := r.URL.Query().Get("days"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid days"})
+			return
+		}
+		days = min(parsed, maxSearchInsightDays)
+	}
+	limit := defaultSearchInsightLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid limit"})
+			return
+		}
+		limit = min(parsed, maxSearchInsightLimit)
+	}
+
+	since := time.Now().UTC().AddDate(0, 0, -days)
+	insights, err := h.searchInsights.Insights(since, limit)
+	if err != nil {
+		log.Printf("admin: search insights query failed: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Server error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, insights)
+}
+
+// handleSearchInsightRequestCreate turns a search query into a draft source
+// request. Drafts stay off the public board until an admin moves them to
+// requested, and the unique source URL keeps repeated clicks from
+// duplicating one.
+func (h *AdminHandler) handleSearchInsightRequestCreate(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Query        string `json:"query"`
+		Title        string `json:"title"`
+		SubmittedURL string `json:"submittedUrl"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	query := services.NormalizeSearchQuery(body.Query)
+	if query == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Query is required"})
+		return
+	}
+	title := strings.TrimSpace(body.Title)
+	if title == "" {
+		title = query
+	}
+	if len(title) > maxTitleLen {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Title is too long"})
+		return
+	}
+	submittedURL := strings.TrimSpace(body.SubmittedURL)
+	if submittedURL == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Submitted URL is required"})
+		return
+	}
+	if u, err := url.ParseRequestURI(submittedURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Please enter a valid URL"})
+		return
+	}
+	if len(submittedURL) > maxURLLen {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "URL is too long"})
+		return
+	}
+
+	status := services.RequestStatusDraft
+	request, err := h.requests.Create(title, submittedURL, "", []services.Tag{}, &status)
+	if err != nil {
+		log.Printf("admin: failed to create request from search query: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to create request"})
+		return
+	}
+	if request == nil {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "A request for this URL already exists"})
+		return
+	}
+
+	h.recordAudit(r, "request", strconv.FormatInt(request.ID, 10), nil, request)
+	writeJSON(w, http.StatusCreated, request)
+}
+
 // Requests handlers
 
+// handleRequestList lists requests with one status, which is how drafts
+// hidden from the public board are found.
+func (h *AdminHandler) handleRequestList(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if !isValidRequestStatus(status) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid status"})
+		return
+	}
+	requests, err := h.requests.ListByStatus(status)
+	if err != nil {
+		log.Printf("admin: failed to list requests: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to fetch requests"})
+		return
+	}
+	writeJSON(w, http.StatusOK, requests)
+}
+
 func (h *AdminHandler) handleRequestCreate(w http.ResponseWriter, r *http.Request) {
 	var body createRequestBody
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
@@ -240,6 +694,7 @@ func (h *AdminHandler) handleRequestCreate(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	h.recordAudit(r, "request", strconv.FormatInt(request.ID, 10), nil, request)
 	writeJSON(w, http.StatusCreated, request)
 }
 
@@ -260,7 +715,11 @@ func (h *AdminHandler) handleRequestUpdateStatus(w http.ResponseWriter, r *http.
 		return
 	}
 
-	if err := h.requests.UpdateStatus(id, body.Status, body.FolderShareKey); err != nil {
+	before, err := h.requests.Get(id)
+	if err == nil {
+		err = h.requests.UpdateStatus(id, body.Status, body.FolderShareKey)
+	}
+	if err != nil {
 		if errors.Is(err, services.ErrNotFound) {
 			writeJSON(w, http.StatusNotFound, map[string]string{"error": "Request not found"})
 			return
@@ -270,6 +729,7 @@ func (h *AdminHandler) handleRequestUpdateStatus(w http.ResponseWriter, r *http.
 		return
 	}
 
+	h.recordRequestAudit(r, id, before)
 	writeJSON(w, http.StatusOK, map[string]bool{"success": true})
 }
 
@@ -297,7 +757,11 @@ func (h *AdminHandler) handleRequestUpdate(w http.ResponseWriter, r *http.Reques
 		body.Tags = []services.Tag{}
 	}
 
-	if err := h.requests.Update(id, body.Title, body.Tags); err != nil {
+	before, err := h.requests.Get(id)
+	if err == nil {
+		err = h.requests.Update(id, body.Title, body.Tags)
+	}
+	if err != nil {
 		if errors.Is(err, services.ErrNotFound) {
 			writeJSON(w, http.StatusNotFound, map[string]string{"error": "Request not found"})
 			return
@@ -307,6 +771,7 @@ func (h *AdminHandler) handleRequestUpdate(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	h.recordRequestAudit(r, id, before)
 	writeJSON(w, http.StatusOK, map[string]bool{"success": true})
 }
 
@@ -317,7 +782,11 @@ func (h *AdminHandler) handleRequestDelete(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	if err := h.requests.Delete(id); err != nil {
+	before, err := h.requests.Get(id)
+	if err == nil {
+		err = h.requests.Delete(id)
+	}
+	if err != nil {
 		if errors.Is(err, services.ErrNotFound) {
 			writeJSON(w, http.StatusNotFound, map[string]string{"error": "Request not found"})
 			return
@@ -327,5 +796,109 @@ func (h *AdminHandler) handleRequestDelete(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	h.recordAudit(r, "request", idStr, before, nil)
 	writeJSON(w, http.StatusOK, map[string]bool{"success": true})
 }
+
+// recordRequestAudit records a change to a source request, reading its new
+// state back so the entry shows exactly what was stored.
+func (h *AdminHandler) recordRequestAudit(r *http.Request, id int64, before *services.SourceRequest) {
+	if h.auditLog == nil {
+		return
+	}
+	after, err := h.requests.Get(id)
+	if err != nil {
+		log.Printf("admin: audit lookup failed for request id=%d: %v", id, err)
+	}
+	h.recordAudit(r, "request", strconv.FormatInt(id, 10), before, after)
+}
+
+// Audit log handlers
+
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 500
+)
+
+// recordAudit stores an admin mutation. A failure is logged rather than
+// returned, since the change itself has already been made.
+func (h *AdminHandler) recordAudit(r *http.Request, targetType, targetID string, before, after any) {
+	if h.auditLog == nil {
+		return
+	}
+	actor := middleware.APIKeyIdentity(r)
+	if err := h.auditLog.Record(actor, r.Method, r.URL.Path, targetType, targetID, before, after); err != nil {
+		log.Printf("admin: audit record failed for %s %s: %v", r.Method, r.URL.Path, err)
+	}
+}
+
+// auditAudioTime reads a track's timestamp column before it is changed. It
+// is skipped when no audit log is configured.
+func (h *AdminHandler) auditAudioTime(key, column string) any {
+	if h.auditLog == nil {
+		return nil
+	}
+	var value sql.NullTime
+	err := h.db.QueryRow(`
+		SELECT `+column+` FROM audio_files WHERE share_key = $1 AND deleted = 0
+	`, key).Scan(&value)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("admin: audit lookup failed for key=%s: %v", key, err)
+	}
+	if !value.Valid {
+		return nil
+	}
+	return value.Time.UTC()
+}
+
+func (h *AdminHandler) handleAuditList(w http.ResponseWriter, r *http.Request) {
+	if h.auditLog == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Not found"})
+		return
+	}
+
+	values := r.URL.Query()
+	filter := services.AuditFilter{
+		Actor:      strings.TrimSpace(values.Get("actor")),
+		TargetType: strings.TrimSpace(values.Get("targetType")),
+		TargetID:   strings.TrimSpace(values.Get("targetId")),
+		Limit:      defaultAuditLimit,
+	}
+	if value := values.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid limit"})
+			return
+		}
+		filter.Limit = min(parsed, maxAuditLimit)
+	}
+	if value := values.Get("offset"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid offset"})
+			return
+		}
+		filter.Offset = parsed
+	}
+	for name, target := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		value := values.Get(name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid " + name})
+			return
+		}
+		*target = parsed
+	}
+
+	page, err := h.auditLog.List(filter)
+	if err != nil {
+		log.Printf("admin: audit log query failed: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Server error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
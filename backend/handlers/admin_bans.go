@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/onion/audio-share-backend/middleware"
+	"github.com/onion/audio-share-backend/services"
+)
+
+type banManager interface {
+	Create(kind, value, mode, reason, createdBy string, expiresAt *time.Time) (*services.Ban, error)
+	List(includeInactive bool) ([]services.Ban, error)
+	Lift(id int64) (*services.Ban, error)
+}
+
+type banRefresher interface {
+	Refresh() error
+}
+
+// SessionIDResolver returns a function that reads the verified session ID
+// from a request's session cookie, for middleware that cannot depend on this
+// package.
+func SessionIDResolver(sessionSecret string) func(*http.Request) (string, bool) {
+	secret := []byte(sessionSecret)
+	return func(r *http.Request) (string, bool) {
+		return currentSessionID(r, secret)
+	}
+}
+
+func (h *AdminHandler) handleBanList(w http.ResponseWriter, r *http.Request) {
+	if h.bans == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Not found"})
+		return
+	}
+
+	bans, err := h.bans.List(r.URL.Query().Get("all") == "1")
+	if err != nil {
+		log.Printf("admin: ban list failed: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Server error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, bans)
+}
+
+func (h *AdminHandler) handleBanCreate(w http.ResponseWriter, r *http.Request) {
+	if h.bans == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Not found"})
+		return
+	}
+
+	var body struct {
+		Kind      string     `json:"kind"`
+		Value     string     `json:"value"`
+		Mode      string     `json:"mode"`
+		Reason    string     `json:"reason"`
+		ExpiresAt *time.Time `json:"expiresAt"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+	if body.Mode == "" {
+		body.Mode = services.BanModeAll
+	}
+	if body.ExpiresAt != nil && !body.ExpiresAt.After(time.Now()) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Expiry must be in the future"})
+		return
+	}
+
+	ban, err := h.bans.Create(body.Kind, body.Value, body.Mode, body.Reason, middleware.APIKeyIdentity(r), body.ExpiresAt)
+	switch {
+	case errors.Is(err, services.ErrInvalidBanKind):
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Kind must be ip, cidr or session"})
+		return
+	case errors.Is(err, services.ErrInvalidBanValue):
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid value for a " + body.Kind + " ban"})
+		return
+	case errors.Is(err, services.ErrInvalidBanMode):
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Mode must be downloads or all"})
+		return
+	case err != nil:
+		log.Printf("admin: ban create failed: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Server error"})
+		return
+	}
+
+	h.refreshBans()
+	h.recordAudit(r, "ban", strconv.FormatInt(ban.ID, 10), nil, ban)
+	writeJSON(w, http.StatusCreated, ban)
+}
+
+func (h *AdminHandler) handleBanLift(w http.ResponseWriter, r *http.Request, idStr string) {
+	if h.bans == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Not found"})
+		return
+	}
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid ban ID"})
+		return
+	}
+
+	ban, err := h.bans.Lift(id)
+	if err != nil {
+		if errors.Is(err, services.ErrBanNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "Ban not found"})
+			return
+		}
+		log.Printf("admin: ban lift failed for id=%d: %v", id, err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Server error"})
+		return
+	}
+
+	h.refreshBans()
+	h.recordAudit(r, "ban", idStr, map[string]bool{"lifted": false}, map[string]bool{"lifted": true})
+	writeJSON(w, http.StatusOK, ban)
+}
+
+// refreshBans applies a ban change right away instead of at the next
+// periodic refresh.
+func (h *AdminHandler) refreshBans() {
+	if h.banList == nil {
+		return
+	}
+	if err := h.banList.Refresh(); err != nil {
+		log.Printf("admin: ban list refresh failed: %v", err)
+	}
+}
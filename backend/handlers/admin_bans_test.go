@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/onion/audio-share-backend/services"
+)
+
+type stubBans struct {
+	created   []services.Ban
+	refreshes int
+}
+
+func (s *stubBans) Create(kind, value, mode, reason, createdBy string, expiresAt *time.Time) (*services.Ban, error) {
+	value, err := services.NormalizeBanTarget(kind, value)
+	if err != nil {
+		return nil, err
+	}
+	ban := services.Ban{ID: int64(len(s.created) + 1), Kind: kind, Value: value, Mode: mode, Reason: reason, CreatedBy: createdBy}
+	s.created = append(s.created, ban)
+	return &ban, nil
+}
+
+func (s *stubBans) List(includeInactive bool) ([]services.Ban, error) {
+	return s.created, nil
+}
+
+func (s *stubBans) Lift(id int64) (*services.Ban, error) {
+	return nil, services.ErrBanNotFound
+}
+
+func (s *stubBans) Refresh() error {
+	s.refreshes++
+	return nil
+}
+
+func TestAdminBanCreateRefreshesAndAudits(t *testing.T) {
+	bans := &stubBans{}
+	audit := &stubAuditLog{}
+	handler := NewAdminHandler(nil, nil, AdminHandlerOptions{Bans: bans, BanList: bans, AuditLog: audit})
+
+	request := httptest.NewRequest(http.MethodPost, "/api/admin/bans",
+		strings.NewReader(`{"kind":"ip","value":"192.0.2.1","reason":"scraping"}`))
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body = %s", recorder.Code, recorder.Body.String())
+	}
+	if len(bans.created) != 1 || bans.created[0].Mode != services.BanModeAll {
+		t.Fatalf("created = %#v, want an all-access ban by default", bans.created)
+	}
+	if bans.refreshes != 1 {
+		t.Fatalf("refreshes = %d, want 1", bans.refreshes)
+	}
+	if len(audit.records) != 1 || audit.records[0].targetType != "ban" || audit.records[0].targetID != "1" {
+		t.Fatalf("audit = %#v", audit.records)
+	}
+
+	for body, want := range map[string]int{
+		`{"kind":"cidr","value":"192.0.2.1"}`:                                  http.StatusBadRequest,
+		`{"kind":"ip","value":"192.0.2.1","expiresAt":"2000-01-01T00:00:00Z"}`: http.StatusBadRequest,
+	} {
+		request := httptest.NewRequest(http.MethodPost, "/api/admin/bans", strings.NewReader(body))
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		if recorder.Code != want {
+			t.Errorf("%s: status = %d, want %d", body, recorder.Code, want)
+		}
+	}
+
+	request = httptest.NewRequest(http.MethodDelete, "/api/admin/bans/9", nil)
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusNotFound || bans.refreshes != 1 {
+		t.Fatalf("lift unknown ban status = %d, refreshes = %d", recorder.Code, bans.refreshes)
+	}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/onion/audio-share-backend/services"
+)
+
+// adminJob is a background job that can be started through the admin API.
+// Only one manual run of each job is allowed at a time, and jobs that also
+// run on a schedule skip a manual run that would overlap a scheduled one.
+type adminJob struct {
+	run     func() error
+	mu      sync.Mutex
+	running bool
+}
+
+func (j *adminJob) start(name string) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.running {
+		return false
+	}
+	j.running = true
+
+	go func() {
+		defer func() {
+			j.mu.Lock()
+			j.running = false
+			j.mu.Unlock()
+		}()
+		log.Printf("admin: starting %s job", name)
+		if err := j.run(); errors.Is(err, services.ErrJobRunning) {
+			log.Printf("admin: %s job skipped: a scheduled run is in progress", name)
+			return
+		} else if err != nil {
+			log.Printf("admin: %s job failed: %v", name, err)
+			return
+		}
+		log.Printf("admin: %s job finished", name)
+	}()
+	return true
+}
+
+func (j *adminJob) isRunning() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.running
+}
+
+type adminJobStatus struct {
+	Name    string `json:"name"`
+	Running bool   `json:"running"`
+}
+
+func (h *AdminHandler) handleJobList(w http.ResponseWriter, r *http.Request) {
+	statuses := make([]adminJobStatus, 0, len(h.jobs))
+	for name, job := range h.jobs {
+		statuses = append(statuses, adminJobStatus{Name: name, Running: job.isRunning()})
+	}
+	slices.SortFunc(statuses, func(a, b adminJobStatus) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	writeJSON(w, http.StatusOK, statuses)
+}
+
+func (h *AdminHandler) handleJobStart(w http.ResponseWriter, r *http.Request, name string) {
+	job, ok := h.jobs[name]
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Job not found"})
+		return
+	}
+	if !job.start(name) {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "Job is already running"})
+		return
+	}
+
+	h.recordAudit(r, "job", name, nil, map[string]bool{"started": true})
+	writeJSON(w, http.StatusAccepted, adminJobStatus{Name: name, Running: true})
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/onion/audio-share-backend/services"
+)
+
+type adminKeyManager interface {
+	Create(name string, scopes []string, expiresAt *time.Time) (*services.CreatedAdminKey, error)
+	List() ([]services.AdminKey, error)
+	Revoke(id int64) error
+}
+
+// AdminRouteScope returns the API key scope needed for an admin request.
+// Routes that are not listed, such as key management, the audit log and the
+// session inspector, need the owner scope.
+func AdminRouteScope(r *http.Request) string {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/admin/"), "/")
+	section, _, _ := strings.Cut(path, "/")
+	switch section {
+	case "audio", "folders", "removal-requests":
+		return services.AdminScopeAudio
+	case "requests", "search-insights":
+		return services.AdminScopeRequests
+	case "targeted-messages":
+		return services.AdminScopeMessages
+	case "jobs":
+		return services.AdminScopeJobs
+	default:
+		return services.AdminScopeOwner
+	}
+}
+
+func (h *AdminHandler) handleAdminKeyList(w http.ResponseWriter, r *http.Request) {
+	if h.adminKeys == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Not found"})
+		return
+	}
+
+	keys, err := h.adminKeys.List()
+	if err != nil {
+		log.Printf("admin: key list failed: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Server error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, keys)
+}
+
+func (h *AdminHandler) handleAdminKeyCreate(w http.ResponseWriter, r *http.Request) {
+	if h.adminKeys == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Not found"})
+		return
+	}
+
+	var body struct {
+		Name      string     `json:"name"`
+		Scopes    []string   `json:"scopes"`
+		ExpiresAt *time.Time `json:"expiresAt"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+	if body.ExpiresAt != nil && !body.ExpiresAt.After(time.Now()) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Expiry must be in the future"})
+		return
+	}
+
+	key, err := h.adminKeys.Create(body.Name, body.Scopes, body.ExpiresAt)
+	switch {
+	case errors.Is(err, services.ErrInvalidAdminKeyName):
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Name is required and must be at most 64 characters"})
+		return
+	case errors.Is(err, services.ErrInvalidAdminKeyScope):
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "Scopes must be one or more of " + strings.Join(services.AdminScopes, ", "),
+		})
+		return
+	case errors.Is(err, services.ErrAdminKeyNameTaken):
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		return
+	case err != nil:
+		log.Printf("admin: key create failed: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Server error"})
+		return
+	}
+
+	h.recordAudit(r, "admin_key", strconv.FormatInt(key.ID, 10), nil, key.AdminKey)
+	writeJSON(w, http.StatusCreated, key)
+}
+
+func (h *AdminHandler) handleAdminKeyRevoke(w http.ResponseWriter, r *http.Request, idStr string) {
+	if h.adminKeys == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Not found"})
+		return
+	}
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid key ID"})
+		return
+	}
+
+	if err := h.adminKeys.Revoke(id); err != nil {
+		if errors.Is(err, services.ErrAdminKeyNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "Key not found"})
+			return
+		}
+		log.Printf("admin: key revoke failed for id=%d: %v", id, err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Server error"})
+		return
+	}
+
+	h.recordAudit(r, "admin_key", idStr, map[string]bool{"revoked": false}, map[string]bool{"revoked": true})
+	writeJSON(w, http.StatusOK, map[string]bool{"success": true})
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/onion/audio-share-backend/services"
+)
+
+const (
+	defaultScraperDecisionLimit = 100
+	maxScraperDecisionLimit     = 500
+)
+
+type scraperReporter interface {
+	Mode() string
+	Flagged() []services.ScraperAssessment
+	Decisions(limit int) []services.ScraperDecision
+	Assessment(kind, identity string) (services.ScraperAssessment, bool)
+}
+
+// handleScraperDecisions lists the clients scraper detection currently
+// flags and its recent decisions, newest first.
+func (h *AdminHandler) handleScraperDecisions(w http.ResponseWriter, r *http.Request) {
+	if h.scraper == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Not found"})
+		return
+	}
+
+	limit := defaultScraperDecisionLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid limit"})
+			return
+		}
+		limit = min(parsed, maxScraperDecisionLimit)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"mode":      h.scraper.Mode(),
+		"flagged":   h.scraper.Flagged(),
+		"decisions": h.scraper.Decisions(limit),
+	})
+}
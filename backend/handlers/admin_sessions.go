@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/onion/audio-share-backend/services"
+)
+
+const (
+	defaultInspectionDays = 30
+	maxInspectionDays     = 365
+)
+
+type keyIssuanceReporter interface {
+	IssuanceUsage(scope services.KeyLimitScope, identity string) ([]services.KeyIssuanceUsage, error)
+}
+
+// handleSessionInspect shows what a session or client IP has been doing, for
+// abuse investigations. Exactly one of sessionId or ip must be given.
+func (h *AdminHandler) handleSessionInspect(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+	sessionID := strings.TrimSpace(values.Get("sessionId"))
+	clientIP := strings.TrimSpace(values.Get("ip"))
+	if (sessionID == "") == (clientIP == "") {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Provide either sessionId or ip"})
+		return
+	}
+	if clientIP != "" && net.ParseIP(clientIP) == nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid ip"})
+		return
+	}
+
+	days := defaultInspectionDays
+	if value := values.Get("days"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid days"})
+			return
+		}
+		days = min(parsed, maxInspectionDays)
+	}
+	since := time.Now().AddDate(0, 0, -days)
+
+	var inspection *services.SessionInspection
+	var err error
+	if sessionID != "" {
+		inspection, err = services.InspectSession(h.db, sessionID, since)
+	} else {
+		inspection, err = services.InspectClientIP(h.db, clientIP, since)
+	}
+	if err != nil {
+		log.Printf("admin: session inspection failed: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Server error"})
+		return
+	}
+
+	inspection.AccessKeys = []services.KeyIssuanceUsage{}
+	if h.accessKeys != nil {
+		scope, identity := services.KeyLimitScopeSession, sessionID
+		if sessionID == "" {
+			scope, identity = services.KeyLimitScopeIP, clientIP
+		}
+		usage, err := h.accessKeys.IssuanceUsage(scope, identity)
+		if err != nil {
+			log.Printf("admin: access key usage lookup failed: %v", err)
+		} else {
+			inspection.AccessKeys = usage
+		}
+	}
+
+	if h.scraper != nil {
+		kind, identity := services.BanKindSession, sessionID
+		if clientIP != "" {
+			kind, identity = services.BanKindIP, clientIP
+		}
+		if assessment, ok := h.scraper.Assessment(kind, identity); ok {
+			inspection.ScraperAssessment = &assessment
+		}
+	}
+
+	writeJSON(w, http.StatusOK, inspection)
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/onion/audio-share-backend/services"
+)
+
+type stubIssuanceReporter struct {
+	scope    services.KeyLimitScope
+	identity string
+}
+
+func (s *stubIssuanceReporter) IssuanceUsage(scope services.KeyLimitScope, identity string) ([]services.KeyIssuanceUsage, error) {
+	s.scope, s.identity = scope, identity
+	return []services.KeyIssuanceUsage{{
+		Purpose: services.MediaPurposeDownload,
+		Windows: []services.KeyWindowUsage{{Window: "1h0m0s", Issued: 9, Limit: 10}},
+	}}, nil
+}
+
+func TestAdminSessionInspectRequiresOneSubject(t *testing.T) {
+	handler := NewAdminHandler(nil, nil)
+	for _, query := range []string{"", "?sessionId=a&ip=192.0.2.1", "?ip=not-an-ip", "?sessionId=a&days=0"} {
+		request := httptest.NewRequest(http.MethodGet, "/api/admin/sessions"+query, nil)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		if recorder.Code != http.StatusBadRequest {
+			t.Fatalf("query %q status = %d, want 400", query, recorder.Code)
+		}
+	}
+}
+
+func TestAdminSessionInspectIncludesKeyIssuance(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	columns := []string{"key", "label", "count", "bytes"}
+	for range 8 {
+		mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows(columns))
+	}
+	mock.ExpectQuery("ORDER BY de.downloaded_at DESC").
+		WillReturnRows(sqlmock.NewRows([]string{"at", "event_type", "share_key", "title", "session_id", "client_ip", "range", "bytes"}))
+
+	keys := &stubIssuanceReporter{}
+	handler := NewAdminHandler(db, nil, AdminHandlerOptions{AccessKeys: keys})
+	request := httptest.NewRequest(http.MethodGet, "/api/admin/sessions?ip=192.0.2.1&days=7", nil)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", recorder.Code, recorder.Body.String())
+	}
+	if keys.scope != services.KeyLimitScopeIP || keys.identity != "192.0.2.1" {
+		t.Fatalf("issuance lookup = %q %q", keys.scope, keys.identity)
+	}
+	var body services.SessionInspection
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.ClientIP != "192.0.2.1" || len(body.AccessKeys) != 1 || body.AccessKeys[0].Windows[0].Issued != 9 {
+		t.Fatalf("body = %s", recorder.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
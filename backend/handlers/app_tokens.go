@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/onion/audio-share-backend/services"
+)
+
+type appTokenManager interface {
+	Create(string, string) (*services.CreatedAppToken, error)
+	List(string) ([]services.AppToken, error)
+	Revoke(string, int64) error
+}
+
+// AppTokenHandler lets a browser profile create and revoke the app tokens
+// that Subsonic-compatible players use to sign in as that profile.
+type AppTokenHandler struct {
+	tokens        appTokenManager
+	sessionSecret []byte
+}
+
+type appTokensResponse struct {
+	Tokens []services.AppToken `json:"tokens"`
+}
+
+type createAppTokenRequest struct {
+	Name string `json:"name"`
+}
+
+func NewAppTokenHandler(tokens appTokenManager, sessionSecret string) *AppTokenHandler {
+	return &AppTokenHandler{
+		tokens:        tokens,
+		sessionSecret: []byte(sessionSecret),
+	}
+}
+
+func (h *AppTokenHandler) TokensHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		preventProfileCaching(w)
+		switch r.Method {
+		case http.MethodGet:
+			sessionID, ok := currentSessionID(r, h.sessionSecret)
+			if !ok {
+				writeJSON(w, http.StatusOK, appTokensResponse{Tokens: []services.AppToken{}})
+				return
+			}
+			tokens, err := h.tokens.List(sessionID)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to load app tokens"})
+				return
+			}
+			writeJSON(w, http.StatusOK, appTokensResponse{Tokens: tokens})
+		case http.MethodPost:
+			r.Body = http.MaxBytesReader(w, r.Body, 2048)
+			var request createAppTokenRequest
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+				return
+			}
+			sessionID, ok := resolveSessionID(r, h.sessionSecret)
+			if !ok {
+				sessionID = generateSessionID()
+			}
+			token, err := h.tokens.Create(sessionID, request.Name)
+			if errors.Is(err, services.ErrInvalidAppTokenName) {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Name must be between 1 and 64 characters"})
+				return
+			}
+			if errors.Is(err, services.ErrTooManyAppTokens) {
+				writeJSON(w, http.StatusConflict, map[string]string{"error": "Revoke an existing app token before creating another"})
+				return
+			}
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to create app token"})
+				return
+			}
+			setSessionCookie(w, r, h.sessionSecret, sessionID)
+			writeJSON(w, http.StatusCreated, token)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+func (h *AppTokenHandler) TokenItemHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		preventProfileCaching(w)
+		if r.Method != http.MethodDelete {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/api/profile/app-tokens/"), 10, 64)
+		if err != nil || id <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid app token id"})
+			return
+		}
+		sessionID, ok := currentSessionID(r, h.sessionSecret)
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "App token not found"})
+			return
+		}
+		if err := h.tokens.Revoke(sessionID, id); err != nil {
+			if errors.Is(err, services.ErrAppTokenNotFound) {
+				writeJSON(w, http.StatusNotFound, map[string]string{"error": "App token not found"})
+				return
+			}
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to revoke app token"})
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/onion/audio-share-backend/services"
+)
+
+const (
+	atomNamespace  = "http://www.w3.org/2005/Atom"
+	atomFeedLimit  = 50
+	atomFeedSuffix = "new.atom"
+)
+
+type recentAdditionsLister interface {
+	GetRecentAdditions(root string, limit int, includeRemovalRequested bool) ([]services.RecentAddition, error)
+}
+
+// AtomFeedHandler serves /feeds/new.atom and /feeds/{slug}/new.atom, Atom
+// feeds of newly added tracks and folders that link to the SPA pages.
+type AtomFeedHandler struct {
+	recent recentAdditionsLister
+	roots  map[string]services.AudioDirConfig
+	title  string
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"feed"`
+	XMLNS   string      `xml:"xmlns,attr"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomPerson  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomEntry struct {
+	ID        string       `xml:"id"`
+	Title     string       `xml:"title"`
+	Updated   string       `xml:"updated"`
+	Published string       `xml:"published"`
+	Author    *atomPerson  `xml:"author,omitempty"`
+	Category  atomCategory `xml:"category"`
+	Links     []atomLink   `xml:"link"`
+	Summary   string       `xml:"summary,omitempty"`
+}
+
+func NewAtomFeedHandler(recent recentAdditionsLister, roots map[string]services.AudioDirConfig, title string) *AtomFeedHandler {
+	return &AtomFeedHandler{recent: recent, roots: roots, title: title}
+}
+
+func (h *AtomFeedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rest := strings.TrimPrefix(r.URL.Path, "/feeds/")
+	if !strings.HasSuffix(rest, atomFeedSuffix) {
+		http.NotFound(w, r)
+		return
+	}
+	slug := strings.TrimSuffix(strings.TrimSuffix(rest, atomFeedSuffix), "/")
+	title := h.title + " - New additions"
+	if slug != "" {
+		root, ok := h.roots[slug]
+		if !ok || strings.Contains(slug, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		title = h.title + " - New in " + root.Name
+	}
+
+	includeRemovalRequested := isLocalRequest(r)
+	additions, err := h.recent.GetRecentAdditions(slug, atomFeedLimit, includeRemovalRequested)
+	if err != nil {
+		log.Printf("Error loading recent additions for feed %q: %v", r.URL.Path, err)
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+
+	origin := siteOrigin(r)
+	selfURL := origin + r.URL.Path
+	homeURL := origin + "/"
+	if slug != "" {
+		homeURL = origin + "/browse/" + encodePath(slug)
+	}
+
+	// An empty feed still needs an updated timestamp; the Unix epoch keeps
+	// it stable so conditional requests keep matching until something lands.
+	updated := time.Unix(0, 0).UTC()
+	if len(additions) > 0 {
+		updated = additions[0].AddedAt
+	}
+
+	feed := atomFeed{
+		XMLNS:   atomNamespace,
+		ID:      selfURL,
+		Title:   title,
+		Updated: updated.Format(time.RFC3339),
+		Author:  atomPerson{Name: h.title},
+		Links: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: selfURL},
+			{Rel: "alternate", Type: "text/html", Href: homeURL},
+		},
+		Entries: make([]atomEntry, 0, len(additions)),
+	}
+	for _, addition := range additions {
+		feed.Entries = append(feed.Entries, atomFeedEntry(origin, addition))
+	}
+
+	var body bytes.Buffer
+	body.WriteString(xml.Header)
+	encoder := xml.NewEncoder(&body)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(feed); err != nil {
+		log.Printf("Error encoding feed %q: %v", r.URL.Path, err)
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+
+	sum := sha256.Sum256(body.Bytes())
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	if includeRemovalRequested {
+		w.Header().Set("Cache-Control", "private, no-store")
+	} else {
+		w.Header().Set("Cache-Control", "public, max-age=300")
+	}
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", updated.Format(http.TimeFormat))
+	if atomNotModified(r, etag, updated) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	if r.Method == http.MethodHead {
+		return
+	}
+	w.Write(body.Bytes())
+}
+
+func atomFeedEntry(origin string, addition services.RecentAddition) atomEntry {
+	timestamp := addition.AddedAt.Format(time.RFC3339)
+	entry := atomEntry{
+		Title:     addition.Title,
+		Updated:   timestamp,
+		Published: timestamp,
+		Category:  atomCategory{Term: addition.Kind},
+	}
+	if addition.Kind == services.RecentAdditionFolder {
+		link := origin + "/browse/" + encodePath(addition.Path)
+		entry.ID = link
+		entry.Links = []atomLink{{Rel: "alternate", Type: "text/html", Href: link}}
+		if addition.PosterImage != "" {
+			entry.Links = append(entry.Links, atomLink{
+				Rel:  "enclosure",
+				Href: origin + "/api/folder/key/" + url.PathEscape(addition.ShareKey) + "/poster",
+			})
+		}
+		entry.Summary = "New folder: " + addition.Title
+		return entry
+	}
+
+	link := origin + "/share/" + url.PathEscape(addition.ShareKey)
+	entry.ID = link
+	entry.Links = []atomLink{{Rel: "alternate", Type: "text/html", Href: link}}
+	if addition.Artist != "" {
+		entry.Author = &atomPerson{Name: addition.Artist}
+	}
+	if addition.FolderName != "" {
+		entry.Summary = "New in " + addition.FolderName
+	}
+	return entry
+}
+
+// etagMatches reports whether an If-None-Match header names etag, using the
+// weak comparison that conditional GETs call for.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// atomNotModified applies RFC 9110 precedence: If-None-Match wins over
+// If-Modified-Since when both are present.
+func atomNotModified(r *http.Request, etag string, updated time.Time) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		return etagMatches(match, etag)
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	return !updated.Truncate(time.Second).After(since)
+}
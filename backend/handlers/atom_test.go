@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/onion/audio-share-backend/services"
+)
+
+type recentAdditionsStub struct {
+	additions []services.RecentAddition
+	roots     []string
+	includes  []bool
+}
+
+func (s *recentAdditionsStub) GetRecentAdditions(root string, limit int, includeRemovalRequested bool) ([]services.RecentAddition, error) {
+	s.roots = append(s.roots, root)
+	s.includes = append(s.includes, includeRemovalRequested)
+	return s.additions, nil
+}
+
+func newAtomTestHandler(stub *recentAdditionsStub) *AtomFeedHandler {
+	return NewAtomFeedHandler(stub, map[string]services.AudioDirConfig{
+		"podcasts": {Path: "/srv/podcasts", Name: "Podcasts", Slug: "podcasts"},
+	}, "Audio Share")
+}
+
+func remoteAtomRequest(target string) *http.Request {
+	request := httptest.NewRequest(http.MethodGet, "https://example.test"+target, nil)
+	request.RemoteAddr = "203.0.113.5:1234"
+	request.Header.Set("X-Forwarded-Proto", "https")
+	return request
+}
+
+func TestAtomFeedLinksEntriesToSPAPages(t *testing.T) {
+	newest := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+	stub := &recentAdditionsStub{additions: []services.RecentAddition{
+		{Kind: services.RecentAdditionAudio, ShareKey: "track-key", Path: "podcasts/show/ep.mp3", Title: "Episode", Artist: "Host", FolderName: "The Show", AddedAt: newest},
+		{Kind: services.RecentAdditionFolder, ShareKey: "folder-key", Path: "podcasts/new show", Title: "New Show", AddedAt: newest.Add(-time.Hour)},
+	}}
+	recorder := httptest.NewRecorder()
+	newAtomTestHandler(stub).ServeHTTP(recorder, remoteAtomRequest("/feeds/podcasts/new.atom"))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, body=%s", recorder.Code, recorder.Body.String())
+	}
+	if len(stub.roots) != 1 || stub.roots[0] != "podcasts" || stub.includes[0] {
+		t.Fatalf("lister called with roots=%v includes=%v", stub.roots, stub.includes)
+	}
+	if got := recorder.Header().Get("Last-Modified"); got != newest.Format(http.TimeFormat) {
+		t.Errorf("Last-Modified = %q", got)
+	}
+	if recorder.Header().Get("ETag") == "" {
+		t.Error("missing ETag")
+	}
+
+	var feed struct {
+		Title   string `xml:"title"`
+		Updated string `xml:"updated"`
+		Entries []struct {
+			ID      string `xml:"id"`
+			Updated string `xml:"updated"`
+			Links   []struct {
+				Href string `xml:"href,attr"`
+			} `xml:"link"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(recorder.Body.Bytes(), &feed); err != nil {
+		t.Fatalf("decode feed: %v\n%s", err, recorder.Body.String())
+	}
+	if feed.Title != "Audio Share - New in Podcasts" || feed.Updated != "2026-03-04T05:06:07Z" {
+		t.Fatalf("feed = %+v", feed)
+	}
+	if len(feed.Entries) != 2 {
+		t.Fatalf("entries = %+v", feed.Entries)
+	}
+	if feed.Entries[0].Links[0].Href != "https://example.test/share/track-key" {
+		t.Errorf("track link = %+v", feed.Entries[0].Links)
+	}
+	if feed.Entries[1].Links[0].Href != "https://example.test/browse/podcasts/new%20show" ||
+		feed.Entries[1].Updated != "2026-03-04T04:06:07Z" {
+		t.Errorf("folder entry = %+v", feed.Entries[1])
+	}
+}
+
+func TestAtomFeedConditionalGet(t *testing.T) {
+	updated := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+	stub := &recentAdditionsStub{additions: []services.RecentAddition{
+		{Kind: services.RecentAdditionAudio, ShareKey: "track-key", Title: "Episode", AddedAt: updated},
+	}}
+	handler := newAtomTestHandler(stub)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, remoteAtomRequest("/feeds/new.atom"))
+	etag := recorder.Header().Get("ETag")
+
+	request := remoteAtomRequest("/feeds/new.atom")
+	request.Header.Set("If-None-Match", etag)
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusNotModified || recorder.Body.Len() != 0 {
+		t.Fatalf("If-None-Match status = %d body=%q", recorder.Code, recorder.Body.String())
+	}
+
+	request = remoteAtomRequest("/feeds/new.atom")
+	request.Header.Set("If-Modified-Since", updated.Format(http.TimeFormat))
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusNotModified {
+		t.Fatalf("If-Modified-Since status = %d", recorder.Code)
+	}
+
+	request = remoteAtomRequest("/feeds/new.atom")
+	request.Header.Set("If-None-Match", `"stale"`)
+	request.Header.Set("If-Modified-Since", updated.Format(http.TimeFormat))
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("stale ETag status = %d, want 200", recorder.Code)
+	}
+}
+
+func TestAtomFeedUnknownRootIsNotFound(t *testing.T) {
+	stub := &recentAdditionsStub{}
+	for _, target := range []string{"/feeds/missing/new.atom", "/feeds/podcasts/extra/new.atom", "/feeds/new.rss"} {
+		recorder := httptest.NewRecorder()
+		newAtomTestHandler(stub).ServeHTTP(recorder, remoteAtomRequest(target))
+		if recorder.Code != http.StatusNotFound {
+			t.Errorf("%s: status = %d, want 404", target, recorder.Code)
+		}
+	}
+	if len(stub.roots) != 0 {
+		t.Fatalf("lister called for unknown roots: %v", stub.roots)
+	}
+}
@@ -17,6 +17,7 @@ import (
 	"mime"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -40,9 +41,11 @@ type AudioHandler struct {
 	streamIPLimiter        *services.IPBandwidthLimiter
 	downloadIPLimiter      *services.IPBandwidthLimiter
 	captchaVerifier        services.CaptchaVerifier
+	scraperScorer          *services.ScraperScorer
 	captchaEnforcement     string
 	downloadCaptchaMode    string
 	streamClearanceTTL     time.Duration
+	siteTitle              string
 	now                    func() time.Time
 	mimeTypes              map[string]string
 }
@@ -64,9 +67,11 @@ type AudioHandlerOptions struct {
 	StreamIPLimiter        *services.IPBandwidthLimiter
 	DownloadIPLimiter      *services.IPBandwidthLimiter
 	CaptchaVerifier        services.CaptchaVerifier
+	ScraperScorer          *services.ScraperScorer
 	CaptchaEnforcement     string
 	DownloadCaptchaMode    string
 	StreamClearanceTTL     time.Duration
+	SiteTitle              string
 }
 
 func NewAudioHandler(fs *services.FileSystemService, db *sql.DB, options AudioHandlerOptions) *AudioHandler {
@@ -84,9 +89,11 @@ func NewAudioHandler(fs *services.FileSystemService, db *sql.DB, options AudioHa
 		streamIPLimiter:        options.StreamIPLimiter,
 		downloadIPLimiter:      options.DownloadIPLimiter,
 		captchaVerifier:        options.CaptchaVerifier,
+		scraperScorer:          options.ScraperScorer,
 		captchaEnforcement:     options.CaptchaEnforcement,
 		downloadCaptchaMode:    options.DownloadCaptchaMode,
 		streamClearanceTTL:     options.StreamClearanceTTL,
+		siteTitle:              options.SiteTitle,
 		now:                    time.Now,
 		mimeTypes: map[string]string{
 			".mp3":  "audio/mpeg",
@@ -108,7 +115,7 @@ func NewAudioHandler(fs *services.FileSystemService, db *sql.DB, options AudioHa
 func (h *AudioHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("X-Robots-Tag", "noindex, nofollow, noarchive")
 
-	// Path format: /api/audio/key/{key}[/thumbnail|/meta|/waveform|/download|/access]
+	// Path format: /api/audio/key/{key}[/thumbnail|/meta|/waveform[.svg|.png]|/og.png|/transcript|/download|/access|/enclosure|/play]
 	path := strings.TrimPrefix(r.URL.Path, "/api/audio/key/")
 	path = strings.Trim(path, "/")
 
@@ -122,12 +129,30 @@ func (h *AudioHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	} else if strings.HasSuffix(path, "/meta") {
 		key = strings.TrimSuffix(path, "/meta")
 		action = "meta"
+	} else if strings.HasSuffix(path, "/transcript") {
+		key = strings.TrimSuffix(path, "/transcript")
+		action = "transcript"
 	} else if strings.HasSuffix(path, "/waveform") {
 		key = strings.TrimSuffix(path, "/waveform")
 		action = "waveform"
 	} else if strings.HasSuffix(path, "/download") {
 		key = strings.TrimSuffix(path, "/download")
 		action = "download"
+	} else if strings.HasSuffix(path, "/enclosure") {
+		key = strings.TrimSuffix(path, "/enclosure")
+		action = "enclosure"
+	} else if strings.HasSuffix(path, "/waveform.svg") {
+		key = strings.TrimSuffix(path, "/waveform.svg")
+		action = "waveform.svg"
+	} else if strings.HasSuffix(path, "/waveform.png") {
+		key = strings.TrimSuffix(path, "/waveform.png")
+		action = "waveform.png"
+	} else if strings.HasSuffix(path, "/og.png") {
+		key = strings.TrimSuffix(path, "/og.png")
+		action = "og"
+	} else if strings.HasSuffix(path, "/play") {
+		key = strings.TrimSuffix(path, "/play")
+		action = "play"
 	} else {
 		key = path
 		action = "stream"
@@ -145,12 +170,24 @@ func (h *AudioHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		h.handleStream(w, r, key, false)
 	case "download":
 		h.handleStream(w, r, key, true)
+	case "enclosure":
+		h.handleEnclosure(w, r, key)
+	case "play":
+		h.handlePlaylistEntry(w, r, key)
 	case "thumbnail":
 		h.handleThumbnail(w, r, key)
 	case "meta":
 		h.handleMeta(w, r, key)
+	case "transcript":
+		h.handleTranscript(w, r, key)
 	case "waveform":
 		h.handleWaveform(w, r, key)
+	case "waveform.svg":
+		h.handleWaveformImage(w, r, key, "svg")
+	case "waveform.png":
+		h.handleWaveformImage(w, r, key, "png")
+	case "og":
+		h.handleOGImage(w, r, key)
 	default:
 		http.Error(w, "Not found", http.StatusNotFound)
 	}
@@ -231,62 +268,43 @@ func (h *AudioHandler) handleAccessKey(w http.ResponseWriter, r *http.Request, k
 
 	clientAddress := clientIP(r)
 	if err := h.accessKeys.CheckLimit(sessionID, clientAddress, request.Purpose); err != nil {
-		if !h.writeKeyLimitError(w, request.Purpose, err) {
+		if !writeKeyLimitError(w, request.Purpose, err) {
 			log.Printf("Error checking %s access key limit: %v", request.Purpose, err)
 			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
 		}
 		return
 	}
 
-	captchaCleared := h.captchaEnforcement == "" || h.captchaEnforcement == "off"
-	if request.Purpose == services.MediaPurposeDownload && h.downloadCaptchaMode != "always" {
-		captchaCleared = true
-	}
-	if request.Purpose == services.MediaPurposeStream &&
-		streamCaptchaClearanceEnabled(r, h.sessionSecret, sessionID, now) {
-		captchaCleared = true
-	}
-
-	var issued services.IssuedAccessKey
-	if captchaCleared {
-		issued, err = h.accessKeys.IssueCaptchaCleared(
-			sessionID,
-			clientAddress,
-			key,
-			request.Purpose,
-		)
-	} else if request.Purpose == services.MediaPurposeStream {
-		issued, err = h.accessKeys.Issue(sessionID, clientAddress, key, request.Purpose)
-	} else {
-		err = services.ErrCaptchaRequired
-	}
+	streamCleared := request.Purpose == services.MediaPurposeStream &&
+		streamCaptchaClearanceEnabled(r, h.sessionSecret, sessionID, now)
+	createdAt, _ := sessionCreatedAt(r, h.sessionSecret, sessionID)
+	issued, err := h.issueMediaAccessKey(sessionID, clientAddress, key, request.Purpose, streamCleared, createdAt)
 
 	captchaVerified := false
 	if errors.Is(err, services.ErrCaptchaRequired) {
-		if h.captchaEnforcement == "observe" {
-			log.Printf("Cap observation: challenge would be required for purpose=%s", request.Purpose)
-		} else {
-			if request.CapToken == "" {
-				writeJSON(w, http.StatusForbidden, map[string]interface{}{
-					"error":   "captcha_required",
-					"purpose": request.Purpose,
-				})
-				return
-			}
-			if h.captchaVerifier == nil {
+		if request.CapToken == "" {
+			writeJSON(w, http.StatusForbidden, map[string]interface{}{
+				"error":   "captcha_required",
+				"purpose": request.Purpose,
+			})
+			return
+		}
+		if h.captchaVerifier == nil {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "captcha_unavailable"})
+			return
+		}
+		if verifyErr := h.captchaVerifier.Verify(r.Context(), request.CapToken); verifyErr != nil {
+			if errors.Is(verifyErr, services.ErrCaptchaInvalid) {
+				writeJSON(w, http.StatusForbidden, map[string]string{"error": "captcha_invalid"})
+			} else {
+				w.Header().Set("Retry-After", "5")
 				writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "captcha_unavailable"})
-				return
-			}
-			if verifyErr := h.captchaVerifier.Verify(r.Context(), request.CapToken); verifyErr != nil {
-				if errors.Is(verifyErr, services.ErrCaptchaInvalid) {
-					writeJSON(w, http.StatusForbidden, map[string]string{"error": "captcha_invalid"})
-				} else {
-					w.Header().Set("Retry-After", "5")
-					writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "captcha_unavailable"})
-				}
-				return
 			}
-			captchaVerified = true
+			return
+		}
+		captchaVerified = true
+		if h.scraperScorer != nil {
+			h.scraperScorer.CaptchaSolved(sessionID, clientAddress)
 		}
 		issued, err = h.accessKeys.IssueCaptchaCleared(
 			sessionID,
@@ -294,10 +312,20 @@ func (h *AudioHandler) handleAccessKey(w http.ResponseWriter, r *http.Request, k
 			key,
 			request.Purpose,
 		)
+		if err == nil && h.scraperScorer != nil {
+			h.scraperScorer.ObserveIssuance(sessionID, clientAddress, createdAt)
+		}
 	}
 
+	if errors.Is(err, services.ErrScraperBanned) {
+		writeJSON(w, http.StatusForbidden, map[string]interface{}{
+			"error":   "temporarily_banned",
+			"purpose": request.Purpose,
+		})
+		return
+	}
 	if err != nil {
-		if h.writeKeyLimitError(w, request.Purpose, err) {
+		if writeKeyLimitError(w, request.Purpose, err) {
 			return
 		}
 		log.Printf("Error issuing %s access key for share_key=%s: %v", request.Purpose, key, err)
@@ -324,7 +352,64 @@ func (h *AudioHandler) handleAccessKey(w http.ResponseWriter, r *http.Request, k
 	writeJSON(w, http.StatusOK, response)
 }
 
-func (h *AudioHandler) writeKeyLimitError(
+// issueMediaAccessKey applies the captcha policy to a key issuance. It
+// returns ErrCaptchaRequired only when enforcement demands a challenge;
+// in observe mode the would-be challenge is logged and the key is issued.
+// When scraper detection is enforced, a flagged client must solve a captcha
+// and a banned one gets ErrScraperBanned.
+func (h *AudioHandler) issueMediaAccessKey(
+	sessionID, clientAddress, key string,
+	purpose services.MediaPurpose,
+	streamCleared bool,
+	sessionCreatedAt time.Time,
+) (services.IssuedAccessKey, error) {
+	if h.scraperScorer != nil && h.scraperScorer.Enforcing() {
+		assessment := h.scraperScorer.Assess(sessionID, clientAddress)
+		if assessment.Action.AtLeast(services.ScraperActionBan) {
+			return services.IssuedAccessKey{}, services.ErrScraperBanned
+		}
+		if assessment.Action.AtLeast(services.ScraperActionCaptcha) && !assessment.CaptchaCleared &&
+			h.captchaVerifier != nil {
+			return services.IssuedAccessKey{}, services.ErrCaptchaRequired
+		}
+	}
+	issued, err := h.issueMediaAccessKeyWithCaptchaPolicy(sessionID, clientAddress, key, purpose, streamCleared)
+	if err == nil && h.scraperScorer != nil {
+		h.scraperScorer.ObserveIssuance(sessionID, clientAddress, sessionCreatedAt)
+	}
+	return issued, err
+}
+
+func (h *AudioHandler) issueMediaAccessKeyWithCaptchaPolicy(
+	sessionID, clientAddress, key string,
+	purpose services.MediaPurpose,
+	streamCleared bool,
+) (services.IssuedAccessKey, error) {
+	captchaCleared := h.captchaEnforcement == "" || h.captchaEnforcement == "off"
+	if purpose == services.MediaPurposeDownload && h.downloadCaptchaMode != "always" {
+		captchaCleared = true
+	}
+	if purpose == services.MediaPurposeStream && streamCleared {
+		captchaCleared = true
+	}
+
+	var issued services.IssuedAccessKey
+	var err error
+	if captchaCleared {
+		issued, err = h.accessKeys.IssueCaptchaCleared(sessionID, clientAddress, key, purpose)
+	} else if purpose == services.MediaPurposeStream {
+		issued, err = h.accessKeys.Issue(sessionID, clientAddress, key, purpose)
+	} else {
+		err = services.ErrCaptchaRequired
+	}
+	if errors.Is(err, services.ErrCaptchaRequired) && h.captchaEnforcement == "observe" {
+		log.Printf("Cap observation: challenge would be required for purpose=%s", purpose)
+		return h.accessKeys.IssueCaptchaCleared(sessionID, clientAddress, key, purpose)
+	}
+	return issued, err
+}
+
+func writeKeyLimitError(
 	w http.ResponseWriter,
 	purpose services.MediaPurpose,
 	err error,
@@ -360,6 +445,8 @@ type audioRow struct {
 	description        sql.NullString
 	ageLimit           sql.NullInt64
 	parentPath         sql.NullString
+	placeholder        *services.ArtworkPlaceholder
+	chapters           services.Chapters
 }
 
 func (h *AudioHandler) lookupByKey(key string) (*audioRow, error) {
@@ -371,12 +458,14 @@ func lookupAudioByKey(db *sql.DB, key string) (*audioRow, error) {
 	var deletedInt int
 	err := db.QueryRow(`
 		SELECT id, path, deleted, unavailable_at, removal_requested_at, thumbnail, title, meta_artist, upload_date,
-		       webpage_url, description, age_limit, parent_path
+		       webpage_url, description, age_limit, parent_path, `+services.ThumbnailPlaceholderColumn("audio_files")+`,
+		       chapters
 		FROM audio_files WHERE share_key = $1
 	`, key).Scan(
 		&row.id, &row.path, &deletedInt, &row.unavailableAt, &row.removalRequestedAt,
 		&row.thumbnail, &row.title, &row.artist,
 		&row.uploadDate, &row.webpageURL, &row.description, &row.ageLimit, &row.parentPath,
+		&row.placeholder, &row.chapters,
 	)
 	if err != nil {
 		return nil, err
@@ -474,6 +563,164 @@ func (h *AudioHandler) handleStream(w http.ResponseWriter, r *http.Request, key
 		return
 	}
 
+	h.serveMedia(w, r, row, key, download, verifiedAccess.Nonce, sessionID, clientAddress)
+}
+
+// handleEnclosure serves a podcast feed enclosure. It is authorized by the
+// feed key in the enclosure URL instead of a browser session, and each GET,
+// ranged or not, counts against the feed limits.
+func (h *AudioHandler) handleEnclosure(w http.ResponseWriter, r *http.Request, key string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method_not_allowed"})
+		return
+	}
+	clientAddress := clientIP(r)
+	if h.accessFailureLimiter != nil {
+		allowed, retryAfter := h.accessFailureLimiter.AllowAccessAttempt(clientAddress)
+		if !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			writeJSON(w, http.StatusTooManyRequests, map[string]string{"error": "too_many_invalid_access_attempts"})
+			return
+		}
+	}
+	if h.accessKeys == nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "access_keys_unavailable"})
+		return
+	}
+	feedKey := r.URL.Query().Get("feed")
+	rejectAccess := func(err error) {
+		if h.accessFailureLimiter != nil {
+			h.accessFailureLimiter.RecordAccessFailure(clientAddress)
+		}
+		errorCode := "invalid_access_key"
+		if errors.Is(err, services.ErrExpiredAccessKey) {
+			errorCode = "expired_access_key"
+		}
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": errorCode})
+	}
+	if _, err := h.accessKeys.VerifyFeedKey(r.URL.Query().Get("token"), feedKey); err != nil {
+		rejectAccess(err)
+		return
+	}
+
+	row, err := h.lookupByKey(key)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+	var folderPath string
+	err = h.db.QueryRow(`SELECT path FROM folders WHERE share_key = $1`, feedKey).Scan(&folderPath)
+	if err != nil && err != sql.ErrNoRows {
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+	if err == sql.ErrNoRows || !row.parentPath.Valid || row.parentPath.String != folderPath {
+		rejectAccess(services.ErrInvalidAccessKey)
+		return
+	}
+	if row.deleted {
+		http.Error(w, "Gone", http.StatusGone)
+		return
+	}
+	if row.removalRestricted(r) {
+		writeJSON(w, http.StatusGone, map[string]string{"error": "removal_requested"})
+		return
+	}
+
+	// Every GET opens a new response, so each one is charged; otherwise a
+	// Range request starting past byte zero would fetch the file for free.
+	if r.Method == http.MethodGet {
+		if err := h.accessKeys.RecordFeedAccess(feedKey, clientAddress); err != nil {
+			if !writeKeyLimitError(w, services.MediaPurposeFeed, err) {
+				log.Printf("Error recording feed access for share_key=%s: %v", key, err)
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+			}
+			return
+		}
+	}
+	h.serveMedia(w, r, row, key, false, generateSessionID(), "", clientAddress)
+}
+
+// handlePlaylistEntry serves one entry of an exported M3U8 or XSPF
+// playlist. Like enclosures it is authorized by a signed key in the URL
+// rather than a browser session, and each GET counts against the playlist
+// limits.
+func (h *AudioHandler) handlePlaylistEntry(w http.ResponseWriter, r *http.Request, key string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method_not_allowed"})
+		return
+	}
+	clientAddress := clientIP(r)
+	if h.accessFailureLimiter != nil {
+		allowed, retryAfter := h.accessFailureLimiter.AllowAccessAttempt(clientAddress)
+		if !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			writeJSON(w, http.StatusTooManyRequests, map[string]string{"error": "too_many_invalid_access_attempts"})
+			return
+		}
+	}
+	if h.accessKeys == nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "access_keys_unavailable"})
+		return
+	}
+	playlistID := r.URL.Query().Get("list")
+	if _, err := h.accessKeys.VerifyPlaylistKey(r.URL.Query().Get("token"), playlistID, key); err != nil {
+		if h.accessFailureLimiter != nil {
+			h.accessFailureLimiter.RecordAccessFailure(clientAddress)
+		}
+		errorCode := "invalid_access_key"
+		if errors.Is(err, services.ErrExpiredAccessKey) {
+			errorCode = "expired_access_key"
+		}
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": errorCode})
+		return
+	}
+
+	row, err := h.lookupByKey(key)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+	if row.deleted {
+		http.Error(w, "Gone", http.StatusGone)
+		return
+	}
+	if row.removalRestricted(r) {
+		writeJSON(w, http.StatusGone, map[string]string{"error": "removal_requested"})
+		return
+	}
+
+	// Charged on every GET for the same reason as enclosures.
+	if r.Method == http.MethodGet {
+		if err := h.accessKeys.RecordPlaylistAccess(playlistID, clientAddress); err != nil {
+			if !writeKeyLimitError(w, services.MediaPurposePlaylist, err) {
+				log.Printf("Error recording playlist access for share_key=%s: %v", key, err)
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+			}
+			return
+		}
+	}
+	h.serveMedia(w, r, row, key, false, generateSessionID(), "", clientAddress)
+}
+
+// serveMedia sends an authorized audio file through the configured
+// bandwidth throttles and records the stream or download event.
+func (h *AudioHandler) serveMedia(
+	w http.ResponseWriter,
+	r *http.Request,
+	row *audioRow,
+	key string,
+	download bool,
+	accessKeyNonce, sessionID, clientAddress string,
+) {
 	fullPath, valid := h.resolveFullPath(row.path)
 	if !valid {
 		http.Error(w, "Not found", http.StatusNotFound)
@@ -512,12 +759,26 @@ func (h *AudioHandler) handleStream(w http.ResponseWriter, r *http.Request, key
 		if download {
 			eventType = "download"
 		}
-		h.recordMediaEvent(r, row.id, key, eventType, verifiedAccess.Nonce, info.Size())
+		h.recordMediaEvent(r, row.id, key, eventType, accessKeyNonce, sessionID, info.Size())
+	}
+	// Enclosures and playlist entries have no session and are scored and
+	// throttled by client IP alone.
+	if r.Method == http.MethodGet && h.scraperScorer != nil {
+		h.scraperScorer.ObserveMedia(sessionID, clientAddress, row.id, download,
+			sessionID != "" && !strings.HasPrefix(r.URL.Path, "/rest/"),
+			estimateRequestedBytes(r.Header.Get("Range"), info.Size()), info.Size())
 	}
 
+	bytesPerSecond := h.bytesPerSecond(download)
+	if h.scraperScorer != nil {
+		if limit := h.scraperScorer.ThrottleBytesPerSecond(sessionID, clientAddress); limit > 0 &&
+			(bytesPerSecond <= 0 || limit < bytesPerSecond) {
+			bytesPerSecond = limit
+		}
+	}
 	reader := newThrottledReadSeeker(
 		file,
-		h.bytesPerSecond(download),
+		bytesPerSecond,
 		h.burstBytes(download),
 		h.ipLimiter(download),
 		clientAddress,
@@ -596,14 +857,10 @@ func (h *AudioHandler) recordMediaEvent(
 	audioFileID int64,
 	shareKey,
 	eventType,
-	accessKeyNonce string,
+	accessKeyNonce,
+	sessionID string,
 	fileSize int64,
 ) {
-	sessionID := ""
-	if id, ok := currentSessionID(r, h.sessionSecret); ok {
-		sessionID = id
-	}
-
 	requestedBytes := estimateRequestedBytes(r.Header.Get("Range"), fileSize)
 	_, err := h.db.Exec(`
 		INSERT INTO download_events (
@@ -729,17 +986,13 @@ func (h *AudioHandler) handleThumbnail(w http.ResponseWriter, r *http.Request, k
 		http.Error(w, "No thumbnail", http.StatusNotFound)
 		return
 	}
-
-	parts := strings.SplitN(row.path, "/", 2)
-	if len(parts) < 2 {
-		http.Error(w, "Invalid path", http.StatusInternalServerError)
+	variant, err := parseImageVariant(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	slug := parts[0]
-	dir := filepath.Dir(parts[1])
-	thumbRelPath := filepath.Join(dir, row.thumbnail.String)
 
-	fullPath, valid := h.fs.ValidatePath(slug, thumbRelPath)
+	fullPath, valid := h.thumbnailFullPath(row)
 	if !valid {
 		http.Error(w, "Not found", http.StatusNotFound)
 		return
@@ -761,7 +1014,11 @@ func (h *AudioHandler) handleThumbnail(w http.ResponseWriter, r *http.Request, k
 	}
 
 	if row.isMature() && view == "blurred" {
-		h.serveBlurredThumbnail(w, r, key, fullPath, info, row.removalRequestedAt.Valid)
+		cacheControl := "private, max-age=86400"
+		if row.removalRequestedAt.Valid {
+			cacheControl = "private, no-store"
+		}
+		serveBlurredArtwork(w, r, key, fullPath, info, cacheControl)
 		return
 	}
 	if row.isMature() && view == "original" && !maturePreferenceEnabled(r, h.sessionSecret) {
@@ -769,6 +1026,15 @@ func (h *AudioHandler) handleThumbnail(w http.ResponseWriter, r *http.Request, k
 		return
 	}
 
+	if !variant.isOriginal() {
+		cacheControl := "private, max-age=86400"
+		if row.removalRequestedAt.Valid {
+			cacheControl = "private, no-store"
+		}
+		serveImageVariant(w, r, "audio-"+key, fullPath, info, variant, cacheControl)
+		return
+	}
+
 	ext := strings.ToLower(filepath.Ext(fullPath))
 	contentType := h.mimeTypes[ext]
 	if contentType == "" {
@@ -792,24 +1058,36 @@ func (h *AudioHandler) handleThumbnail(w http.ResponseWriter, r *http.Request, k
 	http.ServeContent(w, r, info.Name(), info.ModTime(), file)
 }
 
-func (h *AudioHandler) serveBlurredThumbnail(
+// thumbnailFullPath resolves a track's thumbnail, which sits next to the
+// audio file, to a validated path on disk.
+func (h *AudioHandler) thumbnailFullPath(row *audioRow) (string, bool) {
+	if !row.thumbnail.Valid || row.thumbnail.String == "" || h.fs == nil {
+		return "", false
+	}
+	parts := strings.SplitN(row.path, "/", 2)
+	if len(parts) < 2 {
+		return "", false
+	}
+	return h.fs.ValidatePath(parts[0], filepath.Join(filepath.Dir(parts[1]), row.thumbnail.String))
+}
+
+// serveBlurredArtwork serves the blurred rendition of mature artwork,
+// falling back to a placeholder pattern when the image cannot be decoded.
+// cacheName must be unique to the artwork across tracks and folders.
+func serveBlurredArtwork(
 	w http.ResponseWriter,
 	r *http.Request,
-	key, fullPath string,
+	cacheName, fullPath string,
 	info os.FileInfo,
-	removalRequested bool,
+	cacheControl string,
 ) {
-	cacheControl := "private, max-age=86400"
-	if removalRequested {
-		cacheControl = "private, no-store"
-	}
 	cacheDir := filepath.Join(os.TempDir(), "audio-share-mature-thumbnails")
 	if err := os.MkdirAll(cacheDir, 0700); err != nil {
 		http.Error(w, "Error preparing thumbnail", http.StatusInternalServerError)
 		return
 	}
 
-	cacheName := fmt.Sprintf("%s-blur-v1-%d-%d.jpg", key, info.ModTime().Unix(), info.Size())
+	cacheName = fmt.Sprintf("%s-blur-v1-%d-%d.jpg", cacheName, info.ModTime().Unix(), info.Size())
 	cachePath := filepath.Join(cacheDir, cacheName)
 	if cachedInfo, err := os.Stat(cachePath); err == nil && !cachedInfo.IsDir() {
 		file, err := os.Open(cachePath)
@@ -857,12 +1135,7 @@ func generateBlurredThumbnail(srcPath, dstPath string) error {
 	if err != nil {
 		return err
 	}
-
-	bounds := src.Bounds()
-	width, height := scaledDimensions(bounds.Dx(), bounds.Dy(), 360)
-	scaled := resizeNearest(src, width, height)
-	blurred := boxBlur(scaled, 14, 3)
-	return writeJPEG(dstPath, blurred)
+	return writeJPEG(dstPath, blurredArtwork(src))
 }
 
 func generateMaturePlaceholder(dstPath string) error {
@@ -983,11 +1256,11 @@ func scaledDimensions(width, height, maxSide int) (int, int) {
 }
 
 func writeJPEG(path string, img image.Image) error {
-	tmp := path + ".tmp"
-	file, err := os.Create(tmp)
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
 	if err != nil {
 		return err
 	}
+	tmp := file.Name()
 	if err := jpeg.Encode(file, img, &jpeg.Options{Quality: 72}); err != nil {
 		file.Close()
 		os.Remove(tmp)
@@ -997,24 +1270,30 @@ func writeJPEG(path string, img image.Image) error {
 		os.Remove(tmp)
 		return err
 	}
-	return os.Rename(tmp, path)
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
 }
 
 type AudioMeta struct {
-	Title              string  `json:"title"`
-	Artist             string  `json:"artist"`
-	UploadDate         string  `json:"uploadDate"`
-	WebpageURL         string  `json:"webpageUrl"`
-	Description        string  `json:"description"`
-	ParentPath         string  `json:"parentPath"`
-	Thumbnail          bool    `json:"thumbnail"`
-	Deleted            bool    `json:"deleted"`
-	UnavailableAt      *string `json:"unavailableAt"`
-	RemovalRequestedAt *string `json:"removalRequestedAt"`
-	LocalAccess        bool    `json:"localAccess"`
-	AgeLimit           *int    `json:"ageLimit,omitempty"`
-	IsMature           bool    `json:"isMature"`
-	ShowMature         bool    `json:"showMature"`
+	Title              string                       `json:"title"`
+	Artist             string                       `json:"artist"`
+	UploadDate         string                       `json:"uploadDate"`
+	WebpageURL         string                       `json:"webpageUrl"`
+	Description        string                       `json:"description"`
+	Chapters           []services.Chapter           `json:"chapters,omitempty"`
+	ParentPath         string                       `json:"parentPath"`
+	Thumbnail          bool                         `json:"thumbnail"`
+	Placeholder        *services.ArtworkPlaceholder `json:"placeholder,omitempty"`
+	Deleted            bool                         `json:"deleted"`
+	UnavailableAt      *string                      `json:"unavailableAt"`
+	RemovalRequestedAt *string                      `json:"removalRequestedAt"`
+	LocalAccess        bool                         `json:"localAccess"`
+	AgeLimit           *int                         `json:"ageLimit,omitempty"`
+	IsMature           bool                         `json:"isMature"`
+	ShowMature         bool                         `json:"showMature"`
 }
 
 func (h *AudioHandler) handleMeta(w http.ResponseWriter, r *http.Request, key string) {
@@ -1048,6 +1327,9 @@ func audioMetaFromRow(r *http.Request, sessionSecret []byte, row *audioRow) Audi
 		return meta
 	}
 	meta.Thumbnail = row.thumbnail.Valid && row.thumbnail.String != ""
+	if meta.Thumbnail {
+		meta.Placeholder = row.placeholder
+	}
 	meta.IsMature = row.isMature()
 	meta.ShowMature = maturePreferenceEnabled(r, sessionSecret)
 	if row.ageLimit.Valid {
@@ -1073,12 +1355,49 @@ func audioMetaFromRow(r *http.Request, sessionSecret []byte, row *audioRow) Audi
 	if row.description.Valid {
 		meta.Description = row.description.String
 	}
+	meta.Chapters = row.chapters
 	if row.parentPath.Valid {
 		meta.ParentPath = row.parentPath.String
 	}
 	return meta
 }
 
+// handleTranscript serves the subtitle and lyrics cues indexed for a track,
+// optionally filtered with ?lang=.
+func (h *AudioHandler) handleTranscript(w http.ResponseWriter, r *http.Request, key string) {
+	row, err := h.lookupByKey(key)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+	if row.deleted {
+		http.Error(w, "Gone", http.StatusGone)
+		return
+	}
+	if row.removalRequestedAt.Valid {
+		w.Header().Set("Cache-Control", "private, no-store")
+	}
+	if row.removalRestricted(r) {
+		writeJSON(w, http.StatusGone, map[string]string{"error": "removal_requested"})
+		return
+	}
+
+	transcripts, err := services.AudioTranscripts(h.db, row.id, r.URL.Query().Get("lang"))
+	if err != nil {
+		log.Printf("Error loading transcripts for share_key=%s: %v", key, err)
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+	if !row.removalRequestedAt.Valid {
+		w.Header().Set("Cache-Control", "private, max-age=3600")
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"transcripts": transcripts})
+}
+
 func (h *AudioHandler) handleWaveform(w http.ResponseWriter, r *http.Request, key string) {
 	var fileID int64
 	var removalRequestedAt sql.NullTime
@@ -1132,30 +1451,68 @@ type BrowseHandler struct {
 }
 
 type directoryBrowser interface {
-	BrowseDirectory(string) (*services.DirectoryContents, error)
+	BrowseDirectory(string, services.BrowseOptions) (*services.DirectoryContents, error)
+}
+
+const maxBrowseLimit = 500
+
+var browseSortValues = map[string]bool{
+	"name_asc": true, "name_desc": true,
+	"date_asc": true, "date_desc": true,
+	"duration_asc": true, "duration_desc": true,
+	"size_asc": true, "size_desc": true,
+	"popularity_asc": true, "popularity_desc": true,
+}
+
+func browseOptionsForValues(values url.Values, includeRemovalRequested bool) services.BrowseOptions {
+	opts := services.BrowseOptions{IncludeRemovalRequested: includeRemovalRequested}
+	if value := values.Get("sort"); browseSortValues[value] {
+		opts.Sort = value
+	}
+	if value := values.Get("type"); value == "audio" || value == "folder" {
+		opts.Type = value
+	}
+	opts.Filter = strings.TrimSpace(values.Get("q"))
+	if limitStr := values.Get("limit"); limitStr != "" {
+		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 {
+			opts.Limit = min(parsed, maxBrowseLimit)
+		}
+	}
+	if offsetStr := values.Get("offset"); offsetStr != "" {
+		if parsed, err := strconv.Atoi(offsetStr); err == nil && parsed >= 0 {
+			opts.Offset = parsed
+		}
+	}
+	return opts
 }
 
 func browseDirectoryContents(search directoryBrowser, path string) (*services.DirectoryContents, error) {
-	return browseDirectoryContentsForAccess(search, path, true)
+	return browseDirectoryContentsForAccess(search, path, services.BrowseOptions{IncludeRemovalRequested: true})
 }
 
-func browseDirectoryContentsForAccess(search directoryBrowser, path string, includeRemovalRequested bool) (*services.DirectoryContents, error) {
-	contents, err := search.BrowseDirectory(path)
+// browseDirectoryContentsForAccess skips through chains of single-folder
+// directories. Filtered listings are returned as-is because a lone match
+// says nothing about the directory's real shape.
+func browseDirectoryContentsForAccess(search directoryBrowser, path string, opts services.BrowseOptions) (*services.DirectoryContents, error) {
+	contents, err := search.BrowseDirectory(path, opts)
 	if err != nil {
 		return nil, err
 	}
-	contents = visibleDirectoryContents(contents, includeRemovalRequested)
+	contents = visibleDirectoryContents(contents, opts.IncludeRemovalRequested)
+	if opts.Type != "" || opts.Filter != "" {
+		return contents, nil
+	}
 
 	const maxSkipDepth = 20
 	for i := 0; i < maxSkipDepth; i++ {
-		if len(contents.Items) != 1 || contents.Items[0].Type != "folder" {
+		if len(contents.Items) != 1 || contents.Items[0].Type != "folder" || contents.Total > 1 {
 			break
 		}
-		next, err := search.BrowseDirectory(contents.Items[0].Path)
+		next, err := search.BrowseDirectory(contents.Items[0].Path, opts)
 		if err != nil {
 			break
 		}
-		contents = visibleDirectoryContents(next, includeRemovalRequested)
+		contents = visibleDirectoryContents(next, opts.IncludeRemovalRequested)
 	}
 	return contents, nil
 }
@@ -1167,9 +1524,15 @@ func visibleDirectoryContents(contents *services.DirectoryContents, includeRemov
 	filtered := &services.DirectoryContents{
 		CurrentPath: contents.CurrentPath,
 		Items:       make([]services.FileSystemItem, 0, len(contents.Items)),
+		Total:       contents.Total,
+		Offset:      contents.Offset,
+		Limit:       contents.Limit,
 	}
 	for _, item := range contents.Items {
 		if item.Type == "audio" && item.RemovalRequestedAt != nil {
+			if filtered.Total > 0 {
+				filtered.Total--
+			}
 			continue
 		}
 		filtered.Items = append(filtered.Items, item)
@@ -1186,7 +1549,8 @@ func (h *BrowseHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	path := strings.TrimPrefix(r.URL.Path, "/api/browse")
 	path = strings.TrimPrefix(path, "/")
 
-	contents, err := browseDirectoryContentsForAccess(h.search, path, isLocalRequest(r))
+	opts := browseOptionsForValues(r.URL.Query(), isLocalRequest(r))
+	contents, err := browseDirectoryContentsForAccess(h.search, path, opts)
 	if err != nil {
 		http.Error(w, "Error reading directory", http.StatusInternalServerError)
 		return
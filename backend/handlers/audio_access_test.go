@@ -414,20 +414,25 @@ func newTestHandlerAccessKeyManager(t *testing.T, streamPolicy string) *services
 }
 
 func expectAudioLookup(mock sqlmock.Sqlmock, shareKey, path string, deleted bool) {
+	expectAudioLookupInFolder(mock, shareKey, path, deleted, nil)
+}
+
+func expectAudioLookupInFolder(mock sqlmock.Sqlmock, shareKey, path string, deleted bool, parentPath any) {
 	deletedValue := 0
 	if deleted {
 		deletedValue = 1
 	}
 	mock.ExpectQuery(regexp.QuoteMeta(`
 		SELECT id, path, deleted, unavailable_at, removal_requested_at, thumbnail, title, meta_artist, upload_date,
-		       webpage_url, description, age_limit, parent_path
+		       webpage_url, description, age_limit, parent_path, ` + services.ThumbnailPlaceholderColumn("audio_files") + `,
+		       chapters
 		FROM audio_files WHERE share_key = $1
 	`)).
 		WithArgs(shareKey).
 		WillReturnRows(sqlmock.NewRows([]string{
 			"id", "path", "deleted", "unavailable_at", "removal_requested_at", "thumbnail", "title", "meta_artist",
-			"upload_date", "webpage_url", "description", "age_limit", "parent_path",
-		}).AddRow(1, path, deletedValue, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil))
+			"upload_date", "webpage_url", "description", "age_limit", "parent_path", "thumbnail_placeholder", "chapters",
+		}).AddRow(1, path, deletedValue, nil, nil, nil, nil, nil, nil, nil, nil, nil, parentPath, nil, nil))
 }
 
 func signedAudioRequest(method, target, body, secret, sessionID string) *http.Request {
@@ -462,3 +467,58 @@ func (l *stubAccessFailureLimiter) AllowAccessAttempt(string) (bool, int) {
 func (l *stubAccessFailureLimiter) RecordAccessFailure(string) {
 	l.failures++
 }
+
+func TestScraperDetectionEscalatesMediaAccess(t *testing.T) {
+	newScorer := func(captcha, throttle, ban int) *services.ScraperScorer {
+		scorer, err := services.NewScraperScorer(services.ScraperScoringOptions{
+			Mode:                   services.ScraperDetectionEnforce,
+			CaptchaScore:           captcha,
+			ThrottleScore:          throttle,
+			BanScore:               ban,
+			Window:                 time.Hour,
+			ThrottleBytesPerSecond: 1024,
+			BanDuration:            time.Hour,
+		})
+		if err != nil {
+			t.Fatalf("NewScraperScorer: %v", err)
+		}
+		return scorer
+	}
+	access := func(handler *AudioHandler, mock sqlmock.Sqlmock, body string) *httptest.ResponseRecorder {
+		expectAudioLookup(mock, "track-key", "audio/track.mp3", false)
+		request := signedAudioRequest(http.MethodPost, "https://example.test/api/audio/key/track-key/access",
+			body, "test-secret", "session-one")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	handler, mock := newMockAudioHandler(t, nil, newTestHandlerAccessKeyManager(t, "100/1m"))
+	handler.captchaVerifier = &stubCaptchaVerifier{}
+	handler.scraperScorer = newScorer(10, 90, 100)
+	for i := 0; i < 20; i++ {
+		if recorder := access(handler, mock, `{"purpose":"stream"}`); recorder.Code != http.StatusOK {
+			t.Fatalf("request %d status = %d, body=%s", i, recorder.Code, recorder.Body.String())
+		}
+	}
+	if recorder := access(handler, mock, `{"purpose":"stream"}`); recorder.Code != http.StatusForbidden ||
+		!strings.Contains(recorder.Body.String(), "captcha_required") {
+		t.Fatalf("flagged status = %d, body=%s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := access(handler, mock, `{"purpose":"stream","capToken":"valid-token"}`); recorder.Code != http.StatusOK {
+		t.Fatalf("solved status = %d, body=%s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := access(handler, mock, `{"purpose":"stream"}`); recorder.Code != http.StatusOK {
+		t.Fatalf("cleared status = %d, body=%s", recorder.Code, recorder.Body.String())
+	}
+
+	handler, mock = newMockAudioHandler(t, nil, newTestHandlerAccessKeyManager(t, "100/1m"))
+	handler.scraperScorer = newScorer(5, 5, 10)
+	for i := 0; i < 20; i++ {
+		access(handler, mock, `{"purpose":"stream"}`)
+	}
+	if recorder := access(handler, mock, `{"purpose":"stream"}`); recorder.Code != http.StatusForbidden ||
+		!strings.Contains(recorder.Body.String(), "temporarily_banned") {
+		t.Fatalf("banned status = %d, body=%s", recorder.Code, recorder.Body.String())
+	}
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/onion/audio-share-backend/middleware"
+	"github.com/onion/audio-share-backend/services"
+)
+
+type auditRecord struct {
+	actor, method, endpoint, targetType, targetID string
+	before, after                                 any
+}
+
+type stubAuditLog struct {
+	records []auditRecord
+	filter  services.AuditFilter
+}
+
+func (s *stubAuditLog) Record(actor, method, endpoint, targetType, targetID string, before, after any) error {
+	s.records = append(s.records, auditRecord{actor, method, endpoint, targetType, targetID, before, after})
+	return nil
+}
+
+func (s *stubAuditLog) List(filter services.AuditFilter) (*services.AuditPage, error) {
+	s.filter = filter
+	return &services.AuditPage{Entries: []services.AuditEntry{}, Limit: filter.Limit, Offset: filter.Offset}, nil
+}
+
+func TestAdminMutationIsAudited(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	previous := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT unavailable_at FROM audio_files").WithArgs("track-key").
+		WillReturnRows(sqlmock.NewRows([]string{"unavailable_at"}).AddRow(previous))
+	mock.ExpectExec("UPDATE audio_files SET unavailable_at").WithArgs(nil, "track-key").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	audit := &stubAuditLog{}
+	handler := middleware.NewAPIKeyAuth("secret").Middleware(
+		NewAdminHandler(db, nil, AdminHandlerOptions{AuditLog: audit}),
+	)
+	request := httptest.NewRequest(http.MethodPatch, "/api/admin/audio/track-key/unavailable",
+		strings.NewReader(`{"unavailable":false}`))
+	request.Header.Set("X-API-Key", "secret")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", recorder.Code, recorder.Body.String())
+	}
+	if len(audit.records) != 1 {
+		t.Fatalf("records = %#v", audit.records)
+	}
+	record := audit.records[0]
+	if record.actor != middleware.DefaultAPIKeyIdentity || record.method != http.MethodPatch ||
+		record.endpoint != "/api/admin/audio/track-key/unavailable" || record.targetType != "audio" || record.targetID != "track-key" {
+		t.Fatalf("record = %#v", record)
+	}
+	before, _ := json.Marshal(record.before)
+	after, _ := json.Marshal(record.after)
+	if string(before) != `{"unavailableAt":"2026-03-01T12:00:00Z"}` || string(after) != `{"unavailableAt":null}` {
+		t.Fatalf("before = %s, after = %s", before, after)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAdminAuditListParsesFilters(t *testing.T) {
+	audit := &stubAuditLog{}
+	handler := NewAdminHandler(nil, nil, AdminHandlerOptions{AuditLog: audit})
+
+	request := httptest.NewRequest(http.MethodGet,
+		"/api/admin/audit?actor=default&targetType=request&targetId=42&since=2026-01-01T00:00:00Z&limit=1000&offset=20", nil)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", recorder.Code, recorder.Body.String())
+	}
+	want := services.AuditFilter{
+		Actor:      "default",
+		TargetType: "request",
+		TargetID:   "42",
+		Since:      time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		Limit:      maxAuditLimit,
+		Offset:     20,
+	}
+	if audit.filter != want {
+		t.Fatalf("filter = %#v", audit.filter)
+	}
+
+	request = httptest.NewRequest(http.MethodGet, "/api/admin/audit?until=yesterday", nil)
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("invalid until status = %d", recorder.Code)
+	}
+}
+
+func TestAdminRouteScope(t *testing.T) {
+	tests := map[string]string{
+		"/api/admin/requests/4/status":        services.AdminScopeRequests,
+		"/api/admin/search-insights":          services.AdminScopeRequests,
+		"/api/admin/audio/key/unavailable":    services.AdminScopeAudio,
+		"/api/admin/folders/key/unavailable":  services.AdminScopeAudio,
+		"/api/admin/removal-requests/2":       services.AdminScopeAudio,
+		"/api/admin/targeted-messages":        services.AdminScopeMessages,
+		"/api/admin/jobs/reindex":             services.AdminScopeJobs,
+		"/api/admin/keys":                     services.AdminScopeOwner,
+		"/api/admin/audit":                    services.AdminScopeOwner,
+		"/api/admin/something-new-and-secret": services.AdminScopeOwner,
+	}
+	for path, want := range tests {
+		if got := AdminRouteScope(httptest.NewRequest(http.MethodGet, path, nil)); got != want {
+			t.Errorf("%s = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
+	"log"
 	"net/http"
 	"path/filepath"
 	"regexp"
@@ -12,16 +13,32 @@ import (
 	"github.com/onion/audio-share-backend/services"
 )
 
+type removalRequestCreator interface {
+	Create(services.RemovalRequestInput) (*services.RemovalRequest, error)
+}
+
+type ContactHandlerOptions struct {
+	RemovalRequests removalRequestCreator
+	CaptchaVerifier services.CaptchaVerifier
+}
+
 type ContactHandler struct {
-	ntfy          *services.NtfyService
-	sessionSecret []byte
+	ntfy            *services.NtfyService
+	sessionSecret   []byte
+	removalRequests removalRequestCreator
+	captchaVerifier services.CaptchaVerifier
 }
 
-func NewContactHandler(ntfy *services.NtfyService, sessionSecret string) *ContactHandler {
-	return &ContactHandler{
+func NewContactHandler(ntfy *services.NtfyService, sessionSecret string, options ...ContactHandlerOptions) *ContactHandler {
+	handler := &ContactHandler{
 		ntfy:          ntfy,
 		sessionSecret: []byte(sessionSecret),
 	}
+	if len(options) > 0 {
+		handler.removalRequests = options[0].RemovalRequests
+		handler.captchaVerifier = options[0].CaptchaVerifier
+	}
+	return handler
 }
 
 type contactRequest struct {
@@ -36,12 +53,18 @@ type contactRequest struct {
 	Timezone   string `json:"timezone"`
 	Page       string `json:"page"`
 	AppBuildID string `json:"appBuildId"`
+	// Removal requests only
+	ShareKey string `json:"shareKey"`
+	Name     string `json:"name"`
+	CapToken string `json:"capToken"`
 }
 
 var emailRegex = regexp.MustCompile(`^[^\s@]+@[^\s@]+\.[^\s@]+$`)
 
 const maxContactImageSize = 15 << 20
 
+const maxRemovalReasonLen = 5000
+
 var (
 	errContactImageTooLarge = errors.New("Image attachment must be 15 MB or smaller")
 	errInvalidContactImage  = errors.New("Attachment must be an image")
@@ -91,6 +114,18 @@ func (h *ContactHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	diagnostics := h.diagnostics(w, r, req)
+	if err := h.ntfy.SendContactNotification(req.Topic, req.Email, req.Message, diagnostics, attachment); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to send notification"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]bool{"success": true})
+}
+
+// diagnostics collects the browser details sent with a form, tagged with the
+// visitor's session, which is created if needed.
+func (h *ContactHandler) diagnostics(w http.ResponseWriter, r *http.Request, req contactRequest) services.ContactDiagnostics {
 	diagnostics := services.ContactDiagnostics{
 		Browser:    sanitizeDiagnostic(req.Browser, 100),
 		Platform:   sanitizeDiagnostic(req.Platform, 100),
@@ -108,13 +143,93 @@ func (h *ContactHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 	setSessionCookie(w, r, h.sessionSecret, sessionID)
 	diagnostics.SessionID = sessionID
+	return diagnostics
+}
 
-	if err := h.ntfy.SendContactNotification(req.Topic, req.Email, req.Message, diagnostics, attachment); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to send notification"})
-		return
-	}
+// RemovalRequestHandler accepts a creator's request to take down a track or
+// folder. It takes the contact form's fields, with the reason as the message
+// and an optional image as proof of ownership, plus the share key and, when
+// a captcha is configured, a solved Cap token.
+func (h *ContactHandler) RemovalRequestHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if h.removalRequests == nil {
+			http.Error(w, "Not found", http.StatusNotFound)
+			return
+		}
 
-	writeJSON(w, http.StatusOK, map[string]bool{"success": true})
+		req, attachment, cleanup, err := parseContactRequest(w, r)
+		if cleanup != nil {
+			defer cleanup()
+		}
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+
+		req.ShareKey = strings.TrimSpace(req.ShareKey)
+		req.Name = sanitizeDiagnostic(req.Name, 200)
+		req.Email = strings.TrimSpace(req.Email)
+		req.Message = strings.TrimSpace(req.Message)
+
+		if req.ShareKey == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Share key is required"})
+			return
+		}
+		if req.Email == "" || !emailRegex.MatchString(req.Email) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Please enter a valid email address"})
+			return
+		}
+		if req.Message == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Reason is required"})
+			return
+		}
+		if len(req.Message) > maxRemovalReasonLen {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Reason is too long"})
+			return
+		}
+
+		if h.captchaVerifier != nil {
+			if err := h.captchaVerifier.Verify(r.Context(), req.CapToken); err != nil {
+				if errors.Is(err, services.ErrCaptchaInvalid) {
+					writeJSON(w, http.StatusForbidden, map[string]string{"error": "captcha_invalid"})
+				} else {
+					w.Header().Set("Retry-After", "5")
+					writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "captcha_unavailable"})
+				}
+				return
+			}
+		}
+
+		diagnostics := h.diagnostics(w, r, req)
+		request, err := h.removalRequests.Create(services.RemovalRequestInput{
+			ShareKey:       req.ShareKey,
+			RequesterName:  req.Name,
+			RequesterEmail: req.Email,
+			Reason:         req.Message,
+			SessionID:      diagnostics.SessionID,
+		})
+		if errors.Is(err, services.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "Track or folder not found"})
+			return
+		}
+		if err != nil {
+			log.Printf("removal request: failed to create for key=%s: %v", req.ShareKey, err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to submit request"})
+			return
+		}
+
+		if h.ntfy.IsConfigured() {
+			if err := h.ntfy.SendRemovalRequestNotification(request, diagnostics, attachment); err != nil {
+				log.Printf("removal request: failed to notify for id=%d: %v", request.ID, err)
+			}
+		}
+
+		writeJSON(w, http.StatusCreated, map[string]any{"id": request.ID, "status": request.Status})
+	}
 }
 
 func parseContactRequest(w http.ResponseWriter, r *http.Request) (contactRequest, *services.NtfyAttachment, func(), error) {
@@ -142,6 +257,9 @@ func parseContactRequest(w http.ResponseWriter, r *http.Request) (contactRequest
 			Timezone:   r.FormValue("timezone"),
 			Page:       r.FormValue("page"),
 			AppBuildID: r.FormValue("appBuildId"),
+			ShareKey:   r.FormValue("shareKey"),
+			Name:       r.FormValue("name"),
+			CapToken:   r.FormValue("capToken"),
 		}
 
 		file, header, err := r.FormFile("image")
@@ -80,3 +80,63 @@ func TestSanitizeDiagnosticRemovesLineBreaksAndTruncates(t *testing.T) {
 		t.Fatalf("sanitizeDiagnostic() = %q", got)
 	}
 }
+
+type stubRemovalRequests struct {
+	inputs []services.RemovalRequestInput
+	err    error
+}
+
+func (s *stubRemovalRequests) Create(input services.RemovalRequestInput) (*services.RemovalRequest, error) {
+	s.inputs = append(s.inputs, input)
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &services.RemovalRequest{ID: 7, Status: services.RemovalPending, ShareKey: input.ShareKey}, nil
+}
+
+func TestRemovalRequestHandler(t *testing.T) {
+	removals := &stubRemovalRequests{}
+	verifier := &stubCaptchaVerifier{}
+	handler := NewContactHandler(
+		services.NewNtfyService("", "", "", 3, ""),
+		"test-secret",
+		ContactHandlerOptions{RemovalRequests: removals, CaptchaVerifier: verifier},
+	).RemovalRequestHandler()
+
+	submit := func(body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/api/removal-requests", strings.NewReader(body))
+		recorder := httptest.NewRecorder()
+		handler(recorder, request)
+		return recorder
+	}
+
+	recorder := submit(`{"shareKey":"track-key","name":"Owner","email":"owner@example.test","message":"This is my recording","capToken":"solved"}`)
+	if recorder.Code != http.StatusCreated || !strings.Contains(recorder.Body.String(), `"status":"pending"`) {
+		t.Fatalf("status = %d, body = %s", recorder.Code, recorder.Body.String())
+	}
+	if len(removals.inputs) != 1 || removals.inputs[0].ShareKey != "track-key" || removals.inputs[0].RequesterName != "Owner" ||
+		removals.inputs[0].Reason != "This is my recording" {
+		t.Fatalf("inputs = %#v", removals.inputs)
+	}
+	if len(verifier.tokens) != 1 || verifier.tokens[0] != "solved" {
+		t.Fatalf("captcha tokens = %#v", verifier.tokens)
+	}
+
+	if recorder := submit(`{"shareKey":"track-key","message":"Mine"}`); recorder.Code != http.StatusBadRequest {
+		t.Fatalf("missing email status = %d", recorder.Code)
+	}
+
+	verifier.err = services.ErrCaptchaInvalid
+	if recorder := submit(`{"shareKey":"track-key","email":"owner@example.test","message":"Mine"}`); recorder.Code != http.StatusForbidden {
+		t.Fatalf("invalid captcha status = %d", recorder.Code)
+	}
+	verifier.err = nil
+
+	removals.err = services.ErrNotFound
+	if recorder := submit(`{"shareKey":"missing","email":"owner@example.test","message":"Mine"}`); recorder.Code != http.StatusNotFound {
+		t.Fatalf("unknown key status = %d", recorder.Code)
+	}
+	if len(removals.inputs) != 2 {
+		t.Fatalf("expected rejected submissions not to be stored, got %d", len(removals.inputs))
+	}
+}
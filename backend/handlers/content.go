@@ -108,7 +108,7 @@ func (h *ContentHandler) SitemapHandler() http.HandlerFunc {
 		}
 		baseURL := scheme + "://" + r.Host
 
-		folderPaths, err := h.searchService.GetAllFolderPaths()
+		folderPaths, err := h.searchService.GetPublicFolderPaths()
 		if err != nil {
 			log.Printf("Error getting folder paths for sitemap: %v", err)
 			folderPaths = nil
@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/xml"
+	"html"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/onion/audio-share-backend/services"
+)
+
+const (
+	embedAudioWidth   = 480
+	embedAudioHeight  = 160
+	embedFolderWidth  = 480
+	embedFolderHeight = 360
+	maxEmbedTracks    = 200
+)
+
+// EmbedHandler serves the oEmbed provider at /oembed and the data behind
+// the /embed/{key} player page at /api/embed/{key}.
+type EmbedHandler struct {
+	db      *sql.DB
+	browser directoryBrowser
+	title   string
+}
+
+type embedTrack struct {
+	ShareKey        string  `json:"shareKey"`
+	Title           string  `json:"title"`
+	Artist          string  `json:"artist,omitempty"`
+	DurationSeconds float64 `json:"durationSeconds,omitempty"`
+	IsMature        bool    `json:"isMature"`
+}
+
+// embedDescriptor is what the embedded player needs for a share key, which
+// may name either a single track or a folder.
+type embedDescriptor struct {
+	Type     string       `json:"type"`
+	ShareKey string       `json:"shareKey"`
+	Title    string       `json:"title"`
+	Artist   string       `json:"artist,omitempty"`
+	PageURL  string       `json:"pageUrl"`
+	ImageURL string       `json:"imageUrl,omitempty"`
+	IsMature bool         `json:"isMature"`
+	Tracks   []embedTrack `json:"tracks"`
+}
+
+type oEmbedResponse struct {
+	XMLName      xml.Name `json:"-" xml:"oembed"`
+	Version      string   `json:"version" xml:"version"`
+	Type         string   `json:"type" xml:"type"`
+	ProviderName string   `json:"provider_name" xml:"provider_name"`
+	ProviderURL  string   `json:"provider_url" xml:"provider_url"`
+	Title        string   `json:"title" xml:"title"`
+	AuthorName   string   `json:"author_name,omitempty" xml:"author_name,omitempty"`
+	CacheAge     int      `json:"cache_age" xml:"cache_age"`
+	HTML         string   `json:"html" xml:"html"`
+	Width        int      `json:"width" xml:"width"`
+	Height       int      `json:"height" xml:"height"`
+}
+
+func NewEmbedHandler(db *sql.DB, browser directoryBrowser, title string) *EmbedHandler {
+	return &EmbedHandler{db: db, browser: browser, title: title}
+}
+
+func (h *EmbedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Robots-Tag", "noindex, nofollow, noarchive")
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method_not_allowed"})
+		return
+	}
+	key := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/embed/"), "/")
+	if key == "" || strings.Contains(key, "/") {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not_found"})
+		return
+	}
+	status, body := lookupEmbedDescriptor(h.db, h.browser, r, key)
+	if status == http.StatusOK {
+		w.Header().Set("Cache-Control", "no-cache")
+	} else {
+		w.Header().Set("Cache-Control", "private, no-store")
+	}
+	writeJSON(w, status, body)
+}
+
+// lookupEmbedDescriptor resolves a share key to an embed descriptor,
+// returning the HTTP status and JSON body to send. Audio keys win over
+// folder keys; removal-requested tracks are gone for remote visitors.
+func lookupEmbedDescriptor(db *sql.DB, browser directoryBrowser, r *http.Request, key string) (int, any) {
+	if db == nil {
+		return http.StatusNotFound, map[string]string{"error": "not_found"}
+	}
+	origin := siteOrigin(r)
+
+	row, err := lookupAudioByKey(db, key)
+	if err == nil {
+		if row.deleted {
+			return http.StatusNotFound, map[string]string{"error": "not_found"}
+		}
+		if row.removalRestricted(r) {
+			return http.StatusGone, map[string]string{"error": "removal_requested"}
+		}
+		track := embedTrack{
+			ShareKey: key,
+			Title:    strings.TrimSuffix(path.Base(row.path), path.Ext(row.path)),
+			IsMature: row.isMature(),
+		}
+		if row.title.Valid && row.title.String != "" {
+			track.Title = row.title.String
+		}
+		if row.artist.Valid {
+			track.Artist = row.artist.String
+		}
+		descriptor := embedDescriptor{
+			Type:     "audio",
+			ShareKey: key,
+			Title:    track.Title,
+			Artist:   track.Artist,
+			PageURL:  origin + "/share/" + url.PathEscape(key),
+			IsMature: track.IsMature,
+			Tracks:   []embedTrack{track},
+		}
+		if row.thumbnail.Valid && row.thumbnail.String != "" {
+			descriptor.ImageURL = origin + "/api/audio/key/" + url.PathEscape(key) + "/thumbnail"
+		}
+		return http.StatusOK, descriptor
+	}
+	if err != sql.ErrNoRows {
+		log.Printf("Error looking up embed audio share_key=%s: %v", key, err)
+		return http.StatusInternalServerError, map[string]string{"error": "server_error"}
+	}
+
+	var folderPath, name, posterImage string
+	err = db.QueryRow(
+		`SELECT path, name, COALESCE(poster_image, '') FROM folders WHERE share_key = $1`, key,
+	).Scan(&folderPath, &name, &posterImage)
+	if err == sql.ErrNoRows || (err == nil && browser == nil) {
+		return http.StatusNotFound, map[string]string{"error": "not_found"}
+	}
+	if err != nil {
+		log.Printf("Error looking up embed folder share_key=%s: %v", key, err)
+		return http.StatusInternalServerError, map[string]string{"error": "server_error"}
+	}
+	contents, err := browser.BrowseDirectory(folderPath, services.BrowseOptions{
+		Type:                    "audio",
+		Limit:                   maxEmbedTracks,
+		IncludeRemovalRequested: isLocalRequest(r),
+	})
+	if err != nil {
+		log.Printf("Error browsing embed folder share_key=%s: %v", key, err)
+		return http.StatusInternalServerError, map[string]string{"error": "server_error"}
+	}
+
+	descriptor := embedDescriptor{
+		Type:     "folder",
+		ShareKey: key,
+		Title:    name,
+		PageURL:  origin + "/browse/" + encodePath(folderPath),
+		Tracks:   []embedTrack{},
+	}
+	if posterImage != "" {
+		descriptor.ImageURL = origin + "/api/folder/key/" + url.PathEscape(key) + "/poster"
+	}
+	for _, item := range contents.Items {
+		if item.Type != "audio" || item.ShareKey == "" {
+			continue
+		}
+		track := embedTrack{
+			ShareKey:        item.ShareKey,
+			Title:           item.Title,
+			DurationSeconds: item.DurationSeconds,
+			IsMature:        item.AgeLimit != nil && *item.AgeLimit >= 18,
+		}
+		if track.Title == "" {
+			track.Title = strings.TrimSuffix(item.Name, path.Ext(item.Name))
+		}
+		descriptor.Tracks = append(descriptor.Tracks, track)
+	}
+	return http.StatusOK, descriptor
+}
+
+// OEmbedHandler implements the oEmbed provider endpoint for share and
+// browse URLs on this site, answering with a rich iframe embed.
+func (h *EmbedHandler) OEmbedHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = "json"
+		}
+		if format != "json" && format != "xml" {
+			http.Error(w, "Unsupported format", http.StatusNotImplemented)
+			return
+		}
+
+		// Only pages of this site are described, so the URL must carry the
+		// same scheme and host as the links the site itself hands out.
+		target, err := url.Parse(r.URL.Query().Get("url"))
+		if err != nil || target.User != nil || !strings.EqualFold(target.Scheme+"://"+target.Host, siteOrigin(r)) {
+			http.Error(w, "Not found", http.StatusNotFound)
+			return
+		}
+
+		response, key, status := h.oEmbedFor(r, target)
+		if status != http.StatusOK {
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+		response.Width, response.Height = oEmbedSize(r, response.Width, response.Height)
+		response.HTML = `<iframe src="` + html.EscapeString(siteOrigin(r)+"/embed/"+url.PathEscape(key)) + `"` +
+			` width="` + strconv.Itoa(response.Width) + `" height="` + strconv.Itoa(response.Height) + `"` +
+			` title="` + html.EscapeString(response.Title) + `" frameborder="0" loading="lazy" allow="autoplay; encrypted-media"></iframe>`
+
+		if isLocalRequest(r) {
+			w.Header().Set("Cache-Control", "private, no-store")
+		} else {
+			w.Header().Set("Cache-Control", "public, max-age=3600")
+		}
+		if format == "xml" {
+			w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+			w.Write([]byte(xml.Header))
+			if err := xml.NewEncoder(w).Encode(response); err != nil {
+				log.Printf("Error encoding oEmbed XML: %v", err)
+			}
+			return
+		}
+		writeJSON(w, http.StatusOK, response)
+	}
+}
+
+// oEmbedFor resolves a share or browse URL to an oEmbed response without
+// its HTML, plus the share key the embedded player should load.
+func (h *EmbedHandler) oEmbedFor(r *http.Request, target *url.URL) (*oEmbedResponse, string, int) {
+	if h.db == nil {
+		return nil, "", http.StatusNotFound
+	}
+	response := &oEmbedResponse{
+		Version:      "1.0",
+		Type:         "rich",
+		ProviderName: h.title,
+		ProviderURL:  siteOrigin(r) + "/",
+		CacheAge:     3600,
+	}
+
+	if key, ok := shareKeyFromPath(target.Path); ok {
+		row, err := lookupAudioByKey(h.db, key)
+		if err == sql.ErrNoRows || (err == nil && (row.deleted || row.removalRestricted(r))) {
+			return nil, "", http.StatusNotFound
+		}
+		if err != nil {
+			return nil, "", http.StatusInternalServerError
+		}
+		response.Title = strings.TrimSuffix(path.Base(row.path), path.Ext(row.path))
+		if row.title.Valid && row.title.String != "" {
+			response.Title = row.title.String
+		}
+		if row.artist.Valid {
+			response.AuthorName = row.artist.String
+		}
+		response.Width, response.Height = embedAudioWidth, embedAudioHeight
+		return response, key, http.StatusOK
+	}
+
+	if isBrowseRoute(target.Path) {
+		folderPath := strings.Trim(strings.TrimPrefix(target.Path, "/browse"), "/")
+		if folderPath == "" {
+			return nil, "", http.StatusNotFound
+		}
+		var key, name string
+		err := h.db.QueryRow(
+			`SELECT share_key, name FROM folders WHERE path = $1 AND share_key IS NOT NULL`, folderPath,
+		).Scan(&key, &name)
+		if err == sql.ErrNoRows {
+			return nil, "", http.StatusNotFound
+		}
+		if err != nil {
+			return nil, "", http.StatusInternalServerError
+		}
+		response.Title = name
+		response.Width, response.Height = embedFolderWidth, embedFolderHeight
+		return response, key, http.StatusOK
+	}
+	return nil, "", http.StatusNotFound
+}
+
+// oEmbedSize shrinks the default player size to fit maxwidth and maxheight.
+func oEmbedSize(r *http.Request, width, height int) (int, int) {
+	if value, err := strconv.Atoi(r.URL.Query().Get("maxwidth")); err == nil && value > 0 {
+		width = min(width, value)
+	}
+	if value, err := strconv.Atoi(r.URL.Query().Get("maxheight")); err == nil && value > 0 {
+		height = min(height, value)
+	}
+	return width, height
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/onion/audio-share-backend/services"
+)
+
+func remoteEmbedRequest(target string) *http.Request {
+	request := httptest.NewRequest(http.MethodGet, "https://example.test"+target, nil)
+	request.RemoteAddr = "203.0.113.5:1234"
+	request.Header.Set("X-Forwarded-Proto", "https")
+	return request
+}
+
+func TestOEmbedDescribesSharePages(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	expectAudioLookup(mock, "track-key", "podcasts/show/episode.mp3", false)
+	expectAudioLookup(mock, "track-key", "podcasts/show/episode.mp3", false)
+	handler := NewEmbedHandler(db, nil, "Audio Share").OEmbedHandler()
+
+	query := url.Values{"url": {"https://example.test/share/track-key"}, "maxwidth": {"320"}}
+	recorder := httptest.NewRecorder()
+	handler(recorder, remoteEmbedRequest("/oembed?"+query.Encode()))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, body=%s", recorder.Code, recorder.Body.String())
+	}
+	var response oEmbedResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if response.Type != "rich" || response.Title != "episode" || response.ProviderName != "Audio Share" ||
+		response.Width != 320 || response.Height != embedAudioHeight {
+		t.Fatalf("response = %+v", response)
+	}
+	if !strings.Contains(response.HTML, `src="https://example.test/embed/track-key"`) ||
+		!strings.Contains(response.HTML, `width="320"`) {
+		t.Fatalf("html = %s", response.HTML)
+	}
+	if got := recorder.Header().Get("Cache-Control"); got != "public, max-age=3600" {
+		t.Errorf("Cache-Control = %q", got)
+	}
+
+	query.Set("format", "xml")
+	recorder = httptest.NewRecorder()
+	handler(recorder, remoteEmbedRequest("/oembed?"+query.Encode()))
+	if !strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/xml") {
+		t.Fatalf("xml Content-Type = %q", recorder.Header().Get("Content-Type"))
+	}
+	var xmlResponse oEmbedResponse
+	if err := xml.Unmarshal(recorder.Body.Bytes(), &xmlResponse); err != nil || xmlResponse.Title != "episode" {
+		t.Fatalf("xml response = %+v err=%v\n%s", xmlResponse, err, recorder.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOEmbedRejectsForeignAndUnsupportedURLs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	handler := NewEmbedHandler(db, nil, "Audio Share").OEmbedHandler()
+
+	for rawQuery, want := range map[string]int{
+		"url=" + url.QueryEscape("https://elsewhere.test/share/track-key"):                 http.StatusNotFound,
+		"url=" + url.QueryEscape("//example.test/share/track-key"):                         http.StatusNotFound,
+		"url=" + url.QueryEscape("ftp://example.test/share/track-key"):                     http.StatusNotFound,
+		"url=" + url.QueryEscape("https://user@example.test/share/track-key"):              http.StatusNotFound,
+		"url=" + url.QueryEscape("https://example.test/about"):                             http.StatusNotFound,
+		"url=" + url.QueryEscape("https://example.test/share/track-key") + "&format=jsonp": http.StatusNotImplemented,
+	} {
+		recorder := httptest.NewRecorder()
+		handler(recorder, remoteEmbedRequest("/oembed?"+rawQuery))
+		if recorder.Code != want {
+			t.Errorf("%s: status = %d, want %d", rawQuery, recorder.Code, want)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unexpected lookups: %v", err)
+	}
+}
+
+func TestEmbedDescriptorListsFolderTracks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		SELECT id, path, deleted, unavailable_at, removal_requested_at, thumbnail, title, meta_artist, upload_date,
+		       webpage_url, description, age_limit, parent_path, ` + services.ThumbnailPlaceholderColumn("audio_files") + `,
+		       chapters
+		FROM audio_files WHERE share_key = $1
+	`)).WithArgs("show-key").WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT path, name, COALESCE(poster_image, '') FROM folders WHERE share_key = $1`)).
+		WithArgs("show-key").
+		WillReturnRows(sqlmock.NewRows([]string{"path", "name", "poster_image"}).AddRow("podcasts/show", "The Show", "poster.jpg"))
+
+	mature := 18
+	browser := &recordingDirectoryBrowser{contents: map[string]*services.DirectoryContents{
+		"podcasts/show": {Items: []services.FileSystemItem{
+			{Type: "audio", ShareKey: "ep-1", Name: "ep1.mp3", Title: "Episode One", DurationSeconds: 61},
+			{Type: "audio", ShareKey: "ep-2", Name: "ep2.mp3", AgeLimit: &mature},
+			{Type: "directory", Name: "extras"},
+		}},
+	}}
+	recorder := httptest.NewRecorder()
+	NewEmbedHandler(db, browser, "Audio Share").ServeHTTP(recorder, remoteEmbedRequest("/api/embed/show-key"))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, body=%s", recorder.Code, recorder.Body.String())
+	}
+	if browser.opts[0].Type != "audio" || browser.opts[0].IncludeRemovalRequested {
+		t.Fatalf("browse options = %+v", browser.opts[0])
+	}
+
+	var descriptor embedDescriptor
+	if err := json.Unmarshal(recorder.Body.Bytes(), &descriptor); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if descriptor.Type != "folder" || descriptor.Title != "The Show" ||
+		descriptor.PageURL != "https://example.test/browse/podcasts/show" ||
+		descriptor.ImageURL != "https://example.test/api/folder/key/show-key/poster" {
+		t.Fatalf("descriptor = %+v", descriptor)
+	}
+	if len(descriptor.Tracks) != 2 || descriptor.Tracks[0].Title != "Episode One" ||
+		descriptor.Tracks[1].Title != "ep2" || !descriptor.Tracks[1].IsMature {
+		t.Fatalf("tracks = %+v", descriptor.Tracks)
+	}
+}
@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/xml"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const itunesNamespace = "http://www.itunes.com/dtds/podcast-1.0.dtd"
+
+type rssFeed struct {
+	XMLName  xml.Name   `xml:"rss"`
+	Version  string     `xml:"version,attr"`
+	ItunesNS string     `xml:"xmlns:itunes,attr"`
+	Channel  rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title          string       `xml:"title"`
+	Link           string       `xml:"link"`
+	Description    string       `xml:"description"`
+	LastBuildDate  string       `xml:"lastBuildDate,omitempty"`
+	Image          *rssImage    `xml:"image,omitempty"`
+	ItunesAuthor   string       `xml:"itunes:author"`
+	ItunesSummary  string       `xml:"itunes:summary"`
+	ItunesImage    *itunesImage `xml:"itunes:image,omitempty"`
+	ItunesExplicit string       `xml:"itunes:explicit"`
+	Items          []rssItem    `xml:"item"`
+}
+
+type rssImage struct {
+	URL   string `xml:"url"`
+	Title string `xml:"title"`
+	Link  string `xml:"link"`
+}
+
+type itunesImage struct {
+	Href string `xml:"href,attr"`
+}
+
+type rssItem struct {
+	Title          string       `xml:"title"`
+	Description    string       `xml:"description,omitempty"`
+	Link           string       `xml:"link"`
+	GUID           rssGUID      `xml:"guid"`
+	PubDate        string       `xml:"pubDate,omitempty"`
+	Enclosure      rssEnclosure `xml:"enclosure"`
+	ItunesDuration string       `xml:"itunes:duration,omitempty"`
+	ItunesImage    *itunesImage `xml:"itunes:image,omitempty"`
+	ItunesExplicit string       `xml:"itunes:explicit"`
+}
+
+type rssGUID struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+// feedDate converts a yt-dlp style YYYYMMDD upload date to RFC 1123.
+func feedDate(uploadDate string) string {
+	parsed, err := time.Parse("20060102", uploadDate)
+	if err != nil {
+		return ""
+	}
+	return parsed.UTC().Format(time.RFC1123Z)
+}
+
+func explicitValue(mature bool) string {
+	if mature {
+		return "true"
+	}
+	return "false"
+}
+
+// serveFeed renders a folder's audio files as an RSS 2.0 podcast feed.
+// Enclosures point at the feed enclosure endpoint with a signed feed key,
+// so podcast apps can fetch them without a browser session.
+func (h *FolderHandler) serveFeed(w http.ResponseWriter, r *http.Request, key string) {
+	if h.accessKeys == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	var folderPath, name, posterImage, uploadDate string
+	err := h.db.QueryRow(`
+		SELECT path, name, COALESCE(poster_image, ''), COALESCE(upload_date, '')
+		FROM folders WHERE share_key = $1
+	`, key).Scan(&folderPath, &name, &posterImage, &uploadDate)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+
+	feedKey, err := h.accessKeys.IssueFeedKey(key)
+	if err != nil {
+		log.Printf("Error issuing feed key for folder share_key=%s: %v", key, err)
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+
+	includeRemovalRequested := isLocalRequest(r)
+	rows, err := h.db.Query(`
+		SELECT af.share_key, af.filename, COALESCE(af.title, ''), COALESCE(af.meta_artist, ''),
+		       COALESCE(af.description, ''), COALESCE(af.upload_date, ''), af.size,
+		       COALESCE(af.mime_type, ''), COALESCE(af.age_limit, 0) >= 18,
+		       COALESCE(af.thumbnail, '') <> '', wc.duration_seconds
+		FROM audio_files af
+		LEFT JOIN waveform_cache wc ON wc.audio_file_id = af.id
+		WHERE af.parent_path = $1 AND af.deleted = 0
+		  AND ($2 OR af.removal_requested_at IS NULL)
+		ORDER BY af.upload_date DESC NULLS LAST, af.id DESC
+	`, folderPath, includeRemovalRequested)
+	if err != nil {
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	origin := siteOrigin(r)
+	channelLink := origin + "/browse/" + encodePath(folderPath)
+	channel := rssChannel{
+		Title:          name,
+		Link:           channelLink,
+		Description:    "Archived audio from " + name,
+		LastBuildDate:  feedDate(uploadDate),
+		ItunesAuthor:   name,
+		ItunesSummary:  "Archived audio from " + name,
+		ItunesExplicit: "false",
+		Items:          []rssItem{},
+	}
+	if posterImage != "" {
+		posterURL := origin + "/api/folder/key/" + url.PathEscape(key) + "/poster"
+		channel.Image = &rssImage{URL: posterURL, Title: name, Link: channelLink}
+		channel.ItunesImage = &itunesImage{Href: posterURL}
+	}
+
+	for rows.Next() {
+		var shareKey, filename, title, artist, description, itemDate, mimeType string
+		var size int64
+		var mature, hasThumbnail bool
+		var duration sql.NullFloat64
+		if err := rows.Scan(
+			&shareKey, &filename, &title, &artist, &description, &itemDate, &size,
+			&mimeType, &mature, &hasThumbnail, &duration,
+		); err != nil {
+			http.Error(w, "Server error", http.StatusInternalServerError)
+			return
+		}
+		if title == "" {
+			title = strings.TrimSuffix(filename, path.Ext(filename))
+		}
+		escapedKey := url.PathEscape(shareKey)
+		enclosure := url.Values{}
+		enclosure.Set("feed", key)
+		enclosure.Set("token", feedKey.AccessKey)
+		item := rssItem{
+			Title:          title,
+			Description:    description,
+			Link:           origin + "/share/" + escapedKey,
+			GUID:           rssGUID{IsPermaLink: "false", Value: shareKey},
+			PubDate:        feedDate(itemDate),
+			ItunesExplicit: explicitValue(mature),
+			Enclosure: rssEnclosure{
+				URL:    origin + "/api/audio/key/" + escapedKey + "/enclosure?" + enclosure.Encode(),
+				Length: size,
+				Type:   mimeType,
+			},
+		}
+		if item.Enclosure.Type == "" {
+			item.Enclosure.Type = "application/octet-stream"
+		}
+		if duration.Valid && duration.Float64 > 0 {
+			item.ItunesDuration = strconv.Itoa(int(math.Round(duration.Float64)))
+		}
+		if hasThumbnail {
+			item.ItunesImage = &itunesImage{Href: origin + "/api/audio/key/" + escapedKey + "/thumbnail"}
+		}
+		if mature {
+			channel.ItunesExplicit = "true"
+		}
+		if artist != "" && channel.ItunesAuthor == name {
+			channel.ItunesAuthor = artist
+		}
+		channel.Items = append(channel.Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+
+	if includeRemovalRequested {
+		w.Header().Set("Cache-Control", "private, no-store")
+	} else {
+		w.Header().Set("Cache-Control", "public, max-age=900")
+	}
+	w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+	w.Write([]byte(xml.Header))
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(rssFeed{Version: "2.0", ItunesNS: itunesNamespace, Channel: channel}); err != nil {
+		log.Printf("Error encoding feed for folder share_key=%s: %v", key, err)
+	}
+}
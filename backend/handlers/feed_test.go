@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/onion/audio-share-backend/services"
+)
+
+func TestFolderFeedRendersEpisodesWithSignedEnclosures(t *testing.T) {
+	manager := newTestHandlerAccessKeyManager(t, "10/1m")
+	if err := manager.SetFeedPolicy("10/1h", 24*time.Hour); err != nil {
+		t.Fatalf("SetFeedPolicy: %v", err)
+	}
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	handler := NewFolderHandler(nil, db, FolderHandlerOptions{AccessKeys: manager})
+
+	mock.ExpectQuery(`SELECT path, name, COALESCE\(poster_image, ''\)`).
+		WithArgs("show-key").
+		WillReturnRows(sqlmock.NewRows([]string{"path", "name", "poster_image", "upload_date"}).
+			AddRow("podcasts/show", "The Show", "poster.jpg", "20260102"))
+	mock.ExpectQuery(`FROM audio_files af\s+LEFT JOIN waveform_cache wc`).
+		WithArgs("podcasts/show", false).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"share_key", "filename", "title", "meta_artist", "description", "upload_date", "size",
+			"mime_type", "mature", "has_thumbnail", "duration_seconds",
+		}).
+			AddRow("ep-2", "ep2.mp3", "Episode Two", "Host", "Second <b>episode</b>", "20260102", 2048, "audio/mpeg", true, true, 125.4).
+			AddRow("ep-1", "ep1.m4a", "", "", "", "", 1024, "", false, false, nil))
+
+	request := httptest.NewRequest(http.MethodGet, "https://example.test/api/folder/key/show-key/feed.xml", nil)
+	request.RemoteAddr = "203.0.113.5:1234"
+	request.Header.Set("X-Forwarded-Proto", "https")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, body=%s", recorder.Code, recorder.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet database expectations: %v", err)
+	}
+
+	var feed struct {
+		Channel struct {
+			Title    string `xml:"title"`
+			Explicit string `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd explicit"`
+			Image    struct {
+				Href string `xml:"href,attr"`
+			} `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+			Items []struct {
+				Title     string `xml:"title"`
+				GUID      string `xml:"guid"`
+				PubDate   string `xml:"pubDate"`
+				Duration  string `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+				Enclosure struct {
+					URL    string `xml:"url,attr"`
+					Length int64  `xml:"length,attr"`
+					Type   string `xml:"type,attr"`
+				} `xml:"enclosure"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	if err := xml.Unmarshal(recorder.Body.Bytes(), &feed); err != nil {
+		t.Fatalf("decode feed: %v\n%s", err, recorder.Body.String())
+	}
+	if feed.Channel.Title != "The Show" || feed.Channel.Explicit != "true" ||
+		feed.Channel.Image.Href != "https://example.test/api/folder/key/show-key/poster" {
+		t.Fatalf("channel = %+v", feed.Channel)
+	}
+	if len(feed.Channel.Items) != 2 {
+		t.Fatalf("items = %+v", feed.Channel.Items)
+	}
+	first, second := feed.Channel.Items[0], feed.Channel.Items[1]
+	if first.GUID != "ep-2" || first.Duration != "125" || first.PubDate != "Fri, 02 Jan 2026 00:00:00 +0000" ||
+		first.Enclosure.Length != 2048 || first.Enclosure.Type != "audio/mpeg" {
+		t.Errorf("first item = %+v", first)
+	}
+	if second.Title != "ep1" || second.Enclosure.Type != "application/octet-stream" {
+		t.Errorf("second item = %+v", second)
+	}
+
+	enclosure, err := url.Parse(first.Enclosure.URL)
+	if err != nil {
+		t.Fatalf("parse enclosure URL: %v", err)
+	}
+	if enclosure.Path != "/api/audio/key/ep-2/enclosure" || enclosure.Query().Get("feed") != "show-key" {
+		t.Fatalf("enclosure URL = %s", first.Enclosure.URL)
+	}
+	if _, err := manager.VerifyFeedKey(enclosure.Query().Get("token"), "show-key"); err != nil {
+		t.Fatalf("enclosure token did not verify: %v", err)
+	}
+}
+
+func TestEnclosureRejectsTracksOutsideTheFeedFolder(t *testing.T) {
+	manager := newTestHandlerAccessKeyManager(t, "10/1m")
+	if err := manager.SetFeedPolicy("10/1h", 24*time.Hour); err != nil {
+		t.Fatalf("SetFeedPolicy: %v", err)
+	}
+	handler, mock := newMockAudioHandler(t, nil, manager)
+	limiter := &stubAccessFailureLimiter{allowed: true}
+	handler.accessFailureLimiter = limiter
+	issued, err := manager.IssueFeedKey("show-key")
+	if err != nil {
+		t.Fatalf("IssueFeedKey: %v", err)
+	}
+
+	expectAudioLookup(mock, "other-track", "podcasts/other/track.mp3", false)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT path FROM folders WHERE share_key = $1`)).
+		WithArgs("show-key").
+		WillReturnRows(sqlmock.NewRows([]string{"path"}).AddRow("podcasts/show"))
+
+	query := url.Values{"feed": {"show-key"}, "token": {issued.AccessKey}}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(
+		http.MethodGet, "/api/audio/key/other-track/enclosure?"+query.Encode(), nil,
+	))
+	if recorder.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want 403; body=%s", recorder.Code, recorder.Body.String())
+	}
+	if limiter.failures != 1 {
+		t.Fatalf("failures = %d, want 1", limiter.failures)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(
+		http.MethodGet, "/api/audio/key/other-track/enclosure?feed=show-key&token=forged", nil,
+	))
+	if recorder.Code != http.StatusForbidden || limiter.failures != 2 {
+		t.Fatalf("forged token status = %d failures = %d", recorder.Code, limiter.failures)
+	}
+}
+
+func TestEnclosureRangeRequestsCountAgainstFeedLimits(t *testing.T) {
+	manager := newTestHandlerAccessKeyManager(t, "10/1m")
+	if err := manager.SetFeedPolicy("1/1h", 24*time.Hour); err != nil {
+		t.Fatalf("SetFeedPolicy: %v", err)
+	}
+	handler, mock := newMockAudioHandler(t, nil, manager)
+	issued, err := manager.IssueFeedKey("show-key")
+	if err != nil {
+		t.Fatalf("IssueFeedKey: %v", err)
+	}
+	if err := manager.RecordFeedAccess("show-key", "192.0.2.1"); err != nil {
+		t.Fatalf("RecordFeedAccess: %v", err)
+	}
+
+	expectAudioLookupInFolder(mock, "ep-1", "podcasts/show/ep1.mp3", false, "podcasts/show")
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT path FROM folders WHERE share_key = $1`)).
+		WithArgs("show-key").
+		WillReturnRows(sqlmock.NewRows([]string{"path"}).AddRow("podcasts/show"))
+
+	query := url.Values{"feed": {"show-key"}, "token": {issued.AccessKey}}
+	request := httptest.NewRequest(http.MethodGet, "/api/audio/key/ep-1/enclosure?"+query.Encode(), nil)
+	request.RemoteAddr = "192.0.2.1:1234"
+	request.Header.Set("Range", "bytes=1-")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429; body=%s", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestEnclosureFetchesAreScoredByClientIP(t *testing.T) {
+	manager := newTestHandlerAccessKeyManager(t, "10/1m")
+	if err := manager.SetFeedPolicy("100/1h", 24*time.Hour); err != nil {
+		t.Fatalf("SetFeedPolicy: %v", err)
+	}
+	audioDir := t.TempDir()
+	for i := 1; i <= 7; i++ {
+		if err := os.WriteFile(filepath.Join(audioDir, fmt.Sprintf("ep%d.mp3", i)), []byte("episode audio"), 0o600); err != nil {
+			t.Fatalf("write audio fixture: %v", err)
+		}
+	}
+	handler, mock := newMockAudioHandler(t, services.NewFileSystemService(audioDir+":Show"), manager)
+	scorer, err := services.NewScraperScorer(services.ScraperScoringOptions{
+		Mode: services.ScraperDetectionObserve, CaptchaScore: 40, ThrottleScore: 60, BanScore: 85,
+		Window: time.Hour, ThrottleBytesPerSecond: 1024, BanDuration: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("NewScraperScorer: %v", err)
+	}
+	handler.scraperScorer = scorer
+	issued, err := manager.IssueFeedKey("show-key")
+	if err != nil {
+		t.Fatalf("IssueFeedKey: %v", err)
+	}
+
+	for i := 1; i <= 7; i++ {
+		key := fmt.Sprintf("ep-%d", i)
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM audio_files WHERE share_key = $1`)).
+			WithArgs(key).
+			WillReturnRows(sqlmock.NewRows([]string{
+				"id", "path", "deleted", "unavailable_at", "removal_requested_at", "thumbnail", "title", "meta_artist",
+				"upload_date", "webpage_url", "description", "age_limit", "parent_path", "thumbnail_placeholder", "chapters",
+			}).AddRow(i, fmt.Sprintf("show/ep%d.mp3", i), 0, nil, nil, nil, nil, nil, nil, nil, nil, nil, "show", nil, nil))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT path FROM folders WHERE share_key = $1`)).
+			WithArgs("show-key").
+			WillReturnRows(sqlmock.NewRows([]string{"path"}).AddRow("show"))
+
+		query := url.Values{"feed": {"show-key"}, "token": {issued.AccessKey}}
+		request := httptest.NewRequest(http.MethodGet, "/api/audio/key/"+key+"/enclosure?"+query.Encode(), nil)
+		request.RemoteAddr = "192.0.2.1:1234"
+		request.Header.Set("Range", "bytes=2-")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		if recorder.Code != http.StatusPartialContent {
+			t.Fatalf("%s status = %d; body=%s", key, recorder.Code, recorder.Body.String())
+		}
+	}
+
+	assessment, ok := scorer.Assessment(services.BanKindIP, "192.0.2.1")
+	if !ok || assessment.Score == 0 {
+		t.Fatalf("assessment = %#v, want the IP scored for sequential enclosure fetches", assessment)
+	}
+}
@@ -11,36 +11,66 @@ import (
 )
 
 type FolderHandler struct {
-	fs *services.FileSystemService
-	db *sql.DB
+	fs            *services.FileSystemService
+	db            *sql.DB
+	accessKeys    *services.AccessKeyManager
+	sessionSecret []byte
 }
 
-func NewFolderHandler(fs *services.FileSystemService, db *sql.DB) *FolderHandler {
-	return &FolderHandler{fs: fs, db: db}
+type FolderHandlerOptions struct {
+	AccessKeys    *services.AccessKeyManager
+	SessionSecret string
+}
+
+func NewFolderHandler(fs *services.FileSystemService, db *sql.DB, options ...FolderHandlerOptions) *FolderHandler {
+	h := &FolderHandler{fs: fs, db: db}
+	if len(options) > 0 {
+		h.accessKeys = options[0].AccessKeys
+		h.sessionSecret = []byte(options[0].SessionSecret)
+	}
+	return h
 }
 
 func (h *FolderHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("X-Robots-Tag", "noindex, nofollow, noarchive")
 
-	// Path format: /api/folder/key/{key}/poster
+	// Path format: /api/folder/key/{key}/poster or /api/folder/key/{key}/feed.xml
 	path := strings.TrimPrefix(r.URL.Path, "/api/folder/key/")
 	path = strings.Trim(path, "/")
 
-	if !strings.HasSuffix(path, "/poster") {
+	var key, action string
+	if strings.HasSuffix(path, "/poster") {
+		key = strings.TrimSuffix(path, "/poster")
+		action = "poster"
+	} else if strings.HasSuffix(path, "/feed.xml") {
+		key = strings.TrimSuffix(path, "/feed.xml")
+		action = "feed"
+	} else {
 		http.Error(w, "Not found", http.StatusNotFound)
 		return
 	}
 
-	key := strings.TrimSuffix(path, "/poster")
 	if key == "" {
 		http.Error(w, "Key required", http.StatusBadRequest)
 		return
 	}
+	switch action {
+	case "poster":
+		h.servePoster(w, r, key)
+	case "feed":
+		h.serveFeed(w, r, key)
+	}
+}
 
+// servePoster serves a folder's poster. Posters of mature folders, whose
+// age limit already includes any inherited from parent folders, are blurred
+// and kept out of shared caches exactly like mature track thumbnails.
+func (h *FolderHandler) servePoster(w http.ResponseWriter, r *http.Request, key string) {
 	var folderPath, posterImage string
+	var mature bool
 	err := h.db.QueryRow(
-		"SELECT path, COALESCE(poster_image, '') FROM folders WHERE share_key = $1", key,
-	).Scan(&folderPath, &posterImage)
+		"SELECT path, COALESCE(poster_image, ''), COALESCE(age_limit, 0) >= 18 FROM folders WHERE share_key = $1", key,
+	).Scan(&folderPath, &posterImage, &mature)
 	if err == sql.ErrNoRows {
 		http.Error(w, "Not found", http.StatusNotFound)
 		return
@@ -53,6 +83,11 @@ func (h *FolderHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "No poster image", http.StatusNotFound)
 		return
 	}
+	variant, err := parseImageVariant(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	parts := strings.SplitN(folderPath, "/", 2)
 	slug := parts[0]
@@ -74,6 +109,32 @@ func (h *FolderHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	cacheControl := "public, max-age=86400"
+	if mature {
+		cacheControl = "private, max-age=86400"
+		view := r.URL.Query().Get("view")
+		if view != "blurred" && view != "original" {
+			if maturePreferenceEnabled(r, h.sessionSecret) {
+				view = "original"
+			} else {
+				view = "blurred"
+			}
+		}
+		if view == "blurred" {
+			serveBlurredArtwork(w, r, "folder-"+key, fullPath, info, cacheControl)
+			return
+		}
+		if !maturePreferenceEnabled(r, h.sessionSecret) {
+			http.Error(w, "Mature content preference required", http.StatusForbidden)
+			return
+		}
+	}
+
+	if !variant.isOriginal() {
+		serveImageVariant(w, r, "folder-"+key, fullPath, info, variant, cacheControl)
+		return
+	}
+
 	mimeTypes := map[string]string{
 		".jpg": "image/jpeg", ".jpeg": "image/jpeg",
 		".png": "image/png", ".gif": "image/gif", ".webp": "image/webp",
@@ -92,6 +153,6 @@ func (h *FolderHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	defer file.Close()
 
 	w.Header().Set("Content-Type", contentType)
-	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Header().Set("Cache-Control", cacheControl)
 	http.ServeContent(w, r, info.Name(), info.ModTime(), file)
 }
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/onion/audio-share-backend/services"
+)
+
+func TestMatureFolderPosterIsBlurredAndPrivate(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "show"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	posterPath := filepath.Join(dir, "show", "poster.jpg")
+	writeTestJPEG(t, posterPath, 64, 64)
+	original, err := os.ReadFile(posterPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	handler := NewFolderHandler(services.NewFileSystemService(dir+":Audio"), db, FolderHandlerOptions{
+		SessionSecret: "test-secret",
+	})
+
+	tests := []struct {
+		name       string
+		target     string
+		showMature bool
+		wantStatus int
+		wantBlur   bool
+	}{
+		{name: "default", target: "/api/folder/key/show-key/poster", wantStatus: http.StatusOK, wantBlur: true},
+		{name: "variant", target: "/api/folder/key/show-key/poster?size=small", wantStatus: http.StatusOK, wantBlur: true},
+		{name: "original without preference", target: "/api/folder/key/show-key/poster?view=original", wantStatus: http.StatusForbidden},
+		{name: "original with preference", target: "/api/folder/key/show-key/poster", showMature: true, wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT path, COALESCE(poster_image, ''), COALESCE(age_limit, 0) >= 18 FROM folders`)).
+				WithArgs("show-key").
+				WillReturnRows(sqlmock.NewRows([]string{"path", "poster_image", "mature"}).AddRow("audio/show", "poster.jpg", true))
+
+			request := signedAudioRequest(http.MethodGet, tt.target, "", "test-secret", "session-one")
+			if tt.showMature {
+				request.AddCookie(&http.Cookie{
+					Name:  matureCookieName,
+					Value: signValue(matureCookiePayload("session-one"), []byte("test-secret")),
+				})
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body = %q", recorder.Code, tt.wantStatus, recorder.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if got := recorder.Header().Get("Cache-Control"); got != "private, max-age=86400" {
+				t.Fatalf("Cache-Control = %q, want private, max-age=86400", got)
+			}
+			if blurred := !bytes.Equal(recorder.Body.Bytes(), original); blurred != tt.wantBlur {
+				t.Fatalf("served blurred = %v, want %v", blurred, tt.wantBlur)
+			}
+		})
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// imageVariantSizes maps ?size= presets to the longest side in pixels.
+var imageVariantSizes = map[string]int{
+	"icon":   96,
+	"small":  240,
+	"medium": 480,
+	"large":  960,
+}
+
+const (
+	webpEncodeTimeout = 20 * time.Second
+
+	imageVariantMaxConcurrentRenders = 4
+	imageVariantCacheMaxAge          = 30 * 24 * time.Hour
+	imageVariantCacheMaxFiles        = 20000
+)
+
+// imageVariant is a resized and possibly re-encoded copy of a sidecar image.
+// The zero value means the original file should be served.
+type imageVariant struct {
+	size   string
+	format string // "" keeps JPEG or PNG, "webp" asks for WebP
+}
+
+func (v imageVariant) isOriginal() bool {
+	return v.size == "" && v.format == ""
+}
+
+func parseImageVariant(r *http.Request) (imageVariant, error) {
+	query := r.URL.Query()
+	variant := imageVariant{size: query.Get("size"), format: query.Get("format")}
+	if _, ok := imageVariantSizes[variant.size]; variant.size != "" && !ok {
+		return variant, fmt.Errorf("size must be one of icon, small, medium or large")
+	}
+	if variant.format != "" && variant.format != "webp" {
+		return variant, fmt.Errorf("format must be webp")
+	}
+	return variant, nil
+}
+
+var (
+	webpEncodeFailed sync.Once
+
+	// imageVariantRenderSlots bounds how many variants render at once; each
+	// render decodes the full source image and may start ffmpeg.
+	imageVariantRenderSlots = make(chan struct{}, imageVariantMaxConcurrentRenders)
+	imageVariantCachePrune  imageCachePruner
+)
+
+// serveImageVariant serves a cached variant of srcPath, rendering it on first
+// use. The source mtime and size are part of the cache name, so replacing the
+// source image renders a fresh variant and removes the stale ones.
+func serveImageVariant(w http.ResponseWriter, r *http.Request, cachePrefix, srcPath string, info os.FileInfo, variant imageVariant, cacheControl string) {
+	cacheDir := filepath.Join(os.TempDir(), "audio-share-image-variants")
+	if err := os.MkdirAll(cacheDir, 0700); err != nil {
+		http.Error(w, "Error preparing image", http.StatusInternalServerError)
+		return
+	}
+	size := variant.size
+	if size == "" {
+		size = "full"
+	}
+	// Share keys never contain dots, so they cleanly separate the parts.
+	stem := cachePrefix + "." + size + "."
+	version := fmt.Sprintf("%d-%d", info.ModTime().UnixNano(), info.Size())
+
+	cachePath, contentType := findImageVariant(cacheDir, stem+version, variant.format)
+	if cachePath == "" {
+		select {
+		case imageVariantRenderSlots <- struct{}{}:
+		case <-r.Context().Done():
+			return
+		}
+		// A concurrent request may have rendered it while this one waited.
+		cachePath, contentType = findImageVariant(cacheDir, stem+version, variant.format)
+		if cachePath == "" {
+			var err error
+			cachePath, contentType, err = renderImageVariant(cacheDir, stem+version, srcPath, variant)
+			<-imageVariantRenderSlots
+			if err != nil {
+				log.Printf("Error rendering image variant %s%s: %v", stem, version, err)
+				http.Error(w, "Error generating image", http.StatusInternalServerError)
+				return
+			}
+			removeStaleImageVariants(cacheDir, stem, version)
+			imageVariantCachePrune.maybePrune(cacheDir, imageVariantCacheMaxAge, imageVariantCacheMaxFiles)
+		} else {
+			<-imageVariantRenderSlots
+		}
+	}
+
+	file, err := os.Open(cachePath)
+	if err != nil {
+		http.Error(w, "Error opening image", http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+	cachedInfo, err := file.Stat()
+	if err != nil {
+		http.Error(w, "Error reading image", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", cacheControl)
+	http.ServeContent(w, r, filepath.Base(cachePath), cachedInfo.ModTime(), file)
+}
+
+// findImageVariant returns an already rendered variant. A WebP request is
+// satisfied by a JPEG or PNG fallback when WebP encoding was unavailable.
+func findImageVariant(cacheDir, name, format string) (string, string) {
+	extensions := []string{".jpg", ".png"}
+	if format == "webp" {
+		extensions = []string{".webp", ".webp.jpg", ".webp.png"}
+	}
+	for _, ext := range extensions {
+		path := filepath.Join(cacheDir, name+ext)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, imageContentType(path)
+		}
+	}
+	return "", ""
+}
+
+func imageContentType(path string) string {
+	switch filepath.Ext(path) {
+	case ".webp":
+		return "image/webp"
+	case ".png":
+		return "image/png"
+	default:
+		return "image/jpeg"
+	}
+}
+
+func renderImageVariant(cacheDir, name, srcPath string, variant imageVariant) (string, string, error) {
+	src, err := decodeImageFile(srcPath)
+	if err != nil {
+		return "", "", err
+	}
+	resized := src
+	if maxSide, ok := imageVariantSizes[variant.size]; ok {
+		bounds := src.Bounds()
+		if bounds.Dx() > maxSide || bounds.Dy() > maxSide {
+			width, height := scaledDimensions(bounds.Dx(), bounds.Dy(), maxSide)
+			dst := image.NewRGBA(image.Rect(0, 0, width, height))
+			xdraw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, xdraw.Src, nil)
+			resized = dst
+		}
+	}
+
+	if variant.format == "webp" {
+		path := filepath.Join(cacheDir, name+".webp")
+		err := writeWebP(path, resized)
+		if err == nil {
+			return path, "image/webp", nil
+		}
+		webpEncodeFailed.Do(func() {
+			log.Printf("WebP encoding unavailable, serving JPEG or PNG variants instead: %v", err)
+		})
+		// Remember the fallback under the WebP name so later requests skip ffmpeg.
+		name += ".webp"
+	}
+
+	if opaque, ok := resized.(interface{ Opaque() bool }); ok && !opaque.Opaque() {
+		path := filepath.Join(cacheDir, name+".png")
+		return path, "image/png", writePNG(path, resized)
+	}
+	path := filepath.Join(cacheDir, name+".jpg")
+	return path, "image/jpeg", writeJPEG(path, resized)
+}
+
+// writeWebP encodes img through ffmpeg, which the server already needs for
+// waveforms, since Go has no WebP encoder.
+func writeWebP(path string, img image.Image) error {
+	var input bytes.Buffer
+	if err := png.Encode(&input, img); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := file.Name()
+	file.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), webpEncodeTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "ffmpeg",
+		"-v", "error", "-y",
+		"-f", "png_pipe", "-i", "pipe:0",
+		"-c:v", "libwebp", "-quality", "80",
+		"-f", "webp", tmp,
+	)
+	cmd.Stdin = &input
+	if output, err := cmd.CombinedOutput(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(output)))
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+func removeStaleImageVariants(cacheDir, stem, version string) {
+	matches, err := filepath.Glob(filepath.Join(cacheDir, stem+"*"))
+	if err != nil {
+		return
+	}
+	for _, match := range matches {
+		rest := strings.TrimPrefix(filepath.Base(match), stem)
+		if !strings.HasPrefix(rest, version+".") {
+			os.Remove(match)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"image"
+	"image/color"
+	"image/jpeg"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func writeTestJPEG(t *testing.T, path string, width, height int) os.FileInfo {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			img.SetRGBA(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 90, A: 255})
+		}
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := jpeg.Encode(file, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
+
+func TestImageVariantResizesAndReplacesStaleCopies(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	srcPath := filepath.Join(t.TempDir(), "cover.jpg")
+	info := writeTestJPEG(t, srcPath, 1280, 720)
+
+	serve := func(info os.FileInfo) image.Config {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodGet, "/api/audio/key/track-key/thumbnail?size=icon", nil)
+		serveImageVariant(recorder, request, "audio-track-key", srcPath, info, imageVariant{size: "icon"}, "private, max-age=86400")
+		if recorder.Code != http.StatusOK || recorder.Header().Get("Content-Type") != "image/jpeg" {
+			t.Fatalf("status = %d content-type = %q", recorder.Code, recorder.Header().Get("Content-Type"))
+		}
+		config, err := jpeg.DecodeConfig(recorder.Body)
+		if err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return config
+	}
+
+	if config := serve(info); config.Width != 96 || config.Height != 54 {
+		t.Fatalf("icon size = %dx%d, want 96x54", config.Width, config.Height)
+	}
+
+	later := info.ModTime().Add(time.Minute)
+	if err := os.Chtimes(srcPath, later, later); err != nil {
+		t.Fatal(err)
+	}
+	info = writeTestJPEG(t, srcPath, 400, 800)
+	if config := serve(info); config.Width != 48 || config.Height != 96 {
+		t.Fatalf("icon size after source change = %dx%d, want 48x96", config.Width, config.Height)
+	}
+	cached, _ := filepath.Glob(filepath.Join(os.TempDir(), "audio-share-image-variants", "audio-track-key.icon.*"))
+	if len(cached) != 1 {
+		t.Fatalf("cached variants = %v, want only the current one", cached)
+	}
+}
+
+func TestImageVariantConcurrentRequestsShareOneRender(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	srcPath := filepath.Join(t.TempDir(), "cover.jpg")
+	info := writeTestJPEG(t, srcPath, 640, 640)
+
+	var wg sync.WaitGroup
+	codes := make(chan int, 3*imageVariantMaxConcurrentRenders)
+	for range cap(codes) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodGet, "/api/audio/key/track-key/thumbnail?size=small", nil)
+			serveImageVariant(recorder, request, "audio-track-key", srcPath, info, imageVariant{size: "small"}, "private, max-age=86400")
+			codes <- recorder.Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+	for code := range codes {
+		if code != http.StatusOK {
+			t.Fatalf("status = %d", code)
+		}
+	}
+	cacheDir := filepath.Join(os.TempDir(), "audio-share-image-variants")
+	if cached, _ := filepath.Glob(filepath.Join(cacheDir, "audio-track-key.small.*")); len(cached) != 1 {
+		t.Fatalf("cached variants = %v, want one", cached)
+	}
+	if leftovers, _ := filepath.Glob(filepath.Join(cacheDir, "*.tmp")); len(leftovers) != 0 {
+		t.Fatalf("temporary files left behind: %v", leftovers)
+	}
+}
+
+func TestImageVariantRejectsUnknownPresets(t *testing.T) {
+	for _, query := range []string{"size=huge", "format=avif"} {
+		if _, err := parseImageVariant(httptest.NewRequest(http.MethodGet, "/thumbnail?"+query, nil)); err == nil {
+			t.Errorf("%s: expected an error", query)
+		}
+	}
+	variant, err := parseImageVariant(httptest.NewRequest(http.MethodGet, "/thumbnail?size=small&format=webp", nil))
+	if err != nil || variant.size != "small" || variant.format != "webp" || variant.isOriginal() {
+		t.Fatalf("variant = %+v err = %v", variant, err)
+	}
+}
@@ -0,0 +1,510 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	ogImageWidth   = 1200
+	ogImageHeight  = 630
+	ogImageMargin  = 60
+	ogArtworkSize  = ogImageHeight - 2*ogImageMargin
+	ogWaveformBars = 85
+	ogImageVersion = "v1"
+
+	ogMaxConcurrentRenders = 4
+	ogCacheMaxAge          = 30 * 24 * time.Hour
+	ogCacheMaxFiles        = 5000
+)
+
+var (
+	ogBackground = color.RGBA{R: 13, G: 11, B: 9, A: 255}
+	ogForeground = color.RGBA{R: 236, G: 231, B: 222, A: 255}
+	ogMuted      = color.RGBA{R: 154, G: 140, B: 122, A: 255}
+	ogAccent     = color.RGBA{R: 196, G: 136, B: 42, A: 255}
+)
+
+type ogFontFaces struct {
+	title  font.Face
+	artist font.Face
+	site   font.Face
+}
+
+var (
+	ogFontsOnce   sync.Once
+	ogBoldFont    *opentype.Font
+	ogRegularFont *opentype.Font
+	ogFontsErr    error
+
+	// ogRenderSlots bounds how many share images render at once; each
+	// render decodes artwork and blurs a full-size canvas.
+	ogRenderSlots = make(chan struct{}, ogMaxConcurrentRenders)
+	ogCachePrune  imageCachePruner
+)
+
+// newOGFontFaces creates the faces for one render. A font.Face caches
+// glyphs and is not safe for concurrent use, so renders never share one;
+// only the parsed fonts are shared.
+func newOGFontFaces() (*ogFontFaces, error) {
+	ogFontsOnce.Do(func() {
+		ogBoldFont, ogFontsErr = opentype.Parse(gobold.TTF)
+		if ogFontsErr == nil {
+			ogRegularFont, ogFontsErr = opentype.Parse(goregular.TTF)
+		}
+	})
+	if ogFontsErr != nil {
+		return nil, ogFontsErr
+	}
+	newFace := func(f *opentype.Font, size float64) (font.Face, error) {
+		return opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	}
+	faces := &ogFontFaces{}
+	var err error
+	if faces.title, err = newFace(ogBoldFont, 56); err != nil {
+		return nil, err
+	}
+	if faces.artist, err = newFace(ogRegularFont, 36); err != nil {
+		faces.Close()
+		return nil, err
+	}
+	if faces.site, err = newFace(ogRegularFont, 28); err != nil {
+		faces.Close()
+		return nil, err
+	}
+	return faces, nil
+}
+
+func (f *ogFontFaces) Close() {
+	for _, face := range []font.Face{f.title, f.artist, f.site} {
+		if face != nil {
+			face.Close()
+		}
+	}
+}
+
+// ogImageSource is everything that goes into a share image. Its hash names
+// the cached PNG, so edits to metadata, artwork or peaks render a new file.
+type ogImageSource struct {
+	title       string
+	artist      string
+	siteTitle   string
+	artworkPath string
+	artworkInfo os.FileInfo
+	mature      bool
+	peaks       []byte
+}
+
+func (s ogImageSource) cacheName(key string) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\x00%s\x00%s\x00%t\x00", s.title, s.artist, s.siteTitle, s.mature)
+	if s.artworkInfo != nil {
+		fmt.Fprintf(hash, "%s\x00%d\x00%d\x00", s.artworkPath, s.artworkInfo.ModTime().UnixNano(), s.artworkInfo.Size())
+	}
+	hash.Write(s.peaks)
+	return fmt.Sprintf("%s-og-%s-%s.png", key, ogImageVersion, hex.EncodeToString(hash.Sum(nil))[:16])
+}
+
+// handleOGImage serves a 1200×630 link preview image for a track, composed
+// from its artwork, title, artist and cached waveform peaks.
+func (h *AudioHandler) handleOGImage(w http.ResponseWriter, r *http.Request, key string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	row, err := h.lookupByKey(key)
+	if err == sql.ErrNoRows || (err == nil && row.deleted) {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+	cacheControl := "public, max-age=86400"
+	if row.removalRequestedAt.Valid {
+		cacheControl = "private, no-store"
+		w.Header().Set("Cache-Control", cacheControl)
+	}
+	if row.removalRestricted(r) {
+		http.Error(w, "Gone", http.StatusGone)
+		return
+	}
+
+	source := ogImageSource{
+		title:     strings.TrimSuffix(filepath.Base(row.path), filepath.Ext(row.path)),
+		siteTitle: h.siteTitle,
+		mature:    row.isMature(),
+		peaks:     h.waveformPeaks(row.id),
+	}
+	if row.title.Valid && row.title.String != "" {
+		source.title = row.title.String
+	}
+	if row.artist.Valid {
+		source.artist = row.artist.String
+	}
+	if fullPath, ok := h.thumbnailFullPath(row); ok {
+		source.artworkPath = fullPath
+	} else if fullPath, ok := h.folderPosterFullPath(row); ok {
+		source.artworkPath = fullPath
+	}
+	if source.artworkPath != "" {
+		if info, err := os.Stat(source.artworkPath); err == nil && !info.IsDir() {
+			source.artworkInfo = info
+		} else {
+			source.artworkPath = ""
+		}
+	}
+
+	cacheDir := filepath.Join(os.TempDir(), "audio-share-og-images")
+	if err := os.MkdirAll(cacheDir, 0700); err != nil {
+		http.Error(w, "Error preparing image", http.StatusInternalServerError)
+		return
+	}
+	cacheName := source.cacheName(key)
+	cachePath := filepath.Join(cacheDir, cacheName)
+	if _, err := os.Stat(cachePath); err != nil {
+		select {
+		case ogRenderSlots <- struct{}{}:
+		case <-r.Context().Done():
+			return
+		}
+		// A concurrent request may have rendered it while this one waited.
+		if _, err := os.Stat(cachePath); err != nil {
+			img, err := renderOGImage(source)
+			if err == nil {
+				err = writePNG(cachePath, img)
+			}
+			<-ogRenderSlots
+			if err != nil {
+				log.Printf("Error rendering share image for %s: %v", key, err)
+				http.Error(w, "Error generating image", http.StatusInternalServerError)
+				return
+			}
+			ogCachePrune.maybePrune(cacheDir, ogCacheMaxAge, ogCacheMaxFiles)
+		} else {
+			<-ogRenderSlots
+		}
+	}
+
+	file, err := os.Open(cachePath)
+	if err != nil {
+		http.Error(w, "Error opening image", http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		http.Error(w, "Error reading image", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", cacheControl)
+	http.ServeContent(w, r, cacheName, info.ModTime(), file)
+}
+
+func (h *AudioHandler) waveformPeaks(fileID int64) []byte {
+	var encoded string
+	err := h.db.QueryRow(`SELECT peaks FROM waveform_cache WHERE audio_file_id = $1`, fileID).Scan(&encoded)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("Error loading waveform peaks for audio_file_id=%d: %v", fileID, err)
+		}
+		return nil
+	}
+	peaks, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil
+	}
+	return peaks
+}
+
+// folderPosterFullPath resolves the poster of the folder a track lives in.
+func (h *AudioHandler) folderPosterFullPath(row *audioRow) (string, bool) {
+	if !row.parentPath.Valid || row.parentPath.String == "" || h.fs == nil {
+		return "", false
+	}
+	var posterImage string
+	err := h.db.QueryRow(
+		`SELECT COALESCE(poster_image, '') FROM folders WHERE path = $1`, row.parentPath.String,
+	).Scan(&posterImage)
+	if err != nil || posterImage == "" {
+		return "", false
+	}
+	parts := strings.SplitN(row.parentPath.String, "/", 2)
+	var relDir string
+	if len(parts) > 1 {
+		relDir = parts[1]
+	}
+	return h.fs.ValidatePath(parts[0], filepath.Join(relDir, posterImage))
+}
+
+func renderOGImage(source ogImageSource) (*image.RGBA, error) {
+	faces, err := newOGFontFaces()
+	if err != nil {
+		return nil, err
+	}
+	defer faces.Close()
+	canvas := image.NewRGBA(image.Rect(0, 0, ogImageWidth, ogImageHeight))
+	draw.Draw(canvas, canvas.Bounds(), &image.Uniform{C: ogBackground}, image.Point{}, draw.Src)
+
+	var artwork image.Image
+	if source.artworkPath != "" {
+		artwork, err = decodeImageFile(source.artworkPath)
+		if err != nil {
+			log.Printf("Error decoding share image artwork %s: %v", source.artworkPath, err)
+			artwork = nil
+		}
+	}
+	if artwork != nil && source.mature {
+		artwork = blurredArtwork(artwork)
+	}
+
+	textLeft := ogImageMargin
+	if artwork != nil {
+		// A blurred, darkened copy of the artwork fills the background.
+		backdrop := image.NewRGBA(image.Rect(0, 0, ogImageWidth/5, ogImageHeight/5))
+		xdraw.ApproxBiLinear.Scale(backdrop, backdrop.Bounds(), artwork, coverRect(artwork.Bounds(), ogImageWidth, ogImageHeight), draw.Src, nil)
+		xdraw.BiLinear.Scale(canvas, canvas.Bounds(), boxBlur(backdrop, 6, 3), backdrop.Bounds(), draw.Src, nil)
+		draw.Draw(canvas, canvas.Bounds(), &image.Uniform{C: color.RGBA{A: 170}}, image.Point{}, draw.Over)
+
+		artRect := image.Rect(ogImageMargin, ogImageMargin, ogImageMargin+ogArtworkSize, ogImageMargin+ogArtworkSize)
+		xdraw.CatmullRom.Scale(canvas, artRect, artwork, coverRect(artwork.Bounds(), 1, 1), draw.Src, nil)
+		textLeft = artRect.Max.X + ogImageMargin
+	}
+	textWidth := ogImageWidth - ogImageMargin - textLeft
+
+	y := ogImageMargin + faces.title.Metrics().Ascent.Ceil()
+	titleLines := wrapText(faces.title, source.title, textWidth, 3)
+	lineHeight := faces.title.Metrics().Height.Ceil() + 6
+	for _, line := range titleLines {
+		drawText(canvas, faces.title, ogForeground, textLeft, y, line)
+		y += lineHeight
+	}
+	if source.artist != "" {
+		y += 4
+		for _, line := range wrapText(faces.artist, source.artist, textWidth, 1) {
+			drawText(canvas, faces.artist, ogMuted, textLeft, y, line)
+		}
+	}
+
+	waveformTop := ogImageHeight - ogImageMargin - 170
+	drawWaveformBars(canvas, image.Rect(textLeft, waveformTop, textLeft+textWidth, waveformTop+120), source.peaks)
+
+	if source.siteTitle != "" {
+		for _, line := range wrapText(faces.site, source.siteTitle, textWidth, 1) {
+			drawText(canvas, faces.site, ogAccent, textLeft, ogImageHeight-ogImageMargin, line)
+		}
+	}
+	return canvas, nil
+}
+
+// blurredArtwork hides mature artwork the same way blurred thumbnails do.
+func blurredArtwork(src image.Image) *image.RGBA {
+	bounds := src.Bounds()
+	width, height := scaledDimensions(bounds.Dx(), bounds.Dy(), 360)
+	scaled := resizeNearest(src, width, height)
+	return boxBlur(scaled, 14, 3)
+}
+
+// coverRect returns the centered part of bounds with the given aspect ratio.
+func coverRect(bounds image.Rectangle, aspectWidth, aspectHeight int) image.Rectangle {
+	width, height := bounds.Dx(), bounds.Dy()
+	if width*aspectHeight > height*aspectWidth {
+		cropped := height * aspectWidth / aspectHeight
+		offset := (width - cropped) / 2
+		return image.Rect(bounds.Min.X+offset, bounds.Min.Y, bounds.Min.X+offset+cropped, bounds.Max.Y)
+	}
+	cropped := width * aspectHeight / aspectWidth
+	offset := (height - cropped) / 2
+	return image.Rect(bounds.Min.X, bounds.Min.Y+offset, bounds.Max.X, bounds.Min.Y+offset+cropped)
+}
+
+// drawWaveformBars draws peaks as centered bars. Without peaks a flat line
+// stands in for the waveform.
+func drawWaveformBars(dst *image.RGBA, rect image.Rectangle, peaks []byte) {
+	step := rect.Dx() / ogWaveformBars
+	barWidth := max(1, step*2/3)
+	mid := rect.Min.Y + rect.Dy()/2
+	for i, level := range waveformBarLevels(peaks, ogWaveformBars) {
+		half := max(2, level*rect.Dy()/255/2)
+		x := rect.Min.X + i*step
+		bar := image.Rect(x, mid-half, x+barWidth, mid+half)
+		draw.Draw(dst, bar, &image.Uniform{C: ogAccent}, image.Point{}, draw.Over)
+	}
+}
+
+func drawText(dst *image.RGBA, face font.Face, c color.Color, x, y int, text string) {
+	drawer := font.Drawer{Dst: dst, Src: image.NewUniform(c), Face: face, Dot: fixed.P(x, y)}
+	drawer.DrawString(text)
+}
+
+// wrapText breaks text into at most maxLines lines no wider than width,
+// ending the last line with an ellipsis when text is cut short.
+func wrapText(face font.Face, text string, width, maxLines int) []string {
+	fits := func(s string) bool { return font.MeasureString(face, s).Ceil() <= width }
+	words := strings.Fields(text)
+	var lines []string
+	current := ""
+	for len(words) > 0 && len(lines) < maxLines {
+		candidate := words[0]
+		if current != "" {
+			candidate = current + " " + words[0]
+		}
+		if fits(candidate) {
+			current = candidate
+			words = words[1:]
+			continue
+		}
+		if current == "" {
+			// A single word wider than the line is split at the edge.
+			current = truncateToWidth(words[0], fits, "")
+			if current == "" {
+				current = string([]rune(words[0])[:1])
+			}
+			words[0] = words[0][len(current):]
+		}
+		lines = append(lines, current)
+		current = ""
+	}
+	if current != "" {
+		lines = append(lines, current)
+	}
+	if len(words) > 0 && len(lines) > 0 {
+		last := len(lines) - 1
+		lines[last] = truncateToWidth(lines[last]+" "+strings.Join(words, " "), fits, "…")
+	}
+	return lines
+}
+
+// truncateToWidth returns the longest prefix of text that fits once suffix
+// is appended, or text itself when it already fits.
+func truncateToWidth(text string, fits func(string) bool, suffix string) string {
+	if fits(text) && suffix == "" {
+		return text
+	}
+	runes := []rune(text)
+	for n := len(runes); n > 0; n-- {
+		candidate := strings.TrimRight(string(runes[:n]), " ") + suffix
+		if fits(candidate) {
+			return candidate
+		}
+	}
+	return suffix
+}
+
+func decodeImageFile(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	img, _, err := image.Decode(file)
+	return img, err
+}
+
+// writePNG writes img through a uniquely named temporary file in the same
+// directory, so concurrent writers of one path never share a file and
+// readers only ever see a complete image.
+func writePNG(path string, img image.Image) error {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := file.Name()
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// imageCachePruner evicts rendered images from a TempDir cache. Cache
+// names include a content hash, so every metadata or artwork edit leaves
+// the previous render behind.
+type imageCachePruner struct {
+	mu         sync.Mutex
+	lastPruned time.Time
+}
+
+const imageCachePruneInterval = 10 * time.Minute
+
+// maybePrune deletes files older than maxAge, then the oldest files beyond
+// maxFiles. It scans the directory at most once per prune interval.
+func (p *imageCachePruner) maybePrune(dir string, maxAge time.Duration, maxFiles int) {
+	now := time.Now()
+	p.mu.Lock()
+	if now.Sub(p.lastPruned) < imageCachePruneInterval {
+		p.mu.Unlock()
+		return
+	}
+	p.lastPruned = now
+	p.mu.Unlock()
+	pruneImageCache(dir, now, maxAge, maxFiles)
+}
+
+func pruneImageCache(dir string, now time.Time, maxAge time.Duration, maxFiles int) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		log.Printf("Error reading image cache %s: %v", dir, err)
+		return
+	}
+	type cachedFile struct {
+		path    string
+		modTime time.Time
+	}
+	var kept []cachedFile
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if now.Sub(info.ModTime()) > maxAge {
+			os.Remove(path)
+			continue
+		}
+		kept = append(kept, cachedFile{path: path, modTime: info.ModTime()})
+	}
+	if len(kept) <= maxFiles {
+		return
+	}
+	sort.Slice(kept, func(i, j int) bool { return kept[i].modTime.Before(kept[j].modTime) })
+	for _, file := range kept[:len(kept)-maxFiles] {
+		os.Remove(file.path)
+	}
+}
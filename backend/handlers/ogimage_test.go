@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"database/sql"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOGImageRendersCachedPNG(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	handler, mock := newMockAudioHandler(t, nil, newTestHandlerAccessKeyManager(t, "10/1m"))
+	handler.siteTitle = "Audio Share"
+
+	for range 2 {
+		expectAudioLookup(mock, "track-key", "audio/show/episode.mp3", false)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT peaks FROM waveform_cache WHERE audio_file_id = $1`)).
+			WithArgs(1).
+			WillReturnError(sql.ErrNoRows)
+	}
+
+	var modTimes []string
+	for range 2 {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/audio/key/track-key/og.png", nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("status = %d, body=%s", recorder.Code, recorder.Body.String())
+		}
+		if got := recorder.Header().Get("Cache-Control"); got != "public, max-age=86400" {
+			t.Errorf("Cache-Control = %q", got)
+		}
+		img, err := png.Decode(recorder.Body)
+		if err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if img.Bounds().Dx() != ogImageWidth || img.Bounds().Dy() != ogImageHeight {
+			t.Fatalf("size = %v", img.Bounds())
+		}
+		modTimes = append(modTimes, recorder.Header().Get("Last-Modified"))
+	}
+	if modTimes[0] != modTimes[1] {
+		t.Errorf("second request re-rendered the image: %v", modTimes)
+	}
+
+	cached, _ := filepath.Glob(filepath.Join(os.TempDir(), "audio-share-og-images", "track-key-og-*.png"))
+	if len(cached) != 1 {
+		t.Fatalf("cached images = %v", cached)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOGImageBlursMatureArtwork(t *testing.T) {
+	artworkPath := filepath.Join(t.TempDir(), "cover.png")
+	stripes := image.NewRGBA(image.Rect(0, 0, 400, 400))
+	for y := range 400 {
+		for x := range 400 {
+			if (x/4)%2 == 0 {
+				stripes.SetRGBA(x, y, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+			} else {
+				stripes.SetRGBA(x, y, color.RGBA{A: 255})
+			}
+		}
+	}
+	file, err := os.Create(artworkPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(file, stripes); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	contrast := func(mature bool) int {
+		img, err := renderOGImage(ogImageSource{title: "Episode", artworkPath: artworkPath, mature: mature})
+		if err != nil {
+			t.Fatalf("renderOGImage: %v", err)
+		}
+		lowest, highest := 255, 0
+		for x := ogImageMargin + 100; x < ogImageMargin+140; x++ {
+			value := int(img.RGBAAt(x, ogImageHeight/2).R)
+			lowest, highest = min(lowest, value), max(highest, value)
+		}
+		return highest - lowest
+	}
+	if got := contrast(false); got < 200 {
+		t.Fatalf("artwork contrast = %d, want the stripes to survive", got)
+	}
+	if got := contrast(true); got > 40 {
+		t.Fatalf("mature artwork contrast = %d, want it blurred", got)
+	}
+}
+
+func TestWrapTextEllipsizesOverflow(t *testing.T) {
+	faces, err := newOGFontFaces()
+	if err != nil {
+		t.Fatalf("newOGFontFaces: %v", err)
+	}
+	defer faces.Close()
+	lines := wrapText(faces.title, strings.Repeat("word ", 40), 400, 2)
+	if len(lines) != 2 || !strings.HasSuffix(lines[1], "…") {
+		t.Fatalf("lines = %q", lines)
+	}
+	lines = wrapText(faces.title, strings.Repeat("x", 80), 400, 3)
+	if len(lines) != 3 || !strings.HasSuffix(lines[2], "…") {
+		t.Fatalf("long word lines = %q", lines)
+	}
+	if lines := wrapText(faces.title, "Short", 400, 3); len(lines) != 1 || lines[0] != "Short" {
+		t.Fatalf("short lines = %q", lines)
+	}
+}
+
+func TestRenderOGImageIsSafeConcurrently(t *testing.T) {
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := renderOGImage(ogImageSource{title: strings.Repeat("Title ", i+1), artist: "Artist", siteTitle: "Site"})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestWritePNGConcurrentWritersOfOnePath(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "track-og.png")
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- writePNG(path, img)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := decodeImageFile(path); err != nil {
+		t.Fatalf("decode written image: %v", err)
+	}
+	if leftovers, _ := filepath.Glob(filepath.Join(dir, "*.tmp")); len(leftovers) != 0 {
+		t.Fatalf("temporary files left behind: %v", leftovers)
+	}
+}
+
+func TestPruneImageCacheEvictsExpiredThenOldestFiles(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	ages := map[string]time.Duration{
+		"expired.png": 40 * 24 * time.Hour,
+		"oldest.png":  3 * time.Hour,
+		"older.png":   2 * time.Hour,
+		"newest.png":  time.Hour,
+	}
+	for name, age := range ages {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("png"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pruneImageCache(dir, now, 30*24*time.Hour, 2)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if strings.Join(names, ",") != "newest.png,older.png" {
+		t.Fatalf("kept = %v, want newest.png and older.png", names)
+	}
+}
@@ -0,0 +1,396 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/onion/audio-share-backend/services"
+)
+
+const xspfNamespace = "http://xspf.org/ns/0/"
+
+type playlistTrackLoader interface {
+	GetPlaylistTracks([]string, bool) ([]services.PlaylistTrack, error)
+}
+
+type likedTrackKeyLister interface {
+	LikedTrackKeys(string, bool) ([]string, error)
+}
+
+type PlaylistHandlerOptions struct {
+	Tracks     playlistTrackLoader
+	Browser    directoryBrowser
+	Library    likedTrackKeyLister
+	Search     searchExecutor
+	AccessKeys *services.AccessKeyManager
+	// CaptchaEnforcement is the CAP_ENFORCEMENT mode. When it is "enforce",
+	// an export past the stream captcha threshold needs the stream captcha
+	// clearance the web player grants.
+	CaptchaEnforcement string
+}
+
+// PlaylistHandler exports folders, liked tracks and search results as
+// extended M3U8 or XSPF playlists whose entries stream without a browser:
+//
+//	/api/playlist/folder/{key}.m3u8
+//	/api/playlist/likes.xspf
+//	/api/playlist/search.m3u8?q=...
+type PlaylistHandler struct {
+	db                 *sql.DB
+	sessionSecret      []byte
+	tracks             playlistTrackLoader
+	browser            directoryBrowser
+	library            likedTrackKeyLister
+	search             searchExecutor
+	accessKeys         *services.AccessKeyManager
+	captchaEnforcement string
+}
+
+type xspfPlaylist struct {
+	XMLName   xml.Name    `xml:"playlist"`
+	Version   string      `xml:"version,attr"`
+	XMLNS     string      `xml:"xmlns,attr"`
+	Title     string      `xml:"title"`
+	Location  string      `xml:"location,omitempty"`
+	TrackList []xspfTrack `xml:"trackList>track"`
+}
+
+type xspfTrack struct {
+	Location string `xml:"location"`
+	Title    string `xml:"title"`
+	Creator  string `xml:"creator,omitempty"`
+	Album    string `xml:"album,omitempty"`
+	Duration int64  `xml:"duration,omitempty"`
+	Image    string `xml:"image,omitempty"`
+	Info     string `xml:"info"`
+}
+
+type playlistEntry struct {
+	url      string
+	title    string
+	artist   string
+	album    string
+	duration float64
+	image    string
+	info     string
+}
+
+func NewPlaylistHandler(db *sql.DB, sessionSecret string, options PlaylistHandlerOptions) *PlaylistHandler {
+	return &PlaylistHandler{
+		db:                 db,
+		sessionSecret:      []byte(sessionSecret),
+		tracks:             options.Tracks,
+		browser:            options.Browser,
+		library:            options.Library,
+		search:             options.Search,
+		accessKeys:         options.AccessKeys,
+		captchaEnforcement: options.CaptchaEnforcement,
+	}
+}
+
+func (h *PlaylistHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Robots-Tag", "noindex, nofollow, noarchive")
+	preventProfileCaching(w)
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method_not_allowed"})
+		return
+	}
+
+	rest := strings.TrimPrefix(r.URL.Path, "/api/playlist/")
+	format := path.Ext(rest)
+	if format != ".m3u8" && format != ".xspf" {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown_playlist_format"})
+		return
+	}
+	source := strings.TrimSuffix(rest, format)
+
+	sessionID, ok := currentSessionID(r, h.sessionSecret)
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_session"})
+		return
+	}
+	if h.accessKeys == nil || h.tracks == nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "access_keys_unavailable"})
+		return
+	}
+
+	includeRemovalRequested := isLocalRequest(r)
+	var title string
+	var keys []string
+	var err error
+	switch {
+	case source == "likes":
+		title = "Liked tracks"
+		keys, err = h.library.LikedTrackKeys(sessionID, includeRemovalRequested)
+	case source == "search":
+		title, keys, err = h.searchKeys(r, includeRemovalRequested)
+	case strings.HasPrefix(source, "folder/"):
+		title, keys, err = h.folderKeys(r, strings.TrimPrefix(source, "folder/"), includeRemovalRequested)
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown_playlist"})
+		return
+	}
+	if err == sql.ErrNoRows {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "folder_not_found"})
+		return
+	}
+	if err != nil {
+		log.Printf("Error loading playlist %s: %v", source, err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	tracks, err := h.tracks.GetPlaylistTracks(keys, includeRemovalRequested)
+	if err != nil {
+		log.Printf("Error loading playlist tracks for %s: %v", source, err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	if !h.chargeExport(w, r, sessionID) {
+		return
+	}
+	entries, err := h.playlistEntries(r, tracks)
+	if err != nil {
+		log.Printf("Error issuing playlist keys for %s: %v", source, err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	var body []byte
+	contentType := "audio/x-mpegurl; charset=utf-8"
+	if format == ".xspf" {
+		contentType = "application/xspf+xml; charset=utf-8"
+		body, err = renderXSPF(title, entries)
+	} else {
+		body = renderM3U8(title, entries)
+	}
+	if err != nil {
+		log.Printf("Error encoding playlist %s: %v", source, err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": playlistFilename(title) + format,
+	}))
+	w.Write(body)
+}
+
+// chargeExport counts the export against the stream limits, the stream
+// captcha threshold and the export cap, writing the error response when
+// one of them refuses it.
+func (h *PlaylistHandler) chargeExport(w http.ResponseWriter, r *http.Request, sessionID string) bool {
+	clientAddress := clientIP(r)
+	captchaCleared := h.captchaEnforcement == "" || h.captchaEnforcement == "off" ||
+		streamCaptchaClearanceEnabled(r, h.sessionSecret, sessionID, time.Now())
+	err := h.accessKeys.RecordPlaylistExport(sessionID, clientAddress, captchaCleared)
+	if errors.Is(err, services.ErrCaptchaRequired) && h.captchaEnforcement == "observe" {
+		log.Printf("Cap observation: challenge would be required for purpose=%s", services.MediaPurposePlaylistExport)
+		err = h.accessKeys.RecordPlaylistExport(sessionID, clientAddress, true)
+	}
+	if err == nil {
+		return true
+	}
+	if errors.Is(err, services.ErrCaptchaRequired) {
+		writeJSON(w, http.StatusForbidden, map[string]interface{}{
+			"error":   "captcha_required",
+			"purpose": services.MediaPurposeStream,
+		})
+		return false
+	}
+	var limited *services.KeyLimitExceededError
+	if errors.As(err, &limited) {
+		writeKeyLimitError(w, limited.Purpose, err)
+		return false
+	}
+	log.Printf("Error recording playlist export: %v", err)
+	writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+	return false
+}
+
+func (h *PlaylistHandler) folderKeys(r *http.Request, folderKey string, includeRemovalRequested bool) (string, []string, error) {
+	if h.browser == nil || folderKey == "" || strings.Contains(folderKey, "/") {
+		return "", nil, sql.ErrNoRows
+	}
+	var folderPath, name string
+	err := h.db.QueryRow(`SELECT path, name FROM folders WHERE share_key = $1`, folderKey).Scan(&folderPath, &name)
+	if err != nil {
+		return "", nil, err
+	}
+	opts := services.BrowseOptions{
+		Type:                    "audio",
+		Limit:                   services.MaxPlaylistTracks,
+		IncludeRemovalRequested: includeRemovalRequested,
+	}
+	if value := r.URL.Query().Get("sort"); browseSortValues[value] {
+		opts.Sort = value
+	}
+	contents, err := h.browser.BrowseDirectory(folderPath, opts)
+	if err != nil {
+		return "", nil, err
+	}
+	keys := make([]string, 0, len(contents.Items))
+	for _, item := range contents.Items {
+		if item.Type == "audio" && item.ShareKey != "" {
+			keys = append(keys, item.ShareKey)
+		}
+	}
+	return name, keys, nil
+}
+
+func (h *PlaylistHandler) searchKeys(r *http.Request, includeRemovalRequested bool) (string, []string, error) {
+	if h.search == nil {
+		return "", nil, sql.ErrNoRows
+	}
+	values := r.URL.Query()
+	values.Set("type", "audio")
+	response, err := searchResponseForValues(h.search, values, includeRemovalRequested)
+	if err != nil {
+		return "", nil, err
+	}
+	keys := make([]string, 0, len(response.Results))
+	for _, result := range response.Results {
+		if result.Type == "audio" && result.ShareKey != "" {
+			keys = append(keys, result.ShareKey)
+		}
+	}
+	title := "Search results"
+	if query := strings.TrimSpace(values.Get("q")); query != "" {
+		title = "Search: " + query
+	}
+	return title, keys, nil
+}
+
+// playlistEntries signs a stream URL for every track under one fresh
+// playlist ID, dropping mature tracks unless the browser opted in.
+func (h *PlaylistHandler) playlistEntries(r *http.Request, tracks []services.PlaylistTrack) ([]playlistEntry, error) {
+	origin := siteOrigin(r)
+	playlistID := generateSessionID()
+	showMature := maturePreferenceEnabled(r, h.sessionSecret)
+
+	entries := make([]playlistEntry, 0, len(tracks))
+	for _, track := range tracks {
+		if track.Mature && !showMature {
+			continue
+		}
+		issued, err := h.accessKeys.IssuePlaylistKey(playlistID, track.ShareKey)
+		if err != nil {
+			return nil, err
+		}
+		escapedKey := url.PathEscape(track.ShareKey)
+		query := url.Values{}
+		query.Set("list", playlistID)
+		query.Set("token", issued.AccessKey)
+		entry := playlistEntry{
+			url:      origin + "/api/audio/key/" + escapedKey + "/play?" + query.Encode(),
+			title:    track.Title,
+			artist:   track.Artist,
+			album:    track.Album,
+			duration: track.DurationSeconds,
+			info:     origin + "/share/" + escapedKey,
+		}
+		if entry.title == "" {
+			entry.title = strings.TrimSuffix(track.Filename, path.Ext(track.Filename))
+		}
+		if track.HasThumbnail {
+			entry.image = origin + "/api/audio/key/" + escapedKey + "/thumbnail"
+		} else if track.HasPoster && track.ParentShareKey != "" {
+			entry.image = origin + "/api/folder/key/" + url.PathEscape(track.ParentShareKey) + "/poster"
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// m3uField keeps metadata on a single line of an M3U8 directive.
+func m3uField(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+func renderM3U8(title string, entries []playlistEntry) []byte {
+	var b bytes.Buffer
+	b.WriteString("#EXTM3U\n")
+	fmt.Fprintf(&b, "#PLAYLIST:%s\n", m3uField(title))
+	for _, entry := range entries {
+		duration := -1
+		if entry.duration > 0 {
+			duration = int(math.Round(entry.duration))
+		}
+		display := m3uField(entry.title)
+		if entry.artist != "" {
+			display = m3uField(entry.artist) + " - " + display
+		}
+		fmt.Fprintf(&b, "\n#EXTINF:%d,%s\n", duration, display)
+		if entry.artist != "" {
+			fmt.Fprintf(&b, "#EXTART:%s\n", m3uField(entry.artist))
+		}
+		if entry.album != "" {
+			fmt.Fprintf(&b, "#EXTALB:%s\n", m3uField(entry.album))
+		}
+		if entry.image != "" {
+			fmt.Fprintf(&b, "#EXTIMG:%s\n", entry.image)
+		}
+		b.WriteString(entry.url + "\n")
+	}
+	return b.Bytes()
+}
+
+func renderXSPF(title string, entries []playlistEntry) ([]byte, error) {
+	playlist := xspfPlaylist{
+		Version:   "1",
+		XMLNS:     xspfNamespace,
+		Title:     title,
+		TrackList: make([]xspfTrack, 0, len(entries)),
+	}
+	for _, entry := range entries {
+		track := xspfTrack{
+			Location: entry.url,
+			Title:    entry.title,
+			Creator:  entry.artist,
+			Album:    entry.album,
+			Image:    entry.image,
+			Info:     entry.info,
+		}
+		if entry.duration > 0 {
+			track.Duration = int64(math.Round(entry.duration * 1000))
+		}
+		playlist.TrackList = append(playlist.TrackList, track)
+	}
+
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	encoder := xml.NewEncoder(&b)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(playlist); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// playlistFilename turns a playlist title into a download-safe file name.
+func playlistFilename(title string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r < 0x20, strings.ContainsRune(`/\:*?"<>|`, r):
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(title))
+	if name == "" {
+		return "playlist"
+	}
+	return name
+}
@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/onion/audio-share-backend/services"
+)
+
+type playlistTracksStub struct {
+	tracks []services.PlaylistTrack
+	keys   [][]string
+}
+
+func (s *playlistTracksStub) GetPlaylistTracks(keys []string, includeRemovalRequested bool) ([]services.PlaylistTrack, error) {
+	s.keys = append(s.keys, keys)
+	return s.tracks, nil
+}
+
+func newTestPlaylistAccessKeys(t *testing.T) *services.AccessKeyManager {
+	t.Helper()
+	manager := newTestHandlerAccessKeyManager(t, "10/1m")
+	if err := manager.SetPlaylistPolicy("10/1h", time.Hour); err != nil {
+		t.Fatalf("SetPlaylistPolicy: %v", err)
+	}
+	return manager
+}
+
+func TestFolderPlaylistExportsSignedM3U8Entries(t *testing.T) {
+	manager := newTestPlaylistAccessKeys(t)
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT path, name FROM folders WHERE share_key = $1`)).
+		WithArgs("show-key").
+		WillReturnRows(sqlmock.NewRows([]string{"path", "name"}).AddRow("podcasts/show", "The Show"))
+
+	browser := &recordingDirectoryBrowser{contents: map[string]*services.DirectoryContents{
+		"podcasts/show": {Items: []services.FileSystemItem{
+			{Type: "audio", ShareKey: "ep-1"},
+			{Type: "audio", ShareKey: "ep-2"},
+			{Type: "audio", ShareKey: "ep-3"},
+		}},
+	}}
+	tracks := &playlistTracksStub{tracks: []services.PlaylistTrack{
+		{ShareKey: "ep-1", Filename: "ep1.mp3", Title: "Episode One", Artist: "Host", Album: "The Show", DurationSeconds: 61.6, HasThumbnail: true},
+		{ShareKey: "ep-2", Filename: "ep2.mp3", ParentShareKey: "show-key", HasPoster: true},
+		{ShareKey: "ep-3", Filename: "ep3.mp3", Title: "Late Night", Mature: true},
+	}}
+	handler := NewPlaylistHandler(db, "secret", PlaylistHandlerOptions{
+		Tracks:     tracks,
+		Browser:    browser,
+		AccessKeys: manager,
+	})
+
+	request := signedAudioRequest(http.MethodGet, "https://example.test/api/playlist/folder/show-key.m3u8", "", "secret", "session-1")
+	request.RemoteAddr = "203.0.113.5:1234"
+	request.Header.Set("X-Forwarded-Proto", "https")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, body=%s", recorder.Code, recorder.Body.String())
+	}
+	if got := strings.Join(tracks.keys[0], ","); got != "ep-1,ep-2,ep-3" {
+		t.Fatalf("track keys = %s", got)
+	}
+	if browser.opts[0].Type != "audio" || browser.opts[0].IncludeRemovalRequested {
+		t.Fatalf("browse options = %+v", browser.opts[0])
+	}
+	if got := recorder.Header().Get("Content-Disposition"); got != `attachment; filename="The Show.m3u8"` {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+
+	body := recorder.Body.String()
+	for _, want := range []string{
+		"#EXTM3U\n#PLAYLIST:The Show\n",
+		"#EXTINF:62,Host - Episode One\n#EXTART:Host\n#EXTALB:The Show\n#EXTIMG:https://example.test/api/audio/key/ep-1/thumbnail\n",
+		"#EXTINF:-1,ep2\n#EXTIMG:https://example.test/api/folder/key/show-key/poster\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("playlist missing %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "Late Night") {
+		t.Errorf("mature track exported without the mature preference:\n%s", body)
+	}
+
+	var entryURL *url.URL
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(line, "https://example.test/api/audio/key/ep-1/play?") {
+			entryURL, _ = url.Parse(line)
+		}
+	}
+	if entryURL == nil {
+		t.Fatalf("no stream URL for ep-1:\n%s", body)
+	}
+	query := entryURL.Query()
+	if _, err := manager.VerifyPlaylistKey(query.Get("token"), query.Get("list"), "ep-1"); err != nil {
+		t.Fatalf("entry token did not verify: %v", err)
+	}
+	if _, err := manager.VerifyPlaylistKey(query.Get("token"), query.Get("list"), "ep-2"); err == nil {
+		t.Fatal("entry token verified for another track")
+	}
+}
+
+func TestSearchPlaylistExportsXSPF(t *testing.T) {
+	tracks := &playlistTracksStub{tracks: []services.PlaylistTrack{
+		{ShareKey: "song", Filename: "song.mp3", Title: "Song", Artist: "Band", Album: "Live", DurationSeconds: 1.5},
+	}}
+	handler := NewPlaylistHandler(nil, "secret", PlaylistHandlerOptions{
+		Tracks:     tracks,
+		Search:     snapshotSearchStub{results: []services.SearchResult{{Type: "audio", ShareKey: "song"}, {Type: "folder", ShareKey: "dir"}}},
+		AccessKeys: newTestPlaylistAccessKeys(t),
+	})
+
+	request := signedAudioRequest(http.MethodGet, "http://example.test/api/playlist/search.xspf?q=song", "", "secret", "session-1")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, body=%s", recorder.Code, recorder.Body.String())
+	}
+	if got := strings.Join(tracks.keys[0], ","); got != "song" {
+		t.Fatalf("track keys = %s", got)
+	}
+
+	var playlist struct {
+		Title  string `xml:"title"`
+		Tracks []struct {
+			Location string `xml:"location"`
+			Title    string `xml:"title"`
+			Creator  string `xml:"creator"`
+			Album    string `xml:"album"`
+			Duration int64  `xml:"duration"`
+			Info     string `xml:"info"`
+		} `xml:"trackList>track"`
+	}
+	if err := xml.Unmarshal(recorder.Body.Bytes(), &playlist); err != nil {
+		t.Fatalf("decode: %v\n%s", err, recorder.Body.String())
+	}
+	if playlist.Title != "Search: song" || len(playlist.Tracks) != 1 {
+		t.Fatalf("playlist = %+v", playlist)
+	}
+	track := playlist.Tracks[0]
+	if track.Title != "Song" || track.Creator != "Band" || track.Album != "Live" || track.Duration != 1500 ||
+		track.Info != "http://example.test/share/song" ||
+		!strings.HasPrefix(track.Location, "http://example.test/api/audio/key/song/play?") {
+		t.Fatalf("track = %+v", track)
+	}
+}
+
+func TestPlaylistExportRequiresSession(t *testing.T) {
+	handler := NewPlaylistHandler(nil, "secret", PlaylistHandlerOptions{
+		Tracks:     &playlistTracksStub{},
+		AccessKeys: newTestPlaylistAccessKeys(t),
+	})
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/playlist/likes.m3u8", nil))
+	if recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, signedAudioRequest(http.MethodGet, "/api/playlist/likes.pls", "", "secret", "session-1"))
+	if recorder.Code != http.StatusNotFound {
+		t.Fatalf("unknown format status = %d, want 404", recorder.Code)
+	}
+}
+
+func TestPlaylistEntryRejectsTokensForOtherTracks(t *testing.T) {
+	manager := newTestPlaylistAccessKeys(t)
+	handler, _ := newMockAudioHandler(t, nil, manager)
+	limiter := &stubAccessFailureLimiter{allowed: true}
+	handler.accessFailureLimiter = limiter
+	issued, err := manager.IssuePlaylistKey("list-1", "track-a")
+	if err != nil {
+		t.Fatalf("IssuePlaylistKey: %v", err)
+	}
+
+	query := url.Values{"list": {"list-1"}, "token": {issued.AccessKey}}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/audio/key/track-b/play?"+query.Encode(), nil))
+	if recorder.Code != http.StatusForbidden || limiter.failures != 1 {
+		t.Fatalf("status = %d failures = %d, want 403 and 1", recorder.Code, limiter.failures)
+	}
+}
+
+func TestPlaylistExportsCountAgainstStreamLimitsAndExportCap(t *testing.T) {
+	tests := []struct {
+		name         string
+		streamPolicy string
+		exportPolicy string
+		wantPurpose  string
+	}{
+		{"stream limit", "1/1m", "", "stream"},
+		{"export cap", "10/1m", "1/1h", "playlist_export"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			manager := newTestHandlerAccessKeyManager(t, test.streamPolicy)
+			if err := manager.SetPlaylistPolicy("10/1h", time.Hour); err != nil {
+				t.Fatalf("SetPlaylistPolicy: %v", err)
+			}
+			if test.exportPolicy != "" {
+				if err := manager.SetPlaylistExportPolicy(test.exportPolicy); err != nil {
+					t.Fatalf("SetPlaylistExportPolicy: %v", err)
+				}
+			}
+			handler := NewPlaylistHandler(nil, "secret", PlaylistHandlerOptions{
+				Tracks:     &playlistTracksStub{tracks: []services.PlaylistTrack{{ShareKey: "song", Filename: "song.mp3"}}},
+				Search:     snapshotSearchStub{results: []services.SearchResult{{Type: "audio", ShareKey: "song"}}},
+				AccessKeys: manager,
+			})
+
+			var statuses []int
+			var body string
+			for range 2 {
+				request := signedAudioRequest(http.MethodGet, "/api/playlist/search.m3u8?q=song", "", "secret", "session-1")
+				recorder := httptest.NewRecorder()
+				handler.ServeHTTP(recorder, request)
+				statuses = append(statuses, recorder.Code)
+				body = recorder.Body.String()
+			}
+			if statuses[0] != http.StatusOK || statuses[1] != http.StatusTooManyRequests {
+				t.Fatalf("statuses = %v, want 200 then 429", statuses)
+			}
+			if !strings.Contains(body, `"purpose":"`+test.wantPurpose+`"`) {
+				t.Fatalf("limit body = %s, want purpose %s", body, test.wantPurpose)
+			}
+		})
+	}
+}
+
+func TestPlaylistEntryRangeRequestsCountAgainstPlaylistLimits(t *testing.T) {
+	manager := newTestHandlerAccessKeyManager(t, "10/1m")
+	if err := manager.SetPlaylistPolicy("1/1h", time.Hour); err != nil {
+		t.Fatalf("SetPlaylistPolicy: %v", err)
+	}
+	handler, mock := newMockAudioHandler(t, nil, manager)
+	issued, err := manager.IssuePlaylistKey("list-1", "track-a")
+	if err != nil {
+		t.Fatalf("IssuePlaylistKey: %v", err)
+	}
+	if err := manager.RecordPlaylistAccess("list-1", "192.0.2.1"); err != nil {
+		t.Fatalf("RecordPlaylistAccess: %v", err)
+	}
+	expectAudioLookup(mock, "track-a", "audio/track-a.mp3", false)
+
+	query := url.Values{"list": {"list-1"}, "token": {issued.AccessKey}}
+	request := httptest.NewRequest(http.MethodGet, "/api/audio/key/track-a/play?"+query.Encode(), nil)
+	request.RemoteAddr = "192.0.2.1:1234"
+	request.Header.Set("Range", "bytes=1-")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429; body=%s", recorder.Code, recorder.Body.String())
+	}
+}
@@ -138,14 +138,15 @@ func TestAudioAccessRejectsRemovalRequestedAudioForExternalClients(t *testing.T)
 	requestedAt := time.Date(2026, time.August, 14, 12, 0, 0, 0, time.UTC)
 	mock.ExpectQuery(regexp.QuoteMeta(`
 		SELECT id, path, deleted, unavailable_at, removal_requested_at, thumbnail, title, meta_artist, upload_date,
-		       webpage_url, description, age_limit, parent_path
+		       webpage_url, description, age_limit, parent_path, ` + services.ThumbnailPlaceholderColumn("audio_files") + `,
+		       chapters
 		FROM audio_files WHERE share_key = $1
 	`)).
 		WithArgs("track-key").
 		WillReturnRows(sqlmock.NewRows([]string{
 			"id", "path", "deleted", "unavailable_at", "removal_requested_at", "thumbnail", "title",
-			"meta_artist", "upload_date", "webpage_url", "description", "age_limit", "parent_path",
-		}).AddRow(1, "audio/track.mp3", 0, nil, requestedAt, nil, nil, nil, nil, nil, nil, nil, nil))
+			"meta_artist", "upload_date", "webpage_url", "description", "age_limit", "parent_path", "thumbnail_placeholder", "chapters",
+		}).AddRow(1, "audio/track.mp3", 0, nil, requestedAt, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil))
 
 	request := signedAudioRequest(
 		http.MethodPost,
@@ -177,14 +178,15 @@ func TestRemovalRequestedThumbnailIsNeverPubliclyCacheable(t *testing.T) {
 	expectLookup := func() {
 		mock.ExpectQuery(regexp.QuoteMeta(`
 			SELECT id, path, deleted, unavailable_at, removal_requested_at, thumbnail, title, meta_artist, upload_date,
-			       webpage_url, description, age_limit, parent_path
+			       webpage_url, description, age_limit, parent_path, ` + services.ThumbnailPlaceholderColumn("audio_files") + `,
+			       chapters
 			FROM audio_files WHERE share_key = $1
 		`)).
 			WithArgs("track-key").
 			WillReturnRows(sqlmock.NewRows([]string{
 				"id", "path", "deleted", "unavailable_at", "removal_requested_at", "thumbnail", "title",
-				"meta_artist", "upload_date", "webpage_url", "description", "age_limit", "parent_path",
-			}).AddRow(1, "audio/track.mp3", 0, nil, requestedAt, "cover.jpg", nil, nil, nil, nil, nil, nil, nil))
+				"meta_artist", "upload_date", "webpage_url", "description", "age_limit", "parent_path", "thumbnail_placeholder", "chapters",
+			}).AddRow(1, "audio/track.mp3", 0, nil, requestedAt, "cover.jpg", nil, nil, nil, nil, nil, nil, nil, nil, nil))
 	}
 
 	expectLookup()
@@ -245,6 +247,61 @@ func TestRemovalRequestedWaveformIsNotPubliclyCacheableForLocalClients(t *testin
 	}
 }
 
+func TestRemovalRequestedTranscriptIsOnlyServedLocally(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	handler := NewAudioHandler(nil, db, AudioHandlerOptions{})
+	requestedAt := time.Date(2026, time.August, 14, 12, 0, 0, 0, time.UTC)
+	expectLookup := func() {
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM audio_files WHERE share_key = $1`)).
+			WithArgs("track-key").
+			WillReturnRows(sqlmock.NewRows([]string{
+				"id", "path", "deleted", "unavailable_at", "removal_requested_at", "thumbnail", "title",
+				"meta_artist", "upload_date", "webpage_url", "description", "age_limit", "parent_path", "thumbnail_placeholder", "chapters",
+			}).AddRow(1, "audio/track.mp3", 0, nil, requestedAt, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil))
+	}
+
+	expectLookup()
+	externalRecorder := httptest.NewRecorder()
+	handler.ServeHTTP(externalRecorder, httptest.NewRequest(http.MethodGet, "https://example.test/api/audio/key/track-key/transcript", nil))
+	if externalRecorder.Code != http.StatusGone || !strings.Contains(externalRecorder.Body.String(), "removal_requested") {
+		t.Fatalf("external status = %d, body = %s", externalRecorder.Code, externalRecorder.Body.String())
+	}
+
+	expectLookup()
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM transcripts t`)).
+		WithArgs(int64(1), "en").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "format", "language", "start_time", "end_time", "text"}).
+			AddRow(4, "vtt", "en", 0.5, 2.0, "Hello there").
+			AddRow(4, "vtt", "en", 2.0, nil, "General Kenobi"))
+	localRequest := httptest.NewRequest(http.MethodGet, "https://example.test/api/audio/key/track-key/transcript?lang=en", nil)
+	localRequest.RemoteAddr = "10.0.0.5:8080"
+	localRecorder := httptest.NewRecorder()
+	handler.ServeHTTP(localRecorder, localRequest)
+	if localRecorder.Code != http.StatusOK {
+		t.Fatalf("local status = %d, body = %s", localRecorder.Code, localRecorder.Body.String())
+	}
+	if got := localRecorder.Header().Get("Cache-Control"); got != "private, no-store" {
+		t.Fatalf("local Cache-Control = %q, want private, no-store", got)
+	}
+	var body struct {
+		Transcripts []services.Transcript `json:"transcripts"`
+	}
+	if err := json.Unmarshal(localRecorder.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Transcripts) != 1 || body.Transcripts[0].Language != "en" || len(body.Transcripts[0].Cues) != 2 ||
+		body.Transcripts[0].Cues[1].Text != "General Kenobi" {
+		t.Fatalf("transcripts = %#v", body.Transcripts)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestAdminCanSetAndClearRemovalRequest(t *testing.T) {
 	tests := []struct {
 		name string
@@ -323,3 +380,13 @@ func TestSPASnapshotHidesRemovalRequestedAudioExternally(t *testing.T) {
 		t.Fatalf("unexpected local initial data: %#v", localContents.Items)
 	}
 }
+
+func TestSearchResponseScopesToFolderPath(t *testing.T) {
+	service := &capturingSearchExecutor{}
+	if _, err := searchResponseForValues(service, url.Values{"path": {" /Audio/Show/ "}}, false); err != nil {
+		t.Fatal(err)
+	}
+	if service.opts.Path != "Audio/Show" {
+		t.Fatalf("Path = %q, want %q", service.opts.Path, "Audio/Show")
+	}
+}
@@ -19,6 +19,7 @@ var validRequestStatuses = map[string]bool{
 	"indexing":    true,
 	"added":       true,
 	"rejected":    true,
+	"draft":       true,
 }
 
 func isValidRequestStatus(s string) bool {
@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -10,12 +12,27 @@ import (
 	"github.com/onion/audio-share-backend/services"
 )
 
+type searchInsightsRecorder interface {
+	Record(query string, resultCount int, filters map[string]string) error
+}
+
+// maxPendingSearchInsights bounds how many searches are written to the
+// insights log at once. Searches beyond it go unrecorded rather than queue
+// behind a slow database.
+const maxPendingSearchInsights = 8
+
 type SearchHandler struct {
 	searchService *services.SearchService
+	insights      searchInsightsRecorder
+	insightSlots  chan struct{}
 }
 
-func NewSearchHandler(searchService *services.SearchService) *SearchHandler {
-	return &SearchHandler{searchService: searchService}
+func NewSearchHandler(searchService *services.SearchService, insights searchInsightsRecorder) *SearchHandler {
+	return &SearchHandler{
+		searchService: searchService,
+		insights:      insights,
+		insightSlots:  make(chan struct{}, maxPendingSearchInsights),
+	}
 }
 
 func isRootSlug(value string) bool {
@@ -50,6 +67,7 @@ func searchResponseForValues(service searchExecutor, values url.Values, includeR
 	query := values.Get("q")
 	root := strings.Trim(strings.TrimSpace(values.Get("root")), "/")
 	hasRootFilter := isRootSlug(root)
+	scopePath := strings.Trim(strings.TrimSpace(values.Get("path")), "/")
 
 	hasFilters := values.Get("type") != "" ||
 		values.Get("unavailableOnly") == "true" ||
@@ -60,7 +78,8 @@ func searchResponseForValues(service searchExecutor, values url.Values, includeR
 		values.Get("durationMax") != "" ||
 		values.Get("fields") != "" ||
 		values.Get("includeMature") == "true" ||
-		hasRootFilter
+		hasRootFilter ||
+		scopePath != ""
 
 	if len(query) < 2 && !hasFilters {
 		return SearchResponse{
@@ -114,7 +133,7 @@ func searchResponseForValues(service searchExecutor, values url.Values, includeR
 		}
 	}
 	if value := values.Get("fields"); value != "" {
-		validFields := map[string]bool{"filename": true, "title": true, "artist": true, "description": true}
+		validFields := map[string]bool{"filename": true, "title": true, "artist": true, "description": true, "chapters": true, "transcript": true}
 		for _, field := range strings.Split(value, ",") {
 			field = strings.TrimSpace(field)
 			if validFields[field] {
@@ -125,6 +144,7 @@ func searchResponseForValues(service searchExecutor, values url.Values, includeR
 	if hasRootFilter {
 		opts.Root = root
 	}
+	opts.Path = scopePath
 
 	results, total, err := service.Search(query, limit, offset, opts)
 	if err != nil {
@@ -143,6 +163,52 @@ func searchResponseForValues(service searchExecutor, values url.Values, includeR
 	}, nil
 }
 
+var searchInsightFilterKeys = []string{
+	"type", "unavailableOnly", "sort", "dateFrom", "dateTo", "durationMin",
+	"durationMax", "fields", "root", "path", "includeMature",
+}
+
+const maxInsightFilterValueLen = 100
+
+// searchInsightFilters returns the recognised filters present on a search.
+func searchInsightFilters(values url.Values) map[string]string {
+	filters := map[string]string{}
+	for _, key := range searchInsightFilterKeys {
+		value := strings.TrimSpace(values.Get(key))
+		if value == "" || value == "false" {
+			continue
+		}
+		if len(value) > maxInsightFilterValueLen {
+			value = value[:maxInsightFilterValueLen]
+		}
+		filters[key] = value
+	}
+	return filters
+}
+
+// recordSearchInsight logs the first page of an executed search. Paging,
+// bots and early-exit queries are skipped so counts reflect real intent.
+func (h *SearchHandler) recordSearchInsight(r *http.Request, values url.Values, response SearchResponse) {
+	if h.insights == nil || response.Offset != 0 || isBotLikeUserAgent(r.UserAgent()) {
+		return
+	}
+	filters := searchInsightFilters(values)
+	if len(response.Query) < 2 && len(filters) == 0 {
+		return
+	}
+	select {
+	case h.insightSlots <- struct{}{}:
+	default:
+		return
+	}
+	go func() {
+		defer func() { <-h.insightSlots }()
+		if err := h.insights.Record(response.Query, response.Total, filters); err != nil {
+			log.Printf("search insights: record failed: %v", err)
+		}
+	}()
+}
+
 func (h *SearchHandler) RandomHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
@@ -166,11 +232,17 @@ func (h *SearchHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response, err := searchResponseForValues(h.searchService, r.URL.Query(), isLocalRequest(r))
+	values := r.URL.Query()
+	response, err := searchResponseForValues(h.searchService, values, isLocalRequest(r))
+	if errors.Is(err, services.ErrFolderNotFound) {
+		http.Error(w, "Folder not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Search error", http.StatusInternalServerError)
 		return
 	}
+	h.recordSearchInsight(r, values, response)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Cache-Control", "private, no-store")
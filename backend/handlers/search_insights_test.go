@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/onion/audio-share-backend/services"
+)
+
+type fakeSearchInsightsReporter struct {
+	since time.Time
+	limit int
+}
+
+func (f *fakeSearchInsightsReporter) Insights(since time.Time, limit int) (*services.SearchInsights, error) {
+	f.since = since
+	f.limit = limit
+	return &services.SearchInsights{
+		TotalSearches:        4,
+		ZeroResultSearches:   1,
+		TopZeroResultQueries: []services.SearchQueryCount{{Query: "missing show", Searches: 1, ZeroResults: 1}},
+	}, nil
+}
+
+func TestSearchInsightFiltersKeepsRecognisedValues(t *testing.T) {
+	filters := searchInsightFilters(url.Values{
+		"q":             {"ignored"},
+		"type":          {"audio"},
+		"includeMature": {"false"},
+		"path":          {"Audio/Show"},
+		"session":       {"secret"},
+	})
+	if len(filters) != 2 || filters["type"] != "audio" || filters["path"] != "Audio/Show" {
+		t.Fatalf("filters = %#v", filters)
+	}
+}
+
+func TestAdminSearchInsightsClampsParameters(t *testing.T) {
+	reporter := &fakeSearchInsightsReporter{}
+	handler := NewAdminHandler(nil, nil, AdminHandlerOptions{SearchInsights: reporter})
+	request := httptest.NewRequest(http.MethodGet, "https://example.test/api/admin/search-insights?days=9999&limit=500", nil)
+	recorder := httptest.NewRecorder()
+
+	handler.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, body=%s", recorder.Code, recorder.Body.String())
+	}
+	if reporter.limit != maxSearchInsightLimit {
+		t.Fatalf("limit = %d, want %d", reporter.limit, maxSearchInsightLimit)
+	}
+	if age := time.Since(reporter.since); age < time.Duration(maxSearchInsightDays-1)*24*time.Hour {
+		t.Fatalf("since is only %s ago", age)
+	}
+	var response services.SearchInsights
+	if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if response.ZeroResultSearches != 1 || response.TopZeroResultQueries[0].Query != "missing show" {
+		t.Fatalf("response = %#v", response)
+	}
+}
+
+func TestAdminSearchInsightsRejectsInvalidDays(t *testing.T) {
+	handler := NewAdminHandler(nil, nil, AdminHandlerOptions{SearchInsights: &fakeSearchInsightsReporter{}})
+	request := httptest.NewRequest(http.MethodGet, "https://example.test/api/admin/search-insights?days=-1", nil)
+	recorder := httptest.NewRecorder()
+
+	handler.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusBadRequest)
+	}
+}
+
+func TestAdminSearchInsightRequestNeedsAbsoluteSourceURL(t *testing.T) {
+	handler := NewAdminHandler(nil, nil, AdminHandlerOptions{SearchInsights: &fakeSearchInsightsReporter{}})
+	for _, submittedURL := range []string{"", "/search?q=missing+show", "ftp://example.com/show"} {
+		body, _ := json.Marshal(map[string]string{"query": "missing show", "submittedUrl": submittedURL})
+		request := httptest.NewRequest(http.MethodPost, "https://example.test/api/admin/search-insights/requests", bytes.NewReader(body))
+		recorder := httptest.NewRecorder()
+
+		handler.ServeHTTP(recorder, request)
+
+		if recorder.Code != http.StatusBadRequest {
+			t.Fatalf("submittedUrl %q: status = %d, want %d", submittedURL, recorder.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+type blockingSearchInsightsRecorder struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingSearchInsightsRecorder) Record(string, int, map[string]string) error {
+	b.started <- struct{}{}
+	<-b.release
+	return nil
+}
+
+func TestSearchInsightsDropRecordsBeyondPendingLimit(t *testing.T) {
+	recorder := &blockingSearchInsightsRecorder{
+		started: make(chan struct{}, maxPendingSearchInsights+1),
+		release: make(chan struct{}),
+	}
+	handler := NewSearchHandler(nil, recorder)
+	request := httptest.NewRequest(http.MethodGet, "https://example.test/api/search?q=missing", nil)
+	for range maxPendingSearchInsights + 1 {
+		handler.recordSearchInsight(request, request.URL.Query(), SearchResponse{Query: "missing"})
+	}
+	for range maxPendingSearchInsights {
+		<-recorder.started
+	}
+	if len(handler.insightSlots) != maxPendingSearchInsights || len(recorder.started) != 0 {
+		t.Fatalf("%d records pending, want %d", len(handler.insightSlots), maxPendingSearchInsights)
+	}
+	close(recorder.release)
+}
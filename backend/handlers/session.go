@@ -67,6 +67,20 @@ func isSecureRequest(r *http.Request) bool {
 	return r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
 }
 
+// isEmbeddedSessionRequest reports whether an /embed/ player asked for its
+// session cookies. Those run in a third-party iframe, where Lax cookies are
+// never sent, so they get partitioned SameSite=None cookies instead.
+func isEmbeddedSessionRequest(r *http.Request) bool {
+	return r.URL.Query().Get("embed") == "1" && isSecureRequest(r)
+}
+
+func sessionSameSite(r *http.Request) http.SameSite {
+	if isEmbeddedSessionRequest(r) {
+		return http.SameSiteNoneMode
+	}
+	return http.SameSiteLaxMode
+}
+
 func resolveSessionID(r *http.Request, secret []byte) (string, bool) {
 	cookie, err := r.Cookie(sessionCookieName)
 	if err != nil || cookie.Value == "" {
@@ -121,13 +135,14 @@ func setSessionCookie(w http.ResponseWriter, r *http.Request, secret []byte, ses
 		}
 	}
 	http.SetCookie(w, &http.Cookie{
-		Name:     sessionCookieName,
-		Value:    signValue(sessionID, secret),
-		MaxAge:   sessionCookieMaxAge,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   isSecureRequest(r),
-		SameSite: http.SameSiteLaxMode,
+		Name:        sessionCookieName,
+		Value:       signValue(sessionID, secret),
+		MaxAge:      sessionCookieMaxAge,
+		Path:        "/",
+		HttpOnly:    true,
+		Secure:      isSecureRequest(r),
+		SameSite:    sessionSameSite(r),
+		Partitioned: isEmbeddedSessionRequest(r),
 	})
 	setSessionCreatedCookie(w, r, secret, sessionID, createdAt)
 }
@@ -149,13 +164,14 @@ func sessionCreatedCookie(
 	createdAt time.Time,
 ) *http.Cookie {
 	return &http.Cookie{
-		Name:     sessionCreatedCookieName,
-		Value:    signSessionCreatedValue(sessionID, secret, createdAt),
-		MaxAge:   sessionCookieMaxAge,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   isSecureRequest(r),
-		SameSite: http.SameSiteLaxMode,
+		Name:        sessionCreatedCookieName,
+		Value:       signSessionCreatedValue(sessionID, secret, createdAt),
+		MaxAge:      sessionCookieMaxAge,
+		Path:        "/",
+		HttpOnly:    true,
+		Secure:      isSecureRequest(r),
+		SameSite:    sessionSameSite(r),
+		Partitioned: isEmbeddedSessionRequest(r),
 	}
 }
 
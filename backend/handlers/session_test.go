@@ -34,6 +34,29 @@ func TestSessionCookieSecurityAttributes(t *testing.T) {
 	}
 }
 
+func TestEmbeddedSessionBootstrapUsesPartitionedCrossSiteCookies(t *testing.T) {
+	handler := NewSessionBootstrapHandler("secret")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "https://example.test/api/session?embed=1", nil))
+	cookies := recorder.Result().Cookies()
+	if len(cookies) != 2 {
+		t.Fatalf("got %d cookies, want 2", len(cookies))
+	}
+	for _, cookie := range cookies {
+		if cookie.SameSite != http.SameSiteNoneMode || !cookie.Partitioned || !cookie.Secure {
+			t.Fatalf("embedded cookie attributes = %#v", cookie)
+		}
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "http://example.test/api/session?embed=1", nil))
+	for _, cookie := range recorder.Result().Cookies() {
+		if cookie.SameSite != http.SameSiteLaxMode || cookie.Partitioned {
+			t.Fatalf("insecure embedded request got cross-site cookie: %#v", cookie)
+		}
+	}
+}
+
 func TestSessionBootstrapCreatesAndPreservesSignedSession(t *testing.T) {
 	secret := "test-secret"
 	handler := NewSessionBootstrapHandler(secret)
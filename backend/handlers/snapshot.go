@@ -50,8 +50,22 @@ type snapshotLink struct {
 type snapshotListPage struct {
 	Heading     string
 	Description string
+	Image       *snapshotImage
 	Items       []snapshotLink
 	MoreItems   int
+	Chapters    []snapshotChapter
+}
+
+type snapshotChapter struct {
+	Time  string
+	Title string
+}
+
+type snapshotImage struct {
+	URL    string
+	Alt    string
+	Width  int
+	Height int
 }
 
 type initialResponse struct {
@@ -90,6 +104,7 @@ var snapshotListTemplate = template.Must(template.New("snapshot-list").Parse(`
 <article class="max-w-4xl mx-auto">
   <h1 class="text-4xl font-bold mb-4 text-[var(--foreground)]">{{.Heading}}</h1>
   {{if .Description}}<p class="text-[var(--muted-foreground)] mb-6">{{.Description}}</p>{{end}}
+  {{with .Image}}<img src="{{.URL}}" alt="{{.Alt}}" width="{{.Width}}" height="{{.Height}}" class="w-full h-auto mb-6">{{end}}
   {{if .Items}}
     <ul class="space-y-3">
       {{range .Items}}
@@ -103,6 +118,12 @@ var snapshotListTemplate = template.Must(template.New("snapshot-list").Parse(`
   {{else}}
     <p>No items are available.</p>
   {{end}}
+  {{if .Chapters}}
+    <h2 class="text-2xl font-semibold mt-8 mb-3 text-[var(--foreground)]">Chapters</h2>
+    <ol class="space-y-1">
+      {{range .Chapters}}<li><span class="font-mono text-sm text-[var(--muted-foreground)]">{{.Time}}</span> {{.Title}}</li>{{end}}
+    </ol>
+  {{end}}
 </article>`))
 
 var snapshotMarkdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
@@ -199,6 +220,9 @@ func (h *SPAHandler) renderSnapshotBody(r *http.Request, meta pageMeta, shareRow
 	case strings.HasPrefix(path, "/share/"):
 		key, _ := shareKeyFromPath(path)
 		return h.renderShareSnapshot(r, key, meta, shareRow, shareLookupErr, responses)
+	case strings.HasPrefix(path, "/embed/"):
+		key, _ := embedKeyFromPath(path)
+		return h.renderEmbedSnapshot(r, key, meta, responses)
 	default:
 		return executeSnapshotTemplate(snapshotListTemplate, snapshotListPage{
 			Heading:     "Page not found",
@@ -232,20 +256,47 @@ func browseAPIPath(path string) string {
 	return "/api/browse/" + encodePath(path)
 }
 
+// browseAPIValuesFromPage maps browse page query parameters onto the API.
+// Page numbers are sized to the snapshot list so each page is fully visible.
+func browseAPIValuesFromPage(values url.Values) url.Values {
+	apiValues := url.Values{}
+	if value := values.Get("sort"); browseSortValues[value] {
+		apiValues.Set("sort", value)
+	}
+	if value := values.Get("type"); value == "audio" || value == "folder" {
+		apiValues.Set("type", value)
+	}
+	if value := strings.TrimSpace(values.Get("q")); value != "" {
+		apiValues.Set("q", value)
+	}
+	if page, err := strconv.Atoi(values.Get("page")); err == nil && page > 0 {
+		apiValues.Set("limit", strconv.Itoa(maxSnapshotItems))
+		if page > 1 {
+			apiValues.Set("offset", strconv.Itoa((page-1)*maxSnapshotItems))
+		}
+	}
+	return apiValues
+}
+
 func (h *SPAHandler) renderDirectorySnapshot(r *http.Request, path, heading, description string, responses initialResponses) template.HTML {
 	page := snapshotListPage{Heading: heading, Description: description}
 	if h.searchService == nil {
 		return executeSnapshotTemplate(snapshotListTemplate, page)
 	}
 
-	contents, err := browseDirectoryContentsForAccess(h.searchService, path, isLocalRequest(r))
+	values := browseAPIValuesFromPage(r.URL.Query())
+	contents, err := browseDirectoryContentsForAccess(h.searchService, path, browseOptionsForValues(values, isLocalRequest(r)))
 	if err != nil {
 		log.Printf("server snapshot browse failed for %q: %v", path, err)
 		return executeSnapshotTemplate(snapshotListTemplate, page)
 	}
-	responses.add(browseAPIPath(path), http.StatusOK, contents)
+	// The SPA sorts and filters a directory itself and only ever fetches it
+	// whole, so a narrowed listing is rendered but not handed to it.
+	if len(values) == 0 {
+		responses.add(browseAPIPath(path), http.StatusOK, contents)
+	}
 	limit, more := cappedSnapshotItems(len(contents.Items))
-	page.MoreItems = more
+	page.MoreItems = max(more, contents.Total-contents.Offset-limit)
 	for _, item := range contents.Items[:limit] {
 		name := item.Title
 		if name == "" {
@@ -366,8 +417,8 @@ func searchAPIValuesFromPage(values url.Values) (url.Values, bool) {
 		}
 	}
 	if value := values.Get("fields"); value != "" {
-		valid := map[string]bool{"filename": true, "title": true, "artist": true, "description": true}
-		fields := make([]string, 0, 4)
+		valid := map[string]bool{"filename": true, "title": true, "artist": true, "description": true, "chapters": true, "transcript": true}
+		fields := make([]string, 0, len(valid))
 		for _, field := range strings.Split(value, ",") {
 			if valid[field] {
 				fields = append(fields, field)
@@ -382,6 +433,10 @@ func searchAPIValuesFromPage(values url.Values) (url.Values, bool) {
 		apiValues.Set("root", value)
 		hasSearch = true
 	}
+	if value := strings.Trim(strings.TrimSpace(values.Get("path")), "/"); value != "" {
+		apiValues.Set("path", value)
+		hasSearch = true
+	}
 	for _, key := range []string{"unavailableOnly", "includeMature"} {
 		if values.Get(key) == "true" {
 			apiValues.Set(key, "true")
@@ -404,12 +459,20 @@ func (h *SPAHandler) renderSearchSnapshot(r *http.Request, responses initialResp
 		return executeSnapshotTemplate(snapshotListTemplate, page)
 	}
 	response, err := searchResponseForValues(h.searchService, values, isLocalRequest(r))
+	if errors.Is(err, services.ErrFolderNotFound) {
+		responses.add("/api/search?"+values.Encode(), http.StatusNotFound, map[string]string{"error": "Folder not found"})
+		page.Description = "The folder to search within does not exist."
+		return executeSnapshotTemplate(snapshotListTemplate, page)
+	}
 	if err != nil {
 		log.Printf("server snapshot search failed: %v", err)
 		return executeSnapshotTemplate(snapshotListTemplate, page)
 	}
 	responses.add("/api/search?"+values.Encode(), http.StatusOK, response)
 	page.Description = fmt.Sprintf("%d results for %q.", response.Total, response.Query)
+	if scope := values.Get("path"); scope != "" {
+		page.Description = fmt.Sprintf("%d results for %q in %s.", response.Total, response.Query, scope)
+	}
 	limit, more := cappedSnapshotItems(len(response.Results))
 	page.MoreItems = more
 	for _, result := range response.Results[:limit] {
@@ -418,6 +481,9 @@ func (h *SPAHandler) renderSearchSnapshot(r *http.Request, responses initialResp
 			link.URL = "/browse/" + encodePath(result.Path)
 		} else if result.ShareKey != "" {
 			link.URL = "/share/" + url.PathEscape(result.ShareKey)
+			if result.TranscriptMatch != nil {
+				link.URL += "?t=" + strconv.Itoa(int(result.TranscriptMatch.StartTime))
+			}
 		}
 		page.Items = append(page.Items, link)
 	}
@@ -448,6 +514,18 @@ func (h *SPAHandler) renderShareSnapshot(r *http.Request, key string, meta pageM
 	if row.artist.Valid && row.artist.String != "" {
 		page.Description = "By " + row.artist.String
 	}
+	if h.db != nil {
+		var hasWaveform bool
+		err := h.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM waveform_cache WHERE audio_file_id = $1)`, row.id).Scan(&hasWaveform)
+		if err == nil && hasWaveform {
+			page.Image = &snapshotImage{
+				URL:    "/api/audio/key/" + url.PathEscape(key) + "/waveform.svg",
+				Alt:    "Waveform of " + page.Heading,
+				Width:  defaultWaveformImageWidth,
+				Height: defaultWaveformImageHeight,
+			}
+		}
+	}
 	if !row.isMature() && row.description.Valid && row.description.String != "" {
 		page.Items = append(page.Items, snapshotLink{Name: "Description", Description: row.description.String})
 	}
@@ -460,6 +538,25 @@ func (h *SPAHandler) renderShareSnapshot(r *http.Request, key string, meta pageM
 	if row.webpageURL.Valid && row.webpageURL.String != "" {
 		page.Items = append(page.Items, snapshotLink{Name: "Original source", URL: row.webpageURL.String})
 	}
+	if !row.isMature() {
+		for _, chapter := range row.chapters {
+			page.Chapters = append(page.Chapters, snapshotChapter{Time: formatChapterTime(chapter.StartTime), Title: chapter.Title})
+		}
+	}
+	return executeSnapshotTemplate(snapshotListTemplate, page)
+}
+
+func (h *SPAHandler) renderEmbedSnapshot(r *http.Request, key string, meta pageMeta, responses initialResponses) template.HTML {
+	page := snapshotListPage{Heading: meta.h1, Description: meta.description}
+	if key == "" || meta.notFound {
+		return executeSnapshotTemplate(snapshotListTemplate, page)
+	}
+	status, descriptor := lookupEmbedDescriptor(h.db, h.searchService, r, key)
+	responses.add("/api/embed/"+key, status, descriptor)
+	if embed, ok := descriptor.(embedDescriptor); ok {
+		page.Heading = embed.Title
+		page.Items = []snapshotLink{{Name: "Listen on " + h.config.DefaultTitle, URL: embed.PageURL}}
+	}
 	return executeSnapshotTemplate(snapshotListTemplate, page)
 }
 
@@ -473,6 +570,15 @@ func formatSnapshotDuration(seconds float64) string {
 	return fmt.Sprintf("%.1f hours", seconds/(60*60))
 }
 
+// formatChapterTime formats a chapter start as M:SS, or H:MM:SS from an hour.
+func formatChapterTime(seconds float64) string {
+	total := int(seconds)
+	if total >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", total/3600, total/60%60, total%60)
+	}
+	return fmt.Sprintf("%d:%02d", total/60, total%60)
+}
+
 func formatSnapshotStorage(bytes int64) string {
 	switch {
 	case bytes >= 1_000_000_000_000:
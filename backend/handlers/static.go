@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"html"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 )
 
@@ -126,6 +128,8 @@ type pageMeta struct {
 	description string
 	h1          string
 	imageURL    string // absolute URL, empty if none
+	imageWidth  int    // og:image dimensions, zero if unknown
+	imageHeight int
 	ogType      string // og:type value, defaults to "website"
 	notFound    bool
 }
@@ -150,8 +154,24 @@ func shareKeyFromPath(path string) (string, bool) {
 	return key, key != ""
 }
 
+func embedKeyFromPath(path string) (string, bool) {
+	if !strings.HasPrefix(path, "/embed/") {
+		return "", false
+	}
+	key := strings.Trim(strings.TrimPrefix(path, "/embed/"), "/")
+	return key, key != "" && !strings.Contains(key, "/")
+}
+
+// trackKeyFromPath returns the share key of a /share/ or /embed/ page.
+func trackKeyFromPath(path string) (string, bool) {
+	if key, ok := shareKeyFromPath(path); ok {
+		return key, true
+	}
+	return embedKeyFromPath(path)
+}
+
 func (h *SPAHandler) audioRowForRoute(r *http.Request) (*audioRow, error) {
-	key, ok := shareKeyFromPath(r.URL.Path)
+	key, ok := trackKeyFromPath(r.URL.Path)
 	if !ok || h.db == nil {
 		return nil, nil
 	}
@@ -163,11 +183,34 @@ func (h *SPAHandler) getPageMeta(r *http.Request) pageMeta {
 	return h.getPageMetaWithAudio(r, row)
 }
 
+// folderPageMeta describes the folder matching where. Mature folders keep
+// their description out of link previews.
+func (h *SPAHandler) folderPageMeta(where string, arg any) (pageMeta, bool) {
+	if h.db == nil {
+		return pageMeta{}, false
+	}
+	var name, description string
+	var ageLimit int
+	if err := h.db.QueryRow(`
+		SELECT name, COALESCE(description, ''), COALESCE(age_limit, 0) FROM folders WHERE `+where,
+		arg).Scan(&name, &description, &ageLimit); err != nil {
+		return pageMeta{}, false
+	}
+	if description == "" || ageLimit >= 18 {
+		description = h.config.DefaultDescription + " · " + name
+	}
+	return pageMeta{
+		title:       name + " - " + h.config.DefaultTitle,
+		description: description,
+		h1:          name,
+	}, true
+}
+
 func (h *SPAHandler) getPageMetaWithAudio(r *http.Request, row *audioRow) pageMeta {
 	urlPath := r.URL.Path
 	origin := siteOrigin(r)
 
-	if _, ok := shareKeyFromPath(urlPath); ok && row != nil && row.removalRestricted(r) {
+	if _, ok := trackKeyFromPath(urlPath); ok && row != nil && row.removalRestricted(r) {
 		const message = "Due to a request from the original creator, this audio is no longer shared."
 		return pageMeta{
 			title:       "Audio no longer shared - " + h.config.DefaultTitle,
@@ -176,8 +219,8 @@ func (h *SPAHandler) getPageMetaWithAudio(r *http.Request, row *audioRow) pageMe
 		}
 	}
 
-	// /share/:key — look up audio metadata from DB
-	if key, ok := shareKeyFromPath(urlPath); ok && row != nil && !row.deleted {
+	// /share/:key and /embed/:key — look up audio metadata from DB
+	if key, ok := trackKeyFromPath(urlPath); ok && row != nil && !row.deleted {
 		t := h.config.DefaultTitle
 		if row.title.Valid && row.title.String != "" {
 			t = row.title.String
@@ -186,24 +229,33 @@ func (h *SPAHandler) getPageMetaWithAudio(r *http.Request, row *audioRow) pageMe
 			t = t + " by " + row.artist.String
 		}
 		desc := h.config.DefaultDescription + " · " + t
-		imageURL := ""
-		if row.thumbnail.Valid && row.thumbnail.String != "" {
-			imageURL = origin + "/api/audio/key/" + key + "/thumbnail"
-		}
 		return pageMeta{
 			title:       t + " - " + h.config.DefaultTitle,
 			description: desc,
 			h1:          t,
-			imageURL:    imageURL,
+			imageURL:    origin + "/api/audio/key/" + key + "/og.png",
+			imageWidth:  ogImageWidth,
+			imageHeight: ogImageHeight,
 			ogType:      "music.song",
 		}
 	}
 
-	// /browse/* — use the last path segment as folder name
+	// /embed/:key for a folder share key
+	if key, ok := embedKeyFromPath(urlPath); ok && row == nil && h.db != nil {
+		if meta, ok := h.folderPageMeta(`share_key = $1`, key); ok {
+			return meta
+		}
+	}
+
+	// /browse/* — use the folder's name from folder.json, or the last path
+	// segment for folders not yet indexed
 	if isBrowseRoute(urlPath) {
 		pathStr := strings.Trim(strings.TrimPrefix(urlPath, "/browse"), "/")
 		folderName := "Root"
 		if pathStr != "" {
+			if meta, ok := h.folderPageMeta(`path = $1`, pathStr); ok {
+				return meta
+			}
 			segments := strings.Split(pathStr, "/")
 			folderName = segments[len(segments)-1]
 		}
@@ -277,8 +329,21 @@ func (h *SPAHandler) serveRoute(w http.ResponseWriter, r *http.Request) {
 		escapedImage := html.EscapeString(meta.imageURL)
 		b.WriteString(`<meta property="og:image" content="` + escapedImage + `">`)
 		b.WriteString(`<meta name="twitter:image" content="` + escapedImage + `">`)
+		if meta.imageWidth > 0 && meta.imageHeight > 0 {
+			b.WriteString(`<meta property="og:image:width" content="` + strconv.Itoa(meta.imageWidth) + `">`)
+			b.WriteString(`<meta property="og:image:height" content="` + strconv.Itoa(meta.imageHeight) + `">`)
+		}
 		b.WriteString(`<meta name="twitter:card" content="summary_large_image">`)
 	}
+	if _, ok := embedKeyFromPath(r.URL.Path); ok {
+		b.WriteString(`<meta name="robots" content="noindex">`)
+	} else if !meta.notFound && (isBrowseRoute(r.URL.Path) || strings.HasPrefix(r.URL.Path, "/share/")) {
+		pageLink := url.QueryEscape(siteOrigin(r) + r.URL.Path)
+		b.WriteString(`<link rel="alternate" type="application/json+oembed" href="` +
+			html.EscapeString(siteOrigin(r)+"/oembed?format=json&url="+pageLink) + `">`)
+		b.WriteString(`<link rel="alternate" type="text/xml+oembed" href="` +
+			html.EscapeString(siteOrigin(r)+"/oembed?format=xml&url="+pageLink) + `">`)
+	}
 
 	doc = strings.Replace(doc, "</head>", b.String()+"</head>", 1)
 
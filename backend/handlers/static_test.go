@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -21,7 +22,7 @@ type snapshotSearchStub struct {
 	total     int
 }
 
-func (s snapshotSearchStub) BrowseDirectory(string) (*services.DirectoryContents, error) {
+func (s snapshotSearchStub) BrowseDirectory(string, services.BrowseOptions) (*services.DirectoryContents, error) {
 	return s.directory, nil
 }
 
@@ -115,6 +116,38 @@ func TestNewLibraryRoutesHavePageMetadata(t *testing.T) {
 	}
 }
 
+func TestBrowsePageMetadataUsesFolderJSON(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	handler := &SPAHandler{db: db, config: FrontendConfig{
+		DefaultTitle:       "Test Archive",
+		DefaultDescription: "Test description",
+	}}
+
+	for _, tc := range []struct {
+		ageLimit int
+		want     string
+	}{
+		{0, "Weekly talk about old radio"},
+		{18, "Test description · Old Radio"},
+	} {
+		mock.ExpectQuery(`FROM folders WHERE path = \$1`).
+			WithArgs("Audio/old-radio").
+			WillReturnRows(sqlmock.NewRows([]string{"name", "description", "age_limit"}).
+				AddRow("Old Radio", "Weekly talk about old radio", tc.ageLimit))
+		meta := handler.getPageMeta(httptest.NewRequest(http.MethodGet, "https://example.test/browse/Audio/old-radio", nil))
+		if meta.h1 != "Old Radio" || meta.description != tc.want {
+			t.Errorf("age limit %d: meta = %#v", tc.ageLimit, meta)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestBrowseRouteRequiresPathBoundary(t *testing.T) {
 	handler := newSnapshotTestHandler(t)
 	request := httptest.NewRequest(http.MethodGet, "https://example.test/browser-extension", nil)
@@ -355,10 +388,11 @@ func TestSPAHandlerReusesShareLookupForMetadataAndInitialData(t *testing.T) {
 		WithArgs("track-key").
 		WillReturnRows(sqlmock.NewRows([]string{
 			"id", "path", "deleted", "unavailable_at", "removal_requested_at", "thumbnail", "title", "meta_artist", "upload_date",
-			"webpage_url", "description", "age_limit", "parent_path",
+			"webpage_url", "description", "age_limit", "parent_path", "thumbnail_placeholder", "chapters",
 		}).AddRow(
 			1, "Audio/track.mp3", 0, nil, nil, "cover.jpg", "Track title", "Artist", "20260810",
-			"https://example.test/source", "Description", 0, "Audio",
+			"https://example.test/source", "Description", 0, "Audio", nil,
+			`[{"startTime":0,"endTime":95,"title":"Intro"},{"startTime":3725,"title":"Encore"}]`,
 		))
 	handler := newSnapshotTestHandler(t)
 	handler.db = db
@@ -382,4 +416,112 @@ func TestSPAHandlerReusesShareLookupForMetadataAndInitialData(t *testing.T) {
 	if meta.Title != "Track title" || meta.Artist != "Artist" || !meta.Thumbnail {
 		t.Fatalf("unexpected initial share metadata: %#v", meta)
 	}
+	if len(meta.Chapters) != 2 || meta.Chapters[1].Title != "Encore" {
+		t.Fatalf("unexpected initial share chapters: %#v", meta.Chapters)
+	}
+	for _, want := range []string{">0:00</span> Intro</li>", ">1:02:05</span> Encore</li>"} {
+		if !strings.Contains(recorder.Body.String(), want) {
+			t.Errorf("snapshot missing chapter %q", want)
+		}
+	}
+}
+
+type recordingDirectoryBrowser struct {
+	contents map[string]*services.DirectoryContents
+	paths    []string
+	opts     []services.BrowseOptions
+}
+
+func (b *recordingDirectoryBrowser) BrowseDirectory(path string, opts services.BrowseOptions) (*services.DirectoryContents, error) {
+	b.paths = append(b.paths, path)
+	b.opts = append(b.opts, opts)
+	return b.contents[path], nil
+}
+
+func TestBrowseDirectoryContentsOnlySkipsUnfilteredSingleFolders(t *testing.T) {
+	newBrowser := func() *recordingDirectoryBrowser {
+		return &recordingDirectoryBrowser{contents: map[string]*services.DirectoryContents{
+			"":      {Items: []services.FileSystemItem{{Name: "Audio", Path: "Audio", Type: "folder"}}, Total: 1},
+			"Audio": {CurrentPath: "Audio", Items: []services.FileSystemItem{{Name: "a.mp3", Type: "audio"}}, Total: 1},
+		}}
+	}
+
+	browser := newBrowser()
+	contents, err := browseDirectoryContentsForAccess(browser, "", services.BrowseOptions{Sort: "name_desc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contents.CurrentPath != "Audio" || len(browser.paths) != 2 || browser.opts[1].Sort != "name_desc" {
+		t.Fatalf("unfiltered browse did not descend with options: paths=%v opts=%#v", browser.paths, browser.opts)
+	}
+
+	browser = newBrowser()
+	contents, err = browseDirectoryContentsForAccess(browser, "", services.BrowseOptions{Filter: "aud"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contents.CurrentPath != "" || len(browser.paths) != 1 {
+		t.Fatalf("filtered browse descended: paths=%v", browser.paths)
+	}
+}
+
+func TestBrowseOptionsForValues(t *testing.T) {
+	opts := browseOptionsForValues(url.Values{
+		"sort":   {"duration_desc"},
+		"type":   {"audio"},
+		"q":      {"  live  "},
+		"limit":  {"9999"},
+		"offset": {"20"},
+	}, true)
+	want := services.BrowseOptions{
+		Sort:                    "duration_desc",
+		Type:                    "audio",
+		Filter:                  "live",
+		Limit:                   maxBrowseLimit,
+		Offset:                  20,
+		IncludeRemovalRequested: true,
+	}
+	if opts != want {
+		t.Fatalf("browseOptionsForValues() = %#v, want %#v", opts, want)
+	}
+
+	opts = browseOptionsForValues(url.Values{"sort": {"random"}, "type": {"image"}, "offset": {"-1"}}, false)
+	if opts != (services.BrowseOptions{}) {
+		t.Fatalf("invalid values were accepted: %#v", opts)
+	}
+}
+
+func TestSPAHandlerRendersBrowseSnapshotForPageParameters(t *testing.T) {
+	browser := &recordingDirectoryBrowser{contents: map[string]*services.DirectoryContents{
+		"Audio": {CurrentPath: "Audio", Items: []services.FileSystemItem{
+			{Name: "a.mp3", Title: "A track", Type: "audio", ShareKey: "a-key"},
+		}, Total: 250, Offset: 100, Limit: 100},
+	}}
+	handler := newSnapshotTestHandler(t, SPAHandlerOptions{SearchService: struct {
+		directoryBrowser
+		statsService
+		searchExecutor
+	}{browser, snapshotSearchStub{}, snapshotSearchStub{}}})
+	request := httptest.NewRequest(http.MethodGet, "https://example.test/browse/Audio?sort=size_asc&q=track&page=2", nil)
+	recorder := httptest.NewRecorder()
+
+	handler.ServeHTTP(recorder, request)
+
+	want := services.BrowseOptions{Sort: "size_asc", Filter: "track", Limit: maxSnapshotItems, Offset: maxSnapshotItems}
+	if len(browser.opts) != 1 || browser.opts[0] != want {
+		t.Fatalf("browse options = %#v, want %#v", browser.opts, want)
+	}
+	if strings.Contains(recorder.Body.String(), `"/api/browse`) {
+		t.Fatal("narrowed listing was embedded for the SPA, which fetches whole directories")
+	}
+	if !strings.Contains(recorder.Body.String(), "149 more items are available") {
+		t.Fatalf("response does not disclose remaining pages: %s", recorder.Body.String())
+	}
+}
+
+func TestSearchAPIValuesFromPageKeepsFolderScope(t *testing.T) {
+	values, hasSearch := searchAPIValuesFromPage(url.Values{"path": {"/Audio/Show/"}})
+	if !hasSearch || values.Get("path") != "Audio/Show" {
+		t.Fatalf("searchAPIValuesFromPage() = %v, %v", values, hasSearch)
+	}
 }
@@ -0,0 +1,918 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/onion/audio-share-backend/services"
+)
+
+const (
+	subsonicAPIVersion    = "1.16.1"
+	subsonicServerType    = "audio-share"
+	subsonicNamespace     = "http://subsonic.org/restapi"
+	subsonicDirPrefix     = "d:"
+	subsonicFolderArt     = "f:"
+	subsonicMaxSearchSize = 500
+	subsonicGrantMargin   = 5 * time.Second
+	subsonicMaxGrants     = 4096
+)
+
+// Subsonic error codes, as defined by the Subsonic API.
+const (
+	subsonicErrGeneric          = 0
+	subsonicErrMissingParameter = 10
+	subsonicErrWrongCredentials = 40
+	subsonicErrConflictingAuth  = 43
+	subsonicErrInvalidAPIKey    = 44
+	subsonicErrNotAuthorized    = 50
+	subsonicErrNotFound         = 70
+)
+
+const (
+	subsonicNotFoundMessage    = "The requested data was not found"
+	subsonicServerErrorMessage = "Server error"
+)
+
+type appTokenAuthenticator interface {
+	AuthenticatePassword(string, string) (*services.AppTokenIdentity, error)
+	AuthenticateToken(string, string, string) (*services.AppTokenIdentity, error)
+	AuthenticateAPIKey(string) (*services.AppTokenIdentity, error)
+}
+
+type subsonicSearcher interface {
+	Search(string, int, int, services.SearchOptions) ([]services.SearchResult, int, error)
+	FolderExists(string) (bool, error)
+}
+
+type subsonicLibrary interface {
+	Like(string, string, bool) error
+	Unlike(string, string) error
+	LikedTracks(string, bool) ([]services.LibraryTrack, error)
+}
+
+type SubsonicHandlerOptions struct {
+	ServerVersion string
+	Tokens        appTokenAuthenticator
+	Browser       directoryBrowser
+	Search        subsonicSearcher
+	Library       subsonicLibrary
+}
+
+// SubsonicHandler exposes the library through the Subsonic/OpenSubsonic
+// REST API so third-party players can browse, search, star and stream.
+// Every request authenticates with an app token tied to an anonymous
+// profile; media requests go through the same access keys, bandwidth
+// throttles and removal rules as the web player.
+type SubsonicHandler struct {
+	serverVersion string
+	audio         *AudioHandler
+	folders       *FolderHandler
+	tokens        appTokenAuthenticator
+	browser       directoryBrowser
+	search        subsonicSearcher
+	library       subsonicLibrary
+
+	mu     sync.Mutex
+	grants map[string]subsonicGrant
+}
+
+// subsonicGrant caches an issued access key so that range and seek
+// requests for the same track do not consume the issuance limits again.
+type subsonicGrant struct {
+	nonce     string
+	expiresAt time.Time
+}
+
+func NewSubsonicHandler(audio *AudioHandler, folders *FolderHandler, options SubsonicHandlerOptions) *SubsonicHandler {
+	return &SubsonicHandler{
+		serverVersion: options.ServerVersion,
+		audio:         audio,
+		folders:       folders,
+		tokens:        options.Tokens,
+		browser:       options.Browser,
+		search:        options.Search,
+		library:       options.Library,
+		grants:        make(map[string]subsonicGrant),
+	}
+}
+
+type subsonicResponse struct {
+	XMLName       xml.Name `xml:"subsonic-response" json:"-"`
+	Xmlns         string   `xml:"xmlns,attr" json:"-"`
+	Status        string   `xml:"status,attr" json:"status"`
+	Version       string   `xml:"version,attr" json:"version"`
+	Type          string   `xml:"type,attr" json:"type"`
+	ServerVersion string   `xml:"serverVersion,attr" json:"serverVersion"`
+	OpenSubsonic  bool     `xml:"openSubsonic,attr" json:"openSubsonic"`
+
+	Error                  *subsonicError         `xml:"error,omitempty" json:"error,omitempty"`
+	License                *subsonicLicense       `xml:"license,omitempty" json:"license,omitempty"`
+	OpenSubsonicExtensions []subsonicExtension    `xml:"openSubsonicExtensions,omitempty" json:"openSubsonicExtensions,omitempty"`
+	MusicFolders           *subsonicMusicFolders  `xml:"musicFolders,omitempty" json:"musicFolders,omitempty"`
+	Indexes                *subsonicIndexes       `xml:"indexes,omitempty" json:"indexes,omitempty"`
+	Directory              *subsonicDirectory     `xml:"directory,omitempty" json:"directory,omitempty"`
+	Album                  *subsonicAlbum         `xml:"album,omitempty" json:"album,omitempty"`
+	Song                   *subsonicChild         `xml:"song,omitempty" json:"song,omitempty"`
+	SearchResult3          *subsonicSearchResult3 `xml:"searchResult3,omitempty" json:"searchResult3,omitempty"`
+	Starred2               *subsonicStarred2      `xml:"starred2,omitempty" json:"starred2,omitempty"`
+}
+
+type subsonicError struct {
+	Code    int    `xml:"code,attr" json:"code"`
+	Message string `xml:"message,attr" json:"message"`
+}
+
+type subsonicLicense struct {
+	Valid bool `xml:"valid,attr" json:"valid"`
+}
+
+type subsonicExtension struct {
+	Name     string `xml:"name,attr" json:"name"`
+	Versions []int  `xml:"versions" json:"versions"`
+}
+
+type subsonicMusicFolders struct {
+	MusicFolder []subsonicMusicFolder `xml:"musicFolder" json:"musicFolder"`
+}
+
+type subsonicMusicFolder struct {
+	ID   int    `xml:"id,attr" json:"id"`
+	Name string `xml:"name,attr" json:"name"`
+}
+
+type subsonicIndexes struct {
+	IgnoredArticles string          `xml:"ignoredArticles,attr" json:"ignoredArticles"`
+	LastModified    int64           `xml:"lastModified,attr" json:"lastModified"`
+	Index           []subsonicIndex `xml:"index" json:"index"`
+}
+
+type subsonicIndex struct {
+	Name   string           `xml:"name,attr" json:"name"`
+	Artist []subsonicArtist `xml:"artist" json:"artist"`
+}
+
+type subsonicArtist struct {
+	ID       string `xml:"id,attr" json:"id"`
+	Name     string `xml:"name,attr" json:"name"`
+	CoverArt string `xml:"coverArt,attr,omitempty" json:"coverArt,omitempty"`
+}
+
+type subsonicDirectory struct {
+	ID     string          `xml:"id,attr" json:"id"`
+	Parent string          `xml:"parent,attr,omitempty" json:"parent,omitempty"`
+	Name   string          `xml:"name,attr" json:"name"`
+	Child  []subsonicChild `xml:"child" json:"child,omitempty"`
+}
+
+type subsonicAlbum struct {
+	ID        string          `xml:"id,attr" json:"id"`
+	Name      string          `xml:"name,attr" json:"name"`
+	CoverArt  string          `xml:"coverArt,attr,omitempty" json:"coverArt,omitempty"`
+	SongCount int             `xml:"songCount,attr" json:"songCount"`
+	Duration  int             `xml:"duration,attr" json:"duration"`
+	Created   string          `xml:"created,attr,omitempty" json:"created,omitempty"`
+	Song      []subsonicChild `xml:"song" json:"song,omitempty"`
+}
+
+type subsonicChild struct {
+	ID          string `xml:"id,attr" json:"id"`
+	Parent      string `xml:"parent,attr,omitempty" json:"parent,omitempty"`
+	IsDir       bool   `xml:"isDir,attr" json:"isDir"`
+	Title       string `xml:"title,attr" json:"title"`
+	Album       string `xml:"album,attr,omitempty" json:"album,omitempty"`
+	Artist      string `xml:"artist,attr,omitempty" json:"artist,omitempty"`
+	CoverArt    string `xml:"coverArt,attr,omitempty" json:"coverArt,omitempty"`
+	Size        int64  `xml:"size,attr,omitempty" json:"size,omitempty"`
+	ContentType string `xml:"contentType,attr,omitempty" json:"contentType,omitempty"`
+	Suffix      string `xml:"suffix,attr,omitempty" json:"suffix,omitempty"`
+	Duration    int    `xml:"duration,attr,omitempty" json:"duration,omitempty"`
+	Path        string `xml:"path,attr,omitempty" json:"path,omitempty"`
+	Type        string `xml:"type,attr,omitempty" json:"type,omitempty"`
+	Created     string `xml:"created,attr,omitempty" json:"created,omitempty"`
+}
+
+type subsonicSearchResult3 struct {
+	Artist []subsonicArtist `xml:"artist" json:"artist,omitempty"`
+	Album  []subsonicAlbum  `xml:"album" json:"album,omitempty"`
+	Song   []subsonicChild  `xml:"song" json:"song,omitempty"`
+}
+
+type subsonicStarred2 struct {
+	Song []subsonicChild `xml:"song" json:"song,omitempty"`
+}
+
+func (h *SubsonicHandler) newResponse() *subsonicResponse {
+	return &subsonicResponse{
+		Xmlns:         subsonicNamespace,
+		Status:        "ok",
+		Version:       subsonicAPIVersion,
+		Type:          subsonicServerType,
+		ServerVersion: h.serverVersion,
+		OpenSubsonic:  true,
+	}
+}
+
+// writeSubsonic encodes a response as XML, or as JSON when the client
+// asks for it with f=json.
+func writeSubsonic(w http.ResponseWriter, r *http.Request, status int, response *subsonicResponse) {
+	w.Header().Set("Cache-Control", "private, no-store")
+	if strings.HasPrefix(strings.ToLower(r.Form.Get("f")), "json") {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]*subsonicResponse{"subsonic-response": response})
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write([]byte(xml.Header))
+	if err := xml.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("subsonic: encoding response failed: %v", err)
+	}
+}
+
+func (h *SubsonicHandler) writeError(w http.ResponseWriter, r *http.Request, status, code int, message string) {
+	response := h.newResponse()
+	response.Status = "failed"
+	response.Error = &subsonicError{Code: code, Message: message}
+	writeSubsonic(w, r, status, response)
+}
+
+func (h *SubsonicHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Robots-Tag", "noindex, nofollow, noarchive")
+
+	// Path format: /rest/{method}[.view]
+	method := strings.TrimPrefix(r.URL.Path, "/rest/")
+	method = strings.TrimSuffix(strings.Trim(method, "/"), ".view")
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, 65536)
+	if err := r.ParseForm(); err != nil {
+		h.writeError(w, r, http.StatusBadRequest, subsonicErrGeneric, "Invalid request")
+		return
+	}
+
+	clientAddress := clientIP(r)
+	if limiter := h.audio.accessFailureLimiter; limiter != nil {
+		allowed, retryAfter := limiter.AllowAccessAttempt(clientAddress)
+		if !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			h.writeError(w, r, http.StatusTooManyRequests, subsonicErrGeneric, "Too many invalid attempts")
+			return
+		}
+	}
+	identity, code, message := h.authenticate(r.Form)
+	if identity == nil {
+		if code != subsonicErrGeneric && h.audio.accessFailureLimiter != nil {
+			h.audio.accessFailureLimiter.RecordAccessFailure(clientAddress)
+		}
+		h.writeError(w, r, http.StatusOK, code, message)
+		return
+	}
+
+	switch method {
+	case "ping":
+		writeSubsonic(w, r, http.StatusOK, h.newResponse())
+	case "getLicense":
+		response := h.newResponse()
+		response.License = &subsonicLicense{Valid: true}
+		writeSubsonic(w, r, http.StatusOK, response)
+	case "getOpenSubsonicExtensions":
+		response := h.newResponse()
+		response.OpenSubsonicExtensions = []subsonicExtension{
+			{Name: "apiKeyAuthentication", Versions: []int{1}},
+			{Name: "formPost", Versions: []int{1}},
+		}
+		writeSubsonic(w, r, http.StatusOK, response)
+	case "getMusicFolders":
+		h.handleMusicFolders(w, r)
+	case "getIndexes":
+		h.handleIndexes(w, r)
+	case "getMusicDirectory":
+		h.handleMusicDirectory(w, r, false)
+	case "getAlbum":
+		h.handleMusicDirectory(w, r, true)
+	case "getSong":
+		h.handleSong(w, r)
+	case "search3":
+		h.handleSearch(w, r)
+	case "getStarred2":
+		h.handleStarred(w, r, identity)
+	case "star", "unstar":
+		h.handleStar(w, r, identity, method == "star")
+	case "stream":
+		h.handleMedia(w, r, identity, clientAddress, false)
+	case "download":
+		h.handleMedia(w, r, identity, clientAddress, true)
+	case "getCoverArt":
+		h.handleCoverArt(w, r)
+	default:
+		h.writeError(w, r, http.StatusNotFound, subsonicErrNotFound, "Unknown method")
+	}
+}
+
+// authenticate resolves the app token behind a request. It supports the
+// OpenSubsonic apiKey parameter, salted tokens (u, t, s) and passwords
+// (u, p), including the hex "enc:" form.
+func (h *SubsonicHandler) authenticate(values map[string][]string) (*services.AppTokenIdentity, int, string) {
+	get := func(key string) string {
+		if v := values[key]; len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+	if h.tokens == nil {
+		return nil, subsonicErrGeneric, "App tokens are not available"
+	}
+	username := get("u")
+	var identity *services.AppTokenIdentity
+	var err error
+	invalidCode, invalidMessage := subsonicErrWrongCredentials, "Wrong username or password"
+	switch {
+	case get("apiKey") != "":
+		if username != "" {
+			return nil, subsonicErrConflictingAuth, "Multiple conflicting authentication mechanisms provided"
+		}
+		invalidCode, invalidMessage = subsonicErrInvalidAPIKey, "Invalid API key"
+		identity, err = h.tokens.AuthenticateAPIKey(get("apiKey"))
+	case username == "":
+		return nil, subsonicErrMissingParameter, "Required parameter is missing: u"
+	case get("t") != "":
+		if get("s") == "" {
+			return nil, subsonicErrMissingParameter, "Required parameter is missing: s"
+		}
+		identity, err = h.tokens.AuthenticateToken(username, get("t"), get("s"))
+	case get("p") != "":
+		password := get("p")
+		if encoded, ok := strings.CutPrefix(password, "enc:"); ok {
+			decoded, decodeErr := hex.DecodeString(encoded)
+			if decodeErr != nil {
+				return nil, invalidCode, invalidMessage
+			}
+			password = string(decoded)
+		}
+		identity, err = h.tokens.AuthenticatePassword(username, password)
+	default:
+		return nil, subsonicErrMissingParameter, "Required parameter is missing: p, t or apiKey"
+	}
+	if errors.Is(err, services.ErrInvalidAppToken) {
+		return nil, invalidCode, invalidMessage
+	}
+	if err != nil {
+		log.Printf("subsonic: authenticating app token failed: %v", err)
+		return nil, subsonicErrGeneric, subsonicServerErrorMessage
+	}
+	return identity, 0, ""
+}
+
+func subsonicDirID(path string) string {
+	return subsonicDirPrefix + path
+}
+
+func subsonicParentID(path string) string {
+	parent := strings.Trim(path, "/")
+	if i := strings.LastIndex(parent, "/"); i >= 0 {
+		return subsonicDirID(parent[:i])
+	}
+	return ""
+}
+
+func subsonicIndexName(name string) string {
+	for _, r := range strings.TrimSpace(name) {
+		if unicode.IsLetter(r) {
+			return strings.ToUpper(string(r))
+		}
+		break
+	}
+	return "#"
+}
+
+func subsonicDuration(seconds float64) int {
+	if seconds <= 0 {
+		return 0
+	}
+	return int(math.Round(seconds))
+}
+
+func subsonicChildFromItem(item services.FileSystemItem, parentID string) subsonicChild {
+	if item.Type == "folder" {
+		child := subsonicChild{
+			ID:     subsonicDirID(item.Path),
+			Parent: parentID,
+			IsDir:  true,
+			Title:  item.Name,
+		}
+		if item.PosterImage != "" && item.ShareKey != "" {
+			child.CoverArt = subsonicFolderArt + item.ShareKey
+		}
+		return child
+	}
+	title := item.Title
+	if title == "" {
+		title = strings.TrimSuffix(item.Name, path.Ext(item.Name))
+	}
+	return subsonicChild{
+		ID:          item.ShareKey,
+		Parent:      parentID,
+		Title:       title,
+		CoverArt:    item.ShareKey,
+		Size:        item.Size,
+		ContentType: item.MimeType,
+		Suffix:      strings.TrimPrefix(strings.ToLower(path.Ext(item.Name)), "."),
+		Duration:    subsonicDuration(item.DurationSeconds),
+		Path:        item.Path,
+		Type:        "music",
+		Created:     item.ModifiedAt,
+	}
+}
+
+func subsonicChildFromTrack(track services.TrackSummary) subsonicChild {
+	child := subsonicChild{
+		ID:       track.ShareKey,
+		Title:    strings.TrimSuffix(track.Filename, path.Ext(track.Filename)),
+		CoverArt: track.ShareKey,
+		Suffix:   strings.TrimPrefix(strings.ToLower(path.Ext(track.Filename)), "."),
+		Path:     track.Path,
+		Type:     "music",
+	}
+	if track.Title != nil && *track.Title != "" {
+		child.Title = *track.Title
+	}
+	if track.Artist != nil {
+		child.Artist = *track.Artist
+	}
+	if track.ParentPath != nil {
+		child.Parent = subsonicDirID(*track.ParentPath)
+	}
+	if track.ParentFolderName != nil {
+		child.Album = *track.ParentFolderName
+	}
+	return child
+}
+
+// rootFolders lists the configured audio roots, which Subsonic clients
+// see as music folders numbered from one.
+func (h *SubsonicHandler) rootFolders(r *http.Request) ([]services.FileSystemItem, error) {
+	contents, err := h.browser.BrowseDirectory("", services.BrowseOptions{
+		Type:                    "folder",
+		IncludeRemovalRequested: isLocalRequest(r),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return contents.Items, nil
+}
+
+func (h *SubsonicHandler) handleMusicFolders(w http.ResponseWriter, r *http.Request) {
+	roots, err := h.rootFolders(r)
+	if err != nil {
+		log.Printf("subsonic: listing music folders failed: %v", err)
+		h.writeError(w, r, http.StatusOK, subsonicErrGeneric, subsonicServerErrorMessage)
+		return
+	}
+	folders := &subsonicMusicFolders{MusicFolder: make([]subsonicMusicFolder, 0, len(roots))}
+	for i, root := range roots {
+		folders.MusicFolder = append(folders.MusicFolder, subsonicMusicFolder{ID: i + 1, Name: root.Name})
+	}
+	response := h.newResponse()
+	response.MusicFolders = folders
+	writeSubsonic(w, r, http.StatusOK, response)
+}
+
+func (h *SubsonicHandler) handleIndexes(w http.ResponseWriter, r *http.Request) {
+	roots, err := h.rootFolders(r)
+	if err != nil {
+		log.Printf("subsonic: listing music folders failed: %v", err)
+		h.writeError(w, r, http.StatusOK, subsonicErrGeneric, subsonicServerErrorMessage)
+		return
+	}
+	if raw := r.Form.Get("musicFolderId"); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil || id < 1 || id > len(roots) {
+			h.writeError(w, r, http.StatusOK, subsonicErrNotFound, "Music folder not found")
+			return
+		}
+		roots = roots[id-1 : id]
+	}
+
+	opts := services.BrowseOptions{Type: "folder", IncludeRemovalRequested: isLocalRequest(r)}
+	byIndex := make(map[string][]subsonicArtist)
+	for _, root := range roots {
+		contents, err := h.browser.BrowseDirectory(root.Path, opts)
+		if err != nil {
+			log.Printf("subsonic: listing %s failed: %v", root.Path, err)
+			h.writeError(w, r, http.StatusOK, subsonicErrGeneric, subsonicServerErrorMessage)
+			return
+		}
+		for _, item := range contents.Items {
+			artist := subsonicArtist{ID: subsonicDirID(item.Path), Name: item.Name}
+			if item.PosterImage != "" && item.ShareKey != "" {
+				artist.CoverArt = subsonicFolderArt + item.ShareKey
+			}
+			name := subsonicIndexName(item.Name)
+			byIndex[name] = append(byIndex[name], artist)
+		}
+	}
+
+	names := make([]string, 0, len(byIndex))
+	for name := range byIndex {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	indexes := &subsonicIndexes{Index: make([]subsonicIndex, 0, len(names))}
+	for _, name := range names {
+		artists := byIndex[name]
+		sort.SliceStable(artists, func(i, j int) bool {
+			return strings.ToLower(artists[i].Name) < strings.ToLower(artists[j].Name)
+		})
+		indexes.Index = append(indexes.Index, subsonicIndex{Name: name, Artist: artists})
+	}
+	response := h.newResponse()
+	response.Indexes = indexes
+	writeSubsonic(w, r, http.StatusOK, response)
+}
+
+func (h *SubsonicHandler) handleMusicDirectory(w http.ResponseWriter, r *http.Request, asAlbum bool) {
+	id := r.Form.Get("id")
+	dirPath, ok := strings.CutPrefix(id, subsonicDirPrefix)
+	if id == "" {
+		h.writeError(w, r, http.StatusOK, subsonicErrMissingParameter, "Required parameter is missing: id")
+		return
+	}
+	dirPath = strings.Trim(dirPath, "/")
+	if !ok || dirPath == "" {
+		h.writeError(w, r, http.StatusOK, subsonicErrNotFound, "Directory not found")
+		return
+	}
+	contents, err := h.browser.BrowseDirectory(dirPath, services.BrowseOptions{
+		IncludeRemovalRequested: isLocalRequest(r),
+	})
+	if err != nil {
+		log.Printf("subsonic: listing %s failed: %v", dirPath, err)
+		h.writeError(w, r, http.StatusOK, subsonicErrGeneric, subsonicServerErrorMessage)
+		return
+	}
+	contents = visibleDirectoryContents(contents, isLocalRequest(r))
+	if len(contents.Items) == 0 {
+		if found, err := h.search.FolderExists(dirPath); err == nil && !found {
+			h.writeError(w, r, http.StatusOK, subsonicErrNotFound, "Directory not found")
+			return
+		}
+	}
+
+	name := path.Base(dirPath)
+	response := h.newResponse()
+	if asAlbum {
+		album := &subsonicAlbum{ID: id, Name: name}
+		for _, item := range contents.Items {
+			if item.Type != "audio" {
+				continue
+			}
+			song := subsonicChildFromItem(item, id)
+			song.Album = name
+			album.Song = append(album.Song, song)
+			album.SongCount++
+			album.Duration += song.Duration
+		}
+		response.Album = album
+	} else {
+		directory := &subsonicDirectory{ID: id, Parent: subsonicParentID(dirPath), Name: name}
+		for _, item := range contents.Items {
+			child := subsonicChildFromItem(item, id)
+			if !child.IsDir {
+				child.Album = name
+			}
+			directory.Child = append(directory.Child, child)
+		}
+		response.Directory = directory
+	}
+	writeSubsonic(w, r, http.StatusOK, response)
+}
+
+func (h *SubsonicHandler) handleSong(w http.ResponseWriter, r *http.Request) {
+	key := r.Form.Get("id")
+	if key == "" {
+		h.writeError(w, r, http.StatusOK, subsonicErrMissingParameter, "Required parameter is missing: id")
+		return
+	}
+	row, err := h.audio.lookupByKey(key)
+	if err == sql.ErrNoRows || (err == nil && (row.deleted || row.removalRestricted(r))) {
+		h.writeError(w, r, http.StatusOK, subsonicErrNotFound, subsonicNotFoundMessage)
+		return
+	}
+	if err != nil {
+		h.writeError(w, r, http.StatusOK, subsonicErrGeneric, subsonicServerErrorMessage)
+		return
+	}
+	filename := path.Base(row.path)
+	song := subsonicChild{
+		ID:       key,
+		Title:    strings.TrimSuffix(filename, path.Ext(filename)),
+		CoverArt: key,
+		Suffix:   strings.TrimPrefix(strings.ToLower(path.Ext(filename)), "."),
+		Path:     row.path,
+		Type:     "music",
+	}
+	if row.title.Valid && row.title.String != "" {
+		song.Title = row.title.String
+	}
+	if row.artist.Valid {
+		song.Artist = row.artist.String
+	}
+	if row.parentPath.Valid {
+		song.Parent = subsonicDirID(row.parentPath.String)
+		song.Album = path.Base(row.parentPath.String)
+	}
+	song.ContentType = h.audio.mimeTypes[strings.ToLower(path.Ext(filename))]
+	response := h.newResponse()
+	response.Song = &song
+	writeSubsonic(w, r, http.StatusOK, response)
+}
+
+func subsonicCount(r *http.Request, name string, fallback int) int {
+	value, err := strconv.Atoi(r.Form.Get(name))
+	if err != nil || value < 0 {
+		return fallback
+	}
+	return min(value, subsonicMaxSearchSize)
+}
+
+func (h *SubsonicHandler) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(strings.Trim(r.Form.Get("query"), `"`))
+	result := &subsonicSearchResult3{}
+	response := h.newResponse()
+	response.SearchResult3 = result
+	if query == "" {
+		writeSubsonic(w, r, http.StatusOK, response)
+		return
+	}
+
+	includeRemovalRequested := isLocalRequest(r)
+	if count := subsonicCount(r, "albumCount", 20); count > 0 {
+		folders, _, err := h.search.Search(query, count, subsonicCount(r, "albumOffset", 0), services.SearchOptions{
+			Type:                    "folder",
+			IncludeRemovalRequested: includeRemovalRequested,
+		})
+		if err != nil {
+			log.Printf("subsonic: searching folders failed: %v", err)
+			h.writeError(w, r, http.StatusOK, subsonicErrGeneric, subsonicServerErrorMessage)
+			return
+		}
+		for _, folder := range folders {
+			album := subsonicAlbum{
+				ID:        subsonicDirID(folder.Path),
+				Name:      folder.Name,
+				SongCount: folder.ItemCount,
+				Created:   folder.ModifiedAt,
+			}
+			if folder.PosterImage != "" && folder.ShareKey != "" {
+				album.CoverArt = subsonicFolderArt + folder.ShareKey
+			}
+			result.Album = append(result.Album, album)
+		}
+	}
+	if count := subsonicCount(r, "songCount", 20); count > 0 {
+		songs, _, err := h.search.Search(query, count, subsonicCount(r, "songOffset", 0), services.SearchOptions{
+			Type:                    "audio",
+			IncludeRemovalRequested: includeRemovalRequested,
+		})
+		if err != nil {
+			log.Printf("subsonic: searching audio failed: %v", err)
+			h.writeError(w, r, http.StatusOK, subsonicErrGeneric, subsonicServerErrorMessage)
+			return
+		}
+		for _, song := range songs {
+			child := subsonicChild{
+				ID:          song.ShareKey,
+				Title:       song.Title,
+				Artist:      song.Artist,
+				CoverArt:    song.ShareKey,
+				Size:        song.Size,
+				ContentType: song.MimeType,
+				Suffix:      strings.TrimPrefix(strings.ToLower(path.Ext(song.Name)), "."),
+				Path:        song.Path,
+				Type:        "music",
+				Created:     song.ModifiedAt,
+			}
+			if child.Title == "" {
+				child.Title = strings.TrimSuffix(song.Name, path.Ext(song.Name))
+			}
+			if song.ParentPath != "" {
+				child.Parent = subsonicDirID(song.ParentPath)
+				child.Album = path.Base(song.ParentPath)
+			}
+			result.Song = append(result.Song, child)
+		}
+	}
+	writeSubsonic(w, r, http.StatusOK, response)
+}
+
+func (h *SubsonicHandler) handleStarred(w http.ResponseWriter, r *http.Request, identity *services.AppTokenIdentity) {
+	tracks, err := h.library.LikedTracks(identity.ProfileID, isLocalRequest(r))
+	if err != nil {
+		log.Printf("subsonic: loading liked tracks failed: %v", err)
+		h.writeError(w, r, http.StatusOK, subsonicErrGeneric, subsonicServerErrorMessage)
+		return
+	}
+	starred := &subsonicStarred2{}
+	for _, track := range tracks {
+		if track.Deleted {
+			continue
+		}
+		starred.Song = append(starred.Song, subsonicChildFromTrack(track.TrackSummary))
+	}
+	response := h.newResponse()
+	response.Starred2 = starred
+	writeSubsonic(w, r, http.StatusOK, response)
+}
+
+// handleStar likes or unlikes tracks. Starring folders (albums and
+// artists) is accepted but ignored because likes only cover tracks.
+func (h *SubsonicHandler) handleStar(w http.ResponseWriter, r *http.Request, identity *services.AppTokenIdentity, star bool) {
+	for _, key := range r.Form["id"] {
+		if key == "" || strings.HasPrefix(key, subsonicDirPrefix) {
+			continue
+		}
+		var err error
+		if star {
+			err = h.library.Like(identity.ProfileID, key, isLocalRequest(r))
+		} else {
+			err = h.library.Unlike(identity.ProfileID, key)
+		}
+		if errors.Is(err, services.ErrTrackNotFound) {
+			h.writeError(w, r, http.StatusOK, subsonicErrNotFound, subsonicNotFoundMessage)
+			return
+		}
+		if err != nil {
+			log.Printf("subsonic: updating like failed: %v", err)
+			h.writeError(w, r, http.StatusOK, subsonicErrGeneric, subsonicServerErrorMessage)
+			return
+		}
+	}
+	writeSubsonic(w, r, http.StatusOK, h.newResponse())
+}
+
+func (h *SubsonicHandler) handleCoverArt(w http.ResponseWriter, r *http.Request) {
+	id := r.Form.Get("id")
+	if id == "" {
+		h.writeError(w, r, http.StatusOK, subsonicErrMissingParameter, "Required parameter is missing: id")
+		return
+	}
+	if folderKey, ok := strings.CutPrefix(id, subsonicFolderArt); ok {
+		h.folders.servePoster(w, r, folderKey)
+		return
+	}
+	h.audio.handleThumbnail(w, r, id)
+}
+
+// handleMedia streams or downloads a track. The app token's profile stands
+// in for the browser session when issuing access keys, so the same
+// issuance limits, captcha policy and bandwidth throttles apply.
+func (h *SubsonicHandler) handleMedia(
+	w http.ResponseWriter,
+	r *http.Request,
+	identity *services.AppTokenIdentity,
+	clientAddress string,
+	download bool,
+) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		h.writeError(w, r, http.StatusMethodNotAllowed, subsonicErrGeneric, "Method not allowed")
+		return
+	}
+	key := r.Form.Get("id")
+	if key == "" {
+		h.writeError(w, r, http.StatusBadRequest, subsonicErrMissingParameter, "Required parameter is missing: id")
+		return
+	}
+	if download && isBotLikeUserAgent(r.UserAgent()) {
+		h.writeError(w, r, http.StatusForbidden, subsonicErrNotAuthorized, "Downloads are not available to automated clients")
+		return
+	}
+	if h.audio.accessKeys == nil {
+		h.writeError(w, r, http.StatusInternalServerError, subsonicErrGeneric, "Access keys are not available")
+		return
+	}
+	if download && h.audio.downloadSessionMinAge > 0 {
+		remaining := h.audio.downloadSessionMinAge - h.audio.now().Sub(identity.CreatedAt)
+		if remaining > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(max(1, int(math.Ceil(remaining.Seconds())))))
+			h.writeError(w, r, http.StatusTooManyRequests, subsonicErrNotAuthorized, "This app token is too new to download")
+			return
+		}
+	}
+
+	row, err := h.audio.lookupByKey(key)
+	if err == sql.ErrNoRows || (err == nil && row.deleted) {
+		h.writeError(w, r, http.StatusNotFound, subsonicErrNotFound, subsonicNotFoundMessage)
+		return
+	}
+	if err != nil {
+		h.writeError(w, r, http.StatusInternalServerError, subsonicErrGeneric, subsonicServerErrorMessage)
+		return
+	}
+	if row.removalRestricted(r) {
+		h.writeError(w, r, http.StatusGone, subsonicErrNotFound, "This track was removed at the creator's request")
+		return
+	}
+
+	purpose := services.MediaPurposeStream
+	if download {
+		purpose = services.MediaPurposeDownload
+	}
+	nonce, err := h.grant(identity.ProfileID, clientAddress, key, purpose, identity.CreatedAt)
+	if err != nil {
+		var limited *services.KeyLimitExceededError
+		switch {
+		case errors.As(err, &limited):
+			w.Header().Set("Retry-After", strconv.Itoa(max(1, int(math.Ceil(limited.RetryAfter.Seconds())))))
+			h.writeError(w, r, http.StatusTooManyRequests, subsonicErrGeneric, "Too many "+string(purpose)+" requests; try again later")
+		case errors.Is(err, services.ErrCaptchaRequired):
+			h.writeError(w, r, http.StatusForbidden, subsonicErrNotAuthorized, "Verification is required; use the web player")
+		case errors.Is(err, services.ErrScraperBanned):
+			h.writeError(w, r, http.StatusForbidden, subsonicErrNotAuthorized, "Access is temporarily blocked")
+		default:
+			log.Printf("subsonic: issuing %s access key for share_key=%s failed: %v", purpose, key, err)
+			h.writeError(w, r, http.StatusInternalServerError, subsonicErrGeneric, subsonicServerErrorMessage)
+		}
+		return
+	}
+	if row.removalRequestedAt.Valid {
+		w.Header().Set("Cache-Control", "private, no-store")
+	}
+	h.audio.serveMedia(w, r, row, key, download, nonce, identity.ProfileID, clientAddress)
+}
+
+// grant returns the nonce of a live access key for the profile and track,
+// issuing a new key only when the cached one has expired.
+func (h *SubsonicHandler) grant(
+	profileID, clientAddress, key string,
+	purpose services.MediaPurpose,
+	tokenCreatedAt time.Time,
+) (string, error) {
+	cacheKey := profileID + "\x00" + key + "\x00" + string(purpose)
+	now := h.audio.now()
+
+	h.mu.Lock()
+	cached, ok := h.grants[cacheKey]
+	h.mu.Unlock()
+	if ok && now.Add(subsonicGrantMargin).Before(cached.expiresAt) {
+		return cached.nonce, nil
+	}
+
+	if err := h.audio.accessKeys.CheckLimit(profileID, clientAddress, purpose); err != nil {
+		return "", err
+	}
+	issued, err := h.audio.issueMediaAccessKey(profileID, clientAddress, key, purpose, false, tokenCreatedAt)
+	if err != nil {
+		return "", err
+	}
+	verified, err := h.audio.accessKeys.VerifyAndExtract(issued.AccessKey, profileID, key, purpose)
+	if err != nil {
+		return "", err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.storeGrantLocked(cacheKey, subsonicGrant{nonce: verified.Nonce, expiresAt: issued.ExpiresAt}, now)
+	return verified.Nonce, nil
+}
+
+// storeGrantLocked caches a grant, keeping at most subsonicMaxGrants. When
+// the cache is full, expired grants are dropped first, then the grant
+// closest to expiry; an evicted track simply issues a new key next time.
+func (h *SubsonicHandler) storeGrantLocked(cacheKey string, grant subsonicGrant, now time.Time) {
+	if _, ok := h.grants[cacheKey]; !ok && len(h.grants) >= subsonicMaxGrants {
+		for k, cached := range h.grants {
+			if !now.Before(cached.expiresAt) {
+				delete(h.grants, k)
+			}
+		}
+		for len(h.grants) >= subsonicMaxGrants {
+			var oldestKey string
+			var oldest time.Time
+			for k, cached := range h.grants {
+				if oldestKey == "" || cached.expiresAt.Before(oldest) {
+					oldestKey, oldest = k, cached.expiresAt
+				}
+			}
+			delete(h.grants, oldestKey)
+		}
+	}
+	h.grants[cacheKey] = grant
+}
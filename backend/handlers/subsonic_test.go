@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/onion/audio-share-backend/services"
+)
+
+type fakeAppTokens struct {
+	identity *services.AppTokenIdentity
+	calls    []string
+}
+
+func (f *fakeAppTokens) result() (*services.AppTokenIdentity, error) {
+	if f.identity == nil {
+		return nil, services.ErrInvalidAppToken
+	}
+	return f.identity, nil
+}
+
+func (f *fakeAppTokens) AuthenticatePassword(username, password string) (*services.AppTokenIdentity, error) {
+	f.calls = append(f.calls, "password:"+username+":"+password)
+	return f.result()
+}
+
+func (f *fakeAppTokens) AuthenticateToken(username, token, salt string) (*services.AppTokenIdentity, error) {
+	f.calls = append(f.calls, "token:"+username+":"+token+":"+salt)
+	return f.result()
+}
+
+func (f *fakeAppTokens) AuthenticateAPIKey(apiKey string) (*services.AppTokenIdentity, error) {
+	f.calls = append(f.calls, "apikey:"+apiKey)
+	return f.result()
+}
+
+type subsonicSearchStub struct {
+	exists bool
+}
+
+func (s subsonicSearchStub) Search(string, int, int, services.SearchOptions) ([]services.SearchResult, int, error) {
+	return nil, 0, nil
+}
+
+func (s subsonicSearchStub) FolderExists(string) (bool, error) {
+	return s.exists, nil
+}
+
+func newSubsonicTestHandler(tokens *fakeAppTokens, browser directoryBrowser) *SubsonicHandler {
+	return NewSubsonicHandler(NewAudioHandler(nil, nil, AudioHandlerOptions{}), nil, SubsonicHandlerOptions{
+		ServerVersion: "test",
+		Tokens:        tokens,
+		Browser:       browser,
+		Search:        subsonicSearchStub{exists: true},
+	})
+}
+
+func TestSubsonicRequiresCredentials(t *testing.T) {
+	handler := newSubsonicTestHandler(&fakeAppTokens{}, nil)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/rest/ping.view", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", recorder.Code)
+	}
+	body := recorder.Body.String()
+	for _, want := range []string{
+		`<subsonic-response xmlns="http://subsonic.org/restapi" status="failed" version="1.16.1"`,
+		`openSubsonic="true"`,
+		`<error code="10"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("response missing %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestSubsonicAuthenticationModes(t *testing.T) {
+	tests := []struct {
+		query    string
+		wantCode int
+		wantCall string
+	}{
+		{"u=as-1&p=secret", 0, "password:as-1:secret"},
+		{"u=as-1&p=enc:736563726574", 0, "password:as-1:secret"},
+		{"u=as-1&t=abc&s=xyz", 0, "token:as-1:abc:xyz"},
+		{"apiKey=key", 0, "apikey:key"},
+		{"apiKey=key&u=as-1", subsonicErrConflictingAuth, ""},
+		{"u=as-1&t=abc", subsonicErrMissingParameter, ""},
+	}
+	for _, tt := range tests {
+		tokens := &fakeAppTokens{identity: &services.AppTokenIdentity{ProfileID: "profile"}}
+		handler := newSubsonicTestHandler(tokens, nil)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/rest/ping?f=json&"+tt.query, nil))
+
+		var payload struct {
+			Response struct {
+				Status string         `json:"status"`
+				Error  *subsonicError `json:"error"`
+			} `json:"subsonic-response"`
+		}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &payload); err != nil {
+			t.Fatalf("%s: decode: %v", tt.query, err)
+		}
+		if tt.wantCode == 0 {
+			if payload.Response.Status != "ok" {
+				t.Errorf("%s: status = %q, error = %+v", tt.query, payload.Response.Status, payload.Response.Error)
+			}
+		} else if payload.Response.Error == nil || payload.Response.Error.Code != tt.wantCode {
+			t.Errorf("%s: error = %+v, want code %d", tt.query, payload.Response.Error, tt.wantCode)
+		}
+		if tt.wantCall != "" && (len(tokens.calls) != 1 || tokens.calls[0] != tt.wantCall) {
+			t.Errorf("%s: calls = %v, want %q", tt.query, tokens.calls, tt.wantCall)
+		}
+	}
+}
+
+func TestSubsonicMusicDirectoryMapsFoldersAndTracks(t *testing.T) {
+	browser := &recordingDirectoryBrowser{contents: map[string]*services.DirectoryContents{
+		"music/artist": {Items: []services.FileSystemItem{
+			{Name: "Live", Path: "music/artist/Live", Type: "folder", ShareKey: "fkey", PosterImage: "poster.jpg"},
+			{Name: "song.mp3", Path: "music/artist/song.mp3", Type: "audio", ShareKey: "skey", Size: 42, DurationSeconds: 61.6, MimeType: "audio/mpeg"},
+		}},
+	}}
+	handler := newSubsonicTestHandler(&fakeAppTokens{identity: &services.AppTokenIdentity{ProfileID: "profile"}}, browser)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(
+		http.MethodGet, "/rest/getMusicDirectory?f=json&apiKey=key&id=d:music/artist", nil,
+	))
+
+	var payload struct {
+		Response struct {
+			Directory subsonicDirectory `json:"directory"`
+		} `json:"subsonic-response"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("decode: %v\n%s", err, recorder.Body.String())
+	}
+	directory := payload.Response.Directory
+	if directory.ID != "d:music/artist" || directory.Parent != "d:music" || directory.Name != "artist" {
+		t.Fatalf("directory = %+v", directory)
+	}
+	if len(directory.Child) != 2 {
+		t.Fatalf("children = %+v", directory.Child)
+	}
+	folder, song := directory.Child[0], directory.Child[1]
+	if !folder.IsDir || folder.ID != "d:music/artist/Live" || folder.CoverArt != "f:fkey" {
+		t.Errorf("folder child = %+v", folder)
+	}
+	if song.IsDir || song.ID != "skey" || song.Title != "song" || song.Suffix != "mp3" ||
+		song.Duration != 62 || song.Album != "artist" || song.CoverArt != "skey" {
+		t.Errorf("song child = %+v", song)
+	}
+	if browser.opts[0].IncludeRemovalRequested {
+		t.Error("remote request listed removal-requested tracks")
+	}
+}
+
+func TestSubsonicIndexesGroupFoldersByInitial(t *testing.T) {
+	browser := &recordingDirectoryBrowser{contents: map[string]*services.DirectoryContents{
+		"": {Items: []services.FileSystemItem{{Name: "Music", Path: "music", Type: "folder"}}},
+		"music": {Items: []services.FileSystemItem{
+			{Name: "beta", Path: "music/beta", Type: "folder"},
+			{Name: "Alpha", Path: "music/Alpha", Type: "folder"},
+			{Name: "2 Many", Path: "music/2 Many", Type: "folder"},
+		}},
+	}}
+	handler := newSubsonicTestHandler(&fakeAppTokens{identity: &services.AppTokenIdentity{ProfileID: "profile"}}, browser)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/rest/getIndexes?apiKey=key", nil))
+
+	body := recorder.Body.String()
+	wantOrder := []string{`<index name="#">`, `name="2 Many"`, `<index name="A">`, `<index name="B">`}
+	last := -1
+	for _, want := range wantOrder {
+		index := strings.Index(body, want)
+		if index <= last {
+			t.Fatalf("%q missing or out of order:\n%s", want, body)
+		}
+		last = index
+	}
+}
+
+func TestSubsonicGrantCacheStaysBounded(t *testing.T) {
+	handler := newSubsonicTestHandler(&fakeAppTokens{}, nil)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := range subsonicMaxGrants {
+		handler.storeGrantLocked(fmt.Sprintf("grant-%d", i), subsonicGrant{
+			nonce:     "nonce",
+			expiresAt: now.Add(time.Hour + time.Duration(i)*time.Second),
+		}, now)
+	}
+
+	handler.storeGrantLocked("newest", subsonicGrant{nonce: "nonce", expiresAt: now.Add(2 * time.Hour)}, now)
+
+	if len(handler.grants) != subsonicMaxGrants {
+		t.Fatalf("cache holds %d grants, want %d", len(handler.grants), subsonicMaxGrants)
+	}
+	if _, ok := handler.grants["grant-0"]; ok {
+		t.Fatal("grant closest to expiry was kept")
+	}
+	if _, ok := handler.grants["newest"]; !ok {
+		t.Fatal("new grant was not cached")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -130,5 +131,6 @@ func (h *AdminHandler) handleTargetedMessageCreate(w http.ResponseWriter, r *htt
 		return
 	}
 
+	h.recordAudit(r, "targeted_message", strconv.FormatInt(message.ID, 10), nil, message)
 	writeJSON(w, http.StatusCreated, message)
 }
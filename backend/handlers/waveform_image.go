@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultWaveformImageWidth  = 800
+	defaultWaveformImageHeight = 120
+	maxWaveformImageWidth      = 2000
+	maxWaveformImageHeight     = 600
+	waveformImageBarStep       = 3
+	waveformImageVersion       = "v1"
+)
+
+var (
+	defaultWaveformColor       = color.RGBA{R: 196, G: 136, B: 42, A: 255}
+	defaultWaveformMarkerColor = color.RGBA{R: 236, G: 231, B: 222, A: 255}
+)
+
+// waveformImageOptions are the query parameters of waveform.svg and
+// waveform.png. Bars after the progress marker are drawn at reduced opacity.
+type waveformImageOptions struct {
+	width       int
+	height      int
+	color       color.RGBA
+	markerColor color.RGBA
+	progress    float64 // 0..1, negative when no marker is drawn
+}
+
+func parseWaveformImageOptions(r *http.Request) (waveformImageOptions, error) {
+	query := r.URL.Query()
+	opts := waveformImageOptions{
+		width:       defaultWaveformImageWidth,
+		height:      defaultWaveformImageHeight,
+		color:       defaultWaveformColor,
+		markerColor: defaultWaveformMarkerColor,
+		progress:    -1,
+	}
+	var err error
+	if value := query.Get("width"); value != "" {
+		if opts.width, err = strconv.Atoi(value); err != nil || opts.width < 16 || opts.width > maxWaveformImageWidth {
+			return opts, fmt.Errorf("width must be between 16 and %d", maxWaveformImageWidth)
+		}
+	}
+	if value := query.Get("height"); value != "" {
+		if opts.height, err = strconv.Atoi(value); err != nil || opts.height < 8 || opts.height > maxWaveformImageHeight {
+			return opts, fmt.Errorf("height must be between 8 and %d", maxWaveformImageHeight)
+		}
+	}
+	if value := query.Get("color"); value != "" {
+		if opts.color, err = parseHexColor(value); err != nil {
+			return opts, fmt.Errorf("color must be a hex colour")
+		}
+	}
+	if value := query.Get("markerColor"); value != "" {
+		if opts.markerColor, err = parseHexColor(value); err != nil {
+			return opts, fmt.Errorf("markerColor must be a hex colour")
+		}
+	}
+	if value := query.Get("progress"); value != "" {
+		if opts.progress, err = strconv.ParseFloat(value, 64); err != nil || opts.progress < 0 || opts.progress > 1 {
+			return opts, fmt.Errorf("progress must be between 0 and 1")
+		}
+	}
+	return opts, nil
+}
+
+// parseHexColor accepts RGB or RRGGBB hex, with or without a leading #.
+func parseHexColor(value string) (color.RGBA, error) {
+	value = strings.TrimPrefix(value, "#")
+	if len(value) == 3 {
+		value = string([]byte{value[0], value[0], value[1], value[1], value[2], value[2]})
+	}
+	if len(value) != 6 {
+		return color.RGBA{}, fmt.Errorf("invalid colour %q", value)
+	}
+	decoded, err := hex.DecodeString(value)
+	if err != nil {
+		return color.RGBA{}, err
+	}
+	return color.RGBA{R: decoded[0], G: decoded[1], B: decoded[2], A: 255}, nil
+}
+
+func cssColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// waveformBarLevels reduces peaks to one 0-255 level per bar, keeping the
+// loudest peak of each bucket.
+func waveformBarLevels(peaks []byte, bars int) []int {
+	levels := make([]int, bars)
+	if len(peaks) == 0 {
+		return levels
+	}
+	for i := range bars {
+		start := i * len(peaks) / bars
+		end := max(start+1, (i+1)*len(peaks)/bars)
+		for _, peak := range peaks[start:min(end, len(peaks))] {
+			levels[i] = max(levels[i], int(peak))
+		}
+	}
+	return levels
+}
+
+// waveformBar is one bar of a rendered waveform in image coordinates.
+type waveformBar struct {
+	x, y, width, height int
+	played              bool
+}
+
+func layoutWaveformBars(peaks []byte, opts waveformImageOptions) []waveformBar {
+	count := max(1, opts.width/waveformImageBarStep)
+	barWidth := max(1, waveformImageBarStep*2/3)
+	mid := opts.height / 2
+	levels := waveformBarLevels(peaks, count)
+	bars := make([]waveformBar, count)
+	for i, level := range levels {
+		half := max(1, level*opts.height/255/2)
+		x := i * waveformImageBarStep
+		bars[i] = waveformBar{
+			x: x, y: mid - half, width: barWidth, height: 2 * half,
+			played: opts.progress >= 0 && float64(x) < opts.progress*float64(opts.width),
+		}
+	}
+	return bars
+}
+
+func renderWaveformSVG(peaks []byte, opts waveformImageOptions) []byte {
+	var played, upcoming strings.Builder
+	for _, bar := range layoutWaveformBars(peaks, opts) {
+		target := &upcoming
+		if bar.played || opts.progress < 0 {
+			target = &played
+		}
+		fmt.Fprintf(target, "M%d %dh%dv%dh-%dz", bar.x, bar.y, bar.width, bar.height, bar.width)
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`,
+		opts.width, opts.height, opts.width, opts.height)
+	if played.Len() > 0 {
+		fmt.Fprintf(&b, `<path fill="%s" d="%s"/>`, cssColor(opts.color), played.String())
+	}
+	if upcoming.Len() > 0 {
+		fmt.Fprintf(&b, `<path fill="%s" fill-opacity="0.35" d="%s"/>`, cssColor(opts.color), upcoming.String())
+	}
+	if opts.progress >= 0 {
+		x := min(opts.width-1, int(opts.progress*float64(opts.width)))
+		fmt.Fprintf(&b, `<rect fill="%s" x="%d" y="0" width="2" height="%d"/>`, cssColor(opts.markerColor), x, opts.height)
+	}
+	b.WriteString(`</svg>`)
+	return b.Bytes()
+}
+
+func renderWaveformPNG(peaks []byte, opts waveformImageOptions) ([]byte, error) {
+	img := image.NewNRGBA(image.Rect(0, 0, opts.width, opts.height))
+	upcoming := opts.color
+	upcoming.A = 90
+	for _, bar := range layoutWaveformBars(peaks, opts) {
+		fill := opts.color
+		if opts.progress >= 0 && !bar.played {
+			fill = upcoming
+		}
+		rect := image.Rect(bar.x, bar.y, bar.x+bar.width, bar.y+bar.height)
+		draw.Draw(img, rect, &image.Uniform{C: color.NRGBA{R: fill.R, G: fill.G, B: fill.B, A: fill.A}}, image.Point{}, draw.Src)
+	}
+	if opts.progress >= 0 {
+		x := min(opts.width-1, int(opts.progress*float64(opts.width)))
+		draw.Draw(img, image.Rect(x, 0, min(opts.width, x+2), opts.height), &image.Uniform{C: opts.markerColor}, image.Point{}, draw.Src)
+	}
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// handleWaveformImage renders the cached peaks as an SVG or PNG for clients
+// that cannot draw the JSON waveform themselves.
+func (h *AudioHandler) handleWaveformImage(w http.ResponseWriter, r *http.Request, key, format string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var fileID int64
+	var removalRequestedAt sql.NullTime
+	err := h.db.QueryRow(`
+		SELECT id, removal_requested_at FROM audio_files WHERE share_key = $1 AND deleted = 0
+	`, key).Scan(&fileID, &removalRequestedAt)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+	if removalRequestedAt.Valid {
+		w.Header().Set("Cache-Control", "private, no-store")
+	}
+	if removalRequestedAt.Valid && !isLocalRequest(r) {
+		http.Error(w, "Gone", http.StatusGone)
+		return
+	}
+
+	opts, err := parseWaveformImageOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var encoded string
+	err = h.db.QueryRow(`SELECT peaks FROM waveform_cache WHERE audio_file_id = $1`, fileID).Scan(&encoded)
+	if err == sql.ErrNoRows {
+		w.Header().Set("Cache-Control", "no-store")
+		http.Error(w, "No waveform", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+	peaks, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\x00%s\x00%+v\x00", waveformImageVersion, format, opts)
+	hash.Write(peaks)
+	etag := `"` + hex.EncodeToString(hash.Sum(nil))[:32] + `"`
+	w.Header().Set("ETag", etag)
+	if !removalRequestedAt.Valid {
+		w.Header().Set("Cache-Control", "private, max-age=86400")
+	}
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	var body []byte
+	if format == "svg" {
+		w.Header().Set("Content-Type", "image/svg+xml")
+		body = renderWaveformSVG(peaks, opts)
+	} else {
+		w.Header().Set("Content-Type", "image/png")
+		if body, err = renderWaveformPNG(peaks, opts); err != nil {
+			http.Error(w, "Error rendering waveform", http.StatusInternalServerError)
+			return
+		}
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	if r.Method == http.MethodHead {
+		return
+	}
+	w.Write(body)
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func expectWaveformImageLookup(mock sqlmock.Sqlmock, removalRequestedAt any, peaks []byte) {
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, removal_requested_at FROM audio_files WHERE share_key = $1 AND deleted = 0`)).
+		WithArgs("track-key").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "removal_requested_at"}).AddRow(7, removalRequestedAt))
+	if peaks != nil {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT peaks FROM waveform_cache WHERE audio_file_id = $1`)).
+			WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"peaks"}).AddRow(base64.StdEncoding.EncodeToString(peaks)))
+	}
+}
+
+func TestWaveformSVGDrawsProgressAndHonoursETag(t *testing.T) {
+	handler, mock := newMockAudioHandler(t, nil, newTestHandlerAccessKeyManager(t, "10/1m"))
+	peaks := []byte{0, 64, 128, 255, 255, 128, 64, 0}
+	expectWaveformImageLookup(mock, nil, peaks)
+	expectWaveformImageLookup(mock, nil, peaks)
+
+	target := "/api/audio/key/track-key/waveform.svg?width=300&height=60&color=%23ff0000&progress=0.5"
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, body=%s", recorder.Code, recorder.Body.String())
+	}
+	if got := recorder.Header().Get("Content-Type"); got != "image/svg+xml" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	body := recorder.Body.String()
+	for _, want := range []string{
+		`width="300" height="60"`,
+		`<path fill="#ff0000" d="M0 `,
+		`<path fill="#ff0000" fill-opacity="0.35"`,
+		`<rect fill="#ece7de" x="150" y="0" width="2" height="60"/>`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("svg missing %q:\n%s", want, body)
+		}
+	}
+
+	etag := recorder.Header().Get("ETag")
+	request := httptest.NewRequest(http.MethodGet, target, nil)
+	request.Header.Set("If-None-Match", etag)
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusNotModified || recorder.Body.Len() != 0 {
+		t.Fatalf("conditional status = %d body=%q", recorder.Code, recorder.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWaveformPNGUsesRequestedSize(t *testing.T) {
+	handler, mock := newMockAudioHandler(t, nil, newTestHandlerAccessKeyManager(t, "10/1m"))
+	expectWaveformImageLookup(mock, nil, []byte{10, 200, 90})
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/audio/key/track-key/waveform.png?width=120&height=40", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, body=%s", recorder.Code, recorder.Body.String())
+	}
+	img, err := png.Decode(recorder.Body)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if img.Bounds().Dx() != 120 || img.Bounds().Dy() != 40 {
+		t.Fatalf("size = %v", img.Bounds())
+	}
+}
+
+func TestWaveformImageRejectsBadParametersAndRemovedAudio(t *testing.T) {
+	handler, mock := newMockAudioHandler(t, nil, newTestHandlerAccessKeyManager(t, "10/1m"))
+	for _, query := range []string{"width=5000", "color=blue", "progress=2"} {
+		expectWaveformImageLookup(mock, nil, nil)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/audio/key/track-key/waveform.svg?"+query, nil))
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, recorder.Code)
+		}
+	}
+
+	expectWaveformImageLookup(mock, time.Date(2026, 8, 14, 12, 0, 0, 0, time.UTC), nil)
+	request := httptest.NewRequest(http.MethodGet, "/api/audio/key/track-key/waveform.png", nil)
+	request.RemoteAddr = "203.0.113.5:1234"
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusGone {
+		t.Fatalf("removal-requested status = %d, want 410", recorder.Code)
+	}
+	if got := recorder.Header().Get("Cache-Control"); got != "private, no-store" {
+		t.Errorf("Cache-Control = %q", got)
+	}
+}
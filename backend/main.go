@@ -1,10 +1,14 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -24,6 +28,140 @@ func sourceNormalizerFromConfig(cfg *config.Config) (*services.ScriptSourceNorma
 	return services.NewScriptSourceNormalizer(cfg.SourceNormalizerScript, timeout), nil
 }
 
+func availabilityServiceFromConfig(cfg *config.Config, db *services.Database, webhook *services.WebhookService) (*services.AvailabilityService, error) {
+	timeout, err := time.ParseDuration(cfg.AvailabilityTimeout)
+	if err != nil || timeout <= 0 {
+		return nil, fmt.Errorf("invalid AVAILABILITY_TIMEOUT %q", cfg.AvailabilityTimeout)
+	}
+	hostInterval, err := time.ParseDuration(cfg.AvailabilityHostInterval)
+	if err != nil || hostInterval < 0 {
+		return nil, fmt.Errorf("invalid AVAILABILITY_HOST_INTERVAL %q", cfg.AvailabilityHostInterval)
+	}
+	retention, err := time.ParseDuration(cfg.AvailabilityHistoryRetention)
+	if err != nil || retention < 0 {
+		return nil, fmt.Errorf("invalid AVAILABILITY_HISTORY_RETENTION %q", cfg.AvailabilityHistoryRetention)
+	}
+	var checker services.AvailabilityChecker
+	switch strings.ToLower(strings.TrimSpace(cfg.AvailabilityChecker)) {
+	case "http":
+		checker = services.NewHTTPAvailabilityChecker(timeout)
+	case "script":
+		if strings.TrimSpace(cfg.AvailabilityScript) == "" {
+			return nil, fmt.Errorf("AVAILABILITY_SCRIPT or SOURCE_NORMALIZER_SCRIPT is required for the script availability checker")
+		}
+		checker = services.NewScriptAvailabilityChecker(cfg.AvailabilityScript, timeout)
+	default:
+		return nil, fmt.Errorf("invalid AVAILABILITY_CHECKER %q", cfg.AvailabilityChecker)
+	}
+	ntfy := services.NewNtfyService(cfg.NtfyURL, cfg.NtfyTopic, cfg.NtfyToken, cfg.NtfyPriority, cfg.NtfyReviewURL)
+	return services.NewAvailabilityService(db.DB(), checker, ntfy, webhook, cfg.AvailabilityConcurrency, hostInterval, retention), nil
+}
+
+func waveformMaxDuration(cfg *config.Config) time.Duration {
+	maxDuration, err := time.ParseDuration(cfg.WaveformMaxDuration)
+	if err != nil {
+		return 2 * time.Hour
+	}
+	return maxDuration
+}
+
+// adminJobs lists the jobs that can be started through
+// POST /api/admin/jobs/{name}. The services are the ones the scheduler runs,
+// so a manual run never overlaps a scheduled one. availabilityService is nil
+// when the availability checker is not configured.
+func adminJobs(
+	searchService *services.SearchService,
+	waveformService *services.WaveformService,
+	waveformMaxDuration time.Duration,
+	artworkService *services.ArtworkService,
+	availabilityService *services.AvailabilityService,
+) map[string]func() error {
+	jobs := map[string]func() error{
+		"reindex": searchService.RebuildIndex,
+		"waveform": func() error {
+			return waveformService.RunJobExclusive(waveformMaxDuration)
+		},
+		"artwork": artworkService.RunJobExclusive,
+	}
+	if availabilityService != nil {
+		jobs["availability"] = func() error {
+			_, err := availabilityService.RunJobExclusive(context.Background())
+			return err
+		}
+	}
+	return jobs
+}
+
+const keysUsage = `usage:
+  keys list
+  keys create <name> <scope>[,<scope>...] [expires-in]
+  keys revoke <id>`
+
+// runKeysCommand manages named admin API keys from the command line. A new
+// key is printed once and cannot be shown again.
+func runKeysCommand(keys *services.AdminKeyService, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(keysUsage)
+	}
+	switch args[0] {
+	case "list":
+		list, err := keys.List()
+		if err != nil {
+			return err
+		}
+		for _, key := range list {
+			state := "active"
+			if key.RevokedAt != nil {
+				state = "revoked " + *key.RevokedAt
+			} else if key.ExpiresAt != nil {
+				state = "expires " + *key.ExpiresAt
+			}
+			lastUsed := "never"
+			if key.LastUsedAt != nil {
+				lastUsed = *key.LastUsedAt
+			}
+			fmt.Fprintf(out, "%d\t%s\t%s\t%s…\t%s\tlast used %s\n",
+				key.ID, key.Name, strings.Join(key.Scopes, ","), key.Prefix, state, lastUsed)
+		}
+		return nil
+	case "create":
+		if len(args) < 3 || len(args) > 4 {
+			return errors.New(keysUsage)
+		}
+		var expiresAt *time.Time
+		if len(args) == 4 {
+			ttl, err := time.ParseDuration(args[3])
+			if err != nil || ttl <= 0 {
+				return fmt.Errorf("invalid expiry %q", args[3])
+			}
+			expires := time.Now().Add(ttl)
+			expiresAt = &expires
+		}
+		key, err := keys.Create(args[1], strings.Split(args[2], ","), expiresAt)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Created key %d (%s) with scopes %s\n", key.ID, key.Name, strings.Join(key.Scopes, ","))
+		fmt.Fprintln(out, key.Key)
+		return nil
+	case "revoke":
+		if len(args) != 2 {
+			return errors.New(keysUsage)
+		}
+		id, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid key ID %q", args[1])
+		}
+		if err := keys.Revoke(id); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Revoked key %d\n", id)
+		return nil
+	default:
+		return errors.New(keysUsage)
+	}
+}
+
 func main() {
 	cfg := config.Load()
 
@@ -44,11 +182,36 @@ func main() {
 		db := services.NewDatabase(cfg.DatabaseURL)
 		defer db.Close()
 		waveformService := services.NewWaveformService(db.DB(), fsService, cfg.WaveformWorkers)
-		maxDuration, err := time.ParseDuration(cfg.WaveformMaxDuration)
+		waveformService.RunJob(waveformMaxDuration(cfg))
+		os.Exit(0)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "artwork" {
+		db := services.NewDatabase(cfg.DatabaseURL)
+		defer db.Close()
+		services.NewArtworkService(db.DB(), fsService).RunJob()
+		os.Exit(0)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "availability" {
+		db := services.NewDatabase(cfg.DatabaseURL)
+		defer db.Close()
+		availabilityService, err := availabilityServiceFromConfig(cfg, db, webhookService)
 		if err != nil {
-			maxDuration = 2 * time.Hour
+			log.Fatal(err)
+		}
+		if _, err := availabilityService.RunJob(context.Background()); err != nil {
+			log.Fatalf("Availability check failed: %v", err)
+		}
+		os.Exit(0)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "keys" {
+		db := services.NewDatabase(cfg.DatabaseURL)
+		defer db.Close()
+		if err := runKeysCommand(services.NewAdminKeyService(db), os.Args[2:], os.Stdout); err != nil {
+			log.Fatal(err)
 		}
-		waveformService.RunJob(maxDuration)
 		os.Exit(0)
 	}
 
@@ -59,11 +222,26 @@ func main() {
 		searchService.StartScheduledReindex(cfg.IndexSchedule)
 	}
 
+	waveformService := services.NewWaveformService(db.DB(), fsService, cfg.WaveformWorkers)
 	if cfg.WaveformCron != "" {
-		waveformService := services.NewWaveformService(db.DB(), fsService, cfg.WaveformWorkers)
 		waveformService.StartScheduledJob(cfg.WaveformCron, cfg.WaveformMaxDuration)
 	}
 
+	artworkService := services.NewArtworkService(db.DB(), fsService)
+	if cfg.ArtworkCron != "" {
+		artworkService.StartScheduledJob(cfg.ArtworkCron)
+	}
+
+	availabilityService, err := availabilityServiceFromConfig(cfg, db, webhookService)
+	if err != nil {
+		if cfg.AvailabilityCron != "" {
+			log.Fatal(err)
+		}
+		log.Printf("Availability job is not available through the admin API: %v", err)
+	} else if cfg.AvailabilityCron != "" {
+		availabilityService.StartScheduledJob(cfg.AvailabilityCron)
+	}
+
 	if cfg.SessionSecret == "" {
 		log.Fatal("SESSION_SECRET is required but not set")
 	}
@@ -89,9 +267,35 @@ func main() {
 	if err != nil {
 		log.Fatalf("Invalid audio access key configuration: %v", err)
 	}
+	feedKeyTTL, err := time.ParseDuration(cfg.FeedKeyTTL)
+	if err != nil || feedKeyTTL <= 0 {
+		log.Fatalf("Invalid FEED_KEY_TTL %q", cfg.FeedKeyTTL)
+	}
+	if err := accessKeys.SetFeedPolicy(cfg.FeedKeyLimits, feedKeyTTL); err != nil {
+		log.Fatalf("Invalid FEED_KEY_LIMITS %q: %v", cfg.FeedKeyLimits, err)
+	}
+	playlistKeyTTL, err := time.ParseDuration(cfg.PlaylistKeyTTL)
+	if err != nil || playlistKeyTTL <= 0 {
+		log.Fatalf("Invalid PLAYLIST_KEY_TTL %q", cfg.PlaylistKeyTTL)
+	}
+	if err := accessKeys.SetPlaylistPolicy(cfg.PlaylistKeyLimits, playlistKeyTTL); err != nil {
+		log.Fatalf("Invalid PLAYLIST_KEY_LIMITS %q: %v", cfg.PlaylistKeyLimits, err)
+	}
+	if err := accessKeys.SetPlaylistExportPolicy(cfg.PlaylistExportLimits); err != nil {
+		log.Fatalf("Invalid PLAYLIST_EXPORT_LIMITS %q: %v", cfg.PlaylistExportLimits, err)
+	}
 	if err := accessKeys.SetCaptchaPolicy(services.MediaPurposeStream, cfg.StreamCaptchaLimits); err != nil {
 		log.Fatalf("Invalid STREAM_CAPTCHA_LIMITS %q: %v", cfg.StreamCaptchaLimits, err)
 	}
+	switch strings.ToLower(strings.TrimSpace(cfg.AccessKeyStore)) {
+	case services.IssuanceStoreMemory:
+	case services.IssuanceStorePostgres:
+		issuanceStore := services.NewPostgresIssuanceStore(db)
+		issuanceStore.StartRetentionCleanup()
+		accessKeys.SetIssuanceStore(issuanceStore)
+	default:
+		log.Fatalf("Invalid ACCESS_KEY_STORE %q", cfg.AccessKeyStore)
+	}
 	captchaEnforcement := strings.ToLower(strings.TrimSpace(cfg.CapEnforcement))
 	if captchaEnforcement != "off" && captchaEnforcement != "observe" && captchaEnforcement != "enforce" {
 		log.Fatalf("Invalid CAP_ENFORCEMENT %q", cfg.CapEnforcement)
@@ -142,12 +346,54 @@ func main() {
 	playbackService := services.NewPlaybackService(db, streamKeyTTL)
 	playbackService.StartAccessKeyClaimCleanup()
 	libraryService := services.NewLibraryService(db)
+	appTokenService := services.NewAppTokenService(db, cfg.SessionSecret)
 	requestsService := services.NewRequestsService(db)
 	sourceNormalizer, err := sourceNormalizerFromConfig(cfg)
 	if err != nil {
 		log.Fatal(err)
 	}
+	searchInsightsRetention, err := time.ParseDuration(cfg.SearchInsightsRetention)
+	if err != nil || searchInsightsRetention < 0 {
+		log.Fatalf("Invalid SEARCH_INSIGHTS_RETENTION %q", cfg.SearchInsightsRetention)
+	}
+	searchInsights := services.NewSearchInsightsService(db, searchInsightsRetention)
+	searchInsights.StartRetentionCleanup()
+	adminAuditRetention, err := time.ParseDuration(cfg.AdminAuditRetention)
+	if err != nil || adminAuditRetention < 0 {
+		log.Fatalf("Invalid ADMIN_AUDIT_RETENTION %q", cfg.AdminAuditRetention)
+	}
+	auditLog := services.NewAuditService(db, adminAuditRetention)
+	auditLog.StartRetentionCleanup()
 	rateLimiter := middleware.NewRateLimiter(cfg)
+	switch strings.ToLower(strings.TrimSpace(cfg.RateLimitStore)) {
+	case services.RateLimitStoreMemory:
+	case services.RateLimitStorePostgres:
+		requestCounters := services.NewPostgresRateLimitBackend(db)
+		requestCounters.StartCleanup()
+		rateLimiter.SetBackend(requestCounters)
+		if streamIPLimiter != nil {
+			streamBuckets := services.NewPostgresBandwidthBackend(db, "stream")
+			streamBuckets.StartCleanup()
+			streamIPLimiter.SetBackend(streamBuckets)
+		}
+		if downloadIPLimiter != nil {
+			downloadBuckets := services.NewPostgresBandwidthBackend(db, "download")
+			downloadBuckets.StartCleanup()
+			downloadIPLimiter.SetBackend(downloadBuckets)
+		}
+	default:
+		log.Fatalf("Invalid RATE_LIMIT_STORE %q", cfg.RateLimitStore)
+	}
+	banRefreshInterval, err := time.ParseDuration(cfg.BanRefreshInterval)
+	if err != nil || banRefreshInterval <= 0 {
+		log.Fatalf("Invalid BAN_REFRESH_INTERVAL %q", cfg.BanRefreshInterval)
+	}
+	bans := services.NewBanService(db)
+	banList := middleware.NewBanList(activeBanRules(bans), middleware.BanListOptions{
+		SessionID: handlers.SessionIDResolver(cfg.SessionSecret),
+	})
+	banList.StartRefresh(banRefreshInterval)
+	scraperScorer := scraperScorerFromConfig(cfg, bans, banList)
 
 	audioHandler := handlers.NewAudioHandler(fsService, db.DB(), handlers.AudioHandlerOptions{
 		StreamBytesPerSecond:   cfg.StreamBytesPerSecond,
@@ -161,21 +407,59 @@ func main() {
 		StreamIPLimiter:        streamIPLimiter,
 		DownloadIPLimiter:      downloadIPLimiter,
 		CaptchaVerifier:        captchaVerifier,
+		ScraperScorer:          scraperScorer,
 		CaptchaEnforcement:     captchaEnforcement,
 		DownloadCaptchaMode:    downloadCaptchaMode,
 		StreamClearanceTTL:     streamClearanceTTL,
+		SiteTitle:              cfg.DefaultTitle,
+	})
+	folderHandler := handlers.NewFolderHandler(fsService, db.DB(), handlers.FolderHandlerOptions{
+		AccessKeys:    accessKeys,
+		SessionSecret: cfg.SessionSecret,
 	})
-	folderHandler := handlers.NewFolderHandler(fsService, db.DB())
 	browseHandler := handlers.NewBrowseHandler(searchService)
 	shareHandler := handlers.NewShareHandler(ntfyService, requestsService, sourceNormalizer)
-	contactHandler := handlers.NewContactHandler(ntfyService, cfg.SessionSecret)
+	removalRequests := services.NewRemovalRequestService(db.DB())
+	contactHandler := handlers.NewContactHandler(ntfyService, cfg.SessionSecret, handlers.ContactHandlerOptions{
+		RemovalRequests: removalRequests,
+		CaptchaVerifier: captchaVerifier,
+	})
 	contentHandler := handlers.NewContentHandler(cfg.ContentDir, cfg.DefaultTitle, searchService)
-	searchHandler := handlers.NewSearchHandler(searchService)
+	searchHandler := handlers.NewSearchHandler(searchService, searchInsights)
 	playbackHandler := handlers.NewPlaybackHandler(playbackService, cfg.SessionSecret, accessKeys)
+	playlistHandler := handlers.NewPlaylistHandler(db.DB(), cfg.SessionSecret, handlers.PlaylistHandlerOptions{
+		Tracks:             playbackService,
+		Browser:            searchService,
+		Library:            libraryService,
+		Search:             searchService,
+		AccessKeys:         accessKeys,
+		CaptchaEnforcement: captchaEnforcement,
+	})
+	embedHandler := handlers.NewEmbedHandler(db.DB(), searchService, cfg.DefaultTitle)
+	atomFeedHandler := handlers.NewAtomFeedHandler(playbackService, fsService.GetSlugToDirectoryMap(), cfg.DefaultTitle)
 	libraryHandler := handlers.NewLibraryHandler(libraryService, cfg.SessionSecret)
+	appTokenHandler := handlers.NewAppTokenHandler(appTokenService, cfg.SessionSecret)
+	subsonicHandler := handlers.NewSubsonicHandler(audioHandler, folderHandler, handlers.SubsonicHandlerOptions{
+		ServerVersion: buildID,
+		Tokens:        appTokenService,
+		Browser:       searchService,
+		Search:        searchService,
+		Library:       libraryService,
+	})
 	preferencesHandler := handlers.NewPreferencesHandler(cfg.SessionSecret)
 	requestsHandler := handlers.NewRequestsHandler(requestsService)
-	adminHandler := handlers.NewAdminHandler(db.DB(), requestsService)
+	adminKeys := services.NewAdminKeyService(db)
+	adminHandler := handlers.NewAdminHandler(db.DB(), requestsService, handlers.AdminHandlerOptions{
+		SearchInsights:  searchInsights,
+		RemovalRequests: removalRequests,
+		AuditLog:        auditLog,
+		AdminKeys:       adminKeys,
+		AccessKeys:      accessKeys,
+		Bans:            bans,
+		BanList:         banList,
+		Scraper:         scraperScorer,
+		Jobs:            adminJobs(searchService, waveformService, waveformMaxDuration(cfg), artworkService, availabilityService),
+	})
 
 	frontendConfig := handlers.FrontendConfig{
 		DefaultTitle:       cfg.DefaultTitle,
@@ -201,10 +485,12 @@ func main() {
 		},
 	)
 
-	securityHeaders := middleware.NewSecurityHeaders(cfg.RybbitURL, cfg.CapPublicEndpoint)
-	apiKeyAuth := middleware.NewAPIKeyAuth(cfg.RequestsAPIKey)
+	securityHeaders := middleware.NewSecurityHeaders(cfg.RybbitURL, cfg.CapPublicEndpoint, middleware.SecurityHeadersOptions{
+		EmbedFrameAncestors: cfg.EmbedFrameAncestors,
+	})
+	apiKeyAuth := middleware.NewAPIKeyAuth(cfg.RequestsAPIKey, middleware.APIKeyAuthOptions{Keys: adminKeys})
 	if cfg.RequestsAPIKey == "" {
-		log.Println("WARNING: REQUESTS_API_KEY is not set — write operations on /api/requests are disabled")
+		log.Println("WARNING: REQUESTS_API_KEY is not set — /api/admin/ only accepts named keys created with the keys command")
 	}
 
 	mux := http.NewServeMux()
@@ -220,6 +506,7 @@ func main() {
 	mux.HandleFunc("/api/audio/random", searchHandler.RandomHandler())
 	mux.Handle("/api/share", shareHandler)
 	mux.Handle("/api/contact", contactHandler)
+	mux.HandleFunc("/api/removal-requests", contactHandler.RemovalRequestHandler())
 	mux.HandleFunc("/api/about", contentHandler.AboutHandler())
 	mux.HandleFunc("/api/stats", contentHandler.StatsHandler())
 	mux.HandleFunc("/api/playback/record", playbackHandler.RecordHandler())
@@ -231,16 +518,23 @@ func main() {
 	mux.HandleFunc("/api/preferences/mature-content", preferencesHandler.MatureContentHandler())
 	mux.HandleFunc("/api/profile/recovery-key", libraryHandler.RecoveryKeyHandler())
 	mux.HandleFunc("/api/profile/recover", libraryHandler.RecoverHandler())
+	mux.HandleFunc("/api/profile/app-tokens", appTokenHandler.TokensHandler())
+	mux.HandleFunc("/api/profile/app-tokens/", appTokenHandler.TokenItemHandler())
+	mux.Handle("/api/playlist/", playlistHandler)
+	mux.Handle("/api/embed/", embedHandler)
 	mux.HandleFunc("/api/likes", libraryHandler.LikesHandler())
 	mux.HandleFunc("/api/likes/tracks", libraryHandler.LikedTracksHandler())
 	mux.HandleFunc("/api/likes/", libraryHandler.LikeItemHandler())
 
 	mux.Handle("/api/requests", requestsHandler)
-	mux.Handle("/api/admin/", apiKeyAuth.Middleware(adminHandler))
+	mux.Handle("/api/admin/", apiKeyAuth.MiddlewareWithScopes(handlers.AdminRouteScope, adminHandler))
+	mux.Handle("/rest/", subsonicHandler)
 
 	mux.HandleFunc("/sitemap.xml", contentHandler.SitemapHandler())
 	mux.HandleFunc("/robots.txt", contentHandler.RobotsHandler())
 	mux.HandleFunc("/site.webmanifest", contentHandler.ManifestHandler())
+	mux.Handle("/feeds/", atomFeedHandler)
+	mux.HandleFunc("/oembed", embedHandler.OEmbedHandler())
 
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -249,7 +543,7 @@ func main() {
 
 	mux.Handle("/", spaHandler)
 
-	handler := securityHeaders.Middleware(rateLimiter.Middleware(corsMiddleware(cfg.CORSOrigins, mux)))
+	handler := banList.Middleware(securityHeaders.Middleware(rateLimiter.Middleware(corsMiddleware(cfg.CORSOrigins, mux))))
 
 	log.Printf("Starting server on :%s", cfg.Port)
 	log.Printf("Audio directories: %v", fsService.GetSlugToDirectoryMap())
@@ -262,6 +556,67 @@ func main() {
 	}
 }
 
+// scraperScorerFromConfig returns nil when scraper detection is off.
+// Enforced bans use the downloads mode, which blocks fetching audio as
+// streams or downloads but leaves browsing open, so a misjudged listener can
+// still read why.
+func scraperScorerFromConfig(cfg *config.Config, bans *services.BanService, banList *middleware.BanList) *services.ScraperScorer {
+	mode := strings.ToLower(strings.TrimSpace(cfg.ScraperDetection))
+	if mode == services.ScraperDetectionOff {
+		return nil
+	}
+	window, err := time.ParseDuration(cfg.ScraperWindow)
+	if err != nil || window <= 0 {
+		log.Fatalf("Invalid SCRAPER_WINDOW %q", cfg.ScraperWindow)
+	}
+	banDuration, err := time.ParseDuration(cfg.ScraperBanDuration)
+	if err != nil || banDuration <= 0 {
+		log.Fatalf("Invalid SCRAPER_BAN_DURATION %q", cfg.ScraperBanDuration)
+	}
+	scorer, err := services.NewScraperScorer(services.ScraperScoringOptions{
+		Mode:                   mode,
+		CaptchaScore:           cfg.ScraperCaptchaScore,
+		ThrottleScore:          cfg.ScraperThrottleScore,
+		BanScore:               cfg.ScraperBanScore,
+		Window:                 window,
+		ThrottleBytesPerSecond: cfg.ScraperThrottleBytesPerSecond,
+		BanDuration:            banDuration,
+		Ban: func(kind, value, reason string, expiresAt time.Time) error {
+			if _, err := bans.Create(kind, value, services.BanModeDownloads, reason,
+				services.ScraperBanCreatedBy, &expiresAt); err != nil {
+				return err
+			}
+			return banList.Refresh()
+		},
+	})
+	if err != nil {
+		log.Fatalf("Invalid scraper detection configuration: %v", err)
+	}
+	return scorer
+}
+
+// activeBanRules loads the active bans in the form the ban middleware
+// enforces.
+func activeBanRules(bans *services.BanService) func() ([]middleware.BanRule, error) {
+	return func() ([]middleware.BanRule, error) {
+		active, err := bans.List(false)
+		if err != nil {
+			return nil, err
+		}
+		rules := make([]middleware.BanRule, 0, len(active))
+		for _, ban := range active {
+			rule := middleware.BanRule{Kind: ban.Kind, Value: ban.Value, Mode: ban.Mode}
+			if ban.ExpiresAt != nil {
+				if rule.ExpiresAt, err = time.Parse(time.RFC3339, *ban.ExpiresAt); err != nil {
+					return nil, err
+				}
+			}
+			rules = append(rules, rule)
+		}
+		return rules, nil
+	}
+}
+
 func corsMiddleware(allowedOrigins []string, next http.Handler) http.Handler {
 	allowed := make(map[string]bool, len(allowedOrigins))
 	for _, o := range allowedOrigins {
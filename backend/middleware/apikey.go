@@ -1,33 +1,110 @@
 package middleware
 
 import (
+	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"net/http"
+	"slices"
 )
 
+// DefaultAPIKeyIdentity names whoever authenticated with REQUESTS_API_KEY.
+const DefaultAPIKeyIdentity = "default"
+
+// OwnerScope grants every scope. REQUESTS_API_KEY always has it.
+const OwnerScope = "owner"
+
+type apiKeyIdentityKey struct{}
+
+type apiKeyIdentity struct {
+	name   string
+	scopes []string
+}
+
+// AdminKeyAuthenticator looks up a named key and returns its name and
+// scopes.
+type AdminKeyAuthenticator interface {
+	AuthenticateAdminKey(key string) (string, []string, error)
+}
+
+type APIKeyAuthOptions struct {
+	Keys AdminKeyAuthenticator
+}
+
 type APIKeyAuth struct {
 	apiKey string
+	keys   AdminKeyAuthenticator
+}
+
+func NewAPIKeyAuth(apiKey string, options ...APIKeyAuthOptions) *APIKeyAuth {
+	auth := &APIKeyAuth{apiKey: apiKey}
+	if len(options) > 0 {
+		auth.keys = options[0].Keys
+	}
+	return auth
+}
+
+// APIKeyIdentity returns the name of the key a request authenticated with,
+// or an empty string for requests that did not pass through APIKeyAuth.
+func APIKeyIdentity(r *http.Request) string {
+	identity, _ := r.Context().Value(apiKeyIdentityKey{}).(apiKeyIdentity)
+	return identity.name
+}
+
+// HasAPIKeyScope reports whether the request's key has the scope, either
+// directly or through the owner scope.
+func HasAPIKeyScope(r *http.Request, scope string) bool {
+	identity, _ := r.Context().Value(apiKeyIdentityKey{}).(apiKeyIdentity)
+	return slices.Contains(identity.scopes, OwnerScope) || slices.Contains(identity.scopes, scope)
 }
 
-func NewAPIKeyAuth(apiKey string) *APIKeyAuth {
-	return &APIKeyAuth{apiKey: apiKey}
+func (a *APIKeyAuth) authenticate(providedKey string) (apiKeyIdentity, bool) {
+	if providedKey == "" {
+		return apiKeyIdentity{}, false
+	}
+	if a.apiKey != "" && subtle.ConstantTimeCompare([]byte(providedKey), []byte(a.apiKey)) == 1 {
+		return apiKeyIdentity{name: DefaultAPIKeyIdentity, scopes: []string{OwnerScope}}, true
+	}
+	if a.keys != nil {
+		if name, scopes, err := a.keys.AuthenticateAdminKey(providedKey); err == nil {
+			return apiKeyIdentity{name: name, scopes: scopes}, true
+		}
+	}
+	return apiKeyIdentity{}, false
 }
 
 func (a *APIKeyAuth) Middleware(next http.Handler) http.Handler {
+	return a.MiddlewareWithScopes(nil, next)
+}
+
+// MiddlewareWithScopes authenticates like Middleware and then rejects keys
+// that lack the scope scopeFor returns for the request. An empty scope
+// means any valid key may proceed.
+func (a *APIKeyAuth) MiddlewareWithScopes(scopeFor func(*http.Request) string, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodOptions {
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		providedKey := r.Header.Get("X-API-Key")
-		if a.apiKey == "" || providedKey != a.apiKey {
+		identity, ok := a.authenticate(r.Header.Get("X-API-Key"))
+		if !ok {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusUnauthorized)
 			json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
 			return
 		}
 
+		r = r.WithContext(context.WithValue(r.Context(), apiKeyIdentityKey{}, identity))
+		if scopeFor != nil {
+			if scope := scopeFor(r); scope != "" && !HasAPIKeyScope(r, scope) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusForbidden)
+				json.NewEncoder(w).Encode(map[string]string{"error": "Forbidden"})
+				return
+			}
+		}
+
 		next.ServeHTTP(w, r)
 	})
 }
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type stubAdminKeys map[string][]string
+
+func (s stubAdminKeys) AuthenticateAdminKey(key string) (string, []string, error) {
+	scopes, ok := s[key]
+	if !ok {
+		return "", nil, errors.New("invalid key")
+	}
+	return "key-" + key, scopes, nil
+}
+
+func TestAPIKeyAuthEnforcesScopes(t *testing.T) {
+	auth := NewAPIKeyAuth("legacy", APIKeyAuthOptions{Keys: stubAdminKeys{
+		"triage": {"requests"},
+		"owner":  {OwnerScope},
+	}})
+	var identity string
+	handler := auth.MiddlewareWithScopes(
+		func(r *http.Request) string { return r.URL.Query().Get("scope") },
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity = APIKeyIdentity(r)
+			w.WriteHeader(http.StatusNoContent)
+		}),
+	)
+
+	tests := []struct {
+		key, scope string
+		status     int
+		identity   string
+	}{
+		{key: "legacy", scope: "audio", status: http.StatusNoContent, identity: DefaultAPIKeyIdentity},
+		{key: "triage", scope: "requests", status: http.StatusNoContent, identity: "key-triage"},
+		{key: "triage", scope: "audio", status: http.StatusForbidden},
+		{key: "triage", scope: OwnerScope, status: http.StatusForbidden},
+		{key: "owner", scope: "messages", status: http.StatusNoContent, identity: "key-owner"},
+		{key: "unknown", scope: "requests", status: http.StatusUnauthorized},
+		{key: "", scope: "", status: http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		identity = ""
+		request := httptest.NewRequest(http.MethodGet, "/api/admin/x?scope="+test.scope, nil)
+		request.Header.Set("X-API-Key", test.key)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		if recorder.Code != test.status || identity != test.identity {
+			t.Errorf("key %q scope %q: status = %d, identity = %q", test.key, test.scope, recorder.Code, identity)
+		}
+	}
+}
+
+func TestAPIKeyAuthWithoutLegacyKeyAcceptsNamedKeys(t *testing.T) {
+	auth := NewAPIKeyAuth("", APIKeyAuthOptions{Keys: stubAdminKeys{"jobs": {"jobs"}}})
+	handler := auth.Middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	for key, status := range map[string]int{"jobs": http.StatusNoContent, "": http.StatusUnauthorized} {
+		request := httptest.NewRequest(http.MethodGet, "/api/admin/jobs", nil)
+		request.Header.Set("X-API-Key", key)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		if recorder.Code != status {
+			t.Errorf("key %q: status = %d, want %d", key, recorder.Code, status)
+		}
+	}
+}
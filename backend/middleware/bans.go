@@ -0,0 +1,153 @@
+package middleware
+
+import (
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BanRule is one active ban as the middleware enforces it. Kind is "ip",
+// "cidr" or "session"; Mode is "downloads" or "all". A zero ExpiresAt never
+// expires.
+type BanRule struct {
+	Kind      string
+	Value     string
+	Mode      string
+	ExpiresAt time.Time
+}
+
+type BanListOptions struct {
+	// SessionID returns the verified session of a request, if it has one.
+	// Without it, session bans are not enforced.
+	SessionID func(*http.Request) (string, bool)
+}
+
+type cidrBan struct {
+	network *net.IPNet
+	rule    BanRule
+}
+
+// BanList blocks banned clients before any other middleware runs. Bans are
+// loaded into memory and refreshed periodically, so enforcing them costs no
+// database query per request.
+type BanList struct {
+	load      func() ([]BanRule, error)
+	sessionID func(*http.Request) (string, bool)
+	now       func() time.Time
+
+	mu       sync.RWMutex
+	ips      map[string][]BanRule
+	sessions map[string][]BanRule
+	cidrs    []cidrBan
+}
+
+func NewBanList(load func() ([]BanRule, error), options ...BanListOptions) *BanList {
+	b := &BanList{load: load, now: time.Now}
+	if len(options) > 0 {
+		b.sessionID = options[0].SessionID
+	}
+	return b
+}
+
+// Refresh reloads the bans. On failure the previous list stays in force.
+func (b *BanList) Refresh() error {
+	rules, err := b.load()
+	if err != nil {
+		return err
+	}
+
+	ips := map[string][]BanRule{}
+	sessions := map[string][]BanRule{}
+	cidrs := []cidrBan{}
+	for _, rule := range rules {
+		switch rule.Kind {
+		case "ip":
+			if ip := net.ParseIP(rule.Value); ip != nil {
+				ips[ip.String()] = append(ips[ip.String()], rule)
+			}
+		case "cidr":
+			if _, network, err := net.ParseCIDR(rule.Value); err == nil {
+				cidrs = append(cidrs, cidrBan{network: network, rule: rule})
+			}
+		case "session":
+			sessions[rule.Value] = append(sessions[rule.Value], rule)
+		}
+	}
+
+	b.mu.Lock()
+	b.ips, b.sessions, b.cidrs = ips, sessions, cidrs
+	b.mu.Unlock()
+	return nil
+}
+
+// StartRefresh loads the bans now and then at every interval.
+func (b *BanList) StartRefresh(interval time.Duration) {
+	if err := b.Refresh(); err != nil {
+		log.Printf("Ban list refresh failed: %v", err)
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := b.Refresh(); err != nil {
+				log.Printf("Ban list refresh failed: %v", err)
+			}
+		}
+	}()
+}
+
+// banned returns the strictest unexpired ban matching the request, if any.
+func (b *BanList) banned(r *http.Request) (BanRule, bool) {
+	var matches []BanRule
+	b.mu.RLock()
+	if ip := net.ParseIP(strings.Trim(clientIP(r), "[]")); ip != nil {
+		matches = append(matches, b.ips[ip.String()]...)
+		for _, ban := range b.cidrs {
+			if ban.network.Contains(ip) {
+				matches = append(matches, ban.rule)
+			}
+		}
+	}
+	if len(b.sessions) > 0 && b.sessionID != nil {
+		if sessionID, ok := b.sessionID(r); ok {
+			matches = append(matches, b.sessions[sessionID]...)
+		}
+	}
+	b.mu.RUnlock()
+
+	now := b.now()
+	var found BanRule
+	ok := false
+	for _, rule := range matches {
+		if !rule.ExpiresAt.IsZero() && !rule.ExpiresAt.After(now) {
+			continue
+		}
+		if !ok || rule.Mode == "all" {
+			found, ok = rule, true
+		}
+	}
+	return found, ok
+}
+
+func (b *BanList) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/api/admin/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		rule, ok := b.banned(r)
+		if !ok || (rule.Mode != "all" && !isProtectedAudioRequest(r.URL.Path)) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Forbidden"})
+	})
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func serveBanned(list *BanList, path, remoteAddr, session string) int {
+	handler := list.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	request := httptest.NewRequest(http.MethodGet, path, nil)
+	request.RemoteAddr = remoteAddr
+	if session != "" {
+		request.Header.Set("X-Test-Session", session)
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder.Code
+}
+
+func TestBanListEnforcesKindsAndModes(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	list := NewBanList(func() ([]BanRule, error) {
+		return []BanRule{
+			{Kind: "ip", Value: "192.0.2.1", Mode: "all"},
+			{Kind: "cidr", Value: "198.51.100.0/24", Mode: "downloads"},
+			{Kind: "session", Value: "scraper", Mode: "all"},
+			{Kind: "ip", Value: "203.0.113.9", Mode: "all", ExpiresAt: now.Add(-time.Minute)},
+		}, nil
+	}, BanListOptions{SessionID: func(r *http.Request) (string, bool) {
+		session := r.Header.Get("X-Test-Session")
+		return session, session != ""
+	}})
+	list.now = func() time.Time { return now }
+	if err := list.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name, path, remoteAddr, session string
+		want                            int
+	}{
+		{"banned IP browsing", "/api/browse", "192.0.2.1:1234", "", http.StatusForbidden},
+		{"banned IP admin", "/api/admin/bans", "192.0.2.1:1234", "", http.StatusNoContent},
+		{"downloads ban browsing", "/api/browse", "198.51.100.7:1234", "", http.StatusNoContent},
+		{"downloads ban streaming", "/api/audio/key/track", "198.51.100.7:1234", "", http.StatusForbidden},
+		{"downloads ban Subsonic", "/rest/download.view", "198.51.100.7:1234", "", http.StatusForbidden},
+		{"banned session", "/", "192.0.2.50:1234", "scraper", http.StatusForbidden},
+		{"expired ban", "/api/audio/key/track", "203.0.113.9:1234", "", http.StatusNoContent},
+		{"unbanned", "/api/audio/key/track", "192.0.2.2:1234", "visitor", http.StatusNoContent},
+	}
+	for _, test := range tests {
+		if got := serveBanned(list, test.path, test.remoteAddr, test.session); got != test.want {
+			t.Errorf("%s: status = %d, want %d", test.name, got, test.want)
+		}
+	}
+}
+
+func TestBanListKeepsPreviousBansWhenRefreshFails(t *testing.T) {
+	fail := false
+	list := NewBanList(func() ([]BanRule, error) {
+		if fail {
+			return nil, errors.New("database unavailable")
+		}
+		return []BanRule{{Kind: "ip", Value: "2001:db8::1", Mode: "all"}}, nil
+	})
+	if err := list.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	fail = true
+	if err := list.Refresh(); err == nil {
+		t.Fatal("Refresh succeeded with a failing loader")
+	}
+	if got := serveBanned(list, "/", "[2001:DB8::1]:443", ""); got != http.StatusForbidden {
+		t.Fatalf("status = %d, want ban kept after failed refresh", got)
+	}
+}
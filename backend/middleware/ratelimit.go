@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"encoding/json"
+	"log"
 	"math/rand"
 	"net/http"
 	"strconv"
@@ -12,80 +13,102 @@ import (
 	"github.com/onion/audio-share-backend/config"
 )
 
-type rateLimitData struct {
-	apiCount           int
-	imageCount         int
-	accessFailureCount int
-	shareCount         int
-	shareTimestamp     int64
-	contactCount       int
-	contactTimestamp   int64
-	timestamp          int64
-	imageTimestamp     int64
+// RateLimitBackend counts requests in fixed windows. A window starts with
+// the first request after the previous one ended, and Add returns the count
+// and start of the window now falls in. A delta of zero only reads it.
+type RateLimitBackend interface {
+	Add(key string, delta int, window time.Duration, now time.Time) (count int, windowStart time.Time, err error)
+}
+
+// MemoryRateLimitBackend keeps request counters local to one instance.
+type MemoryRateLimitBackend struct {
+	mu      sync.Mutex
+	windows map[string]*rateLimitWindow
+}
+
+type rateLimitWindow struct {
+	count  int
+	start  time.Time
+	length time.Duration
+}
+
+func NewMemoryRateLimitBackend() *MemoryRateLimitBackend {
+	return &MemoryRateLimitBackend{windows: make(map[string]*rateLimitWindow)}
+}
+
+func (b *MemoryRateLimitBackend) Add(key string, delta int, window time.Duration, now time.Time) (int, time.Time, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	current, exists := b.windows[key]
+	if !exists || now.Sub(current.start) > window {
+		current = &rateLimitWindow{start: now}
+		b.windows[key] = current
+	}
+	current.count += delta
+	current.length = window
+
+	if rand.Float64() < 0.01 {
+		for key, val := range b.windows {
+			if now.Sub(val.start) > val.length {
+				delete(b.windows, key)
+			}
+		}
+	}
+	return current.count, current.start, nil
 }
 
 type RateLimiter struct {
-	mu     sync.RWMutex
-	limits map[string]*rateLimitData
-	cfg    *config.Config
+	backend RateLimitBackend
+	cfg     *config.Config
 }
 
 func NewRateLimiter(cfg *config.Config) *RateLimiter {
 	return &RateLimiter{
-		limits: make(map[string]*rateLimitData),
-		cfg:    cfg,
+		backend: NewMemoryRateLimitBackend(),
+		cfg:     cfg,
 	}
 }
 
-func (rl *RateLimiter) AllowAccessAttempt(clientIP string) (bool, int) {
-	now := time.Now().UnixMilli()
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
+// SetBackend replaces the local request counters, for example with a
+// backend shared by every instance.
+func (rl *RateLimiter) SetBackend(backend RateLimitBackend) {
+	rl.backend = backend
+}
 
-	data := rl.dataLocked(clientIP, now)
-	rl.resetGeneralWindowLocked(data, now)
-	if data.accessFailureCount < rl.cfg.MaxRequestsPerWindow {
+func (rl *RateLimiter) AllowAccessAttempt(clientIP string) (bool, int) {
+	now := time.Now()
+	window := windowDuration(rl.cfg.RateLimitWindow)
+	failures, start, err := rl.backend.Add("access:"+clientIP, 0, window, now)
+	if err != nil {
+		log.Printf("Error reading access failure count: %v", err)
+		return true, 0
+	}
+	if failures < rl.cfg.MaxRequestsPerWindow {
 		return true, 0
 	}
 
-	remainingMillis := int64(rl.cfg.RateLimitWindow) - (now - data.timestamp)
+	remainingMillis := (window - now.Sub(start)).Milliseconds()
 	retryAfter := max(1, int((remainingMillis+999)/1000))
 	return false, retryAfter
 }
 
 func (rl *RateLimiter) RecordAccessFailure(clientIP string) {
-	now := time.Now().UnixMilli()
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
-
-	data := rl.dataLocked(clientIP, now)
-	rl.resetGeneralWindowLocked(data, now)
-	data.accessFailureCount++
-}
-
-func (rl *RateLimiter) dataLocked(ip string, now int64) *rateLimitData {
-	data, exists := rl.limits[ip]
-	if !exists {
-		data = &rateLimitData{
-			shareTimestamp:   now,
-			contactTimestamp: now,
-			timestamp:        now,
-			imageTimestamp:   now,
-		}
-		rl.limits[ip] = data
+	window := windowDuration(rl.cfg.RateLimitWindow)
+	if _, _, err := rl.backend.Add("access:"+clientIP, 1, window, time.Now()); err != nil {
+		log.Printf("Error recording access failure: %v", err)
 	}
-	return data
 }
 
-func (rl *RateLimiter) resetGeneralWindowLocked(data *rateLimitData, now int64) {
-	if now-data.timestamp > int64(rl.cfg.RateLimitWindow) {
-		data.apiCount = 0
-		data.accessFailureCount = 0
-		data.timestamp = now
-	}
+func windowDuration(millis int) time.Duration {
+	return time.Duration(millis) * time.Millisecond
 }
 
 func (rl *RateLimiter) getClientIP(r *http.Request) string {
+	return clientIP(r)
+}
+
+func clientIP(r *http.Request) string {
 	if ip := r.Header.Get("CF-Connecting-IP"); ip != "" {
 		return ip
 	}
@@ -106,26 +129,43 @@ func (rl *RateLimiter) getClientIP(r *http.Request) string {
 }
 
 func (rl *RateLimiter) isProtectedAudioRequest(path string) bool {
+	return isProtectedAudioRequest(path)
+}
+
+// isProtectedAudioRequest reports whether a path serves an audio file, as a
+// stream or a download.
+func isProtectedAudioRequest(path string) bool {
 	path = strings.TrimRight(path, "/")
+	switch strings.TrimSuffix(path, ".view") {
+	case "/rest/stream", "/rest/download":
+		return true
+	}
 	if !strings.HasPrefix(path, "/api/audio/key/") {
 		return false
 	}
 	return strings.HasSuffix(path, "/download") ||
-		(!strings.HasSuffix(path, "/thumbnail") &&
+		(!isImageRequest(path) &&
 			!strings.HasSuffix(path, "/access") &&
 			!strings.HasSuffix(path, "/meta") &&
+			!strings.HasSuffix(path, "/transcript") &&
 			!strings.HasSuffix(path, "/waveform"))
 }
 
 func (rl *RateLimiter) isImageRequest(path string) bool {
+	return isImageRequest(path)
+}
+
+func isImageRequest(path string) bool {
 	path = strings.ToLower(strings.TrimRight(path, "/"))
-	return strings.HasSuffix(path, "/poster") || strings.HasSuffix(path, "/thumbnail")
+	return strings.HasSuffix(path, "/poster") || strings.HasSuffix(path, "/thumbnail") ||
+		strings.HasSuffix(path, "/og.png") || strings.HasSuffix(path, "/waveform.svg") ||
+		strings.HasSuffix(path, "/waveform.png") ||
+		strings.TrimSuffix(path, ".view") == "/rest/getcoverart"
 }
 
 func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ip := rl.getClientIP(r)
-		now := time.Now().UnixMilli()
 		path := r.URL.Path
 
 		if strings.HasPrefix(path, "/api/") {
@@ -140,71 +180,40 @@ func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
 		isImage := rl.isImageRequest(path)
 		isShare := path == "/api/share" && r.Method == "POST"
 		isContact := path == "/api/contact" && r.Method == "POST"
+		isRemoval := path == "/api/removal-requests" && r.Method == "POST"
 
-		rl.mu.Lock()
-		data := rl.dataLocked(ip, now)
-		rl.resetGeneralWindowLocked(data, now)
-		if now-data.shareTimestamp > int64(rl.cfg.ShareLimitWindow) {
-			data.shareCount = 0
-			data.shareTimestamp = now
-		}
-		if now-data.contactTimestamp > int64(rl.cfg.ContactLimitWindow) {
-			data.contactCount = 0
-			data.contactTimestamp = now
-		}
-		if now-data.imageTimestamp > int64(rl.cfg.ImageRateLimitWindow) {
-			data.imageCount = 0
-			data.imageTimestamp = now
-		}
-
-		if isShare {
-			data.shareCount++
-		} else if isContact {
-			data.contactCount++
-		} else if isImage {
-			data.imageCount++
-		} else {
-			data.apiCount++
-		}
-
-		if rand.Float64() < 0.01 {
-			for key, val := range rl.limits {
-				if now-val.timestamp > int64(rl.cfg.RateLimitWindow) &&
-					now-val.imageTimestamp > int64(rl.cfg.ImageRateLimitWindow) &&
-					now-val.shareTimestamp > int64(rl.cfg.ShareLimitWindow) &&
-					now-val.contactTimestamp > int64(rl.cfg.ContactLimitWindow) {
-					delete(rl.limits, key)
-				}
-			}
-		}
-
-		var limit, current int
+		var limit int
 		var limitWindow int
 		var limitType string
 
 		if isShare {
 			limit = rl.cfg.ShareRequestLimit
-			current = data.shareCount
 			limitWindow = rl.cfg.ShareLimitWindow
 			limitType = "share"
 		} else if isContact {
 			limit = rl.cfg.ContactRequestLimit
-			current = data.contactCount
 			limitWindow = rl.cfg.ContactLimitWindow
 			limitType = "contact"
+		} else if isRemoval {
+			limit = rl.cfg.RemovalRequestLimit
+			limitWindow = rl.cfg.RemovalLimitWindow
+			limitType = "removal"
 		} else if isImage {
 			limit = rl.cfg.MaxImagesPerWindow
-			current = data.imageCount
 			limitWindow = rl.cfg.ImageRateLimitWindow
 			limitType = "image"
 		} else {
 			limit = rl.cfg.MaxRequestsPerWindow
-			current = data.apiCount
 			limitWindow = rl.cfg.RateLimitWindow
 			limitType = "api"
 		}
 
-		rl.mu.Unlock()
+		current, _, err := rl.backend.Add(limitType+":"+ip, 1, windowDuration(limitWindow), time.Now())
+		if err != nil {
+			log.Printf("Error counting %s request: %v", limitType, err)
+			next.ServeHTTP(w, r)
+			return
+		}
 
 		if current > limit {
 			w.Header().Set("Content-Type", "application/json")
@@ -216,6 +225,8 @@ func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
 				message = "You've reached the limit of " + strconv.Itoa(limit) + " artist requests per day. Please try again tomorrow."
 			} else if limitType == "contact" {
 				message = "You've reached the limit of " + strconv.Itoa(limit) + " contact submissions per day. Please try again tomorrow."
+			} else if limitType == "removal" {
+				message = "You've reached the limit of " + strconv.Itoa(limit) + " removal requests per day. Please try again tomorrow."
 			} else {
 				message = "Too many requests"
 			}
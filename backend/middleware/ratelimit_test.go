@@ -12,13 +12,20 @@ import (
 func TestProtectedAudioRequestClassification(t *testing.T) {
 	limiter := NewRateLimiter(&config.Config{})
 	tests := map[string]bool{
-		"/api/audio/key/track":           true,
-		"/api/audio/key/track/download":  true,
-		"/api/audio/key/track/access":    false,
-		"/api/audio/key/track/access/":   false,
-		"/api/audio/key/track/meta":      false,
-		"/api/audio/key/track/waveform":  false,
-		"/api/audio/key/track/thumbnail": false,
+		"/api/audio/key/track":              true,
+		"/api/audio/key/track/download":     true,
+		"/api/audio/key/track/access":       false,
+		"/api/audio/key/track/access/":      false,
+		"/api/audio/key/track/meta":         false,
+		"/api/audio/key/track/transcript":   false,
+		"/api/audio/key/track/waveform":     false,
+		"/api/audio/key/track/thumbnail":    false,
+		"/api/audio/key/track/og.png":       false,
+		"/api/audio/key/track/waveform.svg": false,
+		"/api/audio/key/track/waveform.png": false,
+		"/rest/stream.view":                 true,
+		"/rest/download":                    true,
+		"/rest/getMusicDirectory.view":      false,
 	}
 	for path, want := range tests {
 		if got := limiter.isProtectedAudioRequest(path); got != want {
@@ -64,6 +71,37 @@ func TestImageRequestsUseSeparateLimit(t *testing.T) {
 	}
 }
 
+func TestRemovalRequestsUseTheirOwnDailyLimit(t *testing.T) {
+	limiter := NewRateLimiter(&config.Config{
+		MaxRequestsPerWindow: 10,
+		RateLimitWindow:      int(time.Minute / time.Millisecond),
+		RemovalRequestLimit:  1,
+		RemovalLimitWindow:   int(24 * time.Hour / time.Millisecond),
+	})
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	request := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	if status := request(http.MethodPost, "/api/removal-requests").Code; status != http.StatusNoContent {
+		t.Fatalf("first removal request status = %d, want %d", status, http.StatusNoContent)
+	}
+	second := request(http.MethodPost, "/api/removal-requests")
+	if second.Code != http.StatusTooManyRequests || second.Header().Get("Retry-After") != "86400" {
+		t.Fatalf("second removal request status = %d, Retry-After = %q", second.Code, second.Header().Get("Retry-After"))
+	}
+	if status := request(http.MethodGet, "/api/stats").Code; status != http.StatusNoContent {
+		t.Fatalf("API status after removal limit = %d, want %d", status, http.StatusNoContent)
+	}
+}
+
 func TestAccessFailuresUseRequestLimitWithoutCountingAllowedAttempts(t *testing.T) {
 	limiter := NewRateLimiter(&config.Config{
 		MaxRequestsPerWindow: 2,
@@ -93,3 +131,48 @@ func TestAccessFailuresUseRequestLimitWithoutCountingAllowedAttempts(t *testing.
 		t.Fatalf("retryAfter = %d, want a positive value", retryAfter)
 	}
 }
+
+func TestRateLimitersSharingABackendEnforceOneBudget(t *testing.T) {
+	cfg := &config.Config{
+		MaxRequestsPerWindow: 2,
+		RateLimitWindow:      int(time.Minute / time.Millisecond),
+		ShareRequestLimit:    2,
+		ShareLimitWindow:     int(24 * time.Hour / time.Millisecond),
+	}
+	backend := NewMemoryRateLimitBackend()
+	instances := []*RateLimiter{NewRateLimiter(cfg), NewRateLimiter(cfg)}
+	var handlers []http.Handler
+	for _, limiter := range instances {
+		limiter.SetBackend(backend)
+		handlers = append(handlers, limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})))
+	}
+
+	share := func(handler http.Handler) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/share", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+	if status := share(handlers[0]); status != http.StatusNoContent {
+		t.Fatalf("first share status = %d", status)
+	}
+	if status := share(handlers[1]); status != http.StatusNoContent {
+		t.Fatalf("second share status = %d", status)
+	}
+	for i, handler := range handlers {
+		if status := share(handler); status != http.StatusTooManyRequests {
+			t.Fatalf("instance %d allowed a share beyond the combined budget: %d", i, status)
+		}
+	}
+
+	instances[0].RecordAccessFailure("192.0.2.1")
+	instances[1].RecordAccessFailure("192.0.2.1")
+	for i, limiter := range instances {
+		if allowed, _ := limiter.AllowAccessAttempt("192.0.2.1"); allowed {
+			t.Fatalf("instance %d allowed an attempt after the combined failure budget was spent", i)
+		}
+	}
+}
@@ -3,18 +3,30 @@ package middleware
 import (
 	"net/http"
 	"net/url"
+	"strings"
 )
 
 type SecurityHeaders struct {
-	rybbitDomain string
-	capDomain    string
+	rybbitDomain        string
+	capDomain           string
+	embedFrameAncestors string
 }
 
-func NewSecurityHeaders(rybbitURL, capURL string) *SecurityHeaders {
-	return &SecurityHeaders{
+type SecurityHeadersOptions struct {
+	// EmbedFrameAncestors is the CSP frame-ancestors source list for the
+	// /embed/ player pages. Empty keeps them unframeable like every other page.
+	EmbedFrameAncestors string
+}
+
+func NewSecurityHeaders(rybbitURL, capURL string, options ...SecurityHeadersOptions) *SecurityHeaders {
+	s := &SecurityHeaders{
 		rybbitDomain: origin(rybbitURL),
 		capDomain:    origin(capURL),
 	}
+	if len(options) > 0 {
+		s.embedFrameAncestors = strings.Join(strings.Fields(options[0].EmbedFrameAncestors), " ")
+	}
+	return s
 }
 
 func origin(rawURL string) string {
@@ -41,6 +53,12 @@ func (s *SecurityHeaders) Middleware(next http.Handler) http.Handler {
 			connectSrc += " " + s.capDomain
 		}
 
+		frameAncestors := "'none'"
+		embeddable := s.embedFrameAncestors != "" && strings.HasPrefix(r.URL.Path, "/embed/")
+		if embeddable {
+			frameAncestors = s.embedFrameAncestors
+		}
+
 		csp := "default-src 'self'; " +
 			"script-src " + scriptSrc + "; " +
 			"style-src 'self' 'unsafe-inline' https://fonts.googleapis.com; " +
@@ -52,12 +70,14 @@ func (s *SecurityHeaders) Middleware(next http.Handler) http.Handler {
 			"object-src 'none'; " +
 			"base-uri 'self'; " +
 			"form-action 'self'; " +
-			"frame-ancestors 'none'; " +
+			"frame-ancestors " + frameAncestors + "; " +
 			"block-all-mixed-content;"
 
 		w.Header().Set("Content-Security-Policy", csp)
 		w.Header().Set("X-Content-Type-Options", "nosniff")
-		w.Header().Set("X-Frame-Options", "DENY")
+		if !embeddable {
+			w.Header().Set("X-Frame-Options", "DENY")
+		}
 		w.Header().Set("Referrer-Policy", "strict-origin-when-cross-origin")
 		w.Header().Set("Permissions-Policy", "camera=(), microphone=(), geolocation=()")
 
@@ -28,3 +28,30 @@ func TestSecurityHeadersAllowConfiguredCapOriginAndBlobWorkers(t *testing.T) {
 		}
 	}
 }
+
+func TestSecurityHeadersOnlyAllowFramingEmbedPages(t *testing.T) {
+	handler := NewSecurityHeaders("", "", SecurityHeadersOptions{
+		EmbedFrameAncestors: "https://blog.example.test  https://forum.example.test",
+	}).Middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "https://audio.example.test/embed/abc", nil))
+	csp := recorder.Header().Get("Content-Security-Policy")
+	if !strings.Contains(csp, "frame-ancestors https://blog.example.test https://forum.example.test;") {
+		t.Fatalf("embed CSP = %q", csp)
+	}
+	if got := recorder.Header().Get("X-Frame-Options"); got != "" {
+		t.Fatalf("embed X-Frame-Options = %q, want none", got)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "https://audio.example.test/share/abc", nil))
+	if csp := recorder.Header().Get("Content-Security-Policy"); !strings.Contains(csp, "frame-ancestors 'none';") {
+		t.Fatalf("share CSP = %q", csp)
+	}
+	if got := recorder.Header().Get("X-Frame-Options"); got != "DENY" {
+		t.Fatalf("share X-Frame-Options = %q, want DENY", got)
+	}
+}
@@ -8,9 +8,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 )
 
@@ -19,6 +19,11 @@ type MediaPurpose string
 const (
 	MediaPurposeStream   MediaPurpose = "stream"
 	MediaPurposeDownload MediaPurpose = "download"
+	MediaPurposeFeed     MediaPurpose = "feed"
+	MediaPurposePlaylist MediaPurpose = "playlist"
+	// MediaPurposePlaylistExport counts playlist exports. It never signs
+	// keys itself; it only caps how often a session or IP exports.
+	MediaPurposePlaylistExport MediaPurpose = "playlist_export"
 )
 
 var (
@@ -82,16 +87,7 @@ type AccessKeyManager struct {
 	captchaPolicies map[MediaPurpose][]KeyLimit
 	ttls            map[MediaPurpose]time.Duration
 	now             func() time.Time
-
-	mu                    sync.Mutex
-	issuances             map[accessIssuanceKey][]time.Time
-	issuancesSinceCleanup int
-}
-
-type accessIssuanceKey struct {
-	scope    KeyLimitScope
-	identity string
-	purpose  MediaPurpose
+	store           IssuanceStore
 }
 
 func ParseKeyPolicy(raw string) ([]KeyLimit, error) {
@@ -154,10 +150,18 @@ func NewAccessKeyManager(
 		},
 		captchaPolicies: make(map[MediaPurpose][]KeyLimit),
 		now:             time.Now,
-		issuances:       make(map[accessIssuanceKey][]time.Time),
+		store:           NewMemoryIssuanceStore(),
 	}, nil
 }
 
+// SetIssuanceStore replaces the in-memory issuance history, for example
+// with a store shared by every instance. Like the request rate limits, the
+// issuance limits fail open: if the store cannot be read or written, keys
+// are issued unchecked and the error is logged.
+func (m *AccessKeyManager) SetIssuanceStore(store IssuanceStore) {
+	m.store = store
+}
+
 func (m *AccessKeyManager) SetCaptchaPolicy(purpose MediaPurpose, raw string) error {
 	if strings.TrimSpace(raw) == "" {
 		delete(m.captchaPolicies, purpose)
@@ -171,6 +175,173 @@ func (m *AccessKeyManager) SetCaptchaPolicy(purpose MediaPurpose, raw string) er
 	return nil
 }
 
+// SetFeedPolicy enables podcast feed keys with the given lifetime and
+// enclosure fetch limits.
+func (m *AccessKeyManager) SetFeedPolicy(raw string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return errors.New("feed key TTL must be positive")
+	}
+	limits, err := ParseKeyPolicy(raw)
+	if err != nil {
+		return err
+	}
+	m.policies[MediaPurposeFeed] = limits
+	m.ttls[MediaPurposeFeed] = ttl
+	return nil
+}
+
+func feedBinding(folderKey string) string {
+	return "feed\x00" + folderKey
+}
+
+// IssueFeedKey returns the enclosure token for a folder's podcast feed.
+// Tokens are fixed for half a TTL so refetching the feed keeps enclosure
+// URLs stable, and each one stays valid for at least half a TTL after it
+// is served. Issuing does not count against any limit; enclosure fetches
+// do, through RecordFeedAccess.
+func (m *AccessKeyManager) IssueFeedKey(folderKey string) (IssuedAccessKey, error) {
+	ttl, ok := m.ttls[MediaPurposeFeed]
+	if !ok || folderKey == "" {
+		return IssuedAccessKey{}, ErrInvalidAccessKey
+	}
+	period := max(ttl/2, time.Millisecond)
+	issuedAt := m.now().Truncate(period)
+	expiresAt := issuedAt.Add(ttl)
+
+	mac := hmac.New(sha256.New, m.secret)
+	mac.Write([]byte("audio-feed-nonce\x00"))
+	mac.Write([]byte(folderKey))
+	mac.Write([]byte(strconv.FormatInt(issuedAt.UnixMilli(), 10)))
+	claims := accessKeyClaims{
+		Version:        1,
+		AudioKey:       folderKey,
+		SessionBinding: m.sessionBinding(feedBinding(folderKey)),
+		Purpose:        MediaPurposeFeed,
+		IssuedAt:       issuedAt.UnixMilli(),
+		ExpiresAt:      expiresAt.UnixMilli(),
+		Nonce:          base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16]),
+	}
+	token, err := m.signClaims(claims)
+	if err != nil {
+		return IssuedAccessKey{}, err
+	}
+	return IssuedAccessKey{AccessKey: token, ExpiresAt: expiresAt}, nil
+}
+
+func (m *AccessKeyManager) VerifyFeedKey(token, folderKey string) (VerifiedAccessKey, error) {
+	return m.VerifyAndExtract(token, feedBinding(folderKey), folderKey, MediaPurposeFeed)
+}
+
+// RecordFeedAccess counts one enclosure fetch against the feed limits,
+// both per subscriber of a feed and per IP across all feeds.
+func (m *AccessKeyManager) RecordFeedAccess(folderKey, clientIP string) error {
+	if _, ok := m.policies[MediaPurposeFeed]; !ok || folderKey == "" || clientIP == "" {
+		return ErrInvalidAccessKey
+	}
+	return m.recordIssuance(feedBinding(folderKey)+"\x00"+clientIP, clientIP, MediaPurposeFeed, m.now(), true)
+}
+
+// SetPlaylistPolicy enables playlist export keys with the given lifetime
+// and per-entry fetch limits.
+func (m *AccessKeyManager) SetPlaylistPolicy(raw string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return errors.New("playlist key TTL must be positive")
+	}
+	limits, err := ParseKeyPolicy(raw)
+	if err != nil {
+		return err
+	}
+	m.policies[MediaPurposePlaylist] = limits
+	m.ttls[MediaPurposePlaylist] = ttl
+	return nil
+}
+
+func playlistBinding(playlistID string) string {
+	return "playlist\x00" + playlistID
+}
+
+// IssuePlaylistKey signs the stream URL for one entry of an exported
+// playlist. Entries are bound to the playlist ID rather than a browser
+// session so external players can fetch them. Issuing does not count
+// against any limit; fetches do, through RecordPlaylistAccess.
+func (m *AccessKeyManager) IssuePlaylistKey(playlistID, audioKey string) (IssuedAccessKey, error) {
+	ttl, ok := m.ttls[MediaPurposePlaylist]
+	if !ok || playlistID == "" || audioKey == "" {
+		return IssuedAccessKey{}, ErrInvalidAccessKey
+	}
+	now := m.now()
+	nonceBytes := make([]byte, 16)
+	if _, err := rand.Read(nonceBytes); err != nil {
+		return IssuedAccessKey{}, fmt.Errorf("generate access key nonce: %w", err)
+	}
+	expiresAt := now.Add(ttl)
+	token, err := m.signClaims(accessKeyClaims{
+		Version:        1,
+		AudioKey:       audioKey,
+		SessionBinding: m.sessionBinding(playlistBinding(playlistID)),
+		Purpose:        MediaPurposePlaylist,
+		IssuedAt:       now.UnixMilli(),
+		ExpiresAt:      expiresAt.UnixMilli(),
+		Nonce:          base64.RawURLEncoding.EncodeToString(nonceBytes),
+	})
+	if err != nil {
+		return IssuedAccessKey{}, err
+	}
+	return IssuedAccessKey{AccessKey: token, ExpiresAt: expiresAt}, nil
+}
+
+func (m *AccessKeyManager) VerifyPlaylistKey(token, playlistID, audioKey string) (VerifiedAccessKey, error) {
+	if playlistID == "" {
+		return VerifiedAccessKey{}, ErrInvalidAccessKey
+	}
+	return m.VerifyAndExtract(token, playlistBinding(playlistID), audioKey, MediaPurposePlaylist)
+}
+
+// RecordPlaylistAccess counts one playlist entry fetch against the playlist
+// limits, both per exported playlist and per IP across all playlists.
+func (m *AccessKeyManager) RecordPlaylistAccess(playlistID, clientIP string) error {
+	if _, ok := m.policies[MediaPurposePlaylist]; !ok || playlistID == "" || clientIP == "" {
+		return ErrInvalidAccessKey
+	}
+	return m.recordIssuance(playlistBinding(playlistID), clientIP, MediaPurposePlaylist, m.now(), true)
+}
+
+// SetPlaylistExportPolicy caps how many playlists a session or IP may
+// export.
+func (m *AccessKeyManager) SetPlaylistExportPolicy(raw string) error {
+	limits, err := ParseKeyPolicy(raw)
+	if err != nil {
+		return err
+	}
+	m.policies[MediaPurposePlaylistExport] = limits
+	return nil
+}
+
+// RecordPlaylistExport charges one playlist export. An export hands out
+// many stream URLs at once and every export gets a fresh playlist ID, so
+// it also counts as one stream key against the session's and IP's stream
+// limits and captcha threshold. The export cap is checked first so a
+// capped session does not use up its stream allowance.
+func (m *AccessKeyManager) RecordPlaylistExport(sessionID, clientIP string, captchaCleared bool) error {
+	if sessionID == "" || clientIP == "" {
+		return ErrInvalidAccessKey
+	}
+	_, capped := m.policies[MediaPurposePlaylistExport]
+	if capped {
+		if err := m.CheckLimit(sessionID, clientIP, MediaPurposePlaylistExport); err != nil {
+			return err
+		}
+	}
+	now := m.now()
+	if err := m.recordIssuance(sessionID, clientIP, MediaPurposeStream, now, captchaCleared); err != nil {
+		return err
+	}
+	if !capped {
+		return nil
+	}
+	return m.recordIssuance(sessionID, clientIP, MediaPurposePlaylistExport, now, true)
+}
+
 func (m *AccessKeyManager) CheckLimit(
 	sessionID string,
 	clientIP string,
@@ -180,19 +351,16 @@ func (m *AccessKeyManager) CheckLimit(
 		return ErrInvalidAccessKey
 	}
 	now := m.now()
-	limits := m.policies[purpose]
-	keys := []accessIssuanceKey{
-		{scope: KeyLimitScopeSession, identity: sessionID, purpose: purpose},
-		{scope: KeyLimitScopeIP, identity: clientIP, purpose: purpose},
-	}
-
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	_, blocked := m.evaluateLimitsLocked(keys, limits, m.retentionWindow(purpose), now)
-	if blocked == nil {
+	keys := issuanceKeys(sessionID, clientIP, purpose)
+	eventsByKey, err := m.store.Events(keys, m.retentionWindow(purpose), now)
+	if err != nil {
+		log.Printf("Error reading %s key issuance history: %v", purpose, err)
 		return nil
 	}
-	return blocked
+	if blocked := evaluateLimits(keys, eventsByKey, m.policies[purpose], now); blocked != nil {
+		return blocked
+	}
+	return nil
 }
 
 func (m *AccessKeyManager) Issue(
@@ -295,52 +463,81 @@ func (m *AccessKeyManager) recordIssuance(
 	captchaCleared bool,
 ) error {
 	limits := m.policies[purpose]
-	keys := []accessIssuanceKey{
-		{scope: KeyLimitScopeSession, identity: sessionID, purpose: purpose},
-		{scope: KeyLimitScopeIP, identity: clientIP, purpose: purpose},
-	}
-
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	eventsByKey, blocked := m.evaluateLimitsLocked(
-		keys,
-		limits,
-		m.retentionWindow(purpose),
-		now,
-	)
-	if blocked != nil {
-		m.storeEvaluatedIssuances(eventsByKey)
-		return blocked
+	keys := issuanceKeys(sessionID, clientIP, purpose)
+	var decision error
+	err := m.store.Record(keys, m.retentionWindow(purpose), now, func(eventsByKey map[IssuanceKey][]time.Time) error {
+		if blocked := evaluateLimits(keys, eventsByKey, limits, now); blocked != nil {
+			decision = blocked
+		} else if !captchaCleared && captchaRequired(eventsByKey, m.captchaPolicies[purpose], now) {
+			decision = ErrCaptchaRequired
+		}
+		return decision
+	})
+	if decision != nil {
+		return decision
 	}
-	if !captchaCleared && captchaRequired(eventsByKey, m.captchaPolicies[purpose], now) {
-		m.storeEvaluatedIssuances(eventsByKey)
-		return ErrCaptchaRequired
+	if err != nil {
+		log.Printf("Error recording %s key issuance: %v", purpose, err)
 	}
+	return nil
+}
 
-	for key, events := range eventsByKey {
-		m.issuances[key] = append(events, now)
+func issuanceKeys(sessionID, clientIP string, purpose MediaPurpose) []IssuanceKey {
+	return []IssuanceKey{
+		{Scope: KeyLimitScopeSession, Identity: sessionID, Purpose: purpose},
+		{Scope: KeyLimitScopeIP, Identity: clientIP, Purpose: purpose},
 	}
-	m.issuancesSinceCleanup++
-	if m.issuancesSinceCleanup >= 256 {
-		m.cleanupLocked(now)
-		m.issuancesSinceCleanup = 0
-	}
-	return nil
 }
 
-func (m *AccessKeyManager) storeEvaluatedIssuances(eventsByKey map[accessIssuanceKey][]time.Time) {
-	for key, events := range eventsByKey {
+// KeyIssuanceUsage is how many keys of one purpose an identity was issued
+// within each of the purpose's limit windows.
+type KeyIssuanceUsage struct {
+	Purpose      MediaPurpose     `json:"purpose"`
+	LastIssuedAt time.Time        `json:"lastIssuedAt"`
+	Windows      []KeyWindowUsage `json:"windows"`
+}
+
+type KeyWindowUsage struct {
+	Window string `json:"window"`
+	Issued int    `json:"issued"`
+	Limit  int    `json:"limit"`
+}
+
+// IssuanceUsage reports the recent key issuance of a session or client IP
+// against the configured limits. Purposes with no recent issuance are left
+// out.
+func (m *AccessKeyManager) IssuanceUsage(scope KeyLimitScope, identity string) ([]KeyIssuanceUsage, error) {
+	now := m.now()
+	usage := []KeyIssuanceUsage{}
+	for _, purpose := range []MediaPurpose{MediaPurposeStream, MediaPurposeDownload, MediaPurposeFeed, MediaPurposePlaylist, MediaPurposePlaylistExport} {
+		limits, ok := m.policies[purpose]
+		if !ok {
+			continue
+		}
+		key := IssuanceKey{Scope: scope, Identity: identity, Purpose: purpose}
+		eventsByKey, err := m.store.Events([]IssuanceKey{key}, m.retentionWindow(purpose), now)
+		if err != nil {
+			return nil, err
+		}
+		events := eventsByKey[key]
 		if len(events) == 0 {
-			delete(m.issuances, key)
-		} else {
-			m.issuances[key] = events
+			continue
 		}
+		purposeUsage := KeyIssuanceUsage{Purpose: purpose, LastIssuedAt: events[len(events)-1].UTC()}
+		for _, limit := range limits {
+			purposeUsage.Windows = append(purposeUsage.Windows, KeyWindowUsage{
+				Window: limit.Window.String(),
+				Issued: len(events) - firstWithinWindow(events, now, limit.Window),
+				Limit:  limit.Count,
+			})
+		}
+		usage = append(usage, purposeUsage)
 	}
+	return usage, nil
 }
 
 func captchaRequired(
-	eventsByKey map[accessIssuanceKey][]time.Time,
+	eventsByKey map[IssuanceKey][]time.Time,
 	limits []KeyLimit,
 	now time.Time,
 ) bool {
@@ -354,17 +551,17 @@ func captchaRequired(
 	return false
 }
 
-func (m *AccessKeyManager) evaluateLimitsLocked(
-	keys []accessIssuanceKey,
+// evaluateLimits returns the limit blocking a new issuance, if any. When
+// several are exceeded it reports the one that clears last.
+func evaluateLimits(
+	keys []IssuanceKey,
+	eventsByKey map[IssuanceKey][]time.Time,
 	limits []KeyLimit,
-	retention time.Duration,
 	now time.Time,
-) (map[accessIssuanceKey][]time.Time, *KeyLimitExceededError) {
-	eventsByKey := make(map[accessIssuanceKey][]time.Time, len(keys))
+) *KeyLimitExceededError {
 	var blocked *KeyLimitExceededError
 	for _, key := range keys {
-		events := pruneIssuances(m.issuances[key], now, retention)
-		eventsByKey[key] = events
+		events := eventsByKey[key]
 		for _, limit := range limits {
 			first := firstWithinWindow(events, now, limit.Window)
 			count := len(events) - first
@@ -374,26 +571,15 @@ func (m *AccessKeyManager) evaluateLimitsLocked(
 			retryAfter := events[first].Add(limit.Window).Sub(now)
 			if blocked == nil || retryAfter > blocked.RetryAfter {
 				blocked = &KeyLimitExceededError{
-					Purpose:    key.purpose,
-					Scope:      key.scope,
+					Purpose:    key.Purpose,
+					Scope:      key.Scope,
 					Limit:      limit,
 					RetryAfter: retryAfter,
 				}
 			}
 		}
 	}
-	return eventsByKey, blocked
-}
-
-func (m *AccessKeyManager) cleanupLocked(now time.Time) {
-	for key, events := range m.issuances {
-		events = pruneIssuances(events, now, m.retentionWindow(key.purpose))
-		if len(events) == 0 {
-			delete(m.issuances, key)
-		} else {
-			m.issuances[key] = events
-		}
-	}
+	return blocked
 }
 
 func (m *AccessKeyManager) retentionWindow(purpose MediaPurpose) time.Duration {
@@ -204,12 +204,12 @@ func TestAccessKeyManagerEnforcesIPLimitAcrossSessions(t *testing.T) {
 			t.Fatalf("limited scope = %q, want %q", limited.Scope, KeyLimitScopeIP)
 		}
 	}
-	blockedSessionKey := accessIssuanceKey{
-		scope:    KeyLimitScopeSession,
-		identity: "session-two",
-		purpose:  MediaPurposeStream,
+	blockedSessionKey := IssuanceKey{
+		Scope:    KeyLimitScopeSession,
+		Identity: "session-two",
+		Purpose:  MediaPurposeStream,
 	}
-	if _, exists := manager.issuances[blockedSessionKey]; exists {
+	if _, exists := manager.store.(*MemoryIssuanceStore).issuances[blockedSessionKey]; exists {
 		t.Fatal("IP-blocked request retained an empty entry for the fresh session")
 	}
 	if _, err := manager.Issue("session-two", "192.0.2.2", "track-two", MediaPurposeStream); err != nil {
@@ -325,3 +325,194 @@ func newTestAccessKeyManager(t *testing.T, now time.Time) *AccessKeyManager {
 	manager.now = func() time.Time { return now }
 	return manager
 }
+
+func TestFeedKeysAreStableWithinHalfTTLAndScopedToFolder(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	manager := newTestAccessKeyManager(t, now)
+	if err := manager.SetFeedPolicy("2/1h", 48*time.Hour); err != nil {
+		t.Fatalf("SetFeedPolicy: %v", err)
+	}
+
+	first, err := manager.IssueFeedKey("folder-a")
+	if err != nil {
+		t.Fatalf("IssueFeedKey: %v", err)
+	}
+	manager.now = func() time.Time { return now.Add(time.Hour) }
+	second, err := manager.IssueFeedKey("folder-a")
+	if err != nil {
+		t.Fatalf("IssueFeedKey: %v", err)
+	}
+	if first.AccessKey != second.AccessKey {
+		t.Fatal("feed key changed within the same period")
+	}
+	if _, err := manager.VerifyFeedKey(first.AccessKey, "folder-a"); err != nil {
+		t.Fatalf("feed key did not verify: %v", err)
+	}
+	if _, err := manager.VerifyFeedKey(first.AccessKey, "folder-b"); !errors.Is(err, ErrInvalidAccessKey) {
+		t.Fatalf("feed key verified for another folder: %v", err)
+	}
+	if err := manager.Verify(first.AccessKey, "folder-a", "folder-a", MediaPurposeStream); !errors.Is(err, ErrInvalidAccessKey) {
+		t.Fatalf("feed key verified as a stream key: %v", err)
+	}
+
+	manager.now = func() time.Time { return now.Add(24 * time.Hour) }
+	third, err := manager.IssueFeedKey("folder-a")
+	if err != nil {
+		t.Fatalf("IssueFeedKey: %v", err)
+	}
+	if third.AccessKey == first.AccessKey {
+		t.Fatal("feed key did not rotate after half the TTL")
+	}
+	if _, err := manager.VerifyFeedKey(first.AccessKey, "folder-a"); err != nil {
+		t.Fatalf("previous feed key expired early: %v", err)
+	}
+}
+
+func TestFeedAccessLimitsPerSubscriberAndIP(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	manager := newTestAccessKeyManager(t, now)
+	if err := manager.RecordFeedAccess("folder-a", "192.0.2.1"); !errors.Is(err, ErrInvalidAccessKey) {
+		t.Fatalf("feed access allowed without a feed policy: %v", err)
+	}
+	if err := manager.SetFeedPolicy("2/1h", time.Hour); err != nil {
+		t.Fatalf("SetFeedPolicy: %v", err)
+	}
+
+	for range 2 {
+		if err := manager.RecordFeedAccess("folder-a", "192.0.2.1"); err != nil {
+			t.Fatalf("RecordFeedAccess: %v", err)
+		}
+	}
+	var limited *KeyLimitExceededError
+	if err := manager.RecordFeedAccess("folder-a", "192.0.2.1"); !errors.As(err, &limited) {
+		t.Fatalf("third fetch err = %v, want limit", err)
+	}
+	if err := manager.RecordFeedAccess("folder-a", "192.0.2.2"); err != nil {
+		t.Fatalf("another subscriber was limited: %v", err)
+	}
+	if err := manager.RecordFeedAccess("folder-b", "192.0.2.1"); !errors.As(err, &limited) || limited.Scope != KeyLimitScopeIP {
+		t.Fatalf("IP limit across feeds err = %v", err)
+	}
+}
+
+func TestPlaylistKeysAreScopedToPlaylistAndTrack(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	manager := newTestAccessKeyManager(t, now)
+	if _, err := manager.IssuePlaylistKey("list-1", "track-a"); !errors.Is(err, ErrInvalidAccessKey) {
+		t.Fatalf("playlist key issued without a playlist policy: %v", err)
+	}
+	if err := manager.SetPlaylistPolicy("2/1h", time.Hour); err != nil {
+		t.Fatalf("SetPlaylistPolicy: %v", err)
+	}
+
+	issued, err := manager.IssuePlaylistKey("list-1", "track-a")
+	if err != nil {
+		t.Fatalf("IssuePlaylistKey: %v", err)
+	}
+	if _, err := manager.VerifyPlaylistKey(issued.AccessKey, "list-1", "track-a"); err != nil {
+		t.Fatalf("playlist key did not verify: %v", err)
+	}
+	if _, err := manager.VerifyPlaylistKey(issued.AccessKey, "list-2", "track-a"); !errors.Is(err, ErrInvalidAccessKey) {
+		t.Fatalf("playlist key verified for another playlist: %v", err)
+	}
+	if _, err := manager.VerifyPlaylistKey(issued.AccessKey, "", "track-a"); !errors.Is(err, ErrInvalidAccessKey) {
+		t.Fatalf("playlist key verified without a playlist ID: %v", err)
+	}
+
+	for range 2 {
+		if err := manager.RecordPlaylistAccess("list-1", "192.0.2.1"); err != nil {
+			t.Fatalf("RecordPlaylistAccess: %v", err)
+		}
+	}
+	var limited *KeyLimitExceededError
+	if err := manager.RecordPlaylistAccess("list-1", "192.0.2.1"); !errors.As(err, &limited) {
+		t.Fatalf("third fetch err = %v, want KeyLimitExceededError", err)
+	}
+
+	manager.now = func() time.Time { return now.Add(time.Hour) }
+	if _, err := manager.VerifyPlaylistKey(issued.AccessKey, "list-1", "track-a"); !errors.Is(err, ErrExpiredAccessKey) {
+		t.Fatalf("expired playlist key err = %v", err)
+	}
+}
+
+func TestIssuanceUsageReportsWindowsPerPurpose(t *testing.T) {
+	now := time.Date(2026, time.July, 26, 12, 0, 0, 0, time.UTC)
+	manager := newTestAccessKeyManager(t, now)
+
+	for _, track := range []string{"track-one", "track-two"} {
+		if _, err := manager.Issue("session-one", "192.0.2.1", track, MediaPurposeStream); err != nil {
+			t.Fatalf("Issue returned error: %v", err)
+		}
+	}
+	if _, err := manager.Issue("session-two", "192.0.2.1", "track-one", MediaPurposeDownload); err != nil {
+		t.Fatalf("Issue returned error: %v", err)
+	}
+
+	usage, err := manager.IssuanceUsage(KeyLimitScopeSession, "session-one")
+	if err != nil {
+		t.Fatalf("IssuanceUsage returned error: %v", err)
+	}
+	if len(usage) != 1 || usage[0].Purpose != MediaPurposeStream {
+		t.Fatalf("session usage = %#v, want stream only", usage)
+	}
+	if len(usage[0].Windows) != 1 || usage[0].Windows[0].Issued != 2 || usage[0].Windows[0].Limit != 10 {
+		t.Fatalf("stream windows = %#v", usage[0].Windows)
+	}
+
+	usage, _ = manager.IssuanceUsage(KeyLimitScopeIP, "192.0.2.1")
+	if len(usage) != 2 {
+		t.Fatalf("IP usage = %#v, want stream and download", usage)
+	}
+
+	now = now.Add(2 * time.Minute)
+	manager.now = func() time.Time { return now }
+	usage, _ = manager.IssuanceUsage(KeyLimitScopeSession, "session-one")
+	if len(usage) != 0 {
+		t.Fatalf("usage after windows passed = %#v, want none", usage)
+	}
+}
+
+func TestPlaylistExportCapDoesNotSpendStreamAllowance(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	manager := newTestAccessKeyManager(t, now)
+	if err := manager.SetPlaylistExportPolicy("1/1h"); err != nil {
+		t.Fatalf("SetPlaylistExportPolicy: %v", err)
+	}
+
+	if err := manager.RecordPlaylistExport("session-one", "192.0.2.1", true); err != nil {
+		t.Fatalf("first export: %v", err)
+	}
+	var limited *KeyLimitExceededError
+	if err := manager.RecordPlaylistExport("session-one", "192.0.2.1", true); !errors.As(err, &limited) ||
+		limited.Purpose != MediaPurposePlaylistExport {
+		t.Fatalf("second export err = %v, want export cap", err)
+	}
+
+	usage, err := manager.IssuanceUsage(KeyLimitScopeSession, "session-one")
+	if err != nil {
+		t.Fatalf("IssuanceUsage: %v", err)
+	}
+	for _, purpose := range usage {
+		if purpose.Purpose == MediaPurposeStream && purpose.Windows[0].Issued != 1 {
+			t.Fatalf("stream keys charged = %d, want 1", purpose.Windows[0].Issued)
+		}
+	}
+}
+
+func TestPlaylistExportAppliesStreamCaptchaThreshold(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	manager := newTestAccessKeyManager(t, now)
+	if err := manager.SetCaptchaPolicy(MediaPurposeStream, "1/1m"); err != nil {
+		t.Fatalf("SetCaptchaPolicy: %v", err)
+	}
+
+	if err := manager.RecordPlaylistExport("session-one", "192.0.2.1", false); err != nil {
+		t.Fatalf("first export: %v", err)
+	}
+	if err := manager.RecordPlaylistExport("session-one", "192.0.2.1", false); !errors.Is(err, ErrCaptchaRequired) {
+		t.Fatalf("second export err = %v, want ErrCaptchaRequired", err)
+	}
+	if err := manager.RecordPlaylistExport("session-one", "192.0.2.1", true); err != nil {
+		t.Fatalf("cleared export: %v", err)
+	}
+}
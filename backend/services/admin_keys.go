@@ -0,0 +1,223 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Admin API key scopes. Owner grants every scope, including key management
+// and the audit log.
+const (
+	AdminScopeRequests = "requests"
+	AdminScopeAudio    = "audio"
+	AdminScopeMessages = "messages"
+	AdminScopeJobs     = "jobs"
+	AdminScopeOwner    = "owner"
+)
+
+var AdminScopes = []string{AdminScopeRequests, AdminScopeAudio, AdminScopeMessages, AdminScopeJobs, AdminScopeOwner}
+
+const (
+	adminKeyPrefix      = "ask_"
+	maxAdminKeyNameLen  = 64
+	adminKeyPrefixShown = len(adminKeyPrefix) + 6
+)
+
+var (
+	ErrInvalidAdminKey      = errors.New("invalid admin API key")
+	ErrAdminKeyNotFound     = errors.New("admin API key not found")
+	ErrInvalidAdminKeyName  = errors.New("invalid admin API key name")
+	ErrInvalidAdminKeyScope = errors.New("invalid admin API key scope")
+	ErrAdminKeyNameTaken    = errors.New("an active admin API key already has this name")
+)
+
+type AdminKey struct {
+	ID         int64    `json:"id"`
+	Name       string   `json:"name"`
+	Scopes     []string `json:"scopes"`
+	Prefix     string   `json:"prefix"`
+	CreatedAt  string   `json:"createdAt"`
+	ExpiresAt  *string  `json:"expiresAt"`
+	LastUsedAt *string  `json:"lastUsedAt"`
+	RevokedAt  *string  `json:"revokedAt,omitempty"`
+}
+
+// CreatedAdminKey is returned once, when a key is created. Only a hash of
+// the key is stored.
+type CreatedAdminKey struct {
+	AdminKey
+	Key string `json:"key"`
+}
+
+// NormalizeAdminScopes lowercases, deduplicates and validates a scope list.
+func NormalizeAdminScopes(scopes []string) ([]string, error) {
+	normalized := []string{}
+	for _, scope := range scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if scope == "" {
+			continue
+		}
+		if !slices.Contains(AdminScopes, scope) {
+			return nil, ErrInvalidAdminKeyScope
+		}
+		if !slices.Contains(normalized, scope) {
+			normalized = append(normalized, scope)
+		}
+	}
+	if len(normalized) == 0 {
+		return nil, ErrInvalidAdminKeyScope
+	}
+	slices.Sort(normalized)
+	return normalized, nil
+}
+
+// AdminKeyService manages named admin API keys. Keys are random, so a plain
+// SHA-256 hash is enough to look them up without storing them.
+type AdminKeyService struct {
+	db *Database
+}
+
+func NewAdminKeyService(db *Database) *AdminKeyService {
+	return &AdminKeyService{db: db}
+}
+
+func (s *AdminKeyService) Create(name string, scopes []string, expiresAt *time.Time) (*CreatedAdminKey, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxAdminKeyNameLen {
+		return nil, ErrInvalidAdminKeyName
+	}
+	scopes, err := NormalizeAdminScopes(scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := randomHex(24)
+	if err != nil {
+		return nil, err
+	}
+	key := &CreatedAdminKey{
+		AdminKey: AdminKey{Name: name, Scopes: scopes, Prefix: (adminKeyPrefix + secret)[:adminKeyPrefixShown]},
+		Key:      adminKeyPrefix + secret,
+	}
+	var expires any
+	if expiresAt != nil {
+		expires = expiresAt.UTC()
+		value := expiresAt.UTC().Format(time.RFC3339)
+		key.ExpiresAt = &value
+	}
+
+	tx, err := s.db.DB().Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Names are unique among unrevoked keys, so an expired key is marked as
+	// revoked when it expired before its name is reused.
+	if _, err := tx.Exec(`
+		UPDATE admin_api_keys SET revoked_at = expires_at
+		WHERE name = $1 AND revoked_at IS NULL AND expires_at <= CURRENT_TIMESTAMP
+	`, name); err != nil {
+		return nil, err
+	}
+	var createdAt time.Time
+	err = tx.QueryRow(`
+		INSERT INTO admin_api_keys (name, scopes, prefix, key_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT DO NOTHING
+		RETURNING id, created_at
+	`, name, strings.Join(scopes, ","), key.Prefix, hashAppTokenPassword(key.Key), expires).Scan(&key.ID, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAdminKeyNameTaken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	key.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+	return key, nil
+}
+
+// List returns every key, revoked ones included, newest first.
+func (s *AdminKeyService) List() ([]AdminKey, error) {
+	rows, err := s.db.DB().Query(`
+		SELECT id, name, scopes, prefix, created_at, expires_at, last_used_at, revoked_at
+		FROM admin_api_keys
+		ORDER BY created_at DESC, id DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]AdminKey, 0)
+	for rows.Next() {
+		var key AdminKey
+		var scopes string
+		var createdAt time.Time
+		var expiresAt, lastUsedAt, revokedAt sql.NullTime
+		if err := rows.Scan(&key.ID, &key.Name, &scopes, &key.Prefix, &createdAt,
+			&expiresAt, &lastUsedAt, &revokedAt); err != nil {
+			return nil, err
+		}
+		key.Scopes = strings.Split(scopes, ",")
+		key.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+		key.ExpiresAt = formatNullTime(expiresAt)
+		key.LastUsedAt = formatNullTime(lastUsedAt)
+		key.RevokedAt = formatNullTime(revokedAt)
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func formatNullTime(value sql.NullTime) *string {
+	if !value.Valid {
+		return nil
+	}
+	formatted := value.Time.UTC().Format(time.RFC3339)
+	return &formatted
+}
+
+// Revoke disables a key. The row is kept so audit log entries still name a
+// known key.
+func (s *AdminKeyService) Revoke(id int64) error {
+	result, err := s.db.DB().Exec(`
+		UPDATE admin_api_keys SET revoked_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND revoked_at IS NULL
+	`, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err == nil && rows == 0 {
+		return ErrAdminKeyNotFound
+	}
+	return err
+}
+
+// AuthenticateAdminKey returns the name and scopes of an active key and
+// records its use.
+func (s *AdminKeyService) AuthenticateAdminKey(key string) (string, []string, error) {
+	if !strings.HasPrefix(key, adminKeyPrefix) {
+		return "", nil, ErrInvalidAdminKey
+	}
+	var name, scopes string
+	err := s.db.DB().QueryRow(`
+		UPDATE admin_api_keys SET last_used_at = CURRENT_TIMESTAMP
+		WHERE key_hash = $1 AND revoked_at IS NULL
+		  AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+		RETURNING name, scopes
+	`, hashAppTokenPassword(key)).Scan(&name, &scopes)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil, ErrInvalidAdminKey
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	return name, strings.Split(scopes, ","), nil
+}
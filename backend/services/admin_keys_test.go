@@ -0,0 +1,96 @@
+package services
+
+import (
+	"database/sql/driver"
+	"errors"
+	"regexp"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestNormalizeAdminScopes(t *testing.T) {
+	scopes, err := NormalizeAdminScopes([]string{" Requests", "audio", "requests", ""})
+	if err != nil || !slices.Equal(scopes, []string{"audio", "requests"}) {
+		t.Fatalf("scopes = %v, %v", scopes, err)
+	}
+	for _, invalid := range [][]string{nil, {""}, {"admin"}} {
+		if _, err := NormalizeAdminScopes(invalid); !errors.Is(err, ErrInvalidAdminKeyScope) {
+			t.Errorf("%v: err = %v", invalid, err)
+		}
+	}
+}
+
+func TestAdminKeyCreateStoresOnlyHash(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var storedHash []byte
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE admin_api_keys SET revoked_at = expires_at
+		WHERE name = $1 AND revoked_at IS NULL AND expires_at <= CURRENT_TIMESTAMP`)).
+		WithArgs("triage").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO admin_api_keys`)).
+		WithArgs("triage", "requests", sqlmock.AnyArg(), hashCapture{&storedHash}, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(3, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)))
+	mock.ExpectCommit()
+
+	key, err := NewAdminKeyService(&Database{db: db}).Create(" triage ", []string{"requests"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.ID != 3 || key.Name != "triage" || len(key.Key) != len(adminKeyPrefix)+48 || key.Prefix != key.Key[:adminKeyPrefixShown] {
+		t.Fatalf("key = %#v", key)
+	}
+	if !slices.Equal(storedHash, hashAppTokenPassword(key.Key)) {
+		t.Fatal("expected the SHA-256 of the key to be stored")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type hashCapture struct{ hash *[]byte }
+
+func (c hashCapture) Match(value driver.Value) bool {
+	hash, ok := value.([]byte)
+	*c.hash = hash
+	return ok
+}
+
+func TestAuthenticateAdminKeyRejectsInactiveKeys(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	service := NewAdminKeyService(&Database{db: db})
+	if _, _, err := service.AuthenticateAdminKey("not-an-admin-key"); !errors.Is(err, ErrInvalidAdminKey) {
+		t.Fatalf("err = %v", err)
+	}
+
+	query := regexp.QuoteMeta(`WHERE key_hash = $1 AND revoked_at IS NULL
+		  AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)`)
+	mock.ExpectQuery(query).WithArgs(hashAppTokenPassword("ask_live")).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "scopes"}).AddRow("triage", "audio,requests"))
+	mock.ExpectQuery(query).WithArgs(hashAppTokenPassword("ask_revoked")).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "scopes"}))
+
+	name, scopes, err := service.AuthenticateAdminKey("ask_live")
+	if err != nil || name != "triage" || !slices.Equal(scopes, []string{"audio", "requests"}) {
+		t.Fatalf("name = %q, scopes = %v, err = %v", name, scopes, err)
+	}
+	if _, _, err := service.AuthenticateAdminKey("ask_revoked"); !errors.Is(err, ErrInvalidAdminKey) {
+		t.Fatalf("err = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
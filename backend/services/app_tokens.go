@@ -0,0 +1,226 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	appTokenUsernamePrefix = "as-"
+	maxAppTokensPerProfile = 10
+	maxAppTokenNameLen     = 64
+)
+
+var (
+	ErrInvalidAppToken     = errors.New("invalid app token")
+	ErrAppTokenNotFound    = errors.New("app token not found")
+	ErrTooManyAppTokens    = errors.New("too many app tokens")
+	ErrInvalidAppTokenName = errors.New("invalid app token name")
+)
+
+type AppToken struct {
+	ID         int64   `json:"id"`
+	Name       string  `json:"name"`
+	Username   string  `json:"username"`
+	CreatedAt  string  `json:"createdAt"`
+	LastUsedAt *string `json:"lastUsedAt"`
+}
+
+// CreatedAppToken is returned once, when a token is created. The password
+// doubles as the OpenSubsonic API key.
+type CreatedAppToken struct {
+	AppToken
+	Password string `json:"password"`
+}
+
+// AppTokenIdentity is the anonymous profile an app token acts for.
+type AppTokenIdentity struct {
+	ProfileID string
+	CreatedAt time.Time
+}
+
+// AppTokenService manages credentials that let third-party players act for
+// an anonymous profile. Passwords are derived from a random per-token salt
+// and the server secret so that Subsonic's md5(password+salt) token scheme
+// can be verified without storing the password itself.
+type AppTokenService struct {
+	db     *Database
+	secret []byte
+}
+
+func NewAppTokenService(db *Database, secret string) *AppTokenService {
+	return &AppTokenService{db: db, secret: []byte(secret)}
+}
+
+func (s *AppTokenService) derivePassword(salt string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte("audio-app-token\x00" + salt))
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+func hashAppTokenPassword(password string) []byte {
+	hash := sha256.Sum256([]byte(password))
+	return hash[:]
+}
+
+func randomHex(n int) (string, error) {
+	raw := make([]byte, n)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+func (s *AppTokenService) Create(profileID, name string) (*CreatedAppToken, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxAppTokenNameLen {
+		return nil, ErrInvalidAppTokenName
+	}
+	if _, err := s.db.DB().Exec(`
+		INSERT INTO anonymous_profiles (session_id)
+		VALUES ($1)
+		ON CONFLICT (session_id) DO UPDATE SET updated_at = CURRENT_TIMESTAMP
+	`, profileID); err != nil {
+		return nil, err
+	}
+
+	var count int
+	if err := s.db.DB().QueryRow(
+		`SELECT COUNT(*) FROM app_tokens WHERE profile_id = $1`, profileID,
+	).Scan(&count); err != nil {
+		return nil, err
+	}
+	if count >= maxAppTokensPerProfile {
+		return nil, ErrTooManyAppTokens
+	}
+
+	suffix, err := randomHex(6)
+	if err != nil {
+		return nil, err
+	}
+	salt, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	password := s.derivePassword(salt)
+
+	token := &CreatedAppToken{
+		AppToken: AppToken{Name: name, Username: appTokenUsernamePrefix + suffix},
+		Password: password,
+	}
+	var createdAt time.Time
+	err = s.db.DB().QueryRow(`
+		INSERT INTO app_tokens (profile_id, name, username, salt, password_hash)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, profileID, name, token.Username, salt, hashAppTokenPassword(password)).Scan(&token.ID, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+	token.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+	return token, nil
+}
+
+func (s *AppTokenService) List(profileID string) ([]AppToken, error) {
+	rows, err := s.db.DB().Query(`
+		SELECT id, name, username, created_at, last_used_at
+		FROM app_tokens
+		WHERE profile_id = $1
+		ORDER BY created_at DESC, id DESC
+	`, profileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := make([]AppToken, 0)
+	for rows.Next() {
+		var token AppToken
+		var createdAt time.Time
+		var lastUsedAt sql.NullTime
+		if err := rows.Scan(&token.ID, &token.Name, &token.Username, &createdAt, &lastUsedAt); err != nil {
+			return nil, err
+		}
+		token.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+		if lastUsedAt.Valid {
+			value := lastUsedAt.Time.UTC().Format(time.RFC3339)
+			token.LastUsedAt = &value
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+func (s *AppTokenService) Revoke(profileID string, id int64) error {
+	result, err := s.db.DB().Exec(
+		`DELETE FROM app_tokens WHERE id = $1 AND profile_id = $2`, id, profileID,
+	)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err == nil && rows == 0 {
+		return ErrAppTokenNotFound
+	}
+	return err
+}
+
+// AuthenticatePassword checks a username and clear-text password.
+func (s *AppTokenService) AuthenticatePassword(username, password string) (*AppTokenIdentity, error) {
+	return s.touch(`username = $1 AND password_hash = $2`, username, hashAppTokenPassword(password))
+}
+
+// AuthenticateAPIKey checks an OpenSubsonic API key, which is the token's
+// password on its own.
+func (s *AppTokenService) AuthenticateAPIKey(apiKey string) (*AppTokenIdentity, error) {
+	if apiKey == "" {
+		return nil, ErrInvalidAppToken
+	}
+	return s.touch(`password_hash = $1`, hashAppTokenPassword(apiKey))
+}
+
+// AuthenticateToken checks Subsonic salted token authentication, where the
+// client sends md5(password + salt) and the salt.
+func (s *AppTokenService) AuthenticateToken(username, token, clientSalt string) (*AppTokenIdentity, error) {
+	if username == "" || token == "" || clientSalt == "" {
+		return nil, ErrInvalidAppToken
+	}
+	var salt string
+	err := s.db.DB().QueryRow(`SELECT salt FROM app_tokens WHERE username = $1`, username).Scan(&salt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidAppToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	sum := md5.Sum([]byte(s.derivePassword(salt) + clientSalt))
+	expected := hex.EncodeToString(sum[:])
+	if !hmac.Equal([]byte(strings.ToLower(token)), []byte(expected)) {
+		return nil, ErrInvalidAppToken
+	}
+	return s.touch(`username = $1`, username)
+}
+
+func (s *AppTokenService) touch(where string, args ...interface{}) (*AppTokenIdentity, error) {
+	var identity AppTokenIdentity
+	err := s.db.DB().QueryRow(`
+		UPDATE app_tokens
+		SET last_used_at = CURRENT_TIMESTAMP
+		WHERE `+where+`
+		RETURNING profile_id, created_at
+	`, args...).Scan(&identity.ProfileID, &identity.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidAppToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
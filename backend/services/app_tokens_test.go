@@ -0,0 +1,103 @@
+package services
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func newMockAppTokenService(t *testing.T) (*AppTokenService, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("create mock database: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet database expectations: %v", err)
+		}
+		db.Close()
+	})
+	return NewAppTokenService(&Database{db: db}, "secret"), mock
+}
+
+func TestAppTokenPasswordIsDerivedFromSaltAndSecret(t *testing.T) {
+	service := NewAppTokenService(nil, "secret")
+	other := NewAppTokenService(nil, "other")
+	if service.derivePassword("salt") != service.derivePassword("salt") {
+		t.Fatal("same salt produced different passwords")
+	}
+	if service.derivePassword("salt") == service.derivePassword("pepper") {
+		t.Fatal("different salts produced the same password")
+	}
+	if service.derivePassword("salt") == other.derivePassword("salt") {
+		t.Fatal("different secrets produced the same password")
+	}
+}
+
+func TestAppTokenSaltedTokenAuthentication(t *testing.T) {
+	service, mock := newMockAppTokenService(t)
+	sum := md5.Sum([]byte(service.derivePassword("stored-salt") + "client-salt"))
+	createdAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT salt FROM app_tokens WHERE username = $1`)).
+		WithArgs("as-abc").
+		WillReturnRows(sqlmock.NewRows([]string{"salt"}).AddRow("stored-salt"))
+	mock.ExpectQuery(`UPDATE app_tokens\s+SET last_used_at = CURRENT_TIMESTAMP\s+WHERE username = \$1`).
+		WithArgs("as-abc").
+		WillReturnRows(sqlmock.NewRows([]string{"profile_id", "created_at"}).AddRow("profile-1", createdAt))
+
+	identity, err := service.AuthenticateToken("as-abc", hex.EncodeToString(sum[:]), "client-salt")
+	if err != nil {
+		t.Fatalf("AuthenticateToken: %v", err)
+	}
+	if identity.ProfileID != "profile-1" || !identity.CreatedAt.Equal(createdAt) {
+		t.Fatalf("identity = %+v", identity)
+	}
+}
+
+func TestAppTokenSaltedTokenRejectsWrongToken(t *testing.T) {
+	service, mock := newMockAppTokenService(t)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT salt FROM app_tokens WHERE username = $1`)).
+		WithArgs("as-abc").
+		WillReturnRows(sqlmock.NewRows([]string{"salt"}).AddRow("stored-salt"))
+
+	_, err := service.AuthenticateToken("as-abc", "00000000000000000000000000000000", "client-salt")
+	if !errors.Is(err, ErrInvalidAppToken) {
+		t.Fatalf("err = %v, want ErrInvalidAppToken", err)
+	}
+}
+
+func TestAppTokenAPIKeyLooksUpPasswordHash(t *testing.T) {
+	service, mock := newMockAppTokenService(t)
+	mock.ExpectQuery(`UPDATE app_tokens\s+SET last_used_at = CURRENT_TIMESTAMP\s+WHERE password_hash = \$1`).
+		WithArgs(hashAppTokenPassword("api-key")).
+		WillReturnRows(sqlmock.NewRows([]string{"profile_id", "created_at"}))
+
+	_, err := service.AuthenticateAPIKey("api-key")
+	if !errors.Is(err, ErrInvalidAppToken) {
+		t.Fatalf("err = %v, want ErrInvalidAppToken", err)
+	}
+}
+
+func TestAppTokenCreateEnforcesPerProfileLimit(t *testing.T) {
+	service, mock := newMockAppTokenService(t)
+	mock.ExpectExec(`INSERT INTO anonymous_profiles`).
+		WithArgs("profile-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM app_tokens WHERE profile_id = $1`)).
+		WithArgs("profile-1").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(maxAppTokensPerProfile))
+
+	if _, err := service.Create("profile-1", "Phone"); !errors.Is(err, ErrTooManyAppTokens) {
+		t.Fatalf("err = %v, want ErrTooManyAppTokens", err)
+	}
+	if _, err := service.Create("profile-1", "  "); !errors.Is(err, ErrInvalidAppTokenName) {
+		t.Fatalf("err = %v, want ErrInvalidAppTokenName", err)
+	}
+}
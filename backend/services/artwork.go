@@ -0,0 +1,287 @@
+package services
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	_ "golang.org/x/image/webp"
+)
+
+// matureArtworkSuffix marks placeholders computed from blurred artwork. It is
+// appended to the thumbnail name stored in thumbnail_placeholder_for.
+const matureArtworkSuffix = "#blurred"
+
+// ArtworkPlaceholder is shown while a thumbnail or poster loads. Mature
+// thumbnails only carry the blurhash of the blurred image.
+type ArtworkPlaceholder struct {
+	Blurhash      string `json:"blurhash"`
+	DominantColor string `json:"dominantColor,omitempty"`
+	AccentColor   string `json:"accentColor,omitempty"`
+}
+
+// Scan decodes the JSON stored in the *_placeholder columns.
+func (p *ArtworkPlaceholder) Scan(src any) error {
+	switch value := src.(type) {
+	case string:
+		return json.Unmarshal([]byte(value), p)
+	case []byte:
+		return json.Unmarshal(value, p)
+	default:
+		return fmt.Errorf("cannot scan %T into ArtworkPlaceholder", src)
+	}
+}
+
+func (p ArtworkPlaceholder) Value() (driver.Value, error) {
+	encoded, err := json.Marshal(p)
+	return string(encoded), err
+}
+
+// NewArtworkPlaceholder computes the placeholder for decoded artwork. Mature
+// artwork is blurred first and gets no colours.
+func NewArtworkPlaceholder(img image.Image, mature bool) ArtworkPlaceholder {
+	sample := placeholderSample(img)
+	if mature {
+		blurSample(sample, 4, 3)
+		return ArtworkPlaceholder{Blurhash: encodeBlurhash(sample, blurhashComponentsX, blurhashComponentsY)}
+	}
+	dominant, accent := artworkColours(sample)
+	return ArtworkPlaceholder{
+		Blurhash:      encodeBlurhash(sample, blurhashComponentsX, blurhashComponentsY),
+		DominantColor: dominant,
+		AccentColor:   accent,
+	}
+}
+
+// ThumbnailPlaceholderColumn selects a track's placeholder, hiding it when
+// the track is mature but the stored placeholder was computed before it was
+// marked as such.
+func ThumbnailPlaceholderColumn(alias string) string {
+	return fmt.Sprintf(`CASE WHEN COALESCE(%[1]s.age_limit, 0) >= 18 AND COALESCE(%[1]s.thumbnail_placeholder_for, '') NOT LIKE '%%%[2]s'
+			THEN NULL ELSE %[1]s.thumbnail_placeholder END`, alias, matureArtworkSuffix)
+}
+
+// PosterPlaceholderColumn selects a folder's poster placeholder, hiding it
+// when the folder is mature but the stored placeholder was computed before
+// it was marked as such.
+func PosterPlaceholderColumn(alias string) string {
+	return fmt.Sprintf(`CASE WHEN COALESCE(%[1]s.age_limit, 0) >= 18 AND COALESCE(%[1]s.poster_placeholder_for, '') NOT LIKE '%%%[2]s'
+			THEN NULL ELSE %[1]s.poster_placeholder END`, alias, matureArtworkSuffix)
+}
+
+// ArtworkService fills in placeholders for thumbnails and posters that do
+// not have one yet, or whose image or age limit changed since.
+type ArtworkService struct {
+	db      *sql.DB
+	fs      *FileSystemService
+	mu      sync.Mutex // guards running flag
+	running bool
+}
+
+func NewArtworkService(db *sql.DB, fs *FileSystemService) *ArtworkService {
+	return &ArtworkService{db: db, fs: fs}
+}
+
+func (s *ArtworkService) StartScheduledJob(cronExpr string) {
+	log.Printf("Artwork: scheduling placeholder job cron=%q", cronExpr)
+
+	c := cron.New()
+	_, err := c.AddFunc(cronExpr, func() {
+		if s.RunJobExclusive() == ErrJobRunning {
+			log.Println("Artwork: job already running, skipping")
+		}
+	})
+	if err != nil {
+		log.Printf("Artwork: error setting up schedule: %v", err)
+		return
+	}
+	c.Start()
+}
+
+// RunJobExclusive runs the job unless a scheduled or manual run is already
+// in progress, in which case it returns ErrJobRunning.
+func (s *ArtworkService) RunJobExclusive() error {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return ErrJobRunning
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+	}()
+
+	s.RunJob()
+	return nil
+}
+
+func (s *ArtworkService) RunJob() {
+	start := time.Now()
+	thumbnails, err := s.updateThumbnails()
+	if err != nil {
+		log.Printf("Artwork: thumbnail error: %v", err)
+	}
+	posters, err := s.updatePosters()
+	if err != nil {
+		log.Printf("Artwork: poster error: %v", err)
+	}
+	log.Printf("Artwork: job done — %d thumbnails and %d posters in %v",
+		thumbnails, posters, time.Since(start).Round(time.Second))
+}
+
+// artworkTask is one image whose placeholder is stale. source is stored in
+// the *_placeholder_for column so the image is not processed again until it
+// changes.
+type artworkTask struct {
+	id       int64
+	fullPath string
+	source   string
+	mature   bool
+}
+
+func (s *ArtworkService) updateThumbnails() (int, error) {
+	if _, err := s.db.Exec(`
+		UPDATE audio_files SET thumbnail_placeholder = NULL, thumbnail_placeholder_for = NULL
+		WHERE COALESCE(thumbnail, '') = '' AND thumbnail_placeholder_for IS NOT NULL
+	`); err != nil {
+		return 0, err
+	}
+	rows, err := s.db.Query(`
+		SELECT id, path, thumbnail, COALESCE(age_limit, 0) >= 18
+		FROM audio_files
+		WHERE deleted = 0 AND COALESCE(thumbnail, '') <> ''
+		  AND thumbnail_placeholder_for IS DISTINCT FROM
+		      thumbnail || CASE WHEN COALESCE(age_limit, 0) >= 18 THEN '` + matureArtworkSuffix + `' ELSE '' END
+		ORDER BY id DESC
+	`)
+	if err != nil {
+		return 0, err
+	}
+	var tasks []artworkTask
+	for rows.Next() {
+		var task artworkTask
+		var audioPath, thumbnail string
+		if err := rows.Scan(&task.id, &audioPath, &thumbnail, &task.mature); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		task.source = thumbnail
+		if task.mature {
+			task.source += matureArtworkSuffix
+		}
+		parts := strings.SplitN(audioPath, "/", 2)
+		if len(parts) == 2 {
+			task.fullPath, _ = s.fs.ValidatePath(parts[0], filepath.Join(filepath.Dir(parts[1]), thumbnail))
+		}
+		tasks = append(tasks, task)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	return s.store(tasks, `
+		UPDATE audio_files SET thumbnail_placeholder = $2, thumbnail_placeholder_for = $3 WHERE id = $1
+	`), nil
+}
+
+func (s *ArtworkService) updatePosters() (int, error) {
+	if _, err := s.db.Exec(`
+		UPDATE folders SET poster_placeholder = NULL, poster_placeholder_for = NULL
+		WHERE COALESCE(poster_image, '') = '' AND poster_placeholder_for IS NOT NULL
+	`); err != nil {
+		return 0, err
+	}
+	// A folder's age limit already includes any inherited from its parents.
+	rows, err := s.db.Query(`
+		SELECT id, path, poster_image, COALESCE(age_limit, 0) >= 18
+		FROM folders
+		WHERE COALESCE(poster_image, '') <> ''
+		  AND poster_placeholder_for IS DISTINCT FROM
+		      poster_image || CASE WHEN COALESCE(age_limit, 0) >= 18 THEN '` + matureArtworkSuffix + `' ELSE '' END
+		ORDER BY id DESC
+	`)
+	if err != nil {
+		return 0, err
+	}
+	var tasks []artworkTask
+	for rows.Next() {
+		var task artworkTask
+		var folderPath, posterImage string
+		if err := rows.Scan(&task.id, &folderPath, &posterImage, &task.mature); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		task.source = posterImage
+		if task.mature {
+			task.source += matureArtworkSuffix
+		}
+		parts := strings.SplitN(folderPath, "/", 2)
+		var relDir string
+		if len(parts) > 1 {
+			relDir = parts[1]
+		}
+		task.fullPath, _ = s.fs.ValidatePath(parts[0], filepath.Join(relDir, posterImage))
+		tasks = append(tasks, task)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	return s.store(tasks, `
+		UPDATE folders SET poster_placeholder = $2, poster_placeholder_for = $3 WHERE id = $1
+	`), nil
+}
+
+// store computes and saves each task's placeholder. Images that cannot be
+// decoded are recorded without a placeholder so they are not retried until
+// the artwork changes.
+func (s *ArtworkService) store(tasks []artworkTask, update string) int {
+	stored := 0
+	for _, task := range tasks {
+		var placeholder any
+		if img, err := decodeArtwork(task.fullPath); err != nil {
+			log.Printf("Artwork: skipping %s: %v", task.source, err)
+		} else {
+			placeholder = NewArtworkPlaceholder(img, task.mature)
+		}
+		if _, err := s.db.Exec(update, task.id, placeholder, task.source); err != nil {
+			log.Printf("Artwork: store error %s: %v", task.source, err)
+			continue
+		}
+		if placeholder != nil {
+			stored++
+		}
+	}
+	return stored
+}
+
+func decodeArtwork(fullPath string) (image.Image, error) {
+	if fullPath == "" {
+		return nil, fmt.Errorf("path outside audio directories")
+	}
+	file, err := os.Open(fullPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	img, _, err := image.Decode(file)
+	return img, err
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func solidImage(width, height int, c color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestBlurhashOfSolidColour(t *testing.T) {
+	placeholder := NewArtworkPlaceholder(solidImage(300, 200, color.RGBA{R: 0x33, G: 0x66, B: 0x99, A: 255}), false)
+
+	// Size flag L for 4x3 components, then the maximum AC and the colour as DC.
+	hash := placeholder.Blurhash
+	if len(hash) != 28 || hash[0] != 'L' || hash[2:6] != encodeBase83(0x336699, 4) {
+		t.Fatalf("blurhash = %q, want a 4x3 hash with DC %q", hash, encodeBase83(0x336699, 4))
+	}
+	if placeholder.DominantColor != "#336699" || placeholder.AccentColor != "" {
+		t.Fatalf("colours = %q %q", placeholder.DominantColor, placeholder.AccentColor)
+	}
+}
+
+func TestArtworkColoursPickDominantAndAccent(t *testing.T) {
+	img := solidImage(100, 100, color.RGBA{R: 40, G: 40, B: 40, A: 255})
+	for y := range 30 {
+		for x := range 100 {
+			img.SetRGBA(x, y, color.RGBA{R: 220, G: 40, B: 30, A: 255})
+		}
+	}
+	placeholder := NewArtworkPlaceholder(img, false)
+	if placeholder.DominantColor != "#282828" || placeholder.AccentColor != "#dc281e" {
+		t.Fatalf("colours = %q %q", placeholder.DominantColor, placeholder.AccentColor)
+	}
+	if len(placeholder.Blurhash) != 28 || placeholder.Blurhash == NewArtworkPlaceholder(img, true).Blurhash {
+		t.Fatalf("blurhash = %q, want a 4x3 hash that differs from the blurred one", placeholder.Blurhash)
+	}
+}
+
+func TestMaturePlaceholderHasNoColours(t *testing.T) {
+	placeholder := NewArtworkPlaceholder(solidImage(64, 64, color.RGBA{R: 200, A: 255}), true)
+	if placeholder.Blurhash == "" || placeholder.DominantColor != "" || placeholder.AccentColor != "" {
+		t.Fatalf("placeholder = %#v", placeholder)
+	}
+}
+
+func TestArtworkJobStoresPlaceholders(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "show"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	artwork := solidImage(40, 40, color.RGBA{R: 10, G: 120, B: 200, A: 255})
+	poster, err := NewArtworkPlaceholder(artwork, false).Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(poster.(string), `"dominantColor":"#0a78c8"`) {
+		t.Fatalf("poster placeholder = %s", poster)
+	}
+	maturePoster, err := NewArtworkPlaceholder(artwork, true).Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(maturePoster.(string), "Color") {
+		t.Fatalf("mature poster placeholder = %s, want no colours", maturePoster)
+	}
+	for _, name := range []string{"show/episode.png", "show/poster.png"} {
+		file, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := png.Encode(file, artwork); err != nil {
+			t.Fatal(err)
+		}
+		file.Close()
+	}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE audio_files SET thumbnail_placeholder = NULL`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, path, thumbnail, COALESCE(age_limit, 0) >= 18`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "path", "thumbnail", "mature"}).
+			AddRow(1, "audio/show/episode.mp3", "episode.png", true).
+			AddRow(2, "audio/show/missing.mp3", "missing.png", false))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE audio_files SET thumbnail_placeholder = $2`)).
+		WithArgs(1, sqlmock.AnyArg(), "episode.png#blurred").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE audio_files SET thumbnail_placeholder = $2`)).
+		WithArgs(2, nil, "missing.png").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE folders SET poster_placeholder = NULL`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, path, poster_image, COALESCE(age_limit, 0) >= 18`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "path", "poster_image", "mature"}).
+			AddRow(3, "audio/show", "poster.png", false).
+			AddRow(4, "audio/show", "poster.png", true))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE folders SET poster_placeholder = $2`)).
+		WithArgs(3, poster, "poster.png").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE folders SET poster_placeholder = $2`)).
+		WithArgs(4, maturePoster, "poster.png#blurred").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	NewArtworkService(db, NewFileSystemService(dir+":Audio")).RunJob()
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestArtworkManualRunSkipsWhileScheduledRunIsInProgress(t *testing.T) {
+	service := NewArtworkService(nil, nil)
+	service.running = true
+	if err := service.RunJobExclusive(); err != ErrJobRunning {
+		t.Fatalf("RunJobExclusive = %v, want ErrJobRunning", err)
+	}
+}
+
+func TestArtworkPlaceholderScansJSON(t *testing.T) {
+	var placeholder ArtworkPlaceholder
+	if err := placeholder.Scan([]byte(`{"blurhash":"L0","dominantColor":"#000000"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if placeholder.Blurhash != "L0" || placeholder.DominantColor != "#000000" {
+		t.Fatalf("placeholder = %#v", placeholder)
+	}
+	if err := placeholder.Scan(42); err == nil {
+		t.Fatal("expected an error for a non-text value")
+	}
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"encoding/json"
+	"log"
+	"strconv"
+	"time"
+)
+
+const auditPruneInterval = time.Hour
+
+// AuditEntry is one admin mutation. Before and After hold the JSON state of
+// the target around the change; either is null when the target was created
+// or deleted.
+type AuditEntry struct {
+	ID         int64           `json:"id"`
+	Actor      string          `json:"actor"`
+	Method     string          `json:"method"`
+	Endpoint   string          `json:"endpoint"`
+	TargetType string          `json:"targetType"`
+	TargetID   string          `json:"targetId"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	CreatedAt  string          `json:"createdAt"`
+}
+
+// AuditFilter narrows an audit log listing. Empty fields match everything.
+type AuditFilter struct {
+	Actor      string
+	TargetType string
+	TargetID   string
+	Since      time.Time
+	Until      time.Time
+	Limit      int
+	Offset     int
+}
+
+type AuditPage struct {
+	Entries []AuditEntry `json:"entries"`
+	Total   int          `json:"total"`
+	Limit   int          `json:"limit"`
+	Offset  int          `json:"offset"`
+}
+
+// AuditService records admin mutations. Entries older than the retention
+// are pruned; a zero retention keeps them forever.
+type AuditService struct {
+	db        *Database
+	retention time.Duration
+}
+
+func NewAuditService(db *Database, retention time.Duration) *AuditService {
+	return &AuditService{db: db, retention: retention}
+}
+
+// Record stores an entry. Before and after are marshalled to JSON; nil
+// values are stored as NULL.
+func (s *AuditService) Record(actor, method, endpoint, targetType, targetID string, before, after any) error {
+	beforeJSON, err := auditValue(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := auditValue(after)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.DB().Exec(`
+		INSERT INTO admin_audit_log (actor, method, endpoint, target_type, target_id, before_value, after_value)
+		VALUES ($1, $2, $3, $4, $5, $6::jsonb, $7::jsonb)
+	`, actor, method, endpoint, targetType, targetID, beforeJSON, afterJSON)
+	return err
+}
+
+func auditValue(value any) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	if string(data) == "null" {
+		return nil, nil
+	}
+	return string(data), nil
+}
+
+// List returns entries matching the filter, newest first.
+func (s *AuditService) List(filter AuditFilter) (*AuditPage, error) {
+	where := "WHERE 1=1"
+	args := []any{}
+	addFilter := func(clause string, value any) {
+		args = append(args, value)
+		where += " AND " + clause + " $" + strconv.Itoa(len(args))
+	}
+	if filter.Actor != "" {
+		addFilter("actor =", filter.Actor)
+	}
+	if filter.TargetType != "" {
+		addFilter("target_type =", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		addFilter("target_id =", filter.TargetID)
+	}
+	if !filter.Since.IsZero() {
+		addFilter("created_at >=", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		addFilter("created_at <", filter.Until)
+	}
+
+	page := &AuditPage{Entries: []AuditEntry{}, Limit: filter.Limit, Offset: filter.Offset}
+	if err := s.db.DB().QueryRow(`SELECT COUNT(*) FROM admin_audit_log `+where, args...).Scan(&page.Total); err != nil {
+		return nil, err
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	rows, err := s.db.DB().Query(`
+		SELECT id, actor, method, endpoint, target_type, target_id,
+		       COALESCE(before_value::text, 'null'), COALESCE(after_value::text, 'null'), created_at
+		FROM admin_audit_log
+		`+where+`
+		ORDER BY created_at DESC, id DESC
+		LIMIT $`+strconv.Itoa(len(args)-1)+` OFFSET $`+strconv.Itoa(len(args)), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry AuditEntry
+		var before, after string
+		var createdAt time.Time
+		if err := rows.Scan(&entry.ID, &entry.Actor, &entry.Method, &entry.Endpoint, &entry.TargetType,
+			&entry.TargetID, &before, &after, &createdAt); err != nil {
+			return nil, err
+		}
+		entry.Before = json.RawMessage(before)
+		entry.After = json.RawMessage(after)
+		entry.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+		page.Entries = append(page.Entries, entry)
+	}
+	return page, rows.Err()
+}
+
+func (s *AuditService) prune() (int64, error) {
+	result, err := s.db.DB().Exec(`
+		DELETE FROM admin_audit_log WHERE created_at < $1
+	`, time.Now().Add(-s.retention))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (s *AuditService) StartRetentionCleanup() {
+	if s.retention <= 0 {
+		return
+	}
+	cleanup := func() {
+		deleted, err := s.prune()
+		if err != nil {
+			log.Printf("Error pruning admin audit log: %v", err)
+			return
+		}
+		if deleted > 0 {
+			log.Printf("Removed %d expired admin audit log entries", deleted)
+		}
+	}
+
+	cleanup()
+	go func() {
+		ticker := time.NewTicker(auditPruneInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			cleanup()
+		}
+	}()
+}
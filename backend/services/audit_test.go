@@ -0,0 +1,61 @@
+package services
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestAuditRecordStoresJSONState(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO admin_audit_log`)).
+		WithArgs("default", "DELETE", "/api/admin/requests/7", "request", "7", `{"status":"added"}`, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	service := NewAuditService(&Database{db: db}, 0)
+	var deleted *SourceRequest
+	if err := service.Record("default", "DELETE", "/api/admin/requests/7", "request", "7",
+		map[string]string{"status": "added"}, deleted); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAuditListFiltersAndPages(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM admin_audit_log WHERE 1=1 AND actor = $1 AND created_at >= $2`)).
+		WithArgs("alice", since).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectQuery(`(?s)WHERE 1=1 AND actor = \$1 AND created_at >= \$2.*LIMIT \$3 OFFSET \$4`).
+		WithArgs("alice", since, 2, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "actor", "method", "endpoint", "target_type", "target_id", "before", "after", "created_at"}).
+			AddRow(9, "alice", "PATCH", "/api/admin/requests/4", "request", "4", `{"status":"requested"}`, `{"status":"added"}`, since))
+
+	service := NewAuditService(&Database{db: db}, 0)
+	page, err := service.List(AuditFilter{Actor: "alice", Since: since, Limit: 2, Offset: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 3 || len(page.Entries) != 1 || string(page.Entries[0].After) != `{"status":"added"}` ||
+		page.Entries[0].CreatedAt != "2026-01-01T00:00:00Z" {
+		t.Fatalf("page = %#v", page)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
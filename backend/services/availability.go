@@ -0,0 +1,510 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Availability check outcomes stored in availability_checks.status. An
+// "error" check says nothing about the source and leaves unavailable_at as
+// it is.
+const (
+	AvailabilityAvailable   = "available"
+	AvailabilityUnavailable = "unavailable"
+	AvailabilityError       = "error"
+)
+
+// AvailabilityResult is what a checker found out about one source URL.
+type AvailabilityResult struct {
+	Available bool
+	Reason    string
+}
+
+// AvailabilityChecker decides whether a source URL is still online. It
+// returns an error when that could not be determined, for example because
+// the host timed out or is rate limiting.
+type AvailabilityChecker interface {
+	Check(ctx context.Context, sourceURL string) (AvailabilityResult, error)
+}
+
+// ScriptAvailabilityChecker asks the source normalizer script. It receives
+// {"url": ..., "action": "availability"} on stdin and answers with
+// {"available": bool, "reason": "..."} or the normalizer's {"error": {...}}.
+type ScriptAvailabilityChecker struct {
+	scriptPath string
+	timeout    time.Duration
+}
+
+func NewScriptAvailabilityChecker(scriptPath string, timeout time.Duration) *ScriptAvailabilityChecker {
+	return &ScriptAvailabilityChecker{scriptPath: strings.TrimSpace(scriptPath), timeout: timeout}
+}
+
+func (c *ScriptAvailabilityChecker) Check(ctx context.Context, sourceURL string) (AvailabilityResult, error) {
+	output, err := runJSONScript(ctx, c.scriptPath, c.timeout, map[string]string{
+		"url":    sourceURL,
+		"action": "availability",
+	})
+	if err != nil {
+		return AvailabilityResult{}, err
+	}
+	if output.timedOut {
+		return AvailabilityResult{}, errors.New("availability script timed out")
+	}
+
+	var response struct {
+		Available *bool        `json:"available"`
+		Reason    string       `json:"reason"`
+		Error     *scriptError `json:"error"`
+	}
+	if err := json.Unmarshal(output.stdout, &response); err != nil {
+		if output.runErr != nil {
+			return AvailabilityResult{}, fmt.Errorf("availability script failed: %w: %s", output.runErr, output.stderr)
+		}
+		return AvailabilityResult{}, fmt.Errorf("availability script returned invalid JSON: %w", err)
+	}
+	if response.Error != nil {
+		return AvailabilityResult{}, fmt.Errorf("availability script: %s: %s", response.Error.Code, response.Error.Message)
+	}
+	if output.runErr != nil {
+		return AvailabilityResult{}, fmt.Errorf("availability script failed: %w: %s", output.runErr, output.stderr)
+	}
+	if response.Available == nil {
+		return AvailabilityResult{}, errors.New("availability script returned no result")
+	}
+	return AvailabilityResult{Available: *response.Available, Reason: response.Reason}, nil
+}
+
+// HTTPAvailabilityChecker sends a HEAD request to the source URL. 404 and 410
+// mean the source is gone; other client and server errors are inconclusive.
+type HTTPAvailabilityChecker struct {
+	client *http.Client
+}
+
+func NewHTTPAvailabilityChecker(timeout time.Duration) *HTTPAvailabilityChecker {
+	return &HTTPAvailabilityChecker{client: &http.Client{Timeout: timeout}}
+}
+
+func (c *HTTPAvailabilityChecker) Check(ctx context.Context, sourceURL string) (AvailabilityResult, error) {
+	status, err := c.request(ctx, http.MethodHead, sourceURL)
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented) {
+		status, err = c.request(ctx, http.MethodGet, sourceURL)
+	}
+	if err != nil {
+		return AvailabilityResult{}, err
+	}
+	switch {
+	case status < 400:
+		return AvailabilityResult{Available: true}, nil
+	case status == http.StatusNotFound || status == http.StatusGone:
+		return AvailabilityResult{Reason: fmt.Sprintf("HTTP %d", status)}, nil
+	default:
+		return AvailabilityResult{}, fmt.Errorf("source returned status %d", status)
+	}
+}
+
+func (c *HTTPAvailabilityChecker) request(ctx context.Context, method, sourceURL string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, sourceURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	if method == http.MethodGet {
+		req.Header.Set("Range", "bytes=0-0")
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// hostBackoff spaces out checks against the same host. Each failed check
+// doubles the spacing for that host; once it reaches max the host is skipped
+// for the rest of the run.
+type hostBackoff struct {
+	mu    sync.Mutex
+	base  time.Duration
+	max   time.Duration
+	hosts map[string]*hostBackoffState
+	now   func() time.Time
+}
+
+type hostBackoffState struct {
+	next  time.Time
+	delay time.Duration
+}
+
+func newHostBackoff(base, max time.Duration) *hostBackoff {
+	return &hostBackoff{base: base, max: max, hosts: make(map[string]*hostBackoffState), now: time.Now}
+}
+
+// reserve returns how long to wait before checking the host, or false when
+// the host has failed too often to be checked again in this run.
+func (b *hostBackoff) reserve(host string) (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state, ok := b.hosts[host]
+	if !ok {
+		state = &hostBackoffState{delay: b.base}
+		b.hosts[host] = state
+	}
+	if state.delay >= b.max {
+		return 0, false
+	}
+	now := b.now()
+	start := state.next
+	if start.Before(now) {
+		start = now
+	}
+	state.next = start.Add(state.delay)
+	return start.Sub(now), true
+}
+
+func (b *hostBackoff) record(host string, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state, ok := b.hosts[host]
+	if !ok {
+		return
+	}
+	if failed {
+		state.delay = min(max(state.delay*2, time.Second), b.max)
+	} else {
+		state.delay = b.base
+	}
+}
+
+// AvailabilitySummary describes one run of the availability job.
+type AvailabilitySummary struct {
+	Checked          int                  `json:"checked"`
+	Errors           int                  `json:"errors"`
+	Skipped          int                  `json:"skipped"`
+	NewlyUnavailable []AvailabilityChange `json:"newlyUnavailable"`
+	Restored         []AvailabilityChange `json:"restored"`
+	Duration         string               `json:"duration"`
+}
+
+// AvailabilityChange is a track whose unavailable_at was set or cleared.
+type AvailabilityChange struct {
+	ShareKey   string `json:"shareKey"`
+	Title      string `json:"title"`
+	WebpageURL string `json:"webpageUrl"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+// AvailabilityCheck is one recorded check of a track's source.
+type AvailabilityCheck struct {
+	CheckedAt string `json:"checkedAt"`
+	Status    string `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+}
+
+type availabilityTask struct {
+	id          int64
+	shareKey    string
+	title       string
+	webpageURL  string
+	unavailable bool
+}
+
+// AvailabilityService checks every track's webpage_url and keeps
+// unavailable_at in step with what it finds.
+type AvailabilityService struct {
+	db          *sql.DB
+	checker     AvailabilityChecker
+	ntfy        *NtfyService
+	webhook     *WebhookService
+	concurrency int
+	retention   time.Duration
+	backoff     func() *hostBackoff
+	mu          sync.Mutex // guards running flag
+	running     bool
+}
+
+func NewAvailabilityService(db *sql.DB, checker AvailabilityChecker, ntfy *NtfyService, webhook *WebhookService, concurrency int, hostInterval, retention time.Duration) *AvailabilityService {
+	return &AvailabilityService{
+		db:          db,
+		checker:     checker,
+		ntfy:        ntfy,
+		webhook:     webhook,
+		concurrency: max(concurrency, 1),
+		retention:   retention,
+		backoff: func() *hostBackoff {
+			return newHostBackoff(hostInterval, max(hostInterval*32, 5*time.Minute))
+		},
+	}
+}
+
+func (s *AvailabilityService) StartScheduledJob(cronExpr string) {
+	log.Printf("Availability: scheduling check job cron=%q", cronExpr)
+
+	c := cron.New()
+	_, err := c.AddFunc(cronExpr, func() {
+		if _, err := s.RunJobExclusive(context.Background()); err == ErrJobRunning {
+			log.Println("Availability: job already running, skipping")
+		}
+	})
+	if err != nil {
+		log.Printf("Availability: error setting up schedule: %v", err)
+		return
+	}
+	c.Start()
+}
+
+// RunJobExclusive runs the job unless a scheduled or manual run is already
+// in progress, in which case it returns ErrJobRunning.
+func (s *AvailabilityService) RunJobExclusive(ctx context.Context) (*AvailabilitySummary, error) {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return nil, ErrJobRunning
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+	}()
+
+	return s.RunJob(ctx)
+}
+
+// RunJob checks every source once, least recently checked first, sends a
+// summary when any track changed state, and prunes checks older than the
+// retention.
+func (s *AvailabilityService) RunJob(ctx context.Context) (*AvailabilitySummary, error) {
+	start := time.Now()
+	tasks, err := s.pendingTasks()
+	if err != nil {
+		log.Printf("Availability: error listing sources: %v", err)
+		return nil, err
+	}
+
+	summary := &AvailabilitySummary{NewlyUnavailable: []AvailabilityChange{}, Restored: []AvailabilityChange{}}
+	var summaryMu sync.Mutex
+	backoff := s.backoff()
+	queue := make(chan availabilityTask)
+	var wg sync.WaitGroup
+	for range s.concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range queue {
+				status, change := s.checkTask(ctx, backoff, task)
+				summaryMu.Lock()
+				switch status {
+				case "":
+					summary.Skipped++
+				case AvailabilityError:
+					summary.Checked++
+					summary.Errors++
+				case AvailabilityUnavailable:
+					summary.Checked++
+					if change != nil {
+						summary.NewlyUnavailable = append(summary.NewlyUnavailable, *change)
+					}
+				default:
+					summary.Checked++
+					if change != nil {
+						summary.Restored = append(summary.Restored, *change)
+					}
+				}
+				summaryMu.Unlock()
+			}
+		}()
+	}
+	for _, task := range tasks {
+		if ctx.Err() != nil {
+			break
+		}
+		queue <- task
+	}
+	close(queue)
+	wg.Wait()
+
+	summary.Duration = time.Since(start).Round(time.Second).String()
+	log.Printf("Availability: job done — %d checked, %d unavailable, %d restored, %d errors, %d skipped in %s",
+		summary.Checked, len(summary.NewlyUnavailable), len(summary.Restored), summary.Errors, summary.Skipped, summary.Duration)
+	s.notify(summary)
+	if deleted, err := s.prune(); err != nil {
+		log.Printf("Availability: error pruning check history: %v", err)
+	} else if deleted > 0 {
+		log.Printf("Availability: removed %d expired checks", deleted)
+	}
+	return summary, nil
+}
+
+// prune deletes checks older than the retention. A zero retention keeps
+// the history forever.
+func (s *AvailabilityService) prune() (int64, error) {
+	if s.retention <= 0 {
+		return 0, nil
+	}
+	result, err := s.db.Exec(`
+		DELETE FROM availability_checks WHERE checked_at < $1
+	`, time.Now().Add(-s.retention))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (s *AvailabilityService) pendingTasks() ([]availabilityTask, error) {
+	rows, err := s.db.Query(`
+		SELECT id, share_key, COALESCE(NULLIF(title, ''), filename), webpage_url, unavailable_at IS NOT NULL
+		FROM audio_files
+		WHERE deleted = 0 AND webpage_url IS NOT NULL AND webpage_url != ''
+		ORDER BY availability_checked_at ASC NULLS FIRST, id ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []availabilityTask
+	for rows.Next() {
+		var task availabilityTask
+		if err := rows.Scan(&task.id, &task.shareKey, &task.title, &task.webpageURL, &task.unavailable); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rows.Err()
+}
+
+// checkTask returns the recorded status, or "" when the host was skipped,
+// and the change to unavailable_at if there was one.
+func (s *AvailabilityService) checkTask(ctx context.Context, backoff *hostBackoff, task availabilityTask) (string, *AvailabilityChange) {
+	u, err := url.Parse(task.webpageURL)
+	if err != nil || u.Host == "" {
+		s.record(task, AvailabilityError, "invalid URL")
+		return AvailabilityError, nil
+	}
+	host := strings.ToLower(u.Hostname())
+	wait, ok := backoff.reserve(host)
+	if !ok {
+		return "", nil
+	}
+	select {
+	case <-time.After(wait):
+	case <-ctx.Done():
+		return "", nil
+	}
+
+	result, err := s.checker.Check(ctx, task.webpageURL)
+	backoff.record(host, err != nil)
+	if err != nil {
+		s.record(task, AvailabilityError, err.Error())
+		return AvailabilityError, nil
+	}
+
+	status := AvailabilityAvailable
+	if !result.Available {
+		status = AvailabilityUnavailable
+	}
+	changed := s.record(task, status, result.Reason)
+	if !changed {
+		return status, nil
+	}
+	return status, &AvailabilityChange{
+		ShareKey:   task.shareKey,
+		Title:      task.title,
+		WebpageURL: task.webpageURL,
+		Reason:     result.Reason,
+	}
+}
+
+// record stores a check and updates unavailable_at, reporting whether the
+// track's availability changed.
+func (s *AvailabilityService) record(task availabilityTask, status, detail string) bool {
+	tx, err := s.db.Begin()
+	if err != nil {
+		log.Printf("Availability: error recording check for %s: %v", task.shareKey, err)
+		return false
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		INSERT INTO availability_checks (audio_file_id, status, detail) VALUES ($1, $2, $3)
+	`, task.id, status, nullIfEmpty(detail)); err != nil {
+		log.Printf("Availability: error recording check for %s: %v", task.shareKey, err)
+		return false
+	}
+
+	changed := false
+	switch {
+	case status == AvailabilityUnavailable && !task.unavailable:
+		_, err = tx.Exec(`UPDATE audio_files SET unavailable_at = CURRENT_TIMESTAMP, availability_checked_at = CURRENT_TIMESTAMP WHERE id = $1`, task.id)
+		changed = true
+	case status == AvailabilityAvailable && task.unavailable:
+		_, err = tx.Exec(`UPDATE audio_files SET unavailable_at = NULL, availability_checked_at = CURRENT_TIMESTAMP WHERE id = $1`, task.id)
+		changed = true
+	default:
+		_, err = tx.Exec(`UPDATE audio_files SET availability_checked_at = CURRENT_TIMESTAMP WHERE id = $1`, task.id)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		log.Printf("Availability: error recording check for %s: %v", task.shareKey, err)
+		return false
+	}
+	return changed
+}
+
+func (s *AvailabilityService) notify(summary *AvailabilitySummary) {
+	if len(summary.NewlyUnavailable) == 0 && len(summary.Restored) == 0 {
+		return
+	}
+	if s.ntfy != nil && s.ntfy.IsConfigured() {
+		if err := s.ntfy.SendAvailabilityNotification(summary); err != nil {
+			log.Printf("Availability: error sending ntfy notification: %v", err)
+		}
+	}
+	if s.webhook != nil {
+		if err := s.webhook.SendAvailabilityComplete(summary); err != nil {
+			log.Printf("Availability: error sending webhook: %v", err)
+		}
+	}
+}
+
+// AvailabilityHistory lists the most recent checks of a track's source.
+func AvailabilityHistory(db *sql.DB, shareKey string, limit int) ([]AvailabilityCheck, error) {
+	rows, err := db.Query(`
+		SELECT ac.checked_at, ac.status, COALESCE(ac.detail, '')
+		FROM availability_checks ac
+		JOIN audio_files af ON af.id = ac.audio_file_id
+		WHERE af.share_key = $1
+		ORDER BY ac.checked_at DESC, ac.id DESC
+		LIMIT $2
+	`, shareKey, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	checks := []AvailabilityCheck{}
+	for rows.Next() {
+		var check AvailabilityCheck
+		var checkedAt time.Time
+		if err := rows.Scan(&checkedAt, &check.Status, &check.Detail); err != nil {
+			return nil, err
+		}
+		check.CheckedAt = checkedAt.UTC().Format(time.RFC3339)
+		checks = append(checks, check)
+	}
+	return checks, rows.Err()
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestHostBackoffSpacesAndBacksOffPerHost(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	backoff := newHostBackoff(time.Second, 8*time.Second)
+	backoff.now = func() time.Time { return now }
+
+	if wait, ok := backoff.reserve("a.example"); !ok || wait != 0 {
+		t.Fatalf("first reserve = %v, %v", wait, ok)
+	}
+	if wait, ok := backoff.reserve("a.example"); !ok || wait != time.Second {
+		t.Fatalf("second reserve = %v, %v", wait, ok)
+	}
+	if wait, ok := backoff.reserve("b.example"); !ok || wait != 0 {
+		t.Fatalf("other host reserve = %v, %v", wait, ok)
+	}
+
+	backoff.record("a.example", true)
+	backoff.record("a.example", true)
+	if wait, ok := backoff.reserve("a.example"); !ok || wait != 2*time.Second {
+		t.Fatalf("reserve after failures = %v, %v", wait, ok)
+	}
+	backoff.record("a.example", true)
+	if _, ok := backoff.reserve("a.example"); ok {
+		t.Fatal("expected the host to be skipped once its delay reaches the maximum")
+	}
+}
+
+func TestHTTPAvailabilityChecker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/gone":
+			w.WriteHeader(http.StatusGone)
+		case "/busy":
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "/get-only":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.WriteHeader(http.StatusPartialContent)
+		}
+	}))
+	defer server.Close()
+
+	checker := NewHTTPAvailabilityChecker(time.Second)
+	for path, want := range map[string]bool{"/ok": true, "/gone": false, "/get-only": true} {
+		result, err := checker.Check(context.Background(), server.URL+path)
+		if err != nil || result.Available != want {
+			t.Errorf("%s = %#v, %v; want available %v", path, result, err, want)
+		}
+	}
+	if _, err := checker.Check(context.Background(), server.URL+"/busy"); err == nil {
+		t.Error("expected rate limiting to be inconclusive")
+	}
+}
+
+func TestScriptAvailabilityChecker(t *testing.T) {
+	script := writeNormalizerTestScript(t, `
+import json, sys
+payload = json.load(sys.stdin)
+assert payload["action"] == "availability"
+if payload["url"].endswith("/error"):
+    print(json.dumps({"error": {"code": "rate_limited", "message": "Try later"}}))
+else:
+    print(json.dumps({"available": False, "reason": "Video removed by uploader"}))
+`)
+	checker := NewScriptAvailabilityChecker(script, time.Second)
+
+	result, err := checker.Check(context.Background(), "https://example.com/watch")
+	if err != nil || result.Available || result.Reason != "Video removed by uploader" {
+		t.Fatalf("result = %#v, %v", result, err)
+	}
+	if _, err := checker.Check(context.Background(), "https://example.com/error"); err == nil {
+		t.Fatal("expected the script error to be returned")
+	}
+}
+
+type availabilityStub map[string]availabilityStubResult
+
+type availabilityStubResult struct {
+	result AvailabilityResult
+	err    error
+}
+
+func (s availabilityStub) Check(_ context.Context, sourceURL string) (AvailabilityResult, error) {
+	return s[sourceURL].result, s[sourceURL].err
+}
+
+func TestAvailabilityJobSetsAndClearsUnavailable(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`ORDER BY availability_checked_at ASC NULLS FIRST`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "share_key", "title", "webpage_url", "unavailable"}).
+			AddRow(1, "gone-key", "Gone", "https://a.example/gone", false).
+			AddRow(2, "back-key", "Back", "https://b.example/back", true).
+			AddRow(3, "flaky-key", "Flaky", "https://c.example/flaky", false))
+	insert := regexp.QuoteMeta(`INSERT INTO availability_checks`)
+	mock.ExpectBegin()
+	mock.ExpectExec(insert).WithArgs(1, AvailabilityUnavailable, "removed").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`SET unavailable_at = CURRENT_TIMESTAMP`)).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(insert).WithArgs(2, AvailabilityAvailable, nil).WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`SET unavailable_at = NULL`)).WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(insert).WithArgs(3, AvailabilityError, "timeout").WillReturnResult(sqlmock.NewResult(3, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE audio_files SET availability_checked_at = CURRENT_TIMESTAMP WHERE id = $1`)).
+		WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM availability_checks WHERE checked_at < $1`)).
+		WithArgs(sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 4))
+
+	checker := availabilityStub{
+		"https://a.example/gone":  {result: AvailabilityResult{Reason: "removed"}},
+		"https://b.example/back":  {result: AvailabilityResult{Available: true}},
+		"https://c.example/flaky": {err: errors.New("timeout")},
+	}
+	service := NewAvailabilityService(db, checker, nil, nil, 1, 0, 30*24*time.Hour)
+	summary, err := service.RunJob(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Checked != 3 || summary.Errors != 1 || len(summary.NewlyUnavailable) != 1 || len(summary.Restored) != 1 ||
+		summary.NewlyUnavailable[0].ShareKey != "gone-key" || summary.Restored[0].ShareKey != "back-key" {
+		t.Fatalf("summary = %#v", summary)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type availabilityCutoff time.Time
+
+func (c availabilityCutoff) Match(v driver.Value) bool {
+	cutoff, ok := v.(time.Time)
+	return ok && cutoff.Sub(time.Time(c)).Abs() < time.Minute
+}
+
+func TestAvailabilityJobPrunesExpiredChecks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	retention := 30 * 24 * time.Hour
+	pending := `ORDER BY availability_checked_at ASC NULLS FIRST`
+	columns := []string{"id", "share_key", "title", "webpage_url", "unavailable"}
+	mock.ExpectQuery(pending).WillReturnRows(sqlmock.NewRows(columns))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM availability_checks WHERE checked_at < $1`)).
+		WithArgs(availabilityCutoff(time.Now().Add(-retention))).WillReturnResult(sqlmock.NewResult(0, 12))
+	if _, err := NewAvailabilityService(db, availabilityStub{}, nil, nil, 1, 0, retention).RunJob(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// A zero retention keeps the history forever.
+	mock.ExpectQuery(pending).WillReturnRows(sqlmock.NewRows(columns))
+	if _, err := NewAvailabilityService(db, availabilityStub{}, nil, nil, 1, 0, 0).RunJob(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
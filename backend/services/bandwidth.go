@@ -1,11 +1,14 @@
 package services
 
 import (
+	"log"
 	"math"
 	"sync"
 	"time"
 )
 
+const bandwidthBucketIdleTimeout = 10 * time.Minute
+
 type bandwidthClock interface {
 	Now() time.Time
 	Sleep(time.Duration)
@@ -21,21 +24,18 @@ func (realBandwidthClock) Sleep(duration time.Duration) {
 	time.Sleep(duration)
 }
 
+// BandwidthBackend holds the token buckets of an IPBandwidthLimiter. Take
+// refills the bucket for key and removes byteCount tokens when it holds
+// enough; otherwise it returns how long until it will.
+type BandwidthBackend interface {
+	Take(key string, byteCount, bytesPerSecond, burstBytes int64, now time.Time) (time.Duration, error)
+}
+
 type IPBandwidthLimiter struct {
 	bytesPerSecond int64
 	burstBytes     int64
 	clock          bandwidthClock
-
-	mu                sync.Mutex
-	buckets           map[string]*bandwidthBucket
-	waitsSinceCleanup int
-}
-
-type bandwidthBucket struct {
-	mu       sync.Mutex
-	tokens   float64
-	last     time.Time
-	lastUsed time.Time
+	backend        BandwidthBackend
 }
 
 func NewIPBandwidthLimiter(bytesPerSecond, burstBytes int64) *IPBandwidthLimiter {
@@ -50,81 +50,122 @@ func newIPBandwidthLimiter(bytesPerSecond, burstBytes int64, clock bandwidthCloc
 		bytesPerSecond: bytesPerSecond,
 		burstBytes:     burstBytes,
 		clock:          clock,
-		buckets:        make(map[string]*bandwidthBucket),
+		backend:        NewMemoryBandwidthBackend(),
 	}
 }
 
+// SetBackend replaces the local token buckets, for example with buckets
+// shared by every instance.
+func (l *IPBandwidthLimiter) SetBackend(backend BandwidthBackend) {
+	l.backend = backend
+}
+
 func (l *IPBandwidthLimiter) Wait(ip string, byteCount int) {
 	if l == nil || l.bytesPerSecond <= 0 || byteCount <= 0 {
 		return
 	}
-	bucket := l.bucket(ip)
 	remaining := int64(byteCount)
 	for remaining > 0 {
 		chunk := min(remaining, l.burstBytes)
-		l.waitChunk(bucket, chunk)
+		if !l.waitChunk(ip, chunk) {
+			return
+		}
 		remaining -= chunk
 	}
 }
 
-func (l *IPBandwidthLimiter) bucket(ip string) *bandwidthBucket {
-	now := l.clock.Now()
-
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	if bucket, ok := l.buckets[ip]; ok {
-		return bucket
-	}
-	bucket := &bandwidthBucket{
-		tokens:   float64(l.burstBytes),
-		last:     now,
-		lastUsed: now,
-	}
-	l.buckets[ip] = bucket
-	return bucket
-}
-
-func (l *IPBandwidthLimiter) waitChunk(bucket *bandwidthBucket, byteCount int64) {
+func (l *IPBandwidthLimiter) waitChunk(ip string, byteCount int64) bool {
 	for {
-		now := l.clock.Now()
-		bucket.mu.Lock()
-		elapsed := now.Sub(bucket.last).Seconds()
-		if elapsed > 0 {
-			bucket.tokens = math.Min(
-				float64(l.burstBytes),
-				bucket.tokens+elapsed*float64(l.bytesPerSecond),
-			)
-			bucket.last = now
+		wait, err := l.backend.Take(ip, byteCount, l.bytesPerSecond, l.burstBytes, l.clock.Now())
+		if err != nil {
+			log.Printf("Error taking IP bandwidth allowance: %v", err)
+			return false
 		}
-		bucket.lastUsed = now
-		if bucket.tokens >= float64(byteCount) {
-			bucket.tokens -= float64(byteCount)
-			bucket.mu.Unlock()
-			l.maybeCleanup(now)
-			return
+		if wait <= 0 {
+			return true
 		}
-		missing := float64(byteCount) - bucket.tokens
-		bucket.mu.Unlock()
-
-		wait := time.Duration(math.Ceil(missing / float64(l.bytesPerSecond) * float64(time.Second)))
 		l.clock.Sleep(wait)
 	}
 }
 
-func (l *IPBandwidthLimiter) maybeCleanup(now time.Time) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	l.waitsSinceCleanup++
-	if l.waitsSinceCleanup < 256 {
-		return
+// MemoryBandwidthBackend keeps token buckets local to one instance.
+type MemoryBandwidthBackend struct {
+	mu                sync.Mutex
+	buckets           map[string]*bandwidthBucket
+	takesSinceCleanup int
+}
+
+type bandwidthBucket struct {
+	tokens   float64
+	last     time.Time
+	lastUsed time.Time
+}
+
+func NewMemoryBandwidthBackend() *MemoryBandwidthBackend {
+	return &MemoryBandwidthBackend{buckets: make(map[string]*bandwidthBucket)}
+}
+
+func (b *MemoryBandwidthBackend) Take(
+	key string,
+	byteCount int64,
+	bytesPerSecond int64,
+	burstBytes int64,
+	now time.Time,
+) (time.Duration, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	bucket, ok := b.buckets[key]
+	if !ok {
+		bucket = &bandwidthBucket{tokens: float64(burstBytes), last: now}
+		b.buckets[key] = bucket
+	}
+	var wait time.Duration
+	bucket.tokens, bucket.last, wait = takeBandwidthTokens(
+		bucket.tokens,
+		bucket.last,
+		byteCount,
+		bytesPerSecond,
+		burstBytes,
+		now,
+	)
+	bucket.lastUsed = now
+	if wait > 0 {
+		return wait, nil
 	}
-	l.waitsSinceCleanup = 0
-	for ip, bucket := range l.buckets {
-		bucket.mu.Lock()
-		inactive := now.Sub(bucket.lastUsed) > 10*time.Minute
-		bucket.mu.Unlock()
-		if inactive {
-			delete(l.buckets, ip)
+
+	b.takesSinceCleanup++
+	if b.takesSinceCleanup >= 256 {
+		b.takesSinceCleanup = 0
+		for key, bucket := range b.buckets {
+			if now.Sub(bucket.lastUsed) > bandwidthBucketIdleTimeout {
+				delete(b.buckets, key)
+			}
 		}
 	}
+	return 0, nil
+}
+
+// takeBandwidthTokens refills a bucket up to burstBytes for the time since
+// last and takes byteCount from it. When the bucket is short it is only
+// refilled, and the wait until it holds enough is returned.
+func takeBandwidthTokens(
+	tokens float64,
+	last time.Time,
+	byteCount int64,
+	bytesPerSecond int64,
+	burstBytes int64,
+	now time.Time,
+) (float64, time.Time, time.Duration) {
+	elapsed := now.Sub(last).Seconds()
+	if elapsed > 0 {
+		tokens = math.Min(float64(burstBytes), tokens+elapsed*float64(bytesPerSecond))
+		last = now
+	}
+	if tokens >= float64(byteCount) {
+		return tokens - float64(byteCount), last, 0
+	}
+	missing := float64(byteCount) - tokens
+	wait := time.Duration(math.Ceil(missing / float64(bytesPerSecond) * float64(time.Second)))
+	return tokens, last, wait
 }
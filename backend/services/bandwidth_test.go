@@ -75,3 +75,18 @@ func (c *fakeBandwidthClock) TotalSleep() time.Duration {
 	defer c.mu.Unlock()
 	return c.slept
 }
+
+func TestIPBandwidthLimitersSharingABackendEnforceOneBudget(t *testing.T) {
+	clock := &fakeBandwidthClock{now: time.Unix(0, 0)}
+	backend := NewMemoryBandwidthBackend()
+	first := newIPBandwidthLimiter(100, 100, clock)
+	second := newIPBandwidthLimiter(100, 100, clock)
+	first.SetBackend(backend)
+	second.SetBackend(backend)
+
+	first.Wait("192.0.2.1", 100)
+	second.Wait("192.0.2.1", 50)
+	if clock.TotalSleep() != 500*time.Millisecond {
+		t.Fatalf("second instance slept for %v, want 500ms", clock.TotalSleep())
+	}
+}
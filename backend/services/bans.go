@@ -0,0 +1,177 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"net"
+	"strings"
+	"time"
+)
+
+// Ban kinds. An IP ban matches one address, a CIDR ban a range of them and
+// a session ban one browser session.
+const (
+	BanKindIP      = "ip"
+	BanKindCIDR    = "cidr"
+	BanKindSession = "session"
+)
+
+// Ban modes. A downloads ban only blocks fetching audio files; an all ban
+// blocks every request apart from the admin API.
+const (
+	BanModeDownloads = "downloads"
+	BanModeAll       = "all"
+)
+
+const (
+	maxBanValueLen  = 128
+	maxBanReasonLen = 500
+)
+
+var (
+	ErrInvalidBanKind  = errors.New("invalid ban kind")
+	ErrInvalidBanValue = errors.New("invalid ban value")
+	ErrInvalidBanMode  = errors.New("invalid ban mode")
+	ErrBanNotFound     = errors.New("ban not found")
+)
+
+type Ban struct {
+	ID        int64   `json:"id"`
+	Kind      string  `json:"kind"`
+	Value     string  `json:"value"`
+	Mode      string  `json:"mode"`
+	Reason    string  `json:"reason"`
+	CreatedBy string  `json:"createdBy"`
+	CreatedAt string  `json:"createdAt"`
+	ExpiresAt *string `json:"expiresAt"`
+	LiftedAt  *string `json:"liftedAt,omitempty"`
+}
+
+// NormalizeBanTarget validates a ban's kind and value and returns the value
+// in canonical form, so "2001:DB8::1" and "2001:db8::1" are the same ban and
+// a CIDR is stored as its network address.
+func NormalizeBanTarget(kind, value string) (string, error) {
+	value = strings.TrimSpace(value)
+	switch kind {
+	case BanKindIP:
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return "", ErrInvalidBanValue
+		}
+		return ip.String(), nil
+	case BanKindCIDR:
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return "", ErrInvalidBanValue
+		}
+		return network.String(), nil
+	case BanKindSession:
+		if value == "" || len(value) > maxBanValueLen {
+			return "", ErrInvalidBanValue
+		}
+		return value, nil
+	default:
+		return "", ErrInvalidBanKind
+	}
+}
+
+// BanService stores the ban list. Bans are lifted rather than deleted so the
+// history of who was banned, and why, is kept.
+type BanService struct {
+	db *Database
+}
+
+func NewBanService(db *Database) *BanService {
+	return &BanService{db: db}
+}
+
+func (s *BanService) Create(kind, value, mode, reason, createdBy string, expiresAt *time.Time) (*Ban, error) {
+	value, err := NormalizeBanTarget(kind, value)
+	if err != nil {
+		return nil, err
+	}
+	if mode != BanModeDownloads && mode != BanModeAll {
+		return nil, ErrInvalidBanMode
+	}
+	reason = strings.TrimSpace(reason)
+	if len(reason) > maxBanReasonLen {
+		reason = reason[:maxBanReasonLen]
+	}
+
+	ban := &Ban{Kind: kind, Value: value, Mode: mode, Reason: reason, CreatedBy: createdBy}
+	var expires any
+	if expiresAt != nil {
+		expires = expiresAt.UTC()
+		formatted := expiresAt.UTC().Format(time.RFC3339)
+		ban.ExpiresAt = &formatted
+	}
+
+	var createdAt time.Time
+	err = s.db.DB().QueryRow(`
+		INSERT INTO bans (kind, value, mode, reason, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, kind, value, mode, reason, createdBy, expires).Scan(&ban.ID, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+	ban.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+	return ban, nil
+}
+
+// List returns bans newest first. Unless includeInactive is set, lifted and
+// expired bans are left out.
+func (s *BanService) List(includeInactive bool) ([]Ban, error) {
+	query := `
+		SELECT id, kind, value, mode, reason, created_by, created_at, expires_at, lifted_at
+		FROM bans`
+	if !includeInactive {
+		query += `
+		WHERE lifted_at IS NULL AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)`
+	}
+	rows, err := s.db.DB().Query(query + `
+		ORDER BY created_at DESC, id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bans := make([]Ban, 0)
+	for rows.Next() {
+		var ban Ban
+		var createdAt time.Time
+		var expiresAt, liftedAt sql.NullTime
+		if err := rows.Scan(&ban.ID, &ban.Kind, &ban.Value, &ban.Mode, &ban.Reason, &ban.CreatedBy,
+			&createdAt, &expiresAt, &liftedAt); err != nil {
+			return nil, err
+		}
+		ban.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+		ban.ExpiresAt = formatNullTime(expiresAt)
+		ban.LiftedAt = formatNullTime(liftedAt)
+		bans = append(bans, ban)
+	}
+	return bans, rows.Err()
+}
+
+// Lift ends a ban before it expires.
+func (s *BanService) Lift(id int64) (*Ban, error) {
+	var ban Ban
+	var createdAt, liftedAt time.Time
+	var expiresAt sql.NullTime
+	err := s.db.DB().QueryRow(`
+		UPDATE bans SET lifted_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND lifted_at IS NULL
+		RETURNING id, kind, value, mode, reason, created_by, created_at, expires_at, lifted_at
+	`, id).Scan(&ban.ID, &ban.Kind, &ban.Value, &ban.Mode, &ban.Reason, &ban.CreatedBy,
+		&createdAt, &expiresAt, &liftedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrBanNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	ban.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+	ban.ExpiresAt = formatNullTime(expiresAt)
+	ban.LiftedAt = formatNullTime(sql.NullTime{Time: liftedAt, Valid: true})
+	return &ban, nil
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestNormalizeBanTarget(t *testing.T) {
+	tests := []struct {
+		kind, value, want string
+		err               error
+	}{
+		{BanKindIP, " 2001:DB8::1 ", "2001:db8::1", nil},
+		{BanKindIP, "192.0.2.300", "", ErrInvalidBanValue},
+		{BanKindCIDR, "198.51.100.7/24", "198.51.100.0/24", nil},
+		{BanKindCIDR, "198.51.100.7", "", ErrInvalidBanValue},
+		{BanKindSession, "abc123", "abc123", nil},
+		{BanKindSession, " ", "", ErrInvalidBanValue},
+		{"user", "abc123", "", ErrInvalidBanKind},
+	}
+	for _, test := range tests {
+		got, err := NormalizeBanTarget(test.kind, test.value)
+		if got != test.want || !errors.Is(err, test.err) {
+			t.Errorf("NormalizeBanTarget(%q, %q) = %q, %v", test.kind, test.value, got, err)
+		}
+	}
+}
+
+func TestBanCreateStoresCanonicalValue(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	expires := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO bans`)).
+		WithArgs(BanKindCIDR, "198.51.100.0/24", BanModeDownloads, "scraping", "triage", expires).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(5, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)))
+
+	service := NewBanService(&Database{db: db})
+	ban, err := service.Create(BanKindCIDR, "198.51.100.9/24", BanModeDownloads, " scraping ", "triage", &expires)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ban.ID != 5 || ban.ExpiresAt == nil || *ban.ExpiresAt != "2026-04-01T00:00:00Z" {
+		t.Fatalf("ban = %#v", ban)
+	}
+	if _, err := service.Create(BanKindIP, "192.0.2.1", "forever", "", "triage", nil); !errors.Is(err, ErrInvalidBanMode) {
+		t.Fatalf("invalid mode err = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBanListActiveOnlyAndLift(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	created := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	columns := []string{"id", "kind", "value", "mode", "reason", "created_by", "created_at", "expires_at", "lifted_at"}
+	mock.ExpectQuery(`(?s)FROM bans\s+WHERE lifted_at IS NULL AND \(expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP\)`).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(5, "ip", "192.0.2.1", "all", "", "default", created, nil, nil))
+	mock.ExpectQuery(regexp.QuoteMeta(`UPDATE bans SET lifted_at = CURRENT_TIMESTAMP`)).WithArgs(int64(5)).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(5, "ip", "192.0.2.1", "all", "", "default", created, nil, created.Add(time.Hour)))
+	mock.ExpectQuery(regexp.QuoteMeta(`UPDATE bans SET lifted_at = CURRENT_TIMESTAMP`)).WithArgs(int64(5)).
+		WillReturnRows(sqlmock.NewRows(columns))
+
+	service := NewBanService(&Database{db: db})
+	bans, err := service.List(false)
+	if err != nil || len(bans) != 1 || bans[0].Value != "192.0.2.1" {
+		t.Fatalf("bans = %#v, %v", bans, err)
+	}
+	ban, err := service.Lift(5)
+	if err != nil || ban.LiftedAt == nil || *ban.LiftedAt != "2026-03-01T01:00:00Z" {
+		t.Fatalf("lifted = %#v, %v", ban, err)
+	}
+	if _, err := service.Lift(5); !errors.Is(err, ErrBanNotFound) {
+		t.Fatalf("second lift err = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
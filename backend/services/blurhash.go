@@ -0,0 +1,247 @@
+package services
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+const (
+	blurhashComponentsX = 4
+	blurhashComponentsY = 3
+	// placeholderSampleSize is the longest side artwork is reduced to before
+	// hashing and colour sampling. Placeholders carry no detail beyond it.
+	placeholderSampleSize = 32
+	blurhashCharacters    = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+)
+
+// placeholderSample scales img down to at most placeholderSampleSize pixels
+// on its longest side.
+func placeholderSample(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > height {
+		height = max(1, height*placeholderSampleSize/width)
+		width = placeholderSampleSize
+	} else {
+		width = max(1, width*placeholderSampleSize/max(height, 1))
+		height = placeholderSampleSize
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	xdraw.ApproxBiLinear.Scale(dst, dst.Bounds(), img, bounds, xdraw.Src, nil)
+	return dst
+}
+
+// blurSample box-blurs a sample in place until only its broad colour fields
+// remain, so the hash of mature artwork is no more revealing than the
+// blurred thumbnail.
+func blurSample(img *image.RGBA, radius, passes int) {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	buffer := make([]uint8, len(img.Pix))
+	for range passes {
+		for _, horizontal := range []bool{true, false} {
+			for y := range height {
+				for x := range width {
+					var sum [4]int
+					count := 0
+					for d := -radius; d <= radius; d++ {
+						sx, sy := x, y
+						if horizontal {
+							sx = min(max(x+d, 0), width-1)
+						} else {
+							sy = min(max(y+d, 0), height-1)
+						}
+						offset := sy*img.Stride + sx*4
+						for c := range 4 {
+							sum[c] += int(img.Pix[offset+c])
+						}
+						count++
+					}
+					offset := y*img.Stride + x*4
+					for c := range 4 {
+						buffer[offset+c] = uint8(sum[c] / count)
+					}
+				}
+			}
+			copy(img.Pix, buffer)
+		}
+	}
+}
+
+// encodeBlurhash implements the blurhash.sh encoding with the given number of
+// horizontal and vertical components.
+func encodeBlurhash(img *image.RGBA, componentsX, componentsY int) string {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	linear := make([][3]float64, width*height)
+	for y := range height {
+		for x := range width {
+			c := img.RGBAAt(bounds.Min.X+x, bounds.Min.Y+y)
+			linear[y*width+x] = [3]float64{srgbToLinear(c.R), srgbToLinear(c.G), srgbToLinear(c.B)}
+		}
+	}
+
+	factors := make([][3]float64, 0, componentsX*componentsY)
+	for j := range componentsY {
+		for i := range componentsX {
+			normalisation := 2.0
+			if i == 0 && j == 0 {
+				normalisation = 1
+			}
+			var factor [3]float64
+			for y := range height {
+				for x := range width {
+					basis := normalisation *
+						math.Cos(math.Pi*float64(i)*float64(x)/float64(width)) *
+						math.Cos(math.Pi*float64(j)*float64(y)/float64(height))
+					pixel := linear[y*width+x]
+					factor[0] += basis * pixel[0]
+					factor[1] += basis * pixel[1]
+					factor[2] += basis * pixel[2]
+				}
+			}
+			scale := 1 / float64(width*height)
+			factors = append(factors, [3]float64{factor[0] * scale, factor[1] * scale, factor[2] * scale})
+		}
+	}
+
+	var hash strings.Builder
+	hash.WriteString(encodeBase83((componentsX-1)+(componentsY-1)*9, 1))
+
+	dc, ac := factors[0], factors[1:]
+	maximumValue := 1.0
+	if len(ac) > 0 {
+		actualMaximum := 0.0
+		for _, factor := range ac {
+			actualMaximum = max(actualMaximum, math.Abs(factor[0]), math.Abs(factor[1]), math.Abs(factor[2]))
+		}
+		quantisedMaximum := int(math.Max(0, math.Min(82, math.Floor(actualMaximum*166-0.5))))
+		maximumValue = float64(quantisedMaximum+1) / 166
+		hash.WriteString(encodeBase83(quantisedMaximum, 1))
+	} else {
+		hash.WriteString(encodeBase83(0, 1))
+	}
+
+	hash.WriteString(encodeBase83(linearToSRGB(dc[0])<<16|linearToSRGB(dc[1])<<8|linearToSRGB(dc[2]), 4))
+	for _, factor := range ac {
+		quantise := func(value float64) int {
+			return int(math.Max(0, math.Min(18, math.Floor(signPow(value/maximumValue, 0.5)*9+9.5))))
+		}
+		hash.WriteString(encodeBase83(quantise(factor[0])*19*19+quantise(factor[1])*19+quantise(factor[2]), 2))
+	}
+	return hash.String()
+}
+
+func encodeBase83(value, length int) string {
+	out := make([]byte, length)
+	for i := range length {
+		digit := (value / int(math.Pow(83, float64(length-i-1)))) % 83
+		out[i] = blurhashCharacters[digit]
+	}
+	return string(out)
+}
+
+func srgbToLinear(value uint8) float64 {
+	v := float64(value) / 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(value float64) int {
+	v := math.Max(0, math.Min(1, value))
+	if v <= 0.0031308 {
+		return int(v*12.92*255 + 0.5)
+	}
+	return int((1.055*math.Pow(v, 1/2.4)-0.055)*255 + 0.5)
+}
+
+func signPow(value, exp float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(value), exp), value)
+}
+
+// artworkColours picks the dominant colour, the average of the most common
+// colour bucket, and an accent, the most saturated bucket covering at least
+// 5% of the image that is clearly distinct from the dominant colour. The
+// accent is empty when no such bucket exists.
+func artworkColours(img *image.RGBA) (string, string) {
+	type bucket struct {
+		count   int
+		r, g, b int
+	}
+	var buckets [512]bucket
+	total := 0
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := img.RGBAAt(x, y)
+			if c.A < 128 {
+				continue
+			}
+			b := &buckets[int(c.R>>5)<<6|int(c.G>>5)<<3|int(c.B>>5)]
+			b.count++
+			b.r += int(c.R)
+			b.g += int(c.G)
+			b.b += int(c.B)
+			total++
+		}
+	}
+	if total == 0 {
+		return "", ""
+	}
+	average := func(b bucket) color.RGBA {
+		return color.RGBA{R: uint8(b.r / b.count), G: uint8(b.g / b.count), B: uint8(b.b / b.count), A: 255}
+	}
+
+	dominantIndex := 0
+	for i, b := range buckets {
+		if b.count > buckets[dominantIndex].count {
+			dominantIndex = i
+		}
+	}
+	dominant := average(buckets[dominantIndex])
+
+	accent := ""
+	bestSaturation := 0.25
+	for i, b := range buckets {
+		if i == dominantIndex || b.count*20 < total {
+			continue
+		}
+		candidate := average(b)
+		if colourDistance(candidate, dominant) < 64 {
+			continue
+		}
+		if saturation := colourSaturation(candidate); saturation > bestSaturation {
+			bestSaturation = saturation
+			accent = hexColour(candidate)
+		}
+	}
+	return hexColour(dominant), accent
+}
+
+func colourDistance(a, b color.RGBA) float64 {
+	dr := float64(a.R) - float64(b.R)
+	dg := float64(a.G) - float64(b.G)
+	db := float64(a.B) - float64(b.B)
+	return math.Sqrt(dr*dr + dg*dg + db*db)
+}
+
+// colourSaturation is the HSV saturation of c.
+func colourSaturation(c color.RGBA) float64 {
+	highest := max(c.R, c.G, c.B)
+	if highest == 0 {
+		return 0
+	}
+	lowest := min(c.R, c.G, c.B)
+	return float64(highest-lowest) / float64(highest)
+}
+
+func hexColour(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
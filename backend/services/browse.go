@@ -2,11 +2,14 @@ package services
 
 import (
 	"database/sql"
+	"fmt"
+	"strings"
 	"time"
 )
 
-func (s *SearchService) GetAllFolderPaths() ([]string, error) {
-	rows, err := s.db.DB().Query(`SELECT path FROM folders ORDER BY path ASC`)
+// GetPublicFolderPaths lists every folder not marked mature in folder.json.
+func (s *SearchService) GetPublicFolderPaths() ([]string, error) {
+	rows, err := s.db.DB().Query(`SELECT path FROM folders WHERE COALESCE(age_limit, 0) < 18 AND removal_requested_at IS NULL ORDER BY path ASC`)
 	if err != nil {
 		return nil, err
 	}
@@ -23,15 +26,122 @@ func (s *SearchService) GetAllFolderPaths() ([]string, error) {
 	return paths, nil
 }
 
-func (s *SearchService) BrowseDirectory(path string) (*DirectoryContents, error) {
-	folders, err := s.getFoldersByParentPath(path)
-	if err != nil {
-		return nil, err
+// BrowseOptions narrows and orders a directory listing. Folders are always
+// listed before audio files; paging applies to the combined listing.
+type BrowseOptions struct {
+	// "name_asc", "name_desc", "date_asc", "date_desc", "duration_asc",
+	// "duration_desc", "size_asc", "size_desc", "popularity_asc",
+	// "popularity_desc", or "" for the folder.json sort_order and
+	// track_order, then folders by name and audio newest first.
+	Sort string
+	// "audio", "folder", or "" (both)
+	Type string
+	// Case-insensitive text matched literally against names within the
+	// directory only.
+	Filter string
+	// Zero means no limit.
+	Limit  int
+	Offset int
+	// Include audio files and folders hidden from public discovery after a creator removal request.
+	IncludeRemovalRequested bool
+}
+
+var browseSortDirections = map[string]string{"asc": "ASC", "desc": "DESC"}
+
+// browsePlayCounts counts plays per audio file in a single pass over
+// play_events.
+const browsePlayCounts = `SELECT audio_file_id, COUNT(*) AS play_count FROM play_events GROUP BY audio_file_id`
+
+// browseFolderPlayCounts sums the plays of every track beneath each folder
+// in the listed directory. It is joined once as fp rather than counted per
+// folder row.
+const browseFolderPlayCounts = `LEFT JOIN (
+			SELECT sibling.id AS folder_id, SUM(pc.play_count) AS play_count
+			FROM folders sibling
+			JOIN audio_files child ON starts_with(child.path, sibling.path || '/')
+			JOIN (` + browsePlayCounts + `) pc ON pc.audio_file_id = child.id
+			WHERE sibling.parent_path = $1
+			GROUP BY sibling.id
+		) fp ON fp.folder_id = folders.id`
+
+// browseOrderClauses returns the ORDER BY expressions for the folder and
+// audio queries. Folder popularity counts plays of every track beneath it.
+func browseOrderClauses(sort string) (string, string) {
+	field, direction, _ := strings.Cut(sort, "_")
+	dir, ok := browseSortDirections[direction]
+	if !ok {
+		return browseDefaultOrder()
+	}
+	switch field {
+	case "name":
+		return "name " + dir, "COALESCE(NULLIF(audio_files.title, ''), audio_files.filename) " + dir
+	case "date":
+		return "upload_date " + dir + " NULLS LAST, name ASC",
+			"audio_files.upload_date " + dir + " NULLS LAST"
+	case "duration":
+		return "name ASC", "wc.duration_seconds " + dir + " NULLS LAST"
+	case "size":
+		return "directory_size_bytes " + dir + ", name ASC", "audio_files.size " + dir
+	case "popularity":
+		return "COALESCE(fp.play_count, 0) " + dir + ", name ASC",
+			"COALESCE(pc.play_count, 0) " + dir
 	}
+	return browseDefaultOrder()
+}
 
-	audioFiles, err := s.getAudioFilesByParentPath(path)
-	if err != nil {
-		return nil, err
+// browseDefaultOrder lists entries with an explicit position from folder.json
+// first, in that order.
+func browseDefaultOrder() (string, string) {
+	return "sort_order ASC NULLS LAST, name ASC",
+		"audio_files.sort_position ASC NULLS LAST, audio_files.upload_date DESC"
+}
+
+// browsePage is the LIMIT and OFFSET pushed into a listing query. The zero
+// value returns every row.
+type browsePage struct {
+	limit  int
+	offset int
+}
+
+// clause appends the page to args and returns the matching SQL.
+func (p browsePage) clause(args []any) ([]any, string) {
+	clause := ""
+	if p.limit > 0 {
+		args = append(args, p.limit)
+		clause += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if p.offset > 0 {
+		args = append(args, p.offset)
+		clause += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+	return args, clause
+}
+
+func (s *SearchService) BrowseDirectory(path string, opts BrowseOptions) (*DirectoryContents, error) {
+	var folders []FolderRecord
+	var audioFiles []AudioFileRecord
+	var folderTotal, audioTotal int
+	var err error
+
+	// A listing of one type is paged by the database. A combined listing is
+	// paged below, since its audio page depends on how many folders match.
+	var page browsePage
+	if opts.Type == "folder" || opts.Type == "audio" {
+		page = browsePage{limit: max(opts.Limit, 0), offset: max(opts.Offset, 0)}
+	}
+
+	if opts.Type != "audio" {
+		folders, folderTotal, err = s.getFoldersByParentPath(path, opts, page)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.Type != "folder" {
+		audioFiles, audioTotal, err = s.getAudioFilesByParentPath(path, opts, page)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	items := make([]FileSystemItem, 0, len(folders)+len(audioFiles))
@@ -43,75 +153,163 @@ func (s *SearchService) BrowseDirectory(path string) (*DirectoryContents, error)
 		items = append(items, s.audioToFileSystemItem(a))
 	}
 
+	total := folderTotal + audioTotal
+	offset := min(max(opts.Offset, 0), total)
+	if page == (browsePage{}) {
+		items = items[offset:]
+		if opts.Limit > 0 && len(items) > opts.Limit {
+			items = items[:opts.Limit]
+		}
+	}
+
 	return &DirectoryContents{
 		Items:       items,
 		CurrentPath: path,
+		Total:       total,
+		Offset:      offset,
+		Limit:       max(opts.Limit, 0),
 	}, nil
 }
 
-func (s *SearchService) getFoldersByParentPath(parentPath string) ([]FolderRecord, error) {
-	rows, err := s.db.DB().Query(`
+// countBrowseRows counts the rows of table matching where, for a page that
+// starts past the last row and so carries no window count.
+func (s *SearchService) countBrowseRows(table, where string, args []any) (int, error) {
+	var total int
+	err := s.db.DB().QueryRow(fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE %s`, table, where), args...).Scan(&total)
+	return total, err
+}
+
+// getFoldersByParentPath returns one page of the folders in parentPath and
+// how many match in total.
+func (s *SearchService) getFoldersByParentPath(parentPath string, opts BrowseOptions, page browsePage) ([]FolderRecord, int, error) {
+	args := []any{parentPath}
+	where := "parent_path = $1"
+	if opts.Filter != "" {
+		args = append(args, "%"+likeEscape(opts.Filter)+"%")
+		where += " AND (name ILIKE $2 OR folder_name ILIKE $2 OR aliases ILIKE $2)"
+	}
+	if !opts.IncludeRemovalRequested {
+		where += " AND removal_requested_at IS NULL"
+	}
+	orderClause, _ := browseOrderClauses(opts.Sort)
+	playJoin := ""
+	if strings.HasPrefix(opts.Sort, "popularity_") {
+		playJoin = browseFolderPlayCounts
+	}
+	whereArgs := args
+	args, pageClause := page.clause(args)
+
+	rows, err := s.db.DB().Query(fmt.Sprintf(`
 		SELECT id, path, parent_path, folder_name, name, original_url,
 		       url_broken, item_count, directory_size_bytes, poster_image,
-		       upload_date, share_key
+		       upload_date, share_key, %s, description, tags,
+		       aliases, age_limit, sort_order, COUNT(*) OVER()
 		FROM folders
-		WHERE parent_path = $1
-		ORDER BY name ASC
-	`, parentPath)
+		%s
+		WHERE %s
+		ORDER BY %s%s
+	`, PosterPlaceholderColumn("folders"), playJoin, where, orderClause, pageClause), args...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer rows.Close()
 
 	var folders []FolderRecord
+	total := 0
 	for rows.Next() {
 		var f FolderRecord
 		var urlBroken int
-		var shareKey *string
+		var shareKey, description, tags, aliases sql.NullString
+		var ageLimit, sortOrder sql.NullInt64
 		if err := rows.Scan(&f.ID, &f.Path, &f.ParentPath, &f.FolderName, &f.Name,
 			&f.OriginalURL, &urlBroken, &f.ItemCount, &f.DirectorySize,
-			&f.PosterImage, &f.UploadDate, &shareKey); err != nil {
-			return nil, err
+			&f.PosterImage, &f.UploadDate, &shareKey, &f.Placeholder, &description,
+			&tags, &aliases, &ageLimit, &sortOrder, &total); err != nil {
+			return nil, 0, err
 		}
 		f.URLBroken = urlBroken == 1
-		if shareKey != nil {
-			f.ShareKey = *shareKey
+		f.ShareKey = shareKey.String
+		f.Description = description.String
+		f.Tags = splitMetadataList(tags.String)
+		f.Aliases = splitMetadataList(aliases.String)
+		if ageLimit.Valid {
+			v := int(ageLimit.Int64)
+			f.AgeLimit = &v
+		}
+		if sortOrder.Valid {
+			v := int(sortOrder.Int64)
+			f.SortOrder = &v
 		}
 		folders = append(folders, f)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	if len(folders) == 0 && page.offset > 0 {
+		total, err = s.countBrowseRows("folders", where, whereArgs)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
 
-	return folders, nil
+	return folders, total, nil
 }
 
-func (s *SearchService) getAudioFilesByParentPath(parentPath string) ([]AudioFileRecord, error) {
-	rows, err := s.db.DB().Query(`
+// getAudioFilesByParentPath returns one page of the audio files in
+// parentPath and how many match in total.
+func (s *SearchService) getAudioFilesByParentPath(parentPath string, opts BrowseOptions, page browsePage) ([]AudioFileRecord, int, error) {
+	args := []any{parentPath}
+	where := "audio_files.parent_path = $1 AND audio_files.deleted = 0"
+	if !opts.IncludeRemovalRequested {
+		where += " AND audio_files.removal_requested_at IS NULL"
+	}
+	if opts.Filter != "" {
+		args = append(args, "%"+likeEscape(opts.Filter)+"%")
+		where += " AND (audio_files.filename ILIKE $2 OR audio_files.title ILIKE $2 OR audio_files.meta_artist ILIKE $2)"
+	}
+	_, orderClause := browseOrderClauses(opts.Sort)
+	playJoin := ""
+	if strings.HasPrefix(opts.Sort, "popularity_") {
+		playJoin = `LEFT JOIN (` + browsePlayCounts + `) pc ON pc.audio_file_id = audio_files.id`
+	}
+	whereArgs := args
+	args, pageClause := page.clause(args)
+
+	rows, err := s.db.DB().Query(fmt.Sprintf(`
 		SELECT audio_files.id, audio_files.path, audio_files.parent_path, audio_files.filename,
 		       audio_files.size, audio_files.mime_type, audio_files.title, audio_files.meta_artist,
 		       audio_files.upload_date, audio_files.webpage_url, audio_files.description, audio_files.age_limit,
 		       audio_files.share_key, audio_files.unavailable_at, audio_files.removal_requested_at,
-		       wc.duration_seconds
+		       wc.duration_seconds, audio_files.sort_position, %s, COUNT(*) OVER()
 		FROM audio_files
 		LEFT JOIN waveform_cache wc ON wc.audio_file_id = audio_files.id
-		WHERE audio_files.parent_path = $1 AND audio_files.deleted = 0
-		ORDER BY audio_files.upload_date DESC
-	`, parentPath)
+		%s
+		WHERE %s
+		ORDER BY %s, audio_files.id ASC%s
+	`, ThumbnailPlaceholderColumn("audio_files"), playJoin, where, orderClause, pageClause), args...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer rows.Close()
 
 	var audioFiles []AudioFileRecord
+	total := 0
 	for rows.Next() {
 		var a AudioFileRecord
 		var unavailableAt sql.NullTime
 		var removalRequestedAt sql.NullTime
 		var ageLimit sql.NullInt64
 		var durationSeconds sql.NullFloat64
+		var sortPosition sql.NullInt64
 		if err := rows.Scan(&a.ID, &a.Path, &a.ParentPath, &a.Filename, &a.Size,
 			&a.MimeType, &a.Title, &a.MetaArtist, &a.UploadDate,
 			&a.WebpageURL, &a.Description, &ageLimit, &a.ShareKey, &unavailableAt,
-			&removalRequestedAt, &durationSeconds); err != nil {
-			return nil, err
+			&removalRequestedAt, &durationSeconds, &sortPosition, &a.Placeholder, &total); err != nil {
+			return nil, 0, err
+		}
+		if sortPosition.Valid {
+			v := int(sortPosition.Int64)
+			a.SortPosition = &v
 		}
 		if ageLimit.Valid {
 			v := int(ageLimit.Int64)
@@ -130,8 +328,17 @@ func (s *SearchService) getAudioFilesByParentPath(parentPath string) ([]AudioFil
 		}
 		audioFiles = append(audioFiles, a)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	if len(audioFiles) == 0 && page.offset > 0 {
+		total, err = s.countBrowseRows("audio_files", where, whereArgs)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
 
-	return audioFiles, nil
+	return audioFiles, total, nil
 }
 
 func (s *SearchService) folderToFileSystemItem(f FolderRecord) FileSystemItem {
@@ -147,15 +354,23 @@ func (s *SearchService) folderToFileSystemItem(f FolderRecord) FileSystemItem {
 		Type:        "folder",
 		PosterImage: f.PosterImage,
 		ShareKey:    f.ShareKey,
+		Placeholder: f.Placeholder,
+		AgeLimit:    f.AgeLimit,
+		Position:    f.SortOrder,
 	}
 
-	if f.OriginalURL != "" || f.URLBroken || f.ItemCount > 0 {
+	if f.OriginalURL != "" || f.URLBroken || f.ItemCount > 0 || f.Description != "" ||
+		len(f.Tags) > 0 || len(f.Aliases) > 0 || f.AgeLimit != nil {
 		item.Metadata = &FolderMetadata{
 			FolderName:  f.FolderName,
 			Name:        f.Name,
 			OriginalURL: f.OriginalURL,
 			URLBroken:   f.URLBroken,
 			Items:       f.ItemCount,
+			Description: f.Description,
+			Tags:        f.Tags,
+			Aliases:     f.Aliases,
+			AgeLimit:    f.AgeLimit,
 		}
 	}
 
@@ -180,5 +395,7 @@ func (s *SearchService) audioToFileSystemItem(a AudioFileRecord) FileSystemItem
 		ShareKey:           a.ShareKey,
 		UnavailableAt:      a.UnavailableAt,
 		RemovalRequestedAt: a.RemovalRequestedAt,
+		Placeholder:        a.Placeholder,
+		Position:           a.SortPosition,
 	}
 }
@@ -0,0 +1,213 @@
+package services
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var browseFolderColumns = []string{
+	"id", "path", "parent_path", "folder_name", "name", "original_url",
+	"url_broken", "item_count", "directory_size_bytes", "poster_image",
+	"upload_date", "share_key", "poster_placeholder", "description", "tags",
+	"aliases", "age_limit", "sort_order", "total",
+}
+
+var browseAudioColumns = []string{
+	"id", "path", "parent_path", "filename", "size", "mime_type", "title", "meta_artist",
+	"upload_date", "webpage_url", "description", "age_limit", "share_key",
+	"unavailable_at", "removal_requested_at", "duration_seconds", "sort_position", "thumbnail_placeholder", "total",
+}
+
+func TestBrowseDirectoryFiltersSortsAndPages(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE parent_path = $1 AND (name ILIKE $2 OR folder_name ILIKE $2 OR aliases ILIKE $2)")).
+		WithArgs("Audio", `%live\_100\%%`).
+		WillReturnRows(sqlmock.NewRows(browseFolderColumns).
+			AddRow(1, "Audio/Live", "Audio", "Live", "Live", "", 0, 2, 100, "", "20260101", "folder-key", nil, nil, nil, nil, nil, nil, 1))
+	mock.ExpectQuery(`FROM play_events GROUP BY audio_file_id[\s\S]+removal_requested_at IS NULL[\s\S]+ORDER BY COALESCE\(pc.play_count, 0\) DESC, audio_files.id ASC$`).
+		WithArgs("Audio", `%live\_100\%%`).
+		WillReturnRows(sqlmock.NewRows(browseAudioColumns).
+			AddRow(2, "Audio/a.mp3", "Audio", "a.mp3", 10, "audio/mpeg", "Live A", "", "20260102", "", "", nil, "a-key", nil, nil, 60.0, nil,
+				`{"blurhash":"LKO2?U%2Tw=w]~RBVZRi};RPxuwH","dominantColor":"#336699"}`, 2).
+			AddRow(3, "Audio/b.mp3", "Audio", "b.mp3", 10, "audio/mpeg", "Live B", "", "20260103", "", "", nil, "b-key", nil, nil, nil, nil, nil, 2))
+
+	service := &SearchService{db: &Database{db: db}}
+	contents, err := service.BrowseDirectory("Audio", BrowseOptions{
+		Sort:   "popularity_desc",
+		Filter: "live_100%",
+		Limit:  1,
+		Offset: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contents.Total != 3 || contents.Offset != 1 || contents.Limit != 1 {
+		t.Fatalf("paging = total %d offset %d limit %d", contents.Total, contents.Offset, contents.Limit)
+	}
+	if len(contents.Items) != 1 || contents.Items[0].ShareKey != "a-key" {
+		t.Fatalf("unexpected items: %#v", contents.Items)
+	}
+	if placeholder := contents.Items[0].Placeholder; placeholder == nil || placeholder.DominantColor != "#336699" {
+		t.Fatalf("placeholder = %#v", placeholder)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBrowseDirectoryTypeFilterSkipsOtherQuery(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta("ORDER BY name DESC")).
+		WithArgs("Audio").
+		WillReturnRows(sqlmock.NewRows(browseFolderColumns))
+
+	service := &SearchService{db: &Database{db: db}}
+	contents, err := service.BrowseDirectory("Audio", BrowseOptions{Type: "folder", Sort: "name_desc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contents.Total != 0 || len(contents.Items) != 0 {
+		t.Fatalf("unexpected contents: %#v", contents)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBrowseFolderPopularityMatchesDescendantsLiterally(t *testing.T) {
+	if !regexp.MustCompile(`JOIN audio_files child ON starts_with\(child.path, sibling.path \|\| '/'\)`).MatchString(browseFolderPlayCounts) {
+		t.Fatalf("folder play counts = %q, want a literal prefix match", browseFolderPlayCounts)
+	}
+}
+
+func TestBrowseFolderPopularityCountsPlaysInOneJoin(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`FROM folders\s+LEFT JOIN \([\s\S]+GROUP BY sibling.id\s+\) fp ON fp.folder_id = folders.id\s+` +
+		`WHERE parent_path = \$1 AND removal_requested_at IS NULL\s+ORDER BY COALESCE\(fp.play_count, 0\) ASC, name ASC$`).
+		WithArgs("Audio").
+		WillReturnRows(sqlmock.NewRows(browseFolderColumns))
+
+	service := &SearchService{db: &Database{db: db}}
+	if _, err := service.BrowseDirectory("Audio", BrowseOptions{Type: "folder", Sort: "popularity_asc"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBrowseDirectoryPagesASingleTypeInSQL(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta("ORDER BY audio_files.upload_date DESC NULLS LAST, audio_files.id ASC LIMIT $2 OFFSET $3")).
+		WithArgs("Audio", 2, 2).
+		WillReturnRows(sqlmock.NewRows(browseAudioColumns).
+			AddRow(3, "Audio/c.mp3", "Audio", "c.mp3", 10, "audio/mpeg", "C", "", "20260103", "", "", nil, "c-key", nil, nil, nil, nil, nil, 5).
+			AddRow(4, "Audio/d.mp3", "Audio", "d.mp3", 10, "audio/mpeg", "D", "", "20260102", "", "", nil, "d-key", nil, nil, nil, nil, nil, 5))
+
+	service := &SearchService{db: &Database{db: db}}
+	contents, err := service.BrowseDirectory("Audio", BrowseOptions{Type: "audio", Sort: "date_desc", Limit: 2, Offset: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contents.Total != 5 || contents.Offset != 2 || contents.Limit != 2 || len(contents.Items) != 2 ||
+		contents.Items[0].ShareKey != "c-key" || contents.Items[1].ShareKey != "d-key" {
+		t.Fatalf("unexpected contents: %#v", contents)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBrowseDirectoryCountsWhenThePageStartsPastTheEnd(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta("ORDER BY name ASC LIMIT $2 OFFSET $3")).
+		WithArgs("Audio", 5, 10).
+		WillReturnRows(sqlmock.NewRows(browseFolderColumns))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM folders WHERE parent_path = $1 AND removal_requested_at IS NULL")).
+		WithArgs("Audio").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	service := &SearchService{db: &Database{db: db}}
+	contents, err := service.BrowseDirectory("Audio", BrowseOptions{Type: "folder", Sort: "name_asc", Limit: 5, Offset: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contents.Total != 3 || contents.Offset != 3 || len(contents.Items) != 0 {
+		t.Fatalf("unexpected contents: %#v", contents)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBrowseOrderClausesFallBackToDefaults(t *testing.T) {
+	for _, sort := range []string{"", "name", "bogus_asc", "date_sideways"} {
+		folders, audio := browseOrderClauses(sort)
+		if folders != "sort_order ASC NULLS LAST, name ASC" ||
+			audio != "audio_files.sort_position ASC NULLS LAST, audio_files.upload_date DESC" {
+			t.Fatalf("browseOrderClauses(%q) = %q, %q", sort, folders, audio)
+		}
+	}
+}
+
+func TestBrowseDirectorySurfacesFolderMetadata(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta("ORDER BY sort_order ASC NULLS LAST, name ASC")).
+		WithArgs("Audio").
+		WillReturnRows(sqlmock.NewRows(browseFolderColumns).
+			AddRow(1, "Audio/Show", "Audio", "Show", "The Show", "", 0, 0, 0, "", "", "show-key", nil,
+				"A weekly show", "comedy\nlive", "TS", 18, 2, 1))
+
+	service := &SearchService{db: &Database{db: db}}
+	contents, err := service.BrowseDirectory("Audio", BrowseOptions{Type: "folder"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(contents.Items) != 1 {
+		t.Fatalf("unexpected items: %#v", contents.Items)
+	}
+	item := contents.Items[0]
+	if item.Position == nil || *item.Position != 2 || item.AgeLimit == nil || *item.AgeLimit != 18 {
+		t.Fatalf("position = %v, age limit = %v", item.Position, item.AgeLimit)
+	}
+	if m := item.Metadata; m == nil || m.Description != "A weekly show" ||
+		!reflect.DeepEqual(m.Tags, []string{"comedy", "live"}) || !reflect.DeepEqual(m.Aliases, []string{"TS"}) {
+		t.Fatalf("metadata = %#v", item.Metadata)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	chaptersFromInfoJSON = "info_json"
+	chaptersEmbedded     = "embedded"
+	chapterProbeTimeout  = 30 * time.Second
+)
+
+// Chapter is a titled section of an audio file, in seconds from the start.
+type Chapter struct {
+	StartTime float64 `json:"startTime"`
+	EndTime   float64 `json:"endTime,omitempty"`
+	Title     string  `json:"title"`
+}
+
+// Chapters is stored as JSON in audio_files.chapters.
+type Chapters []Chapter
+
+func (c *Chapters) Scan(src any) error {
+	switch value := src.(type) {
+	case nil:
+		*c = nil
+		return nil
+	case string:
+		return json.Unmarshal([]byte(value), c)
+	case []byte:
+		return json.Unmarshal(value, c)
+	default:
+		return fmt.Errorf("cannot scan %T into Chapters", src)
+	}
+}
+
+func (c Chapters) Value() (driver.Value, error) {
+	if len(c) == 0 {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(c)
+	return string(encoded), err
+}
+
+// titles joins the chapter titles for the chapter_titles search column.
+func (c Chapters) titles() any {
+	if len(c) == 0 {
+		return nil
+	}
+	titles := make([]string, len(c))
+	for i, chapter := range c {
+		titles[i] = chapter.Title
+	}
+	return strings.Join(titles, "\n")
+}
+
+// normalizeChapters drops untitled chapters, orders the rest by start time
+// and fills in missing end times from the next chapter.
+func normalizeChapters(chapters Chapters) Chapters {
+	normalized := make(Chapters, 0, len(chapters))
+	for _, chapter := range chapters {
+		chapter.Title = strings.TrimSpace(chapter.Title)
+		if chapter.Title == "" || chapter.StartTime < 0 {
+			continue
+		}
+		normalized = append(normalized, chapter)
+	}
+	sort.SliceStable(normalized, func(i, j int) bool {
+		return normalized[i].StartTime < normalized[j].StartTime
+	})
+	for i := range normalized {
+		if normalized[i].EndTime <= normalized[i].StartTime {
+			normalized[i].EndTime = 0
+			if i+1 < len(normalized) {
+				normalized[i].EndTime = normalized[i+1].StartTime
+			}
+		}
+	}
+	if len(normalized) == 0 {
+		return nil
+	}
+	return normalized
+}
+
+// infoJSONChapter is one entry of the chapters array yt-dlp writes.
+type infoJSONChapter struct {
+	StartTime float64 `json:"start_time"`
+	EndTime   float64 `json:"end_time"`
+	Title     string  `json:"title"`
+}
+
+func chaptersFromInfo(entries []infoJSONChapter) Chapters {
+	chapters := make(Chapters, len(entries))
+	for i, entry := range entries {
+		chapters[i] = Chapter{StartTime: entry.StartTime, EndTime: entry.EndTime, Title: entry.Title}
+	}
+	return normalizeChapters(chapters)
+}
+
+// probeChapters reads chapter atoms and ID3 CHAP frames through ffprobe.
+func probeChapters(filePath string) (Chapters, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), chapterProbeTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "ffprobe",
+		"-v", "quiet",
+		"-print_format", "json",
+		"-show_chapters",
+		filePath,
+	).Output()
+	if err != nil {
+		return nil, err
+	}
+	return parseProbeChapters(out)
+}
+
+func parseProbeChapters(out []byte) (Chapters, error) {
+	var probe struct {
+		Chapters []struct {
+			StartTime string            `json:"start_time"`
+			EndTime   string            `json:"end_time"`
+			Tags      map[string]string `json:"tags"`
+		} `json:"chapters"`
+	}
+	if err := json.Unmarshal(out, &probe); err != nil {
+		return nil, err
+	}
+	chapters := make(Chapters, 0, len(probe.Chapters))
+	for _, entry := range probe.Chapters {
+		start, err := strconv.ParseFloat(entry.StartTime, 64)
+		if err != nil {
+			continue
+		}
+		end, _ := strconv.ParseFloat(entry.EndTime, 64)
+		title := entry.Tags["title"]
+		if title == "" {
+			title = entry.Tags["TITLE"]
+		}
+		chapters = append(chapters, Chapter{StartTime: start, EndTime: end, Title: title})
+	}
+	return normalizeChapters(chapters), nil
+}
+
+// probeEmbeddedChapters reads embedded chapters for files without info.json
+// chapters that have not been probed at their current size. Files without
+// chapters are marked as probed too, so each file is only read once.
+func (s *SearchService) probeEmbeddedChapters() {
+	if _, err := exec.LookPath("ffprobe"); err != nil {
+		log.Println("Chapters: ffprobe not found, skipping embedded chapters")
+		return
+	}
+	rows, err := s.db.DB().Query(`
+		SELECT id, path FROM audio_files
+		WHERE deleted = 0 AND chapters_source IS NULL
+		ORDER BY id
+	`)
+	if err != nil {
+		log.Printf("Chapters: query error: %v", err)
+		return
+	}
+	type pendingFile struct {
+		id   int64
+		path string
+	}
+	var pending []pendingFile
+	for rows.Next() {
+		var file pendingFile
+		if err := rows.Scan(&file.id, &file.path); err != nil {
+			continue
+		}
+		pending = append(pending, file)
+	}
+	rows.Close()
+	if len(pending) == 0 {
+		return
+	}
+
+	start := time.Now()
+	found := 0
+	for _, file := range pending {
+		parts := strings.SplitN(file.path, "/", 2)
+		if len(parts) < 2 {
+			continue
+		}
+		fullPath, ok := s.fs.ValidatePath(parts[0], parts[1])
+		if !ok {
+			continue
+		}
+		chapters, err := probeChapters(fullPath)
+		if err != nil {
+			// Still mark the file as probed; it is read again once its size changes.
+			log.Printf("Chapters: probe failed %s: %v", file.path, err)
+		}
+		if _, err := s.db.DB().Exec(`
+			UPDATE audio_files SET chapters = $2, chapter_titles = $3, chapters_source = $4 WHERE id = $1
+		`, file.id, chapters, chapters.titles(), chaptersEmbedded); err != nil {
+			log.Printf("Chapters: store error %s: %v", file.path, err)
+			continue
+		}
+		if len(chapters) > 0 {
+			found++
+		}
+	}
+	log.Printf("Chapters: probed %d files, %d with embedded chapters, in %v",
+		len(pending), found, time.Since(start).Round(time.Second))
+}
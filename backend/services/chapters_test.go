@@ -0,0 +1,69 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChaptersFromInfoNormalizes(t *testing.T) {
+	chapters := chaptersFromInfo([]infoJSONChapter{
+		{StartTime: 120, Title: "Second"},
+		{StartTime: 60, Title: "  "},
+		{StartTime: 0, EndTime: 120, Title: " First "},
+		{StartTime: 300, Title: "Last"},
+	})
+	want := Chapters{
+		{StartTime: 0, EndTime: 120, Title: "First"},
+		{StartTime: 120, EndTime: 300, Title: "Second"},
+		{StartTime: 300, Title: "Last"},
+	}
+	if !reflect.DeepEqual(chapters, want) {
+		t.Fatalf("chapters = %#v, want %#v", chapters, want)
+	}
+	if got := chapters.titles(); got != "First\nSecond\nLast" {
+		t.Fatalf("titles = %q", got)
+	}
+	if chaptersFromInfo(nil) != nil {
+		t.Fatal("expected no chapters for an empty list")
+	}
+}
+
+func TestParseProbeChapters(t *testing.T) {
+	out := []byte(`{"chapters": [
+		{"id": 0, "start_time": "0.000000", "end_time": "95.500000", "tags": {"title": "Intro"}},
+		{"id": 1, "start_time": "95.500000", "end_time": "200.000000", "tags": {"TITLE": "Interview"}},
+		{"id": 2, "start_time": "200.000000", "end_time": "250.000000", "tags": {}}
+	]}`)
+	chapters, err := parseProbeChapters(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Chapters{
+		{StartTime: 0, EndTime: 95.5, Title: "Intro"},
+		{StartTime: 95.5, EndTime: 200, Title: "Interview"},
+	}
+	if !reflect.DeepEqual(chapters, want) {
+		t.Fatalf("chapters = %#v, want %#v", chapters, want)
+	}
+}
+
+func TestChaptersScanAndValue(t *testing.T) {
+	value, err := Chapters(nil).Value()
+	if err != nil || value != nil {
+		t.Fatalf("empty chapters value = %#v, %v", value, err)
+	}
+	value, err = Chapters{{StartTime: 1.5, Title: "Only"}}.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var chapters Chapters
+	if err := chapters.Scan([]byte(value.(string))); err != nil {
+		t.Fatal(err)
+	}
+	if len(chapters) != 1 || chapters[0].StartTime != 1.5 || chapters[0].Title != "Only" {
+		t.Fatalf("chapters = %#v", chapters)
+	}
+	if err := chapters.Scan(nil); err != nil || chapters != nil {
+		t.Fatalf("scanning NULL gave %#v, %v", chapters, err)
+	}
+}
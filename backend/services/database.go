@@ -187,6 +187,176 @@ func (d *Database) migrate() {
 		`DROP INDEX IF EXISTS idx_audio_files_search`,
 		`ALTER TABLE folders ALTER COLUMN item_count SET DEFAULT 0`,
 		`UPDATE folders SET item_count = 0 WHERE item_count IS NULL`,
+		`CREATE TABLE IF NOT EXISTS search_events (
+			id BIGSERIAL PRIMARY KEY,
+			query TEXT NOT NULL,
+			result_count INTEGER NOT NULL,
+			filters JSONB NOT NULL DEFAULT '{}',
+			searched_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_search_events_searched_at ON search_events(searched_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_search_events_query ON search_events(query)`,
+		`CREATE TABLE IF NOT EXISTS app_tokens (
+			id BIGSERIAL PRIMARY KEY,
+			profile_id TEXT NOT NULL REFERENCES anonymous_profiles(session_id) ON DELETE CASCADE,
+			name TEXT NOT NULL,
+			username TEXT NOT NULL UNIQUE,
+			salt TEXT NOT NULL,
+			password_hash BYTEA NOT NULL UNIQUE,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			last_used_at TIMESTAMPTZ
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_app_tokens_profile_id ON app_tokens(profile_id)`,
+		`ALTER TABLE folders ADD COLUMN IF NOT EXISTS first_indexed_at TIMESTAMP`,
+		`UPDATE folders f SET first_indexed_at = COALESCE(
+			(SELECT MIN(af.downloaded_at)::timestamp FROM audio_files af
+			 WHERE af.parent_path = f.path AND af.downloaded_at IS NOT NULL),
+			f.indexed_at)
+		WHERE f.first_indexed_at IS NULL`,
+		`ALTER TABLE folders ALTER COLUMN first_indexed_at SET DEFAULT CURRENT_TIMESTAMP`,
+		`CREATE INDEX IF NOT EXISTS idx_folders_first_indexed_at ON folders(first_indexed_at DESC)`,
+		`ALTER TABLE audio_files ADD COLUMN IF NOT EXISTS thumbnail_placeholder TEXT`,
+		`ALTER TABLE audio_files ADD COLUMN IF NOT EXISTS thumbnail_placeholder_for TEXT`,
+		`ALTER TABLE folders ADD COLUMN IF NOT EXISTS poster_placeholder TEXT`,
+		`ALTER TABLE folders ADD COLUMN IF NOT EXISTS poster_placeholder_for TEXT`,
+		`ALTER TABLE audio_files ADD COLUMN IF NOT EXISTS chapters TEXT`,
+		`ALTER TABLE audio_files ADD COLUMN IF NOT EXISTS chapter_titles TEXT`,
+		`ALTER TABLE audio_files ADD COLUMN IF NOT EXISTS chapters_source TEXT`,
+		`CREATE INDEX IF NOT EXISTS idx_audio_files_trgm_chapter_titles ON audio_files USING gin (chapter_titles gin_trgm_ops)`,
+		`CREATE TABLE IF NOT EXISTS transcripts (
+			id BIGSERIAL PRIMARY KEY,
+			audio_file_id BIGINT NOT NULL REFERENCES audio_files(id) ON DELETE CASCADE,
+			filename TEXT NOT NULL,
+			format TEXT NOT NULL,
+			language TEXT,
+			size BIGINT NOT NULL,
+			modified_at TIMESTAMPTZ NOT NULL,
+			indexed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (audio_file_id, filename)
+		)`,
+		`CREATE TABLE IF NOT EXISTS transcript_cues (
+			transcript_id BIGINT NOT NULL REFERENCES transcripts(id) ON DELETE CASCADE,
+			position INTEGER NOT NULL,
+			start_time DOUBLE PRECISION NOT NULL,
+			end_time DOUBLE PRECISION,
+			text TEXT NOT NULL,
+			PRIMARY KEY (transcript_id, position)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_transcript_cues_trgm_text ON transcript_cues USING gin (text gin_trgm_ops)`,
+		`ALTER TABLE folders ADD COLUMN IF NOT EXISTS description TEXT`,
+		`ALTER TABLE folders ADD COLUMN IF NOT EXISTS tags TEXT`,
+		`ALTER TABLE folders ADD COLUMN IF NOT EXISTS aliases TEXT`,
+		`ALTER TABLE folders ADD COLUMN IF NOT EXISTS age_limit INTEGER`,
+		`ALTER TABLE folders ADD COLUMN IF NOT EXISTS sort_order INTEGER`,
+		`ALTER TABLE audio_files ADD COLUMN IF NOT EXISTS sort_position INTEGER`,
+		`CREATE INDEX IF NOT EXISTS idx_folders_trgm_description ON folders USING gin (description gin_trgm_ops)`,
+		`CREATE INDEX IF NOT EXISTS idx_folders_trgm_tags ON folders USING gin (tags gin_trgm_ops)`,
+		`CREATE INDEX IF NOT EXISTS idx_folders_trgm_aliases ON folders USING gin (aliases gin_trgm_ops)`,
+		`ALTER TABLE audio_files ADD COLUMN IF NOT EXISTS availability_checked_at TIMESTAMPTZ`,
+		`CREATE TABLE IF NOT EXISTS availability_checks (
+			id BIGSERIAL PRIMARY KEY,
+			audio_file_id BIGINT NOT NULL REFERENCES audio_files(id) ON DELETE CASCADE,
+			checked_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			status TEXT NOT NULL,
+			detail TEXT
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_availability_checks_audio_file ON availability_checks(audio_file_id, checked_at DESC)`,
+		`ALTER TABLE folders ADD COLUMN IF NOT EXISTS removal_requested_at TIMESTAMPTZ`,
+		`CREATE TABLE IF NOT EXISTS removal_requests (
+			id BIGSERIAL PRIMARY KEY,
+			status TEXT NOT NULL DEFAULT 'pending',
+			target_type TEXT NOT NULL,
+			share_key TEXT NOT NULL,
+			target_path TEXT NOT NULL,
+			target_name TEXT NOT NULL,
+			requester_name TEXT,
+			requester_email TEXT NOT NULL,
+			reason TEXT NOT NULL,
+			admin_notes TEXT,
+			session_id TEXT,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			decided_at TIMESTAMPTZ
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_removal_requests_status ON removal_requests(status, created_at)`,
+		`CREATE TABLE IF NOT EXISTS removal_request_events (
+			id BIGSERIAL PRIMARY KEY,
+			removal_request_id BIGINT NOT NULL REFERENCES removal_requests(id),
+			from_status TEXT,
+			to_status TEXT NOT NULL,
+			note TEXT,
+			affected BIGINT NOT NULL DEFAULT 0,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_removal_request_events_request ON removal_request_events(removal_request_id, created_at)`,
+		`CREATE TABLE IF NOT EXISTS admin_audit_log (
+			id BIGSERIAL PRIMARY KEY,
+			actor TEXT NOT NULL,
+			method TEXT NOT NULL,
+			endpoint TEXT NOT NULL,
+			target_type TEXT NOT NULL,
+			target_id TEXT NOT NULL,
+			before_value JSONB,
+			after_value JSONB,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_admin_audit_log_created_at ON admin_audit_log(created_at DESC, id DESC)`,
+		`CREATE INDEX IF NOT EXISTS idx_admin_audit_log_target ON admin_audit_log(target_type, target_id, created_at DESC)`,
+		`CREATE INDEX IF NOT EXISTS idx_admin_audit_log_actor ON admin_audit_log(actor, created_at DESC)`,
+		`CREATE TABLE IF NOT EXISTS admin_api_keys (
+			id BIGSERIAL PRIMARY KEY,
+			name TEXT NOT NULL,
+			scopes TEXT NOT NULL,
+			prefix TEXT NOT NULL,
+			key_hash BYTEA NOT NULL UNIQUE,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			expires_at TIMESTAMPTZ,
+			last_used_at TIMESTAMPTZ,
+			revoked_at TIMESTAMPTZ
+		)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_admin_api_keys_active_name ON admin_api_keys(name) WHERE revoked_at IS NULL`,
+		`CREATE TABLE IF NOT EXISTS bans (
+			id BIGSERIAL PRIMARY KEY,
+			kind TEXT NOT NULL,
+			value TEXT NOT NULL,
+			mode TEXT NOT NULL,
+			reason TEXT NOT NULL DEFAULT '',
+			created_by TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			expires_at TIMESTAMPTZ,
+			lifted_at TIMESTAMPTZ
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_bans_active ON bans(created_at DESC) WHERE lifted_at IS NULL`,
+		`CREATE TABLE IF NOT EXISTS access_key_issuances (
+			scope TEXT NOT NULL,
+			identity_hash TEXT NOT NULL,
+			purpose TEXT NOT NULL,
+			events BYTEA NOT NULL,
+			expires_at TIMESTAMPTZ NOT NULL,
+			PRIMARY KEY (scope, identity_hash, purpose)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_access_key_issuances_expires_at ON access_key_issuances(expires_at)`,
+		`CREATE UNLOGGED TABLE IF NOT EXISTS rate_limit_windows (
+			key TEXT PRIMARY KEY,
+			count INTEGER NOT NULL,
+			started_at TIMESTAMPTZ NOT NULL,
+			expires_at TIMESTAMPTZ NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_rate_limit_windows_expires_at ON rate_limit_windows(expires_at)`,
+		`CREATE UNLOGGED TABLE IF NOT EXISTS bandwidth_buckets (
+			namespace TEXT NOT NULL,
+			client_ip TEXT NOT NULL,
+			tokens DOUBLE PRECISION NOT NULL,
+			refilled_at TIMESTAMPTZ NOT NULL,
+			used_at TIMESTAMPTZ NOT NULL,
+			PRIMARY KEY (namespace, client_ip)
+		)`,
+		`CREATE TABLE IF NOT EXISTS removal_request_items (
+			removal_request_id BIGINT NOT NULL REFERENCES removal_requests(id),
+			item_type TEXT NOT NULL,
+			item_id BIGINT NOT NULL,
+			PRIMARY KEY (removal_request_id, item_type, item_id)
+		)`,
 	}
 
 	for _, stmt := range statements {
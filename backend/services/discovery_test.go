@@ -1,6 +1,7 @@
 package services
 
 import (
+	"errors"
 	"regexp"
 	"testing"
 	"time"
@@ -16,7 +17,7 @@ func TestSearchExcludesRemovalRequestsBeforePagination(t *testing.T) {
 	defer db.Close()
 
 	mock.ExpectQuery("removal_requested_at IS NULL").
-		WithArgs("%track%", "%track%", "%track%", "%track%", "%track%", "%track%", 50, 0).
+		WithArgs("%track%", "%track%", "%track%", "%track%", "%track%", "%track%", "%track%", "%track%", "%track%", "%track%", "%track%", "%track%", 50, 0).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}))
 
 	service := &SearchService{db: &Database{db: db}}
@@ -166,10 +167,10 @@ func TestLocalPlaybackDiscoveryIncludesRemovalState(t *testing.T) {
 		WillReturnRows(sqlmock.NewRows([]string{
 			"share_key", "path", "filename", "title", "meta_artist", "parent_path",
 			"folder_name", "folder_share_key", "thumbnail", "poster_image", "age_limit",
-			"removal_requested_at",
+			"removal_requested_at", "thumbnail_placeholder", "poster_placeholder",
 		}).AddRow(
 			"track-key", "Audio/track.mp3", "track.mp3", "Track", "Artist", "Audio",
-			"Audio", "folder-key", nil, nil, 0, requestedAt,
+			"Audio", "folder-key", nil, nil, 0, requestedAt, nil, nil,
 		))
 
 	service := &PlaybackService{db: &Database{db: db}}
@@ -184,3 +185,78 @@ func TestLocalPlaybackDiscoveryIncludesRemovalState(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestSearchScopesBothArmsToFolderPath(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS(SELECT 1 FROM folders WHERE path = $1)")).
+		WithArgs("Audio/Show_1").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery(`FROM audio_files\s+WHERE deleted = 0 AND removal_requested_at IS NULL AND COALESCE\(age_limit, 0\) < 18 AND path LIKE \$1[\s\S]+FROM folders\s+WHERE 1=1 AND removal_requested_at IS NULL AND COALESCE\(age_limit, 0\) < 18 AND path LIKE \$2`).
+		WithArgs(`Audio/Show\_1/%`, `Audio/Show\_1/%`, 50, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	service := &SearchService{db: &Database{db: db}}
+	if _, _, err := service.Search("", 50, 0, SearchOptions{Path: "Audio/Show_1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSearchRejectsUnknownFolderPath(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS(SELECT 1 FROM folders WHERE path = $1)")).
+		WithArgs("Missing").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	service := &SearchService{db: &Database{db: db}}
+	if _, _, err := service.Search("track", 50, 0, SearchOptions{Path: "Missing"}); !errors.Is(err, ErrFolderNotFound) {
+		t.Fatalf("err = %v, want ErrFolderNotFound", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRecentAdditionsMergesTracksAndFoldersNewestFirst(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	folderAdded := time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`FROM audio_files af[\s\S]*af.path LIKE \$1 AND af.removal_requested_at IS NULL`).
+		WithArgs(`my\_root/%`, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"share_key", "path", "title", "artist", "folder", "downloaded_at"}).
+			AddRow("newest", "my_root/a/1.mp3", "One", "", "A", "2026-03-05T00:00:00Z").
+			AddRow("older", "my_root/a/2.mp3", "Two", "", "A", "2026-03-01T00:00:00Z"))
+	mock.ExpectQuery(`FROM folders f[\s\S]*COALESCE\(af.age_limit, 0\) < 18 AND af.removal_requested_at IS NULL`).
+		WithArgs(`my\_root/%`, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"share_key", "path", "name", "poster", "first_indexed_at"}).
+			AddRow("folder", "my_root/b", "B", "", folderAdded))
+
+	service := &PlaybackService{db: &Database{db: db}}
+	additions, err := service.GetRecentAdditions("my_root", 2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(additions) != 2 || additions[0].ShareKey != "newest" || additions[1].Kind != RecentAdditionFolder ||
+		!additions[1].AddedAt.Equal(folderAdded) {
+		t.Fatalf("additions = %#v", additions)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -14,34 +14,51 @@ type AudioDirConfig struct {
 }
 
 type FolderMetadata struct {
-	FolderName  string `json:"folder_name"`
-	Name        string `json:"name"`
-	OriginalURL string `json:"original_url,omitempty"`
-	URLBroken   bool   `json:"url_broken,omitempty"`
-	Items       int    `json:"items,omitempty"`
-	Description string `json:"description,omitempty"`
+	FolderName  string   `json:"folder_name"`
+	Name        string   `json:"name"`
+	OriginalURL string   `json:"original_url,omitempty"`
+	URLBroken   bool     `json:"url_broken,omitempty"`
+	Items       int      `json:"items,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	// Aliases are other names the folder is found by in search and filters.
+	Aliases []string `json:"aliases,omitempty"`
+	// AgeLimit applies to every track beneath the folder that does not have
+	// a higher one of its own.
+	AgeLimit *int `json:"age_limit,omitempty"`
+	// SortOrder places the folder among its siblings, lowest first. Folders
+	// without one follow in name order.
+	SortOrder *int `json:"sort_order,omitempty"`
+	// TrackOrder lists the filenames of the folder's tracks in their manual
+	// order. Only read from folder.json.
+	TrackOrder []string `json:"track_order,omitempty"`
 }
 
 type FileSystemItem struct {
-	Name               string          `json:"name"`
-	Path               string          `json:"path"`
-	Size               int64           `json:"size,omitempty"`
-	DurationSeconds    float64         `json:"durationSeconds,omitempty"`
-	ModifiedAt         string          `json:"modifiedAt"`
-	Type               string          `json:"type"`
-	MimeType           string          `json:"mimeType,omitempty"`
-	Title              string          `json:"title,omitempty"`
-	AgeLimit           *int            `json:"ageLimit,omitempty"`
-	Metadata           *FolderMetadata `json:"metadata,omitempty"`
-	PosterImage        string          `json:"posterImage,omitempty"`
-	ShareKey           string          `json:"shareKey,omitempty"`
-	UnavailableAt      *string         `json:"unavailableAt,omitempty"`
-	RemovalRequestedAt *string         `json:"removalRequestedAt,omitempty"`
+	Name               string              `json:"name"`
+	Path               string              `json:"path"`
+	Size               int64               `json:"size,omitempty"`
+	DurationSeconds    float64             `json:"durationSeconds,omitempty"`
+	ModifiedAt         string              `json:"modifiedAt"`
+	Type               string              `json:"type"`
+	MimeType           string              `json:"mimeType,omitempty"`
+	Title              string              `json:"title,omitempty"`
+	AgeLimit           *int                `json:"ageLimit,omitempty"`
+	Position           *int                `json:"position,omitempty"`
+	Metadata           *FolderMetadata     `json:"metadata,omitempty"`
+	PosterImage        string              `json:"posterImage,omitempty"`
+	ShareKey           string              `json:"shareKey,omitempty"`
+	UnavailableAt      *string             `json:"unavailableAt,omitempty"`
+	RemovalRequestedAt *string             `json:"removalRequestedAt,omitempty"`
+	Placeholder        *ArtworkPlaceholder `json:"placeholder,omitempty"`
 }
 
 type DirectoryContents struct {
 	Items       []FileSystemItem `json:"items"`
 	CurrentPath string           `json:"currentPath"`
+	Total       int              `json:"total"`
+	Offset      int              `json:"offset,omitempty"`
+	Limit       int              `json:"limit,omitempty"`
 }
 
 type FileSystemService struct {
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"database/sql"
+	"time"
+)
+
+// FolderActionResult reports what a folder-wide admin action changed.
+// Tracks and folders that already had the requested state are not counted.
+type FolderActionResult struct {
+	Tracks    int64 `json:"tracks"`
+	Folders   int64 `json:"folders"`
+	URLBroken bool  `json:"urlBroken"`
+}
+
+// SetFolderRemovalRequested sets or clears removal_requested_at on a folder,
+// its subfolders, and every track beneath it. It returns ErrNotFound when no
+// folder has the share key.
+func SetFolderRemovalRequested(db *sql.DB, shareKey string, requested bool) (*FolderActionResult, error) {
+	var requestedAt any
+	if requested {
+		requestedAt = time.Now().UTC()
+	}
+	return applyFolderAction(db, shareKey, func(tx *sql.Tx, folderPath string) (int64, int64, error) {
+		return setFolderTreeRemovalRequested(tx, folderPath, requestedAt)
+	})
+}
+
+// SetFolderUnavailable sets or clears unavailable_at on every track beneath
+// a folder. It returns ErrNotFound when no folder has the share key.
+func SetFolderUnavailable(db *sql.DB, shareKey string, unavailable bool) (*FolderActionResult, error) {
+	return applyFolderAction(db, shareKey, func(tx *sql.Tx, folderPath string) (int64, int64, error) {
+		filter := "unavailable_at IS NOT NULL"
+		var unavailableAt any
+		if unavailable {
+			filter = "unavailable_at IS NULL"
+			unavailableAt = time.Now().UTC()
+		}
+		result, err := tx.Exec(`
+			UPDATE audio_files SET unavailable_at = $1
+			WHERE path LIKE $2 AND deleted = 0 AND `+filter,
+			unavailableAt, likePrefix(folderPath))
+		if err != nil {
+			return 0, 0, err
+		}
+		tracks, err := result.RowsAffected()
+		return tracks, 0, err
+	})
+}
+
+// applyFolderAction runs a folder-wide change in one transaction and then
+// recomputes url_broken for the folder, the folders beneath it, and the
+// folders above it, so the change shows without waiting for a reindex.
+func applyFolderAction(db *sql.DB, shareKey string, apply func(tx *sql.Tx, folderPath string) (int64, int64, error)) (*FolderActionResult, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var folderPath string
+	err = tx.QueryRow(`SELECT path FROM folders WHERE share_key = $1 FOR UPDATE`, shareKey).Scan(&folderPath)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var result FolderActionResult
+	result.Tracks, result.Folders, err = apply(tx, folderPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE folders SET url_broken = `+folderURLBrokenExpr+`
+		WHERE path = $1 OR path LIKE $2 OR $1 LIKE path || '/%'
+	`, folderPath, likePrefix(folderPath)); err != nil {
+		return nil, err
+	}
+	var urlBroken int
+	if err := tx.QueryRow(`SELECT url_broken FROM folders WHERE path = $1`, folderPath).Scan(&urlBroken); err != nil {
+		return nil, err
+	}
+	result.URLBroken = urlBroken != 0
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// setFolderTreeRemovalRequested sets or clears removal_requested_at on a
+// folder and everything beneath it, and returns how many tracks and folders
+// changed.
+func setFolderTreeRemovalRequested(tx *sql.Tx, folderPath string, requestedAt any) (int64, int64, error) {
+	filter := "removal_requested_at IS NULL"
+	if requestedAt == nil {
+		filter = "removal_requested_at IS NOT NULL"
+	}
+	prefix := likePrefix(folderPath)
+	tracks, err := tx.Exec(`
+		UPDATE audio_files SET removal_requested_at = $1
+		WHERE path LIKE $2 AND deleted = 0 AND `+filter,
+		requestedAt, prefix)
+	if err != nil {
+		return 0, 0, err
+	}
+	folders, err := tx.Exec(`
+		UPDATE folders SET removal_requested_at = $1
+		WHERE (path = $2 OR path LIKE $3) AND `+filter,
+		requestedAt, folderPath, prefix)
+	if err != nil {
+		return 0, 0, err
+	}
+	trackCount, err := tracks.RowsAffected()
+	if err != nil {
+		return 0, 0, err
+	}
+	folderCount, err := folders.RowsAffected()
+	if err != nil {
+		return 0, 0, err
+	}
+	return trackCount, folderCount, nil
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestSetFolderUnavailableRecomputesURLBroken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT path FROM folders WHERE share_key = $1 FOR UPDATE`)).WithArgs("channel-key").
+		WillReturnRows(sqlmock.NewRows([]string{"path"}).AddRow("Channels/Some_Channel"))
+	mock.ExpectExec(regexp.QuoteMeta(`WHERE path LIKE $2 AND deleted = 0 AND unavailable_at IS NULL`)).
+		WithArgs(sqlmock.AnyArg(), `Channels/Some\_Channel/%`).WillReturnResult(sqlmock.NewResult(0, 12))
+	mock.ExpectExec(regexp.QuoteMeta(`WHERE path = $1 OR path LIKE $2 OR $1 LIKE path || '/%'`)).
+		WithArgs("Channels/Some_Channel", `Channels/Some\_Channel/%`).WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT url_broken FROM folders WHERE path = $1`)).WithArgs("Channels/Some_Channel").
+		WillReturnRows(sqlmock.NewRows([]string{"url_broken"}).AddRow(1))
+	mock.ExpectCommit()
+
+	result, err := SetFolderUnavailable(db, "channel-key", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Tracks != 12 || result.Folders != 0 || !result.URLBroken {
+		t.Fatalf("result = %#v", result)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSetFolderRemovalRequestedRevertsTree(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`FOR UPDATE`)).WithArgs("channel-key").
+		WillReturnRows(sqlmock.NewRows([]string{"path"}).AddRow("Channels/A"))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE audio_files SET removal_requested_at = $1`)).
+		WithArgs(nil, `Channels/A/%`).WillReturnResult(sqlmock.NewResult(0, 40))
+	mock.ExpectExec(regexp.QuoteMeta(`WHERE (path = $2 OR path LIKE $3) AND removal_requested_at IS NOT NULL`)).
+		WithArgs(nil, "Channels/A", `Channels/A/%`).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE folders SET url_broken`)).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT url_broken`)).
+		WillReturnRows(sqlmock.NewRows([]string{"url_broken"}).AddRow(0))
+	mock.ExpectCommit()
+
+	result, err := SetFolderRemovalRequested(db, "channel-key", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Tracks != 40 || result.Folders != 2 || result.URLBroken {
+		t.Fatalf("result = %#v", result)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSetFolderUnavailableUnknownKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`FOR UPDATE`)).WillReturnRows(sqlmock.NewRows([]string{"path"}))
+	mock.ExpectRollback()
+
+	if _, err := SetFolderUnavailable(db, "missing", false); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("err = %v", err)
+	}
+}
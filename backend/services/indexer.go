@@ -27,6 +27,12 @@ type FolderRecord struct {
 	PosterImage   string
 	UploadDate    string // computed from MAX(child upload_dates); seeded from filesystem mtime as fallback
 	ShareKey      string
+	Placeholder   *ArtworkPlaceholder // filled in by ArtworkService, not stored by the indexer
+	Description   string
+	Tags          []string
+	Aliases       []string
+	AgeLimit      *int // the folder's own limit raised to any inherited from parent folders
+	SortOrder     *int
 }
 
 type AudioFileRecord struct {
@@ -50,6 +56,9 @@ type AudioFileRecord struct {
 	DurationSeconds    float64
 	UnavailableAt      *string
 	RemovalRequestedAt *string
+	Placeholder        *ArtworkPlaceholder // filled in by ArtworkService, not stored by the indexer
+	Chapters           Chapters            // from info.json; embedded chapters are probed after indexing
+	SortPosition       *int                // index in the parent folder's track_order
 }
 
 type AudioInfoJSON struct {
@@ -61,6 +70,57 @@ type AudioInfoJSON struct {
 	Description string  `json:"description"`
 	Epoch       float64 `json:"epoch"`
 	AgeLimit    *int    `json:"age_limit"`
+
+	Chapters []infoJSONChapter `json:"chapters"`
+}
+
+// folderDefaults carries folder.json settings down to the tracks and
+// subfolders of a folder.
+type folderDefaults struct {
+	ageLimit   *int
+	trackOrder map[string]int
+}
+
+func (d folderDefaults) child(m FolderMetadata) folderDefaults {
+	child := folderDefaults{ageLimit: maxAgeLimit(d.ageLimit, m.AgeLimit)}
+	if len(m.TrackOrder) > 0 {
+		child.trackOrder = make(map[string]int, len(m.TrackOrder))
+		for i, filename := range m.TrackOrder {
+			if _, seen := child.trackOrder[filename]; !seen {
+				child.trackOrder[filename] = i
+			}
+		}
+	}
+	return child
+}
+
+func maxAgeLimit(a, b *int) *int {
+	if a == nil || (b != nil && *b > *a) {
+		return b
+	}
+	return a
+}
+
+// metadataList stores folder.json lists one entry per line, so they can be
+// matched with ILIKE like any other text column.
+func metadataList(values []string) any {
+	var kept []string
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			kept = append(kept, strings.ReplaceAll(value, "\n", " "))
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	return strings.Join(kept, "\n")
+}
+
+func splitMetadataList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, "\n")
 }
 
 func generateShareKey() (string, error) {
@@ -78,6 +138,22 @@ func nullIfEmpty(s string) interface{} {
 	return s
 }
 
+// folderURLBrokenExpr marks a folder broken when it has tracks and every one
+// of them is unavailable.
+const folderURLBrokenExpr = `CASE
+	WHEN EXISTS (
+		SELECT 1 FROM audio_files
+		WHERE (parent_path = folders.path OR parent_path LIKE folders.path || '/%')
+		AND deleted = 0
+	)
+	AND NOT EXISTS (
+		SELECT 1 FROM audio_files
+		WHERE (parent_path = folders.path OR parent_path LIKE folders.path || '/%')
+		AND deleted = 0 AND unavailable_at IS NULL
+	)
+	THEN 1 ELSE 0
+END`
+
 func (s *SearchService) RebuildIndex() error {
 	lockFile, err := os.OpenFile(s.lockPath, os.O_CREATE|os.O_RDWR, 0600)
 	if err != nil {
@@ -112,7 +188,7 @@ func (s *SearchService) RebuildIndex() error {
 			log.Printf("Error indexing root folder %s: %v", slug, err)
 		}
 
-		if err := s.indexDirectory(slug, dirConfig.Path, "", ""); err != nil {
+		if err := s.indexDirectory(slug, dirConfig.Path, "", "", folderDefaults{}); err != nil {
 			log.Printf("Error indexing %s: %v", slug, err)
 		}
 	}
@@ -123,6 +199,10 @@ func (s *SearchService) RebuildIndex() error {
 	if _, err := s.db.DB().Exec("UPDATE audio_files SET deleted = 1 WHERE indexed_at < $1 AND deleted = 0", start); err != nil {
 		log.Printf("Error soft-deleting stale audio files: %v", err)
 	}
+	if _, err := s.db.DB().Exec("DELETE FROM transcripts WHERE indexed_at < $1", start); err != nil {
+		log.Printf("Error cleaning up stale transcripts: %v", err)
+	}
+	s.probeEmbeddedChapters()
 
 	if _, err := s.db.DB().Exec(`
 		UPDATE folders SET item_count = (
@@ -145,19 +225,7 @@ func (s *SearchService) RebuildIndex() error {
 				 AND deleted = 0),
 				0
 			),
-			url_broken = CASE
-				WHEN EXISTS (
-					SELECT 1 FROM audio_files
-					WHERE (parent_path = folders.path OR parent_path LIKE folders.path || '/%')
-					AND deleted = 0
-				)
-				AND NOT EXISTS (
-					SELECT 1 FROM audio_files
-					WHERE (parent_path = folders.path OR parent_path LIKE folders.path || '/%')
-					AND deleted = 0 AND unavailable_at IS NULL
-				)
-				THEN 1 ELSE 0
-			END,
+			url_broken = ` + folderURLBrokenExpr + `,
 			upload_date = COALESCE(
 				(SELECT MAX(upload_date) FROM audio_files
 				 WHERE (parent_path = folders.path OR parent_path LIKE folders.path || '/%')
@@ -225,7 +293,7 @@ func (s *SearchService) getIndexedFoldersWithURLForWebhook(start time.Time) ([]N
 	return folders, nil
 }
 
-func (s *SearchService) indexDirectory(slug, basePath, relativePath, sourcePath string) error {
+func (s *SearchService) indexDirectory(slug, basePath, relativePath, sourcePath string, defaults folderDefaults) error {
 	fullPath := filepath.Join(basePath, relativePath)
 
 	entries, err := os.ReadDir(fullPath)
@@ -242,6 +310,7 @@ func (s *SearchService) indexDirectory(slug, basePath, relativePath, sourcePath
 	for _, m := range folderMetadataList {
 		metadataMap[m.FolderName] = m
 	}
+	transcripts := transcriptSidecars(entries)
 
 	for _, entry := range entries {
 		name := entry.Name()
@@ -272,10 +341,17 @@ func (s *SearchService) indexDirectory(slug, basePath, relativePath, sourcePath
 				UploadDate: info.ModTime().Format("20060102"),
 			}
 
-			if m, ok := metadataMap[name]; ok {
+			m, ok := metadataMap[name]
+			if ok {
 				record.Name = m.Name
 				record.OriginalURL = m.OriginalURL
+				record.Description = m.Description
+				record.Tags = m.Tags
+				record.Aliases = m.Aliases
+				record.SortOrder = m.SortOrder
 			}
+			childDefaults := defaults.child(m)
+			record.AgeLimit = childDefaults.ageLimit
 
 			entryPath := filepath.Join(fullPath, name)
 			for _, posterName := range s.fs.posterNames {
@@ -299,7 +375,7 @@ func (s *SearchService) indexDirectory(slug, basePath, relativePath, sourcePath
 			if relativePath != "" {
 				subRelativePath = relativePath + "/" + name
 			}
-			if err := s.indexDirectory(slug, basePath, subRelativePath, childSourcePath); err != nil {
+			if err := s.indexDirectory(slug, basePath, subRelativePath, childSourcePath, childDefaults); err != nil {
 				log.Printf("Error indexing subdirectory %s: %v", subRelativePath, err)
 			}
 		} else {
@@ -339,15 +415,24 @@ func (s *SearchService) indexDirectory(slug, basePath, relativePath, sourcePath
 						if infoJSON.Epoch > 0 {
 							record.DownloadedAt = time.Unix(int64(infoJSON.Epoch), 0).Format("2006-01-02T15:04:05Z")
 						}
-						record.AgeLimit = infoJSON.AgeLimit
+						record.AgeLimit = maxAgeLimit(infoJSON.AgeLimit, defaults.ageLimit)
+						record.Chapters = chaptersFromInfo(infoJSON.Chapters)
 					}
 				}
 				if record.UploadDate == "" {
 					record.UploadDate = info.ModTime().Format("20060102")
 				}
+				if record.AgeLimit == nil {
+					record.AgeLimit = defaults.ageLimit
+				}
+				if position, ok := defaults.trackOrder[name]; ok {
+					record.SortPosition = &position
+				}
 
 				if err := s.insertAudioFile(record); err != nil {
 					log.Printf("Error indexing audio %s: %v", virtualPath, err)
+				} else if sidecars := transcripts[baseName]; len(sidecars) > 0 {
+					s.indexTranscripts(virtualPath, fullPath, sidecars)
 				}
 			}
 		}
@@ -373,19 +458,26 @@ func (s *SearchService) insertFolder(f FolderRecord) error {
 	_, err = s.db.DB().Exec(`
 		INSERT INTO folders
 		(path, parent_path, folder_name, name, original_url,
-		 poster_image, upload_date, share_key, indexed_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, CURRENT_TIMESTAMP)
+		 poster_image, upload_date, share_key, description, tags,
+		 aliases, age_limit, sort_order, indexed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, CURRENT_TIMESTAMP)
 		ON CONFLICT(path) DO UPDATE SET
 			parent_path = excluded.parent_path,
 			folder_name = excluded.folder_name,
 			name = excluded.name,
 			original_url = excluded.original_url,
 			poster_image = excluded.poster_image,
+			description = excluded.description,
+			tags = excluded.tags,
+			aliases = excluded.aliases,
+			age_limit = excluded.age_limit,
+			sort_order = excluded.sort_order,
 			upload_date = COALESCE(folders.upload_date, excluded.upload_date),
 			share_key = COALESCE(folders.share_key, excluded.share_key),
 			indexed_at = CURRENT_TIMESTAMP
 	`, f.Path, f.ParentPath, f.FolderName, f.Name, f.OriginalURL,
-		f.PosterImage, f.UploadDate, shareKey)
+		f.PosterImage, f.UploadDate, shareKey, nullIfEmpty(f.Description), metadataList(f.Tags),
+		metadataList(f.Aliases), f.AgeLimit, f.SortOrder)
 	return err
 }
 
@@ -394,13 +486,20 @@ func (s *SearchService) insertAudioFile(a AudioFileRecord) error {
 	if err != nil {
 		return err
 	}
+	var chaptersSource any
+	if len(a.Chapters) > 0 {
+		chaptersSource = chaptersFromInfoJSON
+	}
 
+	// Embedded chapters found by an earlier probe are kept while the file size
+	// is unchanged; otherwise a NULL chapters_source queues the file for probing.
 	_, err = s.db.DB().Exec(`
 		INSERT INTO audio_files
 		(path, parent_path, filename, size, mime_type,
 		 title, meta_artist, upload_date, webpage_url, description,
-		 downloaded_at, source_path, thumbnail, age_limit, share_key, deleted, indexed_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, 0, CURRENT_TIMESTAMP)
+		 downloaded_at, source_path, thumbnail, age_limit, share_key,
+		 chapters, chapter_titles, chapters_source, sort_position, deleted, indexed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, 0, CURRENT_TIMESTAMP)
 		ON CONFLICT(path) DO UPDATE SET
 			parent_path = excluded.parent_path,
 			filename = excluded.filename,
@@ -416,11 +515,25 @@ func (s *SearchService) insertAudioFile(a AudioFileRecord) error {
 			thumbnail = excluded.thumbnail,
 			age_limit = excluded.age_limit,
 			share_key = COALESCE(audio_files.share_key, excluded.share_key),
+			chapters = CASE
+				WHEN excluded.chapters_source IS NULL AND audio_files.chapters_source = 'embedded'
+					AND audio_files.size = excluded.size THEN audio_files.chapters
+				ELSE excluded.chapters END,
+			chapter_titles = CASE
+				WHEN excluded.chapters_source IS NULL AND audio_files.chapters_source = 'embedded'
+					AND audio_files.size = excluded.size THEN audio_files.chapter_titles
+				ELSE excluded.chapter_titles END,
+			chapters_source = CASE
+				WHEN excluded.chapters_source IS NULL AND audio_files.chapters_source = 'embedded'
+					AND audio_files.size = excluded.size THEN audio_files.chapters_source
+				ELSE excluded.chapters_source END,
+			sort_position = excluded.sort_position,
 			deleted = 0,
 			indexed_at = CURRENT_TIMESTAMP
 	`, a.Path, a.ParentPath, a.Filename, a.Size, a.MimeType,
 		a.Title, a.MetaArtist, a.UploadDate, a.WebpageURL, a.Description,
-		nullIfEmpty(a.DownloadedAt), nullIfEmpty(a.SourcePath), nullIfEmpty(a.Thumbnail), a.AgeLimit, shareKey)
+		nullIfEmpty(a.DownloadedAt), nullIfEmpty(a.SourcePath), nullIfEmpty(a.Thumbnail), a.AgeLimit, shareKey,
+		a.Chapters, a.Chapters.titles(), chaptersSource, a.SortPosition)
 	return err
 }
 
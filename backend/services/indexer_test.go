@@ -0,0 +1,31 @@
+package services
+
+import "testing"
+
+func TestFolderDefaultsInheritAgeLimitAndTrackOrder(t *testing.T) {
+	twelve, eighteen := 12, 18
+	parent := folderDefaults{ageLimit: &eighteen}
+
+	child := parent.child(FolderMetadata{AgeLimit: &twelve, TrackOrder: []string{"b.mp3", "a.mp3", "b.mp3"}})
+	if child.ageLimit == nil || *child.ageLimit != 18 {
+		t.Fatalf("age limit = %v, want the parent's 18", child.ageLimit)
+	}
+	if child.trackOrder["b.mp3"] != 0 || child.trackOrder["a.mp3"] != 1 || len(child.trackOrder) != 2 {
+		t.Fatalf("track order = %v", child.trackOrder)
+	}
+	if grandchild := child.child(FolderMetadata{}); grandchild.trackOrder != nil || *grandchild.ageLimit != 18 {
+		t.Fatalf("grandchild = %#v", grandchild)
+	}
+	if none := (folderDefaults{}).child(FolderMetadata{AgeLimit: &twelve}); *none.ageLimit != 12 {
+		t.Fatalf("age limit = %d, want 12", *none.ageLimit)
+	}
+}
+
+func TestMetadataListTrimsAndJoins(t *testing.T) {
+	if got := metadataList([]string{" comedy ", "", "two\nlines"}); got != "comedy\ntwo lines" {
+		t.Fatalf("metadataList = %q", got)
+	}
+	if got := metadataList([]string{"  "}); got != nil {
+		t.Fatalf("metadataList of blanks = %#v, want nil", got)
+	}
+}
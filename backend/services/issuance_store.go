@@ -0,0 +1,296 @@
+package services
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	IssuanceStoreMemory   = "memory"
+	IssuanceStorePostgres = "postgres"
+
+	memoryIssuanceCleanupEvery    = 256
+	issuanceStoreCleanupInterval  = time.Hour
+	issuanceEventEncodedByteCount = 8
+)
+
+// IssuanceKey identifies one issuance history: the keys of a purpose issued
+// to a session or client IP.
+type IssuanceKey struct {
+	Scope    KeyLimitScope
+	Identity string
+	Purpose  MediaPurpose
+}
+
+// IssuanceStore holds the access-key issuance history that limits and
+// captcha thresholds are evaluated against. Event lists are returned oldest
+// first and only contain events newer than the retention window.
+type IssuanceStore interface {
+	Events(keys []IssuanceKey, retention time.Duration, now time.Time) (map[IssuanceKey][]time.Time, error)
+	// Record passes the current history of keys to decide and, when decide
+	// returns nil, appends now to every key. No other Record for the same
+	// keys may interleave between the two.
+	Record(
+		keys []IssuanceKey,
+		retention time.Duration,
+		now time.Time,
+		decide func(map[IssuanceKey][]time.Time) error,
+	) error
+}
+
+type MemoryIssuanceStore struct {
+	mu                  sync.Mutex
+	issuances           map[IssuanceKey][]time.Time
+	retention           map[MediaPurpose]time.Duration
+	recordsSinceCleanup int
+}
+
+func NewMemoryIssuanceStore() *MemoryIssuanceStore {
+	return &MemoryIssuanceStore{
+		issuances: make(map[IssuanceKey][]time.Time),
+		retention: make(map[MediaPurpose]time.Duration),
+	}
+}
+
+func (s *MemoryIssuanceStore) Events(
+	keys []IssuanceKey,
+	retention time.Duration,
+	now time.Time,
+) (map[IssuanceKey][]time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	eventsByKey := make(map[IssuanceKey][]time.Time, len(keys))
+	for _, key := range keys {
+		events := pruneIssuances(s.issuances[key], now, retention)
+		eventsByKey[key] = append([]time.Time(nil), events...)
+	}
+	return eventsByKey, nil
+}
+
+func (s *MemoryIssuanceStore) Record(
+	keys []IssuanceKey,
+	retention time.Duration,
+	now time.Time,
+	decide func(map[IssuanceKey][]time.Time) error,
+) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	eventsByKey := make(map[IssuanceKey][]time.Time, len(keys))
+	for _, key := range keys {
+		eventsByKey[key] = pruneIssuances(s.issuances[key], now, retention)
+		if retention > s.retention[key.Purpose] {
+			s.retention[key.Purpose] = retention
+		}
+	}
+	if err := decide(eventsByKey); err != nil {
+		for key, events := range eventsByKey {
+			if len(events) == 0 {
+				delete(s.issuances, key)
+			} else {
+				s.issuances[key] = events
+			}
+		}
+		return err
+	}
+
+	for key, events := range eventsByKey {
+		s.issuances[key] = append(events, now)
+	}
+	s.recordsSinceCleanup++
+	if s.recordsSinceCleanup >= memoryIssuanceCleanupEvery {
+		s.cleanupLocked(now)
+		s.recordsSinceCleanup = 0
+	}
+	return nil
+}
+
+func (s *MemoryIssuanceStore) cleanupLocked(now time.Time) {
+	for key, events := range s.issuances {
+		events = pruneIssuances(events, now, s.retention[key.Purpose])
+		if len(events) == 0 {
+			delete(s.issuances, key)
+		} else {
+			s.issuances[key] = events
+		}
+	}
+}
+
+// PostgresIssuanceStore shares issuance history between every instance
+// using the same database, so limits survive restarts and cannot be
+// sidestepped by spreading requests across replicas. Identities are stored
+// hashed.
+type PostgresIssuanceStore struct {
+	db *Database
+}
+
+func NewPostgresIssuanceStore(db *Database) *PostgresIssuanceStore {
+	return &PostgresIssuanceStore{db: db}
+}
+
+func (s *PostgresIssuanceStore) Events(
+	keys []IssuanceKey,
+	retention time.Duration,
+	now time.Time,
+) (map[IssuanceKey][]time.Time, error) {
+	eventsByKey := make(map[IssuanceKey][]time.Time, len(keys))
+	for _, key := range keys {
+		var encoded []byte
+		err := s.db.DB().QueryRow(`
+			SELECT events FROM access_key_issuances
+			WHERE scope = $1 AND identity_hash = $2 AND purpose = $3
+		`, string(key.Scope), issuanceIdentityHash(key.Identity), string(key.Purpose)).Scan(&encoded)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		events, err := decodeIssuanceEvents(encoded)
+		if err != nil {
+			return nil, err
+		}
+		eventsByKey[key] = pruneIssuances(events, now, retention)
+	}
+	return eventsByKey, nil
+}
+
+// Record locks the rows of every key for the length of one transaction.
+// Rows are locked in a fixed order so concurrent issuances for overlapping
+// keys cannot deadlock.
+func (s *PostgresIssuanceStore) Record(
+	keys []IssuanceKey,
+	retention time.Duration,
+	now time.Time,
+	decide func(map[IssuanceKey][]time.Time) error,
+) error {
+	ordered := append([]IssuanceKey(nil), keys...)
+	sort.Slice(ordered, func(i, j int) bool {
+		a, b := ordered[i], ordered[j]
+		if a.Scope != b.Scope {
+			return a.Scope < b.Scope
+		}
+		if a.Purpose != b.Purpose {
+			return a.Purpose < b.Purpose
+		}
+		return issuanceIdentityHash(a.Identity) < issuanceIdentityHash(b.Identity)
+	})
+
+	tx, err := s.db.DB().Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	eventsByKey := make(map[IssuanceKey][]time.Time, len(ordered))
+	for _, key := range ordered {
+		var encoded []byte
+		if err := tx.QueryRow(`
+			INSERT INTO access_key_issuances (scope, identity_hash, purpose, events, expires_at)
+			VALUES ($1, $2, $3, ''::bytea, $4)
+			ON CONFLICT (scope, identity_hash, purpose)
+			DO UPDATE SET events = access_key_issuances.events
+			RETURNING events
+		`, string(key.Scope), issuanceIdentityHash(key.Identity), string(key.Purpose), now.Add(retention)).Scan(&encoded); err != nil {
+			return fmt.Errorf("lock issuance history: %w", err)
+		}
+		events, err := decodeIssuanceEvents(encoded)
+		if err != nil {
+			return err
+		}
+		eventsByKey[key] = pruneIssuances(events, now, retention)
+	}
+
+	decision := decide(eventsByKey)
+	for _, key := range ordered {
+		events := eventsByKey[key]
+		if decision == nil {
+			events = append(events, now)
+		}
+		if len(events) == 0 {
+			if _, err := tx.Exec(`
+				DELETE FROM access_key_issuances
+				WHERE scope = $1 AND identity_hash = $2 AND purpose = $3
+			`, string(key.Scope), issuanceIdentityHash(key.Identity), string(key.Purpose)); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := tx.Exec(`
+			UPDATE access_key_issuances
+			SET events = $4, expires_at = $5
+			WHERE scope = $1 AND identity_hash = $2 AND purpose = $3
+		`, string(key.Scope), issuanceIdentityHash(key.Identity), string(key.Purpose),
+			encodeIssuanceEvents(events), events[len(events)-1].Add(retention)); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return decision
+}
+
+func (s *PostgresIssuanceStore) prune(now time.Time) (int64, error) {
+	result, err := s.db.DB().Exec(`
+		DELETE FROM access_key_issuances WHERE expires_at < $1
+	`, now)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (s *PostgresIssuanceStore) StartRetentionCleanup() {
+	cleanup := func() {
+		deleted, err := s.prune(time.Now())
+		if err != nil {
+			log.Printf("Error pruning access key issuance history: %v", err)
+			return
+		}
+		if deleted > 0 {
+			log.Printf("Removed %d expired access key issuance histories", deleted)
+		}
+	}
+
+	cleanup()
+	go func() {
+		ticker := time.NewTicker(issuanceStoreCleanupInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			cleanup()
+		}
+	}()
+}
+
+func issuanceIdentityHash(identity string) string {
+	sum := sha256.Sum256([]byte(identity))
+	return hex.EncodeToString(sum[:])
+}
+
+// Events are packed as big-endian Unix nanoseconds so they round-trip
+// exactly and RetryAfter matches the in-memory store.
+func encodeIssuanceEvents(events []time.Time) []byte {
+	encoded := make([]byte, len(events)*issuanceEventEncodedByteCount)
+	for i, event := range events {
+		binary.BigEndian.PutUint64(encoded[i*issuanceEventEncodedByteCount:], uint64(event.UnixNano()))
+	}
+	return encoded
+}
+
+func decodeIssuanceEvents(encoded []byte) ([]time.Time, error) {
+	if len(encoded)%issuanceEventEncodedByteCount != 0 {
+		return nil, fmt.Errorf("corrupt issuance history of %d bytes", len(encoded))
+	}
+	events := make([]time.Time, 0, len(encoded)/issuanceEventEncodedByteCount)
+	for offset := 0; offset < len(encoded); offset += issuanceEventEncodedByteCount {
+		nanos := int64(binary.BigEndian.Uint64(encoded[offset:]))
+		events = append(events, time.Unix(0, nanos).UTC())
+	}
+	return events, nil
+}
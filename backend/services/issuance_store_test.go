@@ -0,0 +1,181 @@
+package services
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type issuanceEventsArg []time.Time
+
+func (want issuanceEventsArg) Match(value driver.Value) bool {
+	encoded, ok := value.([]byte)
+	return ok && bytes.Equal(encoded, encodeIssuanceEvents(want))
+}
+
+func TestIssuanceEventsRoundTripExactly(t *testing.T) {
+	events := []time.Time{
+		time.Date(2026, 3, 1, 12, 0, 0, 123456789, time.UTC),
+		time.Date(2026, 3, 1, 12, 0, 1, 987654321, time.UTC),
+	}
+	decoded, err := decodeIssuanceEvents(encodeIssuanceEvents(events))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != len(events) {
+		t.Fatalf("decoded = %v", decoded)
+	}
+	for i := range events {
+		if !decoded[i].Equal(events[i]) {
+			t.Fatalf("event %d = %v, want %v", i, decoded[i], events[i])
+		}
+	}
+	if _, err := decodeIssuanceEvents([]byte{1, 2, 3}); err == nil {
+		t.Fatal("truncated history decoded without error")
+	}
+}
+
+func TestPostgresIssuanceStoreReportsSameRetryAfterAsMemory(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	var history []time.Time
+	for i := range 10 {
+		history = append(history, now.Add(time.Duration(i-50)*time.Second))
+	}
+	ipHash := issuanceIdentityHash("192.0.2.1")
+	sessionHash := issuanceIdentityHash("session-one")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO access_key_issuances`)).
+		WithArgs("ip", ipHash, "stream", now.Add(time.Minute)).
+		WillReturnRows(sqlmock.NewRows([]string{"events"}).AddRow([]byte{}))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO access_key_issuances`)).
+		WithArgs("session", sessionHash, "stream", now.Add(time.Minute)).
+		WillReturnRows(sqlmock.NewRows([]string{"events"}).AddRow(encodeIssuanceEvents(history)))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM access_key_issuances`)).
+		WithArgs("ip", ipHash, "stream").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE access_key_issuances`)).
+		WithArgs("session", sessionHash, "stream", issuanceEventsArg(history), history[9].Add(time.Minute)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	shared := newTestAccessKeyManager(t, now)
+	shared.SetIssuanceStore(NewPostgresIssuanceStore(&Database{db: db}))
+	_, sharedErr := shared.Issue("session-one", "192.0.2.1", "track", MediaPurposeStream)
+
+	local := newTestAccessKeyManager(t, now)
+	local.store.(*MemoryIssuanceStore).issuances[IssuanceKey{
+		Scope:    KeyLimitScopeSession,
+		Identity: "session-one",
+		Purpose:  MediaPurposeStream,
+	}] = history
+	_, localErr := local.Issue("session-one", "192.0.2.1", "track", MediaPurposeStream)
+
+	var sharedLimit, localLimit *KeyLimitExceededError
+	if !errors.As(sharedErr, &sharedLimit) || !errors.As(localErr, &localLimit) {
+		t.Fatalf("errors = %v, %v; want KeyLimitExceededError", sharedErr, localErr)
+	}
+	if *sharedLimit != *localLimit || sharedLimit.RetryAfter != 10*time.Second {
+		t.Fatalf("postgres limit = %#v, memory limit = %#v", sharedLimit, localLimit)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPostgresIssuanceStoreAppendsAllowedIssuance(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	stale := now.Add(-2 * time.Minute)
+	recent := now.Add(-30 * time.Second)
+	feedIdentity := feedBinding("folder")
+	feedHash := issuanceIdentityHash(feedIdentity)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO access_key_issuances`)).
+		WithArgs("session", feedHash, "feed", now.Add(time.Minute)).
+		WillReturnRows(sqlmock.NewRows([]string{"events"}).AddRow(encodeIssuanceEvents([]time.Time{stale, recent})))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE access_key_issuances`)).
+		WithArgs("session", feedHash, "feed", issuanceEventsArg{recent, now}, now.Add(time.Minute)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	store := NewPostgresIssuanceStore(&Database{db: db})
+	key := IssuanceKey{Scope: KeyLimitScopeSession, Identity: feedIdentity, Purpose: MediaPurposeFeed}
+	var seen []time.Time
+	err = store.Record([]IssuanceKey{key}, time.Minute, now, func(eventsByKey map[IssuanceKey][]time.Time) error {
+		seen = eventsByKey[key]
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != 1 || !seen[0].Equal(recent) {
+		t.Fatalf("decide saw %v, want only the event inside the window", seen)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPostgresIssuanceStorePrunesExpiredHistories(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM access_key_issuances WHERE expires_at < $1`)).
+		WithArgs(now).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+
+	deleted, err := NewPostgresIssuanceStore(&Database{db: db}).prune(now)
+	if err != nil || deleted != 4 {
+		t.Fatalf("prune = %d, %v", deleted, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAccessKeyManagerFailsOpenWhenIssuanceStoreErrors(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	unavailable := errors.New("connection refused")
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT events FROM access_key_issuances`)).WillReturnError(unavailable)
+	mock.ExpectBegin().WillReturnError(unavailable)
+
+	manager := newTestAccessKeyManager(t, now)
+	manager.SetIssuanceStore(NewPostgresIssuanceStore(&Database{db: db}))
+	if err := manager.CheckLimit("session-one", "192.0.2.1", MediaPurposeStream); err != nil {
+		t.Fatalf("CheckLimit = %v, want the limit skipped", err)
+	}
+	if _, err := manager.Issue("session-one", "192.0.2.1", "track", MediaPurposeStream); err != nil {
+		t.Fatalf("Issue = %v, want a key issued unchecked", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -169,7 +169,8 @@ func (s *LibraryService) LikedTracks(sessionID string, includeRemovalRequested b
 	rows, err := s.db.DB().Query(`
 		SELECT af.share_key, af.path, af.filename, af.title, af.meta_artist,
 		       af.parent_path, f.name, f.share_key, af.thumbnail, f.poster_image,
-		       af.age_limit, af.removal_requested_at, af.unavailable_at, af.deleted
+		       af.age_limit, af.removal_requested_at, af.unavailable_at, af.deleted,
+		       `+ThumbnailPlaceholderColumn("af")+`, `+PosterPlaceholderColumn("f")+`
 		FROM likes l
 		JOIN audio_files af ON af.id = l.audio_file_id
 		LEFT JOIN folders f ON f.path = af.parent_path
@@ -191,7 +192,7 @@ func (s *LibraryService) LikedTracks(sessionID string, includeRemovalRequested b
 			&track.ShareKey, &track.Path, &track.Filename, &track.Title, &track.Artist,
 			&track.ParentPath, &track.ParentFolderName, &track.ParentShareKey,
 			&track.AudioImage, &track.PosterImage, &track.AgeLimit, &track.RemovalRequestedAt, &unavailableAt,
-			&deleted,
+			&deleted, &track.AudioImagePlaceholder, &track.PosterImagePlaceholder,
 		); err != nil {
 			return nil, err
 		}
@@ -149,7 +149,8 @@ func TestLikedTracksScansSharedTrackSummary(t *testing.T) {
 	mock.ExpectQuery(regexp.QuoteMeta(`
 		SELECT af.share_key, af.path, af.filename, af.title, af.meta_artist,
 		       af.parent_path, f.name, f.share_key, af.thumbnail, f.poster_image,
-		       af.age_limit, af.removal_requested_at, af.unavailable_at, af.deleted
+		       af.age_limit, af.removal_requested_at, af.unavailable_at, af.deleted,
+		       `+ThumbnailPlaceholderColumn("af")+`, `+PosterPlaceholderColumn("f")+`
 		FROM likes l
 		JOIN audio_files af ON af.id = l.audio_file_id
 		LEFT JOIN folders f ON f.path = af.parent_path
@@ -162,10 +163,12 @@ func TestLikedTracksScansSharedTrackSummary(t *testing.T) {
 			"share_key", "path", "filename", "title", "meta_artist",
 			"parent_path", "folder_name", "folder_share_key", "thumbnail", "poster_image",
 			"age_limit", "removal_requested_at", "unavailable_at", "deleted",
+			"thumbnail_placeholder", "poster_placeholder",
 		}).AddRow(
 			"track-key", "folder/track.mp3", "track.mp3", "Track", "Artist",
 			"folder", "Folder", "folder-key", "thumbnail.jpg", "poster.jpg",
 			0, requestedAt, nil, 0,
+			nil, `{"blurhash":"LKO2?U%2Tw=w]~RBVZRi};RPxuwH","dominantColor":"#336699","accentColor":"#cc3322"}`,
 		))
 
 	tracks, err := service.LikedTracks("profile-id", true)
@@ -176,6 +179,10 @@ func TestLikedTracksScansSharedTrackSummary(t *testing.T) {
 		tracks[0].RemovalRequestedAt == nil || !tracks[0].RemovalRequestedAt.Equal(requestedAt) {
 		t.Fatalf("unexpected tracks: %#v", tracks)
 	}
+	if tracks[0].AudioImagePlaceholder != nil || tracks[0].PosterImagePlaceholder == nil ||
+		tracks[0].PosterImagePlaceholder.AccentColor != "#cc3322" {
+		t.Fatalf("unexpected placeholders: %#v", tracks[0].TrackSummary)
+	}
 }
 
 func TestLikedTrackKeysReturnsLightweightMembership(t *testing.T) {
@@ -126,6 +126,73 @@ func (n *NtfyService) SendContactNotification(
 	return nil
 }
 
+// SendAvailabilityNotification lists the tracks the availability job marked
+// unavailable or found back online.
+func (n *NtfyService) SendAvailabilityNotification(summary *AvailabilitySummary) error {
+	if !n.IsConfigured() {
+		return fmt.Errorf("ntfy not configured")
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Checked %d sources (%d errors, %d skipped).", summary.Checked, summary.Errors, summary.Skipped)
+	sections := []struct {
+		label   string
+		changes []AvailabilityChange
+	}{
+		{"Now unavailable", summary.NewlyUnavailable},
+		{"Available again", summary.Restored},
+	}
+	for _, section := range sections {
+		if len(section.changes) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n\n%s (%d):", section.label, len(section.changes))
+		for i, change := range section.changes {
+			if i == availabilityNotificationLimit {
+				fmt.Fprintf(&b, "\n… and %d more", len(section.changes)-i)
+				break
+			}
+			fmt.Fprintf(&b, "\n- %s: %s", change.Title, change.WebpageURL)
+		}
+	}
+
+	return n.send(b.String(), "Source Availability Changed", "audio,availability", "")
+}
+
+// availabilityNotificationLimit caps how many tracks of each kind are listed.
+const availabilityNotificationLimit = 20
+
+// SendRemovalRequestNotification announces a new creator removal request,
+// followed by its proof of ownership when one was attached.
+func (n *NtfyService) SendRemovalRequestNotification(
+	request *RemovalRequest,
+	diagnostics ContactDiagnostics,
+	attachment *NtfyAttachment,
+) error {
+	if !n.IsConfigured() {
+		return fmt.Errorf("ntfy not configured")
+	}
+
+	requester := request.RequesterEmail
+	if request.RequesterName != "" {
+		requester = request.RequesterName + " <" + request.RequesterEmail + ">"
+	}
+	body := fmt.Sprintf("Request #%d for %s %q (%s)\nFrom: %s\n\nReason:\n%s",
+		request.ID, request.TargetType, request.TargetName, request.TargetPath, requester, request.Reason)
+	if diagnosticLines := contactDiagnosticLines(diagnostics); len(diagnosticLines) > 0 {
+		body += "\n\nDiagnostics:\n" + strings.Join(diagnosticLines, "\n")
+	}
+	if err := n.send(body, "New Removal Request", "removal,request", ""); err != nil {
+		return err
+	}
+
+	if attachment != nil {
+		return n.sendAttachment(attachment)
+	}
+
+	return nil
+}
+
 func contactDiagnosticLines(diagnostics ContactDiagnostics) []string {
 	fields := []struct {
 		label string
@@ -15,7 +15,7 @@ const (
 	playbackClaimCleanupBatches  = 10
 )
 
-const trackSummaryColumns = `
+var trackSummaryColumns = `
 	af.share_key,
 	af.path,
 	af.filename,
@@ -27,7 +27,9 @@ const trackSummaryColumns = `
 	af.thumbnail,
 	f.poster_image,
 	af.age_limit,
-	af.removal_requested_at
+	af.removal_requested_at,
+	` + ThumbnailPlaceholderColumn("af") + `,
+	` + PosterPlaceholderColumn("f") + `
 `
 
 type PlaybackResult struct {
@@ -55,6 +57,8 @@ func trackSummaryScanDest(track *TrackSummary) []any {
 		&track.PosterImage,
 		&track.AgeLimit,
 		&track.RemovalRequestedAt,
+		&track.AudioImagePlaceholder,
+		&track.PosterImagePlaceholder,
 	}
 }
 
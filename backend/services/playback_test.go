@@ -161,3 +161,33 @@ func TestCleanupExpiredAccessKeyClaimsBackfillsAndDeletesABatch(t *testing.T) {
 		t.Fatalf("unmet database expectations: %v", err)
 	}
 }
+
+func TestPlaylistTracksKeepRequestedOrder(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	columns := []string{
+		"share_key", "path", "filename", "title", "artist", "album", "mime_type", "duration",
+		"has_thumbnail", "parent_share_key", "has_poster", "mature",
+	}
+	mock.ExpectQuery(`WHERE af.share_key IN \(\$1, \$2, \$3\) AND af.deleted = 0 AND af.removal_requested_at IS NULL`).
+		WithArgs("b", "missing", "a").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("a", "root/a.mp3", "a.mp3", "A", "", "root", "audio/mpeg", 10.0, false, "", false, false).
+			AddRow("b", "root/b.mp3", "b.mp3", "B", "", "root", "audio/mpeg", 0.0, true, "", false, true))
+
+	service := &PlaybackService{db: &Database{db: db}}
+	tracks, err := service.GetPlaylistTracks([]string{"b", "missing", "a"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tracks) != 2 || tracks[0].ShareKey != "b" || !tracks[0].Mature || tracks[1].ShareKey != "a" || tracks[1].DurationSeconds != 10 {
+		t.Fatalf("tracks = %#v", tracks)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
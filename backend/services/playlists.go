@@ -0,0 +1,83 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxPlaylistTracks caps how many entries a single playlist export holds.
+const MaxPlaylistTracks = 1000
+
+// PlaylistTrack carries what M3U8 and XSPF exports need for one entry.
+type PlaylistTrack struct {
+	ShareKey        string
+	Path            string
+	Filename        string
+	Title           string
+	Artist          string
+	Album           string
+	MimeType        string
+	DurationSeconds float64
+	HasThumbnail    bool
+	ParentShareKey  string
+	HasPoster       bool
+	Mature          bool
+}
+
+// GetPlaylistTracks loads playlist entries for the given share keys in the
+// order given. Deleted tracks, unknown keys and, unless includeRemovalRequested
+// is set, tracks with a pending removal request are left out.
+func (s *PlaybackService) GetPlaylistTracks(shareKeys []string, includeRemovalRequested bool) ([]PlaylistTrack, error) {
+	if len(shareKeys) > MaxPlaylistTracks {
+		shareKeys = shareKeys[:MaxPlaylistTracks]
+	}
+	if len(shareKeys) == 0 {
+		return []PlaylistTrack{}, nil
+	}
+
+	placeholders := make([]string, len(shareKeys))
+	args := make([]any, len(shareKeys))
+	for i, key := range shareKeys {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = key
+	}
+	rows, err := s.db.DB().Query(`
+		SELECT af.share_key, af.path, af.filename, COALESCE(af.title, ''), COALESCE(af.meta_artist, ''),
+		       COALESCE(f.name, ''), COALESCE(af.mime_type, ''), COALESCE(wc.duration_seconds, 0),
+		       COALESCE(af.thumbnail, '') <> '', COALESCE(f.share_key, ''),
+		       COALESCE(f.poster_image, '') <> '', COALESCE(af.age_limit, 0) >= 18
+		FROM audio_files af
+		LEFT JOIN folders f ON f.path = af.parent_path
+		LEFT JOIN waveform_cache wc ON wc.audio_file_id = af.id
+		WHERE af.share_key IN (`+strings.Join(placeholders, ", ")+`) AND af.deleted = 0`+removalDiscoveryFilter(includeRemovalRequested)+`
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byKey := make(map[string]PlaylistTrack, len(shareKeys))
+	for rows.Next() {
+		var track PlaylistTrack
+		if err := rows.Scan(
+			&track.ShareKey, &track.Path, &track.Filename, &track.Title, &track.Artist,
+			&track.Album, &track.MimeType, &track.DurationSeconds, &track.HasThumbnail, &track.ParentShareKey,
+			&track.HasPoster, &track.Mature,
+		); err != nil {
+			return nil, err
+		}
+		byKey[track.ShareKey] = track
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tracks := make([]PlaylistTrack, 0, len(byKey))
+	for _, key := range shareKeys {
+		if track, ok := byKey[key]; ok {
+			tracks = append(tracks, track)
+			delete(byKey, key)
+		}
+	}
+	return tracks, nil
+}
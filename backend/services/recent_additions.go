@@ -0,0 +1,126 @@
+package services
+
+import (
+	"database/sql"
+	"sort"
+	"time"
+)
+
+// RecentAddition is one entry in the "new additions" feeds: either a newly
+// downloaded audio file or a newly indexed folder.
+type RecentAddition struct {
+	Kind        string
+	ShareKey    string
+	Path        string
+	Title       string
+	Artist      string
+	FolderName  string
+	PosterImage string
+	AddedAt     time.Time
+}
+
+const (
+	RecentAdditionAudio  = "audio"
+	RecentAdditionFolder = "folder"
+)
+
+// GetRecentAdditions returns the newest audio files and folders, newest
+// first. When root is non-empty only paths beneath that top-level directory
+// are considered. Mature tracks are never listed, and folders are only listed
+// once they contain at least one track that would itself be listed.
+func (s *PlaybackService) GetRecentAdditions(root string, limit int, includeRemovalRequested bool) ([]RecentAddition, error) {
+	pathFilter := "%"
+	if root != "" {
+		pathFilter = likePrefix(root)
+	}
+
+	additions, err := s.recentAudioAdditions(pathFilter, limit, includeRemovalRequested)
+	if err != nil {
+		return nil, err
+	}
+	folders, err := s.recentFolderAdditions(pathFilter, limit, includeRemovalRequested)
+	if err != nil {
+		return nil, err
+	}
+	additions = append(additions, folders...)
+
+	sort.SliceStable(additions, func(i, j int) bool {
+		return additions[i].AddedAt.After(additions[j].AddedAt)
+	})
+	if len(additions) > limit {
+		additions = additions[:limit]
+	}
+	return additions, nil
+}
+
+func (s *PlaybackService) recentAudioAdditions(pathFilter string, limit int, includeRemovalRequested bool) ([]RecentAddition, error) {
+	rows, err := s.db.DB().Query(`
+		SELECT af.share_key, af.path, COALESCE(NULLIF(af.title, ''), af.filename),
+		       COALESCE(af.meta_artist, ''), COALESCE(f.name, ''), af.downloaded_at
+		FROM audio_files af
+		LEFT JOIN folders f ON f.path = af.parent_path
+		WHERE af.downloaded_at IS NOT NULL AND af.deleted = 0 AND COALESCE(af.age_limit, 0) < 18
+		  AND af.path LIKE $1`+removalDiscoveryFilter(includeRemovalRequested)+`
+		ORDER BY af.downloaded_at DESC
+		LIMIT $2
+	`, pathFilter, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []RecentAddition
+	for rows.Next() {
+		addition := RecentAddition{Kind: RecentAdditionAudio}
+		var downloadedAt string
+		if err := rows.Scan(
+			&addition.ShareKey, &addition.Path, &addition.Title,
+			&addition.Artist, &addition.FolderName, &downloadedAt,
+		); err != nil {
+			return nil, err
+		}
+		addedAt, err := time.Parse(time.RFC3339, downloadedAt)
+		if err != nil {
+			continue
+		}
+		addition.AddedAt = addedAt.UTC()
+		results = append(results, addition)
+	}
+	return results, rows.Err()
+}
+
+func (s *PlaybackService) recentFolderAdditions(pathFilter string, limit int, includeRemovalRequested bool) ([]RecentAddition, error) {
+	rows, err := s.db.DB().Query(`
+		SELECT f.share_key, f.path, f.name, COALESCE(f.poster_image, ''), f.first_indexed_at
+		FROM folders f
+		WHERE f.first_indexed_at IS NOT NULL AND f.share_key IS NOT NULL
+		  AND f.path LIKE $1
+		  AND EXISTS (
+			SELECT 1 FROM audio_files af
+			WHERE af.parent_path = f.path AND af.deleted = 0
+			  AND COALESCE(af.age_limit, 0) < 18`+removalDiscoveryFilter(includeRemovalRequested)+`
+		  )
+		ORDER BY f.first_indexed_at DESC
+		LIMIT $2
+	`, pathFilter, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []RecentAddition
+	for rows.Next() {
+		addition := RecentAddition{Kind: RecentAdditionFolder}
+		var firstIndexedAt sql.NullTime
+		if err := rows.Scan(
+			&addition.ShareKey, &addition.Path, &addition.Title,
+			&addition.PosterImage, &firstIndexedAt,
+		); err != nil {
+			return nil, err
+		}
+		addition.FolderName = addition.Title
+		addition.AddedAt = firstIndexedAt.Time.UTC()
+		results = append(results, addition)
+	}
+	return results, rows.Err()
+}
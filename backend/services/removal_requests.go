@@ -0,0 +1,395 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Removal request statuses. Approving a request hides its track, or every
+// track and folder beneath its folder, from public discovery; restoring it
+// shows them again.
+const (
+	RemovalPending  = "pending"
+	RemovalApproved = "approved"
+	RemovalRejected = "rejected"
+	RemovalRestored = "restored"
+)
+
+var ErrInvalidRemovalTransition = errors.New("invalid removal request status change")
+
+// removalTransitions lists the statuses each status can move to.
+var removalTransitions = map[string][]string{
+	RemovalPending:  {RemovalApproved, RemovalRejected},
+	RemovalApproved: {RemovalRestored},
+	RemovalRejected: {RemovalApproved},
+	RemovalRestored: {RemovalApproved},
+}
+
+func IsValidRemovalStatus(status string) bool {
+	_, ok := removalTransitions[status]
+	return ok
+}
+
+func canTransitionRemoval(from, to string) bool {
+	for _, status := range removalTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+type RemovalRequest struct {
+	ID             int64                 `json:"id"`
+	Status         string                `json:"status"`
+	TargetType     string                `json:"targetType"`
+	ShareKey       string                `json:"shareKey"`
+	TargetName     string                `json:"targetName"`
+	TargetPath     string                `json:"targetPath"`
+	RequesterName  string                `json:"requesterName,omitempty"`
+	RequesterEmail string                `json:"requesterEmail"`
+	Reason         string                `json:"reason"`
+	AdminNotes     string                `json:"adminNotes,omitempty"`
+	CreatedAt      string                `json:"createdAt"`
+	UpdatedAt      string                `json:"updatedAt"`
+	DecidedAt      *string               `json:"decidedAt,omitempty"`
+	Events         []RemovalRequestEvent `json:"events,omitempty"`
+}
+
+// RemovalRequestEvent records a submission or decision. Events are never
+// changed or deleted.
+type RemovalRequestEvent struct {
+	FromStatus *string `json:"fromStatus"`
+	ToStatus   string  `json:"toStatus"`
+	Note       string  `json:"note,omitempty"`
+	Affected   int64   `json:"affected"`
+	CreatedAt  string  `json:"createdAt"`
+}
+
+type RemovalRequestInput struct {
+	ShareKey       string
+	RequesterName  string
+	RequesterEmail string
+	Reason         string
+	SessionID      string
+}
+
+type RemovalRequestService struct {
+	db *sql.DB
+}
+
+func NewRemovalRequestService(db *sql.DB) *RemovalRequestService {
+	return &RemovalRequestService{db: db}
+}
+
+// Create files a pending request for a track or folder share key. It
+// returns ErrNotFound when the key matches neither.
+func (s *RemovalRequestService) Create(input RemovalRequestInput) (*RemovalRequest, error) {
+	request := &RemovalRequest{
+		Status:         RemovalPending,
+		ShareKey:       input.ShareKey,
+		RequesterName:  input.RequesterName,
+		RequesterEmail: input.RequesterEmail,
+		Reason:         input.Reason,
+	}
+	err := s.db.QueryRow(`
+		SELECT 'audio', path, COALESCE(NULLIF(title, ''), filename) FROM audio_files WHERE share_key = $1 AND deleted = 0
+		UNION ALL
+		SELECT 'folder', path, name FROM folders WHERE share_key = $1
+		LIMIT 1
+	`, input.ShareKey).Scan(&request.TargetType, &request.TargetPath, &request.TargetName)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var createdAt time.Time
+	if err := tx.QueryRow(`
+		INSERT INTO removal_requests
+		(status, target_type, share_key, target_path, target_name, requester_name, requester_email, reason, session_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at
+	`, RemovalPending, request.TargetType, request.ShareKey, request.TargetPath, request.TargetName,
+		nullIfEmpty(input.RequesterName), input.RequesterEmail, input.Reason, nullIfEmpty(input.SessionID),
+	).Scan(&request.ID, &createdAt); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO removal_request_events (removal_request_id, from_status, to_status, affected)
+		VALUES ($1, NULL, $2, 0)
+	`, request.ID, RemovalPending); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	request.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+	request.UpdatedAt = request.CreatedAt
+	return request, nil
+}
+
+const removalRequestColumns = `id, status, target_type, share_key, target_path, target_name,
+	COALESCE(requester_name, ''), requester_email, reason, COALESCE(admin_notes, ''),
+	created_at, updated_at, decided_at`
+
+func scanRemovalRequest(row interface{ Scan(...any) error }) (*RemovalRequest, error) {
+	var request RemovalRequest
+	var createdAt, updatedAt time.Time
+	var decidedAt sql.NullTime
+	if err := row.Scan(&request.ID, &request.Status, &request.TargetType, &request.ShareKey,
+		&request.TargetPath, &request.TargetName, &request.RequesterName, &request.RequesterEmail,
+		&request.Reason, &request.AdminNotes, &createdAt, &updatedAt, &decidedAt); err != nil {
+		return nil, err
+	}
+	request.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+	request.UpdatedAt = updatedAt.UTC().Format(time.RFC3339)
+	if decidedAt.Valid {
+		s := decidedAt.Time.UTC().Format(time.RFC3339)
+		request.DecidedAt = &s
+	}
+	return &request, nil
+}
+
+// List returns requests with the given status, or all of them. Pending
+// requests are listed oldest first so the queue is worked in order.
+func (s *RemovalRequestService) List(status string) ([]RemovalRequest, error) {
+	order := "created_at DESC"
+	if status == RemovalPending {
+		order = "created_at ASC"
+	}
+	rows, err := s.db.Query(`
+		SELECT `+removalRequestColumns+`
+		FROM removal_requests
+		WHERE $1 = '' OR status = $1
+		ORDER BY `+order+`, id ASC
+	`, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := []RemovalRequest{}
+	for rows.Next() {
+		request, err := scanRemovalRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, *request)
+	}
+	return requests, rows.Err()
+}
+
+// Get returns a request with its full history.
+func (s *RemovalRequestService) Get(id int64) (*RemovalRequest, error) {
+	request, err := scanRemovalRequest(s.db.QueryRow(`
+		SELECT `+removalRequestColumns+` FROM removal_requests WHERE id = $1
+	`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`
+		SELECT from_status, to_status, COALESCE(note, ''), affected, created_at
+		FROM removal_request_events
+		WHERE removal_request_id = $1
+		ORDER BY created_at ASC, id ASC
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	request.Events = []RemovalRequestEvent{}
+	for rows.Next() {
+		var event RemovalRequestEvent
+		var createdAt time.Time
+		if err := rows.Scan(&event.FromStatus, &event.ToStatus, &event.Note, &event.Affected, &createdAt); err != nil {
+			return nil, err
+		}
+		event.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+		request.Events = append(request.Events, event)
+	}
+	return request, rows.Err()
+}
+
+// UpdateNotes replaces the admin notes of a request.
+func (s *RemovalRequestService) UpdateNotes(id int64, notes string) error {
+	result, err := s.db.Exec(`
+		UPDATE removal_requests SET admin_notes = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2
+	`, nullIfEmpty(notes), id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Transition moves a request to a new status and applies it to the library.
+// Approval sets removal_requested_at on the track, or on the folder and
+// everything beneath it, and records which rows it flagged. Restoring clears
+// only those rows, leaving any that another approved request still covers.
+// The number of tracks and folders changed is recorded with the decision.
+func (s *RemovalRequestService) Transition(id int64, status, note string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var current, targetType, shareKey string
+	err = tx.QueryRow(`
+		SELECT status, target_type, share_key FROM removal_requests WHERE id = $1 FOR UPDATE
+	`, id).Scan(&current, &targetType, &shareKey)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if !canTransitionRemoval(current, status) {
+		return ErrInvalidRemovalTransition
+	}
+
+	var affected int64
+	switch status {
+	case RemovalApproved:
+		affected, err = approveRemoval(tx, id, targetType, shareKey, time.Now().UTC())
+	case RemovalRestored:
+		affected, err = restoreRemoval(tx, id)
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE removal_requests SET status = $1,
+			updated_at = CURRENT_TIMESTAMP, decided_at = CURRENT_TIMESTAMP
+		WHERE id = $2
+	`, status, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO removal_request_events (removal_request_id, from_status, to_status, note, affected)
+		VALUES ($1, $2, $3, $4, $5)
+	`, id, current, status, nullIfEmpty(note), affected); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// removalTargetFilters returns the WHERE conditions selecting the tracks and
+// folders a request covers, starting at argument index first. Tracks of a
+// track request are matched by share key and cover no folders, so folders
+// is empty; a folder request covers the folder and everything beneath it.
+// ok is false when the folder no longer exists.
+func removalTargetFilters(tx *sql.Tx, targetType, shareKey string, first int) (tracks, folders string, args []any, ok bool, err error) {
+	if targetType == "audio" {
+		return fmt.Sprintf("share_key = $%d AND deleted = 0", first), "", []any{shareKey}, true, nil
+	}
+	var folderPath string
+	err = tx.QueryRow(`SELECT path FROM folders WHERE share_key = $1`, shareKey).Scan(&folderPath)
+	if err == sql.ErrNoRows {
+		return "", "", nil, false, nil
+	}
+	if err != nil {
+		return "", "", nil, false, err
+	}
+	tracks = fmt.Sprintf("path LIKE $%d AND deleted = 0", first+1)
+	folders = fmt.Sprintf("(path = $%d OR path LIKE $%d)", first, first+1)
+	return tracks, folders, []any{folderPath, likePrefix(folderPath)}, true, nil
+}
+
+// approveRemoval flags the request's target rows that are not flagged yet
+// and records them as set by this request.
+func approveRemoval(tx *sql.Tx, id int64, targetType, shareKey string, requestedAt time.Time) (int64, error) {
+	trackFilter, folderFilter, args, ok, err := removalTargetFilters(tx, targetType, shareKey, 3)
+	if err != nil || !ok {
+		return 0, err
+	}
+	var affected int64
+	for _, target := range []struct{ table, itemType, filter string }{
+		{"audio_files", "audio", trackFilter},
+		{"folders", "folder", folderFilter},
+	} {
+		if target.filter == "" {
+			continue
+		}
+		result, err := tx.Exec(`
+			WITH flagged AS (
+				UPDATE `+target.table+` SET removal_requested_at = $1
+				WHERE removal_requested_at IS NULL AND `+target.filter+`
+				RETURNING id
+			)
+			INSERT INTO removal_request_items (removal_request_id, item_type, item_id)
+			SELECT $2, '`+target.itemType+`', id FROM flagged
+			ON CONFLICT DO NOTHING
+		`, append([]any{requestedAt, id}, args...)...)
+		if err != nil {
+			return 0, err
+		}
+		count, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		affected += count
+	}
+	return affected, nil
+}
+
+// removalStillCovered matches rows of the aliased table that another
+// approved request covers, with the restored request's id as $1.
+const removalStillCovered = `EXISTS (
+	SELECT 1 FROM removal_requests other
+	WHERE other.id <> $1 AND other.status = 'approved'
+	  AND (other.target_path = t.path
+	       OR (other.target_type = 'folder' AND starts_with(t.path, other.target_path || '/')))
+)`
+
+// restoreRemoval clears the rows the request flagged, except those another
+// approved request still covers.
+func restoreRemoval(tx *sql.Tx, id int64) (int64, error) {
+	var affected int64
+	for _, target := range []struct{ table, itemType string }{
+		{"audio_files", "audio"},
+		{"folders", "folder"},
+	} {
+		result, err := tx.Exec(`
+			UPDATE `+target.table+` t SET removal_requested_at = NULL
+			WHERE t.removal_requested_at IS NOT NULL
+			  AND t.id IN (SELECT item_id FROM removal_request_items WHERE removal_request_id = $1 AND item_type = '`+target.itemType+`')
+			  AND NOT `+removalStillCovered,
+			id)
+		if err != nil {
+			return 0, err
+		}
+		count, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		affected += count
+	}
+	if _, err := tx.Exec(`DELETE FROM removal_request_items WHERE removal_request_id = $1`, id); err != nil {
+		return 0, err
+	}
+	return affected, nil
+}
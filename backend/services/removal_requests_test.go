@@ -0,0 +1,93 @@
+package services
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestRemovalTransitionApprovesFolderTree(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM removal_requests WHERE id = $1 FOR UPDATE`)).WithArgs(int64(4)).
+		WillReturnRows(sqlmock.NewRows([]string{"status", "target_type", "share_key"}).
+			AddRow(RemovalPending, "folder", "folder-key"))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT path FROM folders WHERE share_key = $1`)).WithArgs("folder-key").
+		WillReturnRows(sqlmock.NewRows([]string{"path"}).AddRow("Artists/100%_Real"))
+	mock.ExpectExec(`UPDATE audio_files SET removal_requested_at = \$1\s+WHERE removal_requested_at IS NULL AND path LIKE \$4[\s\S]+INSERT INTO removal_request_items`).
+		WithArgs(sqlmock.AnyArg(), int64(4), "Artists/100%_Real", `Artists/100\%\_Real/%`).WillReturnResult(sqlmock.NewResult(0, 5))
+	mock.ExpectExec(`UPDATE folders SET removal_requested_at = \$1\s+WHERE removal_requested_at IS NULL AND \(path = \$3 OR path LIKE \$4\)[\s\S]+INSERT INTO removal_request_items`).
+		WithArgs(sqlmock.AnyArg(), int64(4), "Artists/100%_Real", `Artists/100\%\_Real/%`).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE removal_requests SET status = $1,`)).
+		WithArgs(RemovalApproved, int64(4)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO removal_request_events`)).
+		WithArgs(int64(4), RemovalPending, RemovalApproved, "Verified owner", int64(7)).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	if err := NewRemovalRequestService(db).Transition(4, RemovalApproved, "Verified owner"); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRemovalRestoreClearsOnlyRowsTheRequestFlagged(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM removal_requests WHERE id = $1 FOR UPDATE`)).WithArgs(int64(4)).
+		WillReturnRows(sqlmock.NewRows([]string{"status", "target_type", "share_key"}).
+			AddRow(RemovalApproved, "folder", "folder-key"))
+	for _, table := range []string{"audio_files", "folders"} {
+		mock.ExpectExec(`UPDATE ` + table + ` t SET removal_requested_at = NULL[\s\S]+removal_request_items WHERE removal_request_id = \$1[\s\S]+AND NOT EXISTS \(\s+SELECT 1 FROM removal_requests other`).
+			WithArgs(int64(4)).WillReturnResult(sqlmock.NewResult(0, 3))
+	}
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM removal_request_items WHERE removal_request_id = $1`)).
+		WithArgs(int64(4)).WillReturnResult(sqlmock.NewResult(0, 8))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE removal_requests SET status = $1,`)).
+		WithArgs(RemovalRestored, int64(4)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO removal_request_events`)).
+		WithArgs(int64(4), RemovalApproved, RemovalRestored, nil, int64(6)).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	if err := NewRemovalRequestService(db).Transition(4, RemovalRestored, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRemovalTransitionRejectsInvalidStatusChange(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`FOR UPDATE`)).WithArgs(int64(4)).
+		WillReturnRows(sqlmock.NewRows([]string{"status", "target_type", "share_key"}).
+			AddRow(RemovalPending, "audio", "track-key"))
+	mock.ExpectRollback()
+
+	err = NewRemovalRequestService(db).Transition(4, RemovalRestored, "")
+	if !errors.Is(err, ErrInvalidRemovalTransition) {
+		t.Fatalf("err = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
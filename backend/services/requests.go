@@ -10,6 +10,14 @@ import (
 
 var ErrNotFound = errors.New("not found")
 
+// ErrJobRunning is returned when a background job is started while a run
+// of it is already in progress.
+var ErrJobRunning = errors.New("job is already running")
+
+// RequestStatusDraft marks a request only admins can see. Drafts are left
+// out of the public board until they are moved to another status.
+const RequestStatusDraft = "draft"
+
 type Tag struct {
 	Name  string `json:"name"`
 	Color string `json:"color"`
@@ -73,13 +81,51 @@ func NewRequestsService(db *Database) *RequestsService {
 	return &RequestsService{db: db}
 }
 
+const sourceRequestSelect = `
+	SELECT sr.id, sr.submitted_url, sr.title, sr.status, sr.tags, sr.folder_share_key, f.path, sr.created_at, sr.updated_at
+	FROM source_requests sr
+	LEFT JOIN folders f ON f.share_key = sr.folder_share_key
+`
+
+func scanSourceRequest(row interface{ Scan(...any) error }) (*SourceRequest, error) {
+	var req SourceRequest
+	var folderShareKey, folderPath *string
+	var tagsJSON sql.NullString
+	var createdAt, updatedAt time.Time
+
+	if err := row.Scan(
+		&req.ID, &req.SubmittedURL, &req.Title,
+		&req.Status, &tagsJSON, &folderShareKey, &folderPath, &createdAt, &updatedAt,
+	); err != nil {
+		return nil, err
+	}
+	req.CreatedAt = createdAt.UTC().Format("2006-01-02T15:04:05Z")
+	req.UpdatedAt = updatedAt.UTC().Format("2006-01-02T15:04:05Z")
+
+	req.FolderShareKey = folderShareKey
+	req.FolderPath = folderPath
+
+	if tagsJSON.Valid {
+		if err := json.Unmarshal([]byte(tagsJSON.String), &req.Tags); err != nil {
+			log.Printf("requests: failed to unmarshal tags for id=%d url=%s: %v", req.ID, req.SubmittedURL, err)
+			req.Tags = []Tag{}
+		}
+	} else {
+		req.Tags = []Tag{}
+	}
+	return &req, nil
+}
+
+func (s *RequestsService) Get(id int64) (*SourceRequest, error) {
+	req, err := scanSourceRequest(s.db.DB().QueryRow(sourceRequestSelect+`WHERE sr.id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return req, err
+}
+
 func (s *RequestsService) GetAllGroupedByStatus() (*RequestsByStatus, error) {
-	rows, err := s.db.DB().Query(`
-		SELECT sr.id, sr.submitted_url, sr.title, sr.status, sr.tags, sr.folder_share_key, f.path, sr.created_at, sr.updated_at
-		FROM source_requests sr
-		LEFT JOIN folders f ON f.share_key = sr.folder_share_key
-		ORDER BY sr.created_at DESC
-	`)
+	rows, err := s.db.DB().Query(sourceRequestSelect+`WHERE sr.status <> $1 ORDER BY sr.created_at DESC`, RequestStatusDraft)
 	if err != nil {
 		return nil, err
 	}
@@ -94,43 +140,22 @@ func (s *RequestsService) GetAllGroupedByStatus() (*RequestsByStatus, error) {
 	}
 
 	for rows.Next() {
-		var req SourceRequest
-		var folderShareKey, folderPath *string
-		var tagsJSON sql.NullString
-		var createdAt, updatedAt time.Time
-
-		if err := rows.Scan(
-			&req.ID, &req.SubmittedURL, &req.Title,
-			&req.Status, &tagsJSON, &folderShareKey, &folderPath, &createdAt, &updatedAt,
-		); err != nil {
+		req, err := scanSourceRequest(rows)
+		if err != nil {
 			return nil, err
 		}
-		req.CreatedAt = createdAt.UTC().Format("2006-01-02T15:04:05Z")
-		req.UpdatedAt = updatedAt.UTC().Format("2006-01-02T15:04:05Z")
-
-		req.FolderShareKey = folderShareKey
-		req.FolderPath = folderPath
-
-		if tagsJSON.Valid {
-			if err := json.Unmarshal([]byte(tagsJSON.String), &req.Tags); err != nil {
-				log.Printf("requests: failed to unmarshal tags for id=%d url=%s: %v", req.ID, req.SubmittedURL, err)
-				req.Tags = []Tag{}
-			}
-		} else {
-			req.Tags = []Tag{}
-		}
 
 		switch req.Status {
 		case "requested":
-			result.Requested = append(result.Requested, req)
+			result.Requested = append(result.Requested, *req)
 		case "downloading":
-			result.Downloading = append(result.Downloading, req)
+			result.Downloading = append(result.Downloading, *req)
 		case "indexing":
-			result.Indexing = append(result.Indexing, req)
+			result.Indexing = append(result.Indexing, *req)
 		case "added":
-			result.Added = append(result.Added, req)
+			result.Added = append(result.Added, *req)
 		case "rejected":
-			result.Rejected = append(result.Rejected, req)
+			result.Rejected = append(result.Rejected, *req)
 		}
 	}
 
@@ -141,6 +166,24 @@ func (s *RequestsService) GetAllGroupedByStatus() (*RequestsByStatus, error) {
 	return result, nil
 }
 
+func (s *RequestsService) ListByStatus(status string) ([]SourceRequest, error) {
+	rows, err := s.db.DB().Query(sourceRequestSelect+`WHERE sr.status = $1 ORDER BY sr.created_at DESC`, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := []SourceRequest{}
+	for rows.Next() {
+		req, err := scanSourceRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, *req)
+	}
+	return requests, rows.Err()
+}
+
 func (s *RequestsService) Create(title, submittedURL, sourceKey string, tags []Tag, status *string) (*SourceRequest, error) {
 	tagsJSON, err := json.Marshal(tags)
 	if err != nil {
@@ -0,0 +1,544 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Scraper detection modes. Observe scores clients and records decisions
+// without acting on them.
+const (
+	ScraperDetectionOff     = "off"
+	ScraperDetectionObserve = "observe"
+	ScraperDetectionEnforce = "enforce"
+)
+
+// ScraperAction is how a client is treated at its current score. Each
+// action includes the ones below it.
+type ScraperAction string
+
+const (
+	ScraperActionNone     ScraperAction = "none"
+	ScraperActionCaptcha  ScraperAction = "captcha"
+	ScraperActionThrottle ScraperAction = "throttle"
+	ScraperActionBan      ScraperAction = "ban"
+)
+
+var scraperActionRanks = map[ScraperAction]int{
+	ScraperActionNone:     0,
+	ScraperActionCaptcha:  1,
+	ScraperActionThrottle: 2,
+	ScraperActionBan:      3,
+}
+
+// AtLeast reports whether a is the given action or a stronger one.
+func (a ScraperAction) AtLeast(action ScraperAction) bool {
+	return scraperActionRanks[a] >= scraperActionRanks[action]
+}
+
+var ErrScraperBanned = errors.New("temporarily banned for automated access")
+
+// ScraperBanCreatedBy names automatic bans in the ban list.
+const ScraperBanCreatedBy = "scraper-detection"
+
+const (
+	scraperRateWindow        = 10 * time.Minute
+	scraperFreshSessionAge   = 10 * time.Minute
+	scraperCaptchaClearance  = 15 * time.Minute
+	scraperMaxEvents         = 500
+	scraperMaxDecisions      = 500
+	scraperCleanupInterval   = 1000
+	scraperMinSequencePairs  = 5
+	scraperMinStreamSample   = 3
+	scraperMinDownloadSample = 3
+	// scraperFullFetchPercent is the share of a file a single stream
+	// response must ask for to count as fetching the whole file.
+	scraperFullFetchPercent = 90
+)
+
+type ScraperScoringOptions struct {
+	Mode string
+	// Scores from 1 to 100 at which a client must solve a captcha, is
+	// throttled, and is temporarily banned.
+	CaptchaScore  int
+	ThrottleScore int
+	BanScore      int
+	// Window is how far back behaviour is scored.
+	Window                 time.Duration
+	ThrottleBytesPerSecond int64
+	BanDuration            time.Duration
+	// Ban blocks a "session" or "ip" until expiresAt. It is called without
+	// any scorer lock held.
+	Ban func(kind, value, reason string, expiresAt time.Time) error
+}
+
+type ScraperSignal struct {
+	Name   string `json:"name"`
+	Points int    `json:"points"`
+	Detail string `json:"detail"`
+}
+
+// ScraperAssessment is the current score of a session or client IP and the
+// signals behind it.
+type ScraperAssessment struct {
+	Kind           string          `json:"kind"`
+	Identity       string          `json:"identity"`
+	Score          int             `json:"score"`
+	Action         ScraperAction   `json:"action"`
+	CaptchaCleared bool            `json:"captchaCleared,omitempty"`
+	Signals        []ScraperSignal `json:"signals"`
+}
+
+// ScraperDecision records a change in how a client is treated.
+type ScraperDecision struct {
+	At string `json:"at"`
+	ScraperAssessment
+	Enforced bool `json:"enforced"`
+}
+
+type scraperIdentity struct {
+	kind  string
+	value string
+}
+
+type scraperMedia struct {
+	at          time.Time
+	audioFileID int64
+	download    bool
+	// fullFetch is a browser stream response that asked for nearly the
+	// whole file, whether without a Range header or with one such as
+	// "bytes=0-" or "bytes=1-".
+	fullFetch   bool
+	browser     bool
+	streamedYet bool
+}
+
+type scraperState struct {
+	issuances       []time.Time
+	freshIssuances  []time.Time
+	sessions        map[string]time.Time
+	media           []scraperMedia
+	streamed        map[int64]time.Time
+	captchaSolvedAt time.Time
+	bannedUntil     time.Time
+	lastSeen        time.Time
+	assessment      ScraperAssessment
+}
+
+// ScraperScorer scores sessions and client IPs on how they use media keys
+// and escalates clients that behave like scrapers. State is held in memory,
+// like the access key limits.
+type ScraperScorer struct {
+	options ScraperScoringOptions
+	now     func() time.Time
+
+	mu                       sync.Mutex
+	states                   map[scraperIdentity]*scraperState
+	decisions                []ScraperDecision
+	observationsSinceCleanup int
+}
+
+func NewScraperScorer(options ScraperScoringOptions) (*ScraperScorer, error) {
+	if options.Mode != ScraperDetectionObserve && options.Mode != ScraperDetectionEnforce {
+		return nil, fmt.Errorf("invalid scraper detection mode %q", options.Mode)
+	}
+	if options.CaptchaScore <= 0 || options.CaptchaScore > options.ThrottleScore ||
+		options.ThrottleScore > options.BanScore || options.BanScore > 100 {
+		return nil, errors.New("scraper scores must satisfy 0 < captcha <= throttle <= ban <= 100")
+	}
+	if options.Window <= 0 || options.BanDuration <= 0 || options.ThrottleBytesPerSecond <= 0 {
+		return nil, errors.New("scraper window, ban duration and throttle rate must be positive")
+	}
+	return &ScraperScorer{
+		options: options,
+		now:     time.Now,
+		states:  make(map[scraperIdentity]*scraperState),
+	}, nil
+}
+
+func (s *ScraperScorer) Mode() string {
+	return s.options.Mode
+}
+
+func (s *ScraperScorer) Enforcing() bool {
+	return s.options.Mode == ScraperDetectionEnforce
+}
+
+// ObserveIssuance records a media key issued to a session from a client IP.
+// sessionCreatedAt is zero when the session's age is unknown.
+func (s *ScraperScorer) ObserveIssuance(sessionID, clientIP string, sessionCreatedAt time.Time) {
+	now := s.now()
+	fresh := !sessionCreatedAt.IsZero() && now.Sub(sessionCreatedAt) < scraperFreshSessionAge
+	s.observe(sessionID, clientIP, now, func(state *scraperState, kind string) {
+		state.issuances = appendCapped(state.issuances, now)
+		if fresh {
+			state.freshIssuances = appendCapped(state.freshIssuances, now)
+		}
+		if kind == BanKindIP && sessionID != "" {
+			if state.sessions == nil {
+				state.sessions = make(map[string]time.Time)
+			}
+			state.sessions[sessionID] = now
+		}
+	})
+}
+
+// ObserveMedia records one stream or download response. It is called for
+// every GET, Range requests included, so a request starting past byte zero
+// cannot hide a stream. requestedBytes is how much of the fileSize-byte
+// file the request asked for. browser is false for app clients, which may
+// fetch whole files.
+func (s *ScraperScorer) ObserveMedia(
+	sessionID, clientIP string,
+	audioFileID int64,
+	download, browser bool,
+	requestedBytes, fileSize int64,
+) {
+	now := s.now()
+	fullFetch := !download && browser && fileSize > 0 &&
+		requestedBytes*100 >= fileSize*scraperFullFetchPercent
+	s.observe(sessionID, clientIP, now, func(state *scraperState, kind string) {
+		_, streamed := state.streamed[audioFileID]
+		state.media = appendCapped(state.media, scraperMedia{
+			at:          now,
+			audioFileID: audioFileID,
+			download:    download,
+			fullFetch:   fullFetch,
+			browser:     browser,
+			streamedYet: streamed,
+		})
+		if !download {
+			if state.streamed == nil {
+				state.streamed = make(map[int64]time.Time)
+			}
+			state.streamed[audioFileID] = now
+		}
+	})
+}
+
+// CaptchaSolved waives the captcha for a while. Throttles and bans still
+// apply if the score keeps rising.
+func (s *ScraperScorer) CaptchaSolved(sessionID, clientIP string) {
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, identity := range scraperIdentities(sessionID, clientIP) {
+		if state, ok := s.states[identity]; ok {
+			state.captchaSolvedAt = now
+		}
+	}
+}
+
+// Assess returns the stronger assessment of the session and the client IP.
+func (s *ScraperScorer) Assess(sessionID, clientIP string) ScraperAssessment {
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	strongest := ScraperAssessment{Action: ScraperActionNone, Signals: []ScraperSignal{}}
+	for _, identity := range scraperIdentities(sessionID, clientIP) {
+		state, ok := s.states[identity]
+		if !ok {
+			continue
+		}
+		assessment := s.currentAssessment(identity, state, now)
+		if scraperActionRanks[assessment.Action] > scraperActionRanks[strongest.Action] ||
+			(assessment.Action == strongest.Action && assessment.Score > strongest.Score) {
+			strongest = assessment
+		}
+	}
+	return strongest
+}
+
+// ThrottleBytesPerSecond returns the bandwidth cap for a client, or 0 when
+// it is not throttled.
+func (s *ScraperScorer) ThrottleBytesPerSecond(sessionID, clientIP string) int64 {
+	if !s.Enforcing() || !s.Assess(sessionID, clientIP).Action.AtLeast(ScraperActionThrottle) {
+		return 0
+	}
+	return s.options.ThrottleBytesPerSecond
+}
+
+// Assessment returns the current assessment of one session or IP.
+func (s *ScraperScorer) Assessment(kind, identity string) (ScraperAssessment, bool) {
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := scraperIdentity{kind: kind, value: identity}
+	state, ok := s.states[key]
+	if !ok {
+		return ScraperAssessment{}, false
+	}
+	return s.currentAssessment(key, state, now), true
+}
+
+// Flagged returns every client currently above the captcha score, highest
+// score first.
+func (s *ScraperScorer) Flagged() []ScraperAssessment {
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	flagged := []ScraperAssessment{}
+	for identity, state := range s.states {
+		if assessment := s.currentAssessment(identity, state, now); assessment.Action != ScraperActionNone {
+			flagged = append(flagged, assessment)
+		}
+	}
+	slices.SortFunc(flagged, func(a, b ScraperAssessment) int {
+		if a.Score != b.Score {
+			return b.Score - a.Score
+		}
+		return strings.Compare(a.Identity, b.Identity)
+	})
+	return flagged
+}
+
+// Decisions returns up to limit recorded decisions, newest first.
+func (s *ScraperScorer) Decisions(limit int) []ScraperDecision {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	decisions := make([]ScraperDecision, 0, min(limit, len(s.decisions)))
+	for i := len(s.decisions) - 1; i >= 0 && len(decisions) < limit; i-- {
+		decisions = append(decisions, s.decisions[i])
+	}
+	return decisions
+}
+
+type pendingScraperBan struct {
+	identity  scraperIdentity
+	reason    string
+	expiresAt time.Time
+}
+
+func (s *ScraperScorer) observe(sessionID, clientIP string, now time.Time, update func(*scraperState, string)) {
+	var bans []pendingScraperBan
+
+	s.mu.Lock()
+	for _, identity := range scraperIdentities(sessionID, clientIP) {
+		state, ok := s.states[identity]
+		if !ok {
+			state = &scraperState{}
+			s.states[identity] = state
+		}
+		state.lastSeen = now
+		update(state, identity.kind)
+		s.rescoreLocked(identity, state, now)
+		if s.Enforcing() && state.assessment.Action == ScraperActionBan && !state.bannedUntil.After(now) {
+			state.bannedUntil = now.Add(s.options.BanDuration)
+			bans = append(bans, pendingScraperBan{
+				identity:  identity,
+				reason:    scraperBanReason(state.assessment),
+				expiresAt: state.bannedUntil,
+			})
+		}
+	}
+	s.observationsSinceCleanup++
+	if s.observationsSinceCleanup >= scraperCleanupInterval {
+		s.cleanupLocked(now)
+		s.observationsSinceCleanup = 0
+	}
+	s.mu.Unlock()
+
+	for _, ban := range bans {
+		if s.options.Ban == nil {
+			continue
+		}
+		if err := s.options.Ban(ban.identity.kind, ban.identity.value, ban.reason, ban.expiresAt); err != nil {
+			log.Printf("Scraper detection: banning %s %s failed: %v", ban.identity.kind, ban.identity.value, err)
+			s.mu.Lock()
+			if state, ok := s.states[ban.identity]; ok {
+				state.bannedUntil = time.Time{}
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// rescoreLocked drops events older than the window and scores what is
+// left, recording a decision when the action changes.
+func (s *ScraperScorer) rescoreLocked(identity scraperIdentity, state *scraperState, now time.Time) {
+	s.pruneLocked(state, now)
+	previous := state.assessment.Action
+	state.assessment = s.score(identity, state, now)
+	if state.assessment.Action != previous && (previous != "" || state.assessment.Action != ScraperActionNone) {
+		s.recordDecisionLocked(state.assessment, now)
+	}
+}
+
+// currentAssessment rescores a client, so signals that have aged out of the
+// window no longer count, and applies a recent captcha solve.
+func (s *ScraperScorer) currentAssessment(identity scraperIdentity, state *scraperState, now time.Time) ScraperAssessment {
+	s.rescoreLocked(identity, state, now)
+	assessment := state.assessment
+	assessment.Signals = slices.Clone(assessment.Signals)
+	if !state.captchaSolvedAt.IsZero() && now.Sub(state.captchaSolvedAt) < scraperCaptchaClearance {
+		assessment.CaptchaCleared = true
+	}
+	return assessment
+}
+
+func (s *ScraperScorer) score(identity scraperIdentity, state *scraperState, now time.Time) ScraperAssessment {
+	signals := []ScraperSignal{}
+	add := func(name string, points int, detail string, args ...any) {
+		if points > 0 {
+			signals = append(signals, ScraperSignal{Name: name, Points: points, Detail: fmt.Sprintf(detail, args...)})
+		}
+	}
+
+	recentKeys := len(state.issuances) - firstWithinWindow(state.issuances, now, scraperRateWindow)
+	switch {
+	case recentKeys >= 80:
+		add("key_rate", 30, "%d media keys in %s", recentKeys, scraperRateWindow)
+	case recentKeys >= 40:
+		add("key_rate", 20, "%d media keys in %s", recentKeys, scraperRateWindow)
+	case recentKeys >= 20:
+		add("key_rate", 10, "%d media keys in %s", recentKeys, scraperRateWindow)
+	}
+
+	var pairs, adjacent, browserStreams, fullFetches, downloads, unstreamed int
+	for i, media := range state.media {
+		if i > 0 && media.audioFileID != state.media[i-1].audioFileID {
+			pairs++
+			if delta := media.audioFileID - state.media[i-1].audioFileID; delta == 1 || delta == -1 {
+				adjacent++
+			}
+		}
+		if !media.download && media.browser {
+			browserStreams++
+			if media.fullFetch {
+				fullFetches++
+			}
+		}
+		if media.download {
+			downloads++
+			if !media.streamedYet {
+				unstreamed++
+			}
+		}
+	}
+	if pairs >= scraperMinSequencePairs {
+		if ratio := float64(adjacent) / float64(pairs); ratio >= 0.5 {
+			add("sequential_access", int(math.Round(25*ratio)),
+				"%d of %d consecutive tracks are neighbours in the catalog", adjacent, pairs)
+		}
+	}
+	if browserStreams >= scraperMinStreamSample && fullFetches*2 >= browserStreams {
+		add("range_pattern", 15, "%d of %d browser stream responses asked for the whole file",
+			fullFetches, browserStreams)
+	}
+	if downloads >= scraperMinDownloadSample {
+		if ratio := float64(unstreamed) / float64(downloads); ratio >= 0.8 {
+			add("download_without_stream", int(math.Round(25*ratio)),
+				"%d of %d downloads were never streamed first", unstreamed, downloads)
+		}
+	}
+	if len(state.freshIssuances) >= 10 {
+		add("fresh_session", 10, "%d media keys issued to sessions younger than %s",
+			len(state.freshIssuances), scraperFreshSessionAge)
+	}
+	switch sessions := len(state.sessions); {
+	case sessions >= 20:
+		add("session_rotation", 20, "%d sessions used this IP", sessions)
+	case sessions >= 5:
+		add("session_rotation", 10, "%d sessions used this IP", sessions)
+	}
+
+	score := 0
+	for _, signal := range signals {
+		score += signal.Points
+	}
+	score = min(score, 100)
+
+	action := ScraperActionNone
+	switch {
+	case score >= s.options.BanScore:
+		action = ScraperActionBan
+	case score >= s.options.ThrottleScore:
+		action = ScraperActionThrottle
+	case score >= s.options.CaptchaScore:
+		action = ScraperActionCaptcha
+	}
+	return ScraperAssessment{
+		Kind:     identity.kind,
+		Identity: identity.value,
+		Score:    score,
+		Action:   action,
+		Signals:  signals,
+	}
+}
+
+func (s *ScraperScorer) recordDecisionLocked(assessment ScraperAssessment, now time.Time) {
+	log.Printf("Scraper detection: %s %s scored %d, action=%s enforced=%t",
+		assessment.Kind, assessment.Identity, assessment.Score, assessment.Action, s.Enforcing())
+	s.decisions = append(s.decisions, ScraperDecision{
+		At:                now.UTC().Format(time.RFC3339),
+		ScraperAssessment: assessment,
+		Enforced:          s.Enforcing(),
+	})
+	if len(s.decisions) > scraperMaxDecisions {
+		s.decisions = slices.Delete(s.decisions, 0, len(s.decisions)-scraperMaxDecisions)
+	}
+}
+
+func (s *ScraperScorer) pruneLocked(state *scraperState, now time.Time) {
+	state.issuances = pruneIssuances(state.issuances, now, s.options.Window)
+	state.freshIssuances = pruneIssuances(state.freshIssuances, now, s.options.Window)
+	first := 0
+	for first < len(state.media) && !state.media[first].at.Add(s.options.Window).After(now) {
+		first++
+	}
+	state.media = state.media[first:]
+	for id, at := range state.streamed {
+		if !at.Add(s.options.Window).After(now) {
+			delete(state.streamed, id)
+		}
+	}
+	for sessionID, at := range state.sessions {
+		if !at.Add(s.options.Window).After(now) {
+			delete(state.sessions, sessionID)
+		}
+	}
+}
+
+func (s *ScraperScorer) cleanupLocked(now time.Time) {
+	for identity, state := range s.states {
+		if !state.lastSeen.Add(s.options.Window).After(now) && !state.bannedUntil.After(now) {
+			delete(s.states, identity)
+		}
+	}
+}
+
+func scraperIdentities(sessionID, clientIP string) []scraperIdentity {
+	identities := make([]scraperIdentity, 0, 2)
+	if sessionID != "" {
+		identities = append(identities, scraperIdentity{kind: BanKindSession, value: sessionID})
+	}
+	if clientIP != "" {
+		identities = append(identities, scraperIdentity{kind: BanKindIP, value: clientIP})
+	}
+	return identities
+}
+
+func scraperBanReason(assessment ScraperAssessment) string {
+	names := make([]string, 0, len(assessment.Signals))
+	for _, signal := range assessment.Signals {
+		names = append(names, signal.Name)
+	}
+	return fmt.Sprintf("Automatic: scraper score %d (%s)", assessment.Score, strings.Join(names, ", "))
+}
+
+func appendCapped[T any](events []T, event T) []T {
+	events = append(events, event)
+	if len(events) > scraperMaxEvents {
+		events = slices.Delete(events, 0, len(events)-scraperMaxEvents)
+	}
+	return events
+}
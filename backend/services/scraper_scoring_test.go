@@ -0,0 +1,213 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func newTestScraperScorer(t *testing.T, mode string, now *time.Time, ban func(kind, value, reason string, expiresAt time.Time) error) *ScraperScorer {
+	t.Helper()
+	scorer, err := NewScraperScorer(ScraperScoringOptions{
+		Mode:                   mode,
+		CaptchaScore:           40,
+		ThrottleScore:          60,
+		BanScore:               85,
+		Window:                 time.Hour,
+		ThrottleBytesPerSecond: 65536,
+		BanDuration:            24 * time.Hour,
+		Ban:                    ban,
+	})
+	if err != nil {
+		t.Fatalf("NewScraperScorer returned error: %v", err)
+	}
+	scorer.now = func() time.Time { return *now }
+	return scorer
+}
+
+func TestNewScraperScorerRejectsInvalidOptions(t *testing.T) {
+	valid := ScraperScoringOptions{
+		Mode: ScraperDetectionEnforce, CaptchaScore: 40, ThrottleScore: 60, BanScore: 85,
+		Window: time.Hour, ThrottleBytesPerSecond: 1, BanDuration: time.Hour,
+	}
+	for name, change := range map[string]func(*ScraperScoringOptions){
+		"mode":     func(o *ScraperScoringOptions) { o.Mode = "strict" },
+		"order":    func(o *ScraperScoringOptions) { o.ThrottleScore = 90 },
+		"range":    func(o *ScraperScoringOptions) { o.BanScore = 101 },
+		"window":   func(o *ScraperScoringOptions) { o.Window = 0 },
+		"throttle": func(o *ScraperScoringOptions) { o.ThrottleBytesPerSecond = 0 },
+	} {
+		options := valid
+		change(&options)
+		if _, err := NewScraperScorer(options); err == nil {
+			t.Errorf("%s: NewScraperScorer succeeded", name)
+		}
+	}
+}
+
+func TestScraperScorerEscalatesSequentialUnstreamedDownloads(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	type banCall struct{ kind, value string }
+	var bans []banCall
+	scorer := newTestScraperScorer(t, ScraperDetectionEnforce, &now, func(kind, value, reason string, expiresAt time.Time) error {
+		if !expiresAt.Equal(now.Add(24 * time.Hour)) {
+			t.Errorf("ban expires at %s", expiresAt)
+		}
+		bans = append(bans, banCall{kind, value})
+		return nil
+	})
+
+	createdAt := now.Add(-time.Minute)
+	for id := int64(1); id <= 85; id++ {
+		scorer.ObserveIssuance("scraper", "192.0.2.1", createdAt)
+		scorer.ObserveMedia("scraper", "192.0.2.1", id, true, true, 4096, 4096)
+		now = now.Add(5 * time.Second)
+	}
+
+	assessment := scorer.Assess("scraper", "192.0.2.1")
+	if assessment.Action != ScraperActionBan || assessment.Score < 85 {
+		t.Fatalf("assessment = %#v, want a ban", assessment)
+	}
+	names := map[string]bool{}
+	for _, signal := range assessment.Signals {
+		names[signal.Name] = true
+	}
+	for _, want := range []string{"key_rate", "sequential_access", "download_without_stream", "fresh_session"} {
+		if !names[want] {
+			t.Errorf("signals %v missing %s", assessment.Signals, want)
+		}
+	}
+	if len(bans) != 2 || bans[0] != (banCall{BanKindSession, "scraper"}) || bans[1] != (banCall{BanKindIP, "192.0.2.1"}) {
+		t.Fatalf("bans = %#v, want one for the session and one for the IP", bans)
+	}
+	if limit := scorer.ThrottleBytesPerSecond("scraper", ""); limit != 65536 {
+		t.Fatalf("throttle = %d", limit)
+	}
+
+	decisions := scorer.Decisions(10)
+	if len(decisions) == 0 || decisions[0].Action != ScraperActionBan || !decisions[0].Enforced {
+		t.Fatalf("decisions = %#v", decisions)
+	}
+	if flagged := scorer.Flagged(); len(flagged) != 2 {
+		t.Fatalf("flagged = %#v", flagged)
+	}
+}
+
+func TestScraperScorerLeavesListenersAlone(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	scorer := newTestScraperScorer(t, ScraperDetectionEnforce, &now, nil)
+
+	for _, id := range []int64{40, 12, 97, 3, 55, 21, 70, 8} {
+		scorer.ObserveIssuance("listener", "192.0.2.2", now.Add(-24*time.Hour))
+		// The audio element opens with "bytes=0-", then seeks.
+		scorer.ObserveMedia("listener", "192.0.2.2", id, false, true, 4096, 4096)
+		scorer.ObserveMedia("listener", "192.0.2.2", id, false, true, 1024, 4096)
+		scorer.ObserveMedia("listener", "192.0.2.2", id, false, true, 512, 4096)
+		if id%2 == 0 {
+			scorer.ObserveMedia("listener", "192.0.2.2", id, true, true, 4096, 4096)
+		}
+		now = now.Add(3 * time.Minute)
+	}
+
+	if assessment := scorer.Assess("listener", "192.0.2.2"); assessment.Action != ScraperActionNone || assessment.Score != 0 {
+		t.Fatalf("assessment = %#v, want no action", assessment)
+	}
+	if decisions := scorer.Decisions(10); len(decisions) != 0 {
+		t.Fatalf("decisions = %#v", decisions)
+	}
+}
+
+func TestScraperScorerCountsRangedWholeFileFetches(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	scorer := newTestScraperScorer(t, ScraperDetectionEnforce, &now, nil)
+
+	// "bytes=0-" and "bytes=1-" both ask for the whole file.
+	for _, requested := range []int64{4096, 4095, 4096, 4095} {
+		scorer.ObserveMedia("fetcher", "192.0.2.5", 7, false, true, requested, 4096)
+	}
+	// Continuation ranges from an app client are not browser streams.
+	scorer.ObserveMedia("fetcher", "192.0.2.5", 7, false, false, 4096, 4096)
+
+	assessment := scorer.Assess("fetcher", "192.0.2.5")
+	var found bool
+	for _, signal := range assessment.Signals {
+		if signal.Name == "range_pattern" {
+			found = true
+			if signal.Detail != "4 of 4 browser stream responses asked for the whole file" {
+				t.Fatalf("range_pattern detail = %q", signal.Detail)
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("signals = %#v, want range_pattern", assessment.Signals)
+	}
+}
+
+func TestScraperScorerObserveModeOnlyRecords(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	scorer := newTestScraperScorer(t, ScraperDetectionObserve, &now, func(kind, value, reason string, expiresAt time.Time) error {
+		t.Fatal("observe mode banned a client")
+		return nil
+	})
+
+	for id := int64(1); id <= 90; id++ {
+		scorer.ObserveIssuance("", "192.0.2.3", now)
+		scorer.ObserveMedia("", "192.0.2.3", id, true, true, 4096, 4096)
+	}
+
+	if scorer.ThrottleBytesPerSecond("", "192.0.2.3") != 0 {
+		t.Fatal("observe mode throttled a client")
+	}
+	decisions := scorer.Decisions(1)
+	if len(decisions) != 1 || decisions[0].Action != ScraperActionBan || decisions[0].Enforced {
+		t.Fatalf("decisions = %#v", decisions)
+	}
+}
+
+func TestScraperScorerCaptchaSolveWaivesOnlyCaptcha(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	scorer := newTestScraperScorer(t, ScraperDetectionEnforce, &now, nil)
+
+	for i := 0; i < 25; i++ {
+		scorer.ObserveIssuance(fmt.Sprintf("session-%d", i), "192.0.2.4", time.Time{})
+	}
+	for i := 0; i < 20; i++ {
+		scorer.ObserveIssuance("session-0", "192.0.2.4", time.Time{})
+	}
+	assessment := scorer.Assess("session-0", "192.0.2.4")
+	if assessment.Action != ScraperActionCaptcha || assessment.CaptchaCleared {
+		t.Fatalf("assessment = %#v, want a captcha", assessment)
+	}
+
+	scorer.CaptchaSolved("session-0", "192.0.2.4")
+	if assessment := scorer.Assess("session-0", "192.0.2.4"); !assessment.CaptchaCleared {
+		t.Fatalf("assessment after solve = %#v", assessment)
+	}
+	now = now.Add(scraperCaptchaClearance)
+	if assessment := scorer.Assess("session-0", "192.0.2.4"); assessment.CaptchaCleared {
+		t.Fatal("captcha clearance did not expire")
+	}
+}
+
+func TestScraperScorerForgetsSignalsOutsideTheWindow(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	scorer := newTestScraperScorer(t, ScraperDetectionEnforce, &now, nil)
+
+	for i := 0; i < 85; i++ {
+		scorer.ObserveIssuance("session-0", "192.0.2.6", now)
+	}
+	if assessment := scorer.Assess("session-0", "192.0.2.6"); assessment.Action != ScraperActionCaptcha {
+		t.Fatalf("assessment = %#v, want a captcha", assessment)
+	}
+
+	now = now.Add(time.Hour)
+	if assessment := scorer.Assess("session-0", "192.0.2.6"); assessment.Action != ScraperActionNone || assessment.Score != 0 {
+		t.Fatalf("assessment after the window = %#v, want no action", assessment)
+	}
+	if flagged := scorer.Flagged(); len(flagged) != 0 {
+		t.Fatalf("flagged after the window = %#v", flagged)
+	}
+	if assessment, ok := scorer.Assessment(BanKindIP, "192.0.2.6"); !ok || assessment.Action != ScraperActionNone {
+		t.Fatalf("IP assessment after the window = %#v, %v", assessment, ok)
+	}
+}
@@ -2,7 +2,9 @@ package services
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 )
@@ -33,6 +35,10 @@ type SearchResult struct {
 	ModifiedAt         string  `json:"modifiedAt,omitempty"`
 	UnavailableAt      *string `json:"unavailableAt,omitempty"`
 	RemovalRequestedAt *string `json:"removalRequestedAt,omitempty"`
+
+	Placeholder *ArtworkPlaceholder `json:"placeholder,omitempty"`
+
+	TranscriptMatch *TranscriptMatch `json:"transcriptMatch,omitempty"`
 }
 
 type SearchOptions struct {
@@ -48,17 +54,44 @@ type SearchOptions struct {
 	// Seconds; 0 means no bound
 	DurationMin float64
 	DurationMax float64
-	// Which audio fields to search in: "filename", "title", "artist", "description"
-	// Empty means search all fields.
+	// Which audio fields to search in: "filename", "title", "artist", "description", "chapters",
+	// "transcript". Empty means search all fields.
 	Fields []string
 	// Root path slug to limit results to a configured root directory.
 	Root string
-	// Include audio files marked with age_limit >= 18.
+	// Folder path to limit results to items beneath it. Must name an indexed folder.
+	Path string
+	// Include audio files and folders marked with age_limit >= 18.
 	IncludeMature bool
-	// Include audio files hidden from public discovery after a creator removal request.
+	// Include audio files and folders hidden from public discovery after a creator removal request.
 	IncludeRemovalRequested bool
 }
 
+var ErrFolderNotFound = errors.New("folder not found")
+
+// searchFieldClauses maps search fields to their audio_files condition; %d is
+// the placeholder number of the LIKE pattern.
+var searchFieldClauses = map[string]string{
+	"filename":    "filename ILIKE $%d",
+	"title":       "title ILIKE $%d",
+	"artist":      "meta_artist ILIKE $%d",
+	"description": "description ILIKE $%d",
+	"chapters":    "chapter_titles ILIKE $%d",
+	"transcript": `EXISTS (SELECT 1 FROM transcripts t JOIN transcript_cues tc ON tc.transcript_id = t.id
+		WHERE t.audio_file_id = audio_files.id AND tc.text ILIKE $%d)`,
+}
+
+var defaultSearchFields = []string{"filename", "title", "artist", "description", "chapters", "transcript"}
+
+// transcriptMatchJoin finds the first cue matching the query for results
+// found through their transcript.
+const transcriptMatchJoin = `LEFT JOIN LATERAL (
+			SELECT tc.start_time, tc.text FROM transcripts t
+			JOIN transcript_cues tc ON tc.transcript_id = t.id
+			WHERE t.audio_file_id = audio_files.id AND tc.text ILIKE $1
+			ORDER BY t.filename, tc.position LIMIT 1
+		) transcript_match ON true`
+
 type SearchService struct {
 	db             *Database
 	fs             *FileSystemService
@@ -82,46 +115,45 @@ func (s *SearchService) Search(query string, limit int, offset int, opts SearchO
 	if offset < 0 {
 		offset = 0
 	}
+	if opts.Path != "" {
+		exists, err := s.FolderExists(opts.Path)
+		if err != nil {
+			return nil, 0, err
+		}
+		if !exists {
+			return nil, 0, ErrFolderNotFound
+		}
+	}
 
 	// Determine which arms of the UNION to include
 	includeAudio := opts.Type != "folder"
 	includeFolders := opts.Type != "audio" && !opts.UnavailableOnly && opts.DurationMin == 0 && opts.DurationMax == 0
 
 	// --- Build audio WHERE clause ---
-	var audioArgs []any
+	var audioArgs, transcriptArgs []any
 	audioWhere := "deleted = 0"
+	transcriptJoin := ""
 
 	if query != "" {
 		likeQuery := "%" + query + "%"
-		fieldMap := map[string]string{
-			"filename":    "filename",
-			"title":       "title",
-			"artist":      "meta_artist",
-			"description": "description",
-		}
 		activeFields := opts.Fields
-		if len(activeFields) == 0 {
-			activeFields = []string{"filename", "title", "artist", "description"}
+		if !slices.ContainsFunc(activeFields, func(f string) bool { return searchFieldClauses[f] != "" }) {
+			activeFields = defaultSearchFields
 		}
 		var fieldClauses []string
 		for _, f := range activeFields {
-			col, ok := fieldMap[f]
+			clause, ok := searchFieldClauses[f]
 			if !ok {
 				continue
 			}
 			audioArgs = append(audioArgs, likeQuery)
-			fieldClauses = append(fieldClauses, fmt.Sprintf("%s ILIKE $%d", col, len(audioArgs)))
-		}
-		if len(fieldClauses) == 0 {
-			audioArgs = append(audioArgs, likeQuery, likeQuery, likeQuery, likeQuery)
-			fieldClauses = []string{
-				fmt.Sprintf("filename ILIKE $%d", len(audioArgs)-3),
-				fmt.Sprintf("title ILIKE $%d", len(audioArgs)-2),
-				fmt.Sprintf("meta_artist ILIKE $%d", len(audioArgs)-1),
-				fmt.Sprintf("description ILIKE $%d", len(audioArgs)),
-			}
+			fieldClauses = append(fieldClauses, fmt.Sprintf(clause, len(audioArgs)))
 		}
 		audioWhere = fmt.Sprintf("(%s) AND deleted = 0", strings.Join(fieldClauses, " OR "))
+		if slices.Contains(activeFields, "transcript") {
+			transcriptJoin = transcriptMatchJoin
+			transcriptArgs = append(transcriptArgs, likeQuery)
+		}
 	}
 
 	argIdx := len(audioArgs) + 1
@@ -150,6 +182,11 @@ func (s *SearchService) Search(query string, limit int, offset int, opts SearchO
 		audioArgs = append(audioArgs, opts.Root, opts.Root+"/%")
 		argIdx += 2
 	}
+	if opts.Path != "" {
+		audioWhere += fmt.Sprintf(" AND path LIKE $%d", argIdx)
+		audioArgs = append(audioArgs, likePrefix(opts.Path))
+		argIdx++
+	}
 
 	hasDurationFilter := opts.DurationMin > 0 || opts.DurationMax > 0
 	audioJoin := ""
@@ -175,9 +212,16 @@ func (s *SearchService) Search(query string, limit int, offset int, opts SearchO
 
 	if query != "" {
 		likeQuery := "%" + query + "%"
-		folderArgs = append(folderArgs, likeQuery, likeQuery)
-		folderArgIdx = 3
-		folderWhere = "(name ILIKE $1 OR folder_name ILIKE $2)"
+		folderArgs = append(folderArgs, likeQuery, likeQuery, likeQuery, likeQuery, likeQuery)
+		folderArgIdx = 6
+		folderWhere = "(name ILIKE $1 OR folder_name ILIKE $2 OR aliases ILIKE $3 OR tags ILIKE $4 OR description ILIKE $5)"
+	}
+
+	if !opts.IncludeRemovalRequested {
+		folderWhere += " AND removal_requested_at IS NULL"
+	}
+	if !opts.IncludeMature {
+		folderWhere += " AND COALESCE(age_limit, 0) < 18"
 	}
 
 	if opts.DateFrom != "" {
@@ -195,6 +239,11 @@ func (s *SearchService) Search(query string, limit int, offset int, opts SearchO
 		folderArgs = append(folderArgs, opts.Root, opts.Root+"/%")
 		folderArgIdx += 2
 	}
+	if opts.Path != "" {
+		folderWhere += fmt.Sprintf(" AND path LIKE $%d", folderArgIdx)
+		folderArgs = append(folderArgs, likePrefix(opts.Path))
+		folderArgIdx++
+	}
 	_ = folderArgIdx
 
 	if !includeAudio && !includeFolders {
@@ -226,10 +275,15 @@ func (s *SearchService) Search(query string, limit int, offset int, opts SearchO
 				audio_files.description, audio_files.webpage_url, audio_files.age_limit,
 				NULL as original_url, NULL::bigint as item_count, NULL as directory_size, NULL as poster_image,
 				SUBSTR(audio_files.upload_date,1,4) || '-' || SUBSTR(audio_files.upload_date,5,2) || '-' || SUBSTR(audio_files.upload_date,7,2) as modified_at,
-				audio_files.share_key, audio_files.unavailable_at, audio_files.removal_requested_at
-			FROM audio_files %s
-			WHERE %s`, audioJoin, reindex(audioWhere, 1))
+				audio_files.share_key, audio_files.unavailable_at, audio_files.removal_requested_at,
+				%s as placeholder,
+				%s as transcript_time, %s as transcript_text
+			FROM audio_files %s %s
+			WHERE %s`, ThumbnailPlaceholderColumn("audio_files"),
+			transcriptMatchColumn("start_time", transcriptJoin), transcriptMatchColumn("text", transcriptJoin),
+			reindex(transcriptJoin, 1), audioJoin, reindex(audioWhere, len(transcriptArgs)+1))
 		unionParts = append(unionParts, audioSelect)
+		allArgs = append(allArgs, transcriptArgs...)
 		allArgs = append(allArgs, audioArgs...)
 	}
 
@@ -239,13 +293,15 @@ func (s *SearchService) Search(query string, limit int, offset int, opts SearchO
 			SELECT
 				id, name, path, 'folder' as type, parent_path,
 				directory_size_bytes as size, NULL as mime_type, NULL as title, NULL as artist,
-				NULL as description, NULL as webpage_url, NULL::integer as age_limit,
+				description, NULL as webpage_url, age_limit,
 				original_url, item_count, directory_size_bytes as directory_size,
 				poster_image,
 				SUBSTR(upload_date,1,4)||'-'||SUBSTR(upload_date,5,2)||'-'||SUBSTR(upload_date,7,2) as modified_at,
-				share_key, NULL::timestamptz as unavailable_at, NULL::timestamptz as removal_requested_at
+				share_key, NULL::timestamptz as unavailable_at, NULL::timestamptz as removal_requested_at,
+				%s as placeholder,
+				NULL::double precision as transcript_time, NULL as transcript_text
 			FROM folders
-			WHERE %s`, reindex(folderWhere, folderOffset))
+			WHERE %s`, PosterPlaceholderColumn("folders"), reindex(folderWhere, folderOffset))
 		unionParts = append(unionParts, folderSelect)
 		allArgs = append(allArgs, folderArgs...)
 	}
@@ -259,7 +315,8 @@ func (s *SearchService) Search(query string, limit int, offset int, opts SearchO
 		SELECT id, name, path, type, parent_path,
 			size, mime_type, title, artist, description, webpage_url,
 			age_limit, original_url, item_count, directory_size, poster_image, modified_at,
-			share_key, unavailable_at, removal_requested_at, COUNT(*) OVER() as total_count
+			share_key, unavailable_at, removal_requested_at, placeholder,
+			transcript_time, transcript_text, COUNT(*) OVER() as total_count
 		FROM (%s) sub
 		ORDER BY %s
 		LIMIT $%d OFFSET $%d
@@ -282,15 +339,21 @@ func (s *SearchService) Search(query string, limit int, offset int, opts SearchO
 		var size, itemCount *int64
 		var unavailableAt sql.NullTime
 		var removalRequestedAt sql.NullTime
+		var transcriptTime sql.NullFloat64
+		var transcriptText sql.NullString
 
 		if err := rows.Scan(
 			&r.ID, &r.Name, &r.Path, &r.Type, &parentPath,
 			&size, &mimeType, &title, &artist, &description, &webpageURL,
 			&ageLimit, &originalURL, &itemCount, &directorySize, &posterImage, &modifiedAt,
-			&shareKey, &unavailableAt, &removalRequestedAt, &total,
+			&shareKey, &unavailableAt, &removalRequestedAt, &r.Placeholder,
+			&transcriptTime, &transcriptText, &total,
 		); err != nil {
 			return nil, 0, err
 		}
+		if transcriptTime.Valid && transcriptText.Valid {
+			r.TranscriptMatch = &TranscriptMatch{StartTime: transcriptTime.Float64, Text: transcriptText.String}
+		}
 		if unavailableAt.Valid {
 			s := unavailableAt.Time.UTC().Format(time.RFC3339)
 			r.UnavailableAt = &s
@@ -346,6 +409,36 @@ func (s *SearchService) Search(query string, limit int, offset int, opts SearchO
 	return results, total, nil
 }
 
+// transcriptMatchColumn selects a column of the transcript match, or NULL when
+// the transcript is not searched.
+func transcriptMatchColumn(column, transcriptJoin string) string {
+	if transcriptJoin == "" {
+		if column == "start_time" {
+			return "NULL::double precision"
+		}
+		return "NULL"
+	}
+	return "transcript_match." + column
+}
+
+// likePrefix returns a LIKE pattern matching paths strictly beneath dir.
+func likePrefix(dir string) string {
+	return likeEscape(dir) + "/%"
+}
+
+// likeEscape makes s match itself literally inside a LIKE pattern.
+func likeEscape(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func (s *SearchService) FolderExists(path string) (bool, error) {
+	var exists bool
+	err := s.db.DB().QueryRow(`SELECT EXISTS(SELECT 1 FROM folders WHERE path = $1)`, path).Scan(&exists)
+	return exists, err
+}
+
 // reindex replaces $1, $2, ... in a SQL fragment with $start, $start+1, ...
 func reindex(sql string, start int) string {
 	// Walk through the string and replace $N placeholders
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	maxInsightQueryLen          = 200
+	searchInsightsPruneInterval = time.Hour
+)
+
+type SearchQueryCount struct {
+	Query          string `json:"query"`
+	Searches       int    `json:"searches"`
+	ZeroResults    int    `json:"zeroResults"`
+	LastSearchedAt string `json:"lastSearchedAt"`
+}
+
+type SearchTrendPoint struct {
+	Date        string `json:"date"`
+	Searches    int    `json:"searches"`
+	ZeroResults int    `json:"zeroResults"`
+}
+
+type SearchFilterCount struct {
+	Filter   string `json:"filter"`
+	Searches int    `json:"searches"`
+}
+
+type SearchInsights struct {
+	Since                string              `json:"since"`
+	TotalSearches        int                 `json:"totalSearches"`
+	ZeroResultSearches   int                 `json:"zeroResultSearches"`
+	TopQueries           []SearchQueryCount  `json:"topQueries"`
+	TopZeroResultQueries []SearchQueryCount  `json:"topZeroResultQueries"`
+	Trend                []SearchTrendPoint  `json:"trend"`
+	Filters              []SearchFilterCount `json:"filters"`
+}
+
+// SearchInsightsService keeps an anonymized log of searches. Only the
+// normalized query text, result count and filter values are stored; no
+// session, IP or user agent is associated with an event. A zero retention
+// disables recording.
+type SearchInsightsService struct {
+	db        *Database
+	retention time.Duration
+}
+
+func NewSearchInsightsService(db *Database, retention time.Duration) *SearchInsightsService {
+	return &SearchInsightsService{db: db, retention: retention}
+}
+
+// NormalizeSearchQuery lowercases and collapses whitespace so that trivially
+// different spellings of a query are counted together.
+func NormalizeSearchQuery(query string) string {
+	normalized := strings.Join(strings.Fields(strings.ToLower(query)), " ")
+	if len(normalized) > maxInsightQueryLen {
+		normalized = strings.ToValidUTF8(normalized[:maxInsightQueryLen], "")
+	}
+	return normalized
+}
+
+func (s *SearchInsightsService) Record(query string, resultCount int, filters map[string]string) error {
+	if s.retention <= 0 {
+		return nil
+	}
+	if filters == nil {
+		filters = map[string]string{}
+	}
+	filtersJSON, err := json.Marshal(filters)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.DB().Exec(`
+		INSERT INTO search_events (query, result_count, filters)
+		VALUES ($1, $2, $3::jsonb)
+	`, NormalizeSearchQuery(query), resultCount, string(filtersJSON))
+	return err
+}
+
+func (s *SearchInsightsService) Insights(since time.Time, limit int) (*SearchInsights, error) {
+	insights := &SearchInsights{
+		Since:                since.UTC().Format(time.RFC3339),
+		TopQueries:           []SearchQueryCount{},
+		TopZeroResultQueries: []SearchQueryCount{},
+		Trend:                []SearchTrendPoint{},
+		Filters:              []SearchFilterCount{},
+	}
+
+	if err := s.db.DB().QueryRow(`
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE result_count = 0)
+		FROM search_events
+		WHERE searched_at >= $1
+	`, since).Scan(&insights.TotalSearches, &insights.ZeroResultSearches); err != nil {
+		return nil, err
+	}
+
+	var err error
+	insights.TopQueries, err = s.topQueries(since, limit, false)
+	if err != nil {
+		return nil, err
+	}
+	insights.TopZeroResultQueries, err = s.topQueries(since, limit, true)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.DB().Query(`
+		SELECT TO_CHAR(DATE_TRUNC('day', searched_at), 'YYYY-MM-DD') AS day,
+		       COUNT(*), COUNT(*) FILTER (WHERE result_count = 0)
+		FROM search_events
+		WHERE searched_at >= $1
+		GROUP BY day
+		ORDER BY day ASC
+	`, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var point SearchTrendPoint
+		if err := rows.Scan(&point.Date, &point.Searches, &point.ZeroResults); err != nil {
+			return nil, err
+		}
+		insights.Trend = append(insights.Trend, point)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	filterRows, err := s.db.DB().Query(`
+		SELECT filter, COUNT(*)
+		FROM search_events, JSONB_OBJECT_KEYS(filters) AS filter
+		WHERE searched_at >= $1
+		GROUP BY filter
+		ORDER BY COUNT(*) DESC, filter ASC
+	`, since)
+	if err != nil {
+		return nil, err
+	}
+	defer filterRows.Close()
+	for filterRows.Next() {
+		var count SearchFilterCount
+		if err := filterRows.Scan(&count.Filter, &count.Searches); err != nil {
+			return nil, err
+		}
+		insights.Filters = append(insights.Filters, count)
+	}
+	return insights, filterRows.Err()
+}
+
+func (s *SearchInsightsService) topQueries(since time.Time, limit int, zeroResultsOnly bool) ([]SearchQueryCount, error) {
+	where := "searched_at >= $1 AND query <> ''"
+	if zeroResultsOnly {
+		where += " AND result_count = 0"
+	}
+	rows, err := s.db.DB().Query(`
+		SELECT query, COUNT(*), COUNT(*) FILTER (WHERE result_count = 0), MAX(searched_at)
+		FROM search_events
+		WHERE `+where+`
+		GROUP BY query
+		ORDER BY COUNT(*) DESC, query ASC
+		LIMIT $2
+	`, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []SearchQueryCount{}
+	for rows.Next() {
+		var count SearchQueryCount
+		var lastSearchedAt time.Time
+		if err := rows.Scan(&count.Query, &count.Searches, &count.ZeroResults, &lastSearchedAt); err != nil {
+			return nil, err
+		}
+		count.LastSearchedAt = lastSearchedAt.UTC().Format(time.RFC3339)
+		counts = append(counts, count)
+	}
+	return counts, rows.Err()
+}
+
+func (s *SearchInsightsService) prune() (int64, error) {
+	result, err := s.db.DB().Exec(`
+		DELETE FROM search_events WHERE searched_at < $1
+	`, time.Now().Add(-s.retention))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (s *SearchInsightsService) StartRetentionCleanup() {
+	if s.retention <= 0 {
+		return
+	}
+	cleanup := func() {
+		deleted, err := s.prune()
+		if err != nil {
+			log.Printf("Error pruning search insights: %v", err)
+			return
+		}
+		if deleted > 0 {
+			log.Printf("Removed %d expired search insight events", deleted)
+		}
+	}
+
+	cleanup()
+	go func() {
+		ticker := time.NewTicker(searchInsightsPruneInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			cleanup()
+		}
+	}()
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestNormalizeSearchQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{query: "  Lo-Fi   BEATS ", want: "lo-fi beats"},
+		{query: "\tone\ntwo", want: "one two"},
+		{query: strings.Repeat("a", maxInsightQueryLen+10), want: strings.Repeat("a", maxInsightQueryLen)},
+	}
+	for _, test := range tests {
+		if got := NormalizeSearchQuery(test.query); got != test.want {
+			t.Fatalf("NormalizeSearchQuery(%q) = %q, want %q", test.query, got, test.want)
+		}
+	}
+}
+
+func TestSearchInsightsRecordStoresOnlyAnonymizedFields(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO search_events (query, result_count, filters)")).
+		WithArgs("missing show", 0, `{"type":"audio"}`).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	service := NewSearchInsightsService(&Database{db: db}, time.Hour)
+	if err := service.Record(" Missing  Show ", 0, map[string]string{"type": "audio"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSearchInsightsRecordDisabledWithoutRetention(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	service := NewSearchInsightsService(&Database{db: db}, 0)
+	if err := service.Record("query", 3, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
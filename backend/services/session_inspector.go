@@ -0,0 +1,240 @@
+package services
+
+import (
+	"database/sql"
+	"time"
+)
+
+const (
+	inspectionTopLimit    = 20
+	inspectionRecentLimit = 50
+)
+
+// InspectionCount is one row of an aggregated breakdown: a key such as an
+// event type, day, IP or share key, an optional label, and how often it
+// occurred. Bytes is the estimated data requested, where that applies.
+type InspectionCount struct {
+	Key   string `json:"key"`
+	Label string `json:"label,omitempty"`
+	Count int64  `json:"count"`
+	Bytes int64  `json:"bytes,omitempty"`
+}
+
+type InspectionDownload struct {
+	At             string `json:"at"`
+	EventType      string `json:"eventType"`
+	ShareKey       string `json:"shareKey"`
+	Title          string `json:"title"`
+	SessionID      string `json:"sessionId,omitempty"`
+	ClientIP       string `json:"clientIp,omitempty"`
+	Range          string `json:"range,omitempty"`
+	RequestedBytes int64  `json:"requestedBytes"`
+}
+
+type InspectionDownloads struct {
+	ByType     []InspectionCount    `json:"byType"`
+	Daily      []InspectionCount    `json:"daily"`
+	TopTracks  []InspectionCount    `json:"topTracks"`
+	UserAgents []InspectionCount    `json:"userAgents"`
+	Recent     []InspectionDownload `json:"recent"`
+}
+
+type InspectionPlays struct {
+	ByOrigin  []InspectionCount `json:"byOrigin"`
+	Daily     []InspectionCount `json:"daily"`
+	TopTracks []InspectionCount `json:"topTracks"`
+}
+
+type InspectedProfile struct {
+	CreatedAt      string            `json:"createdAt"`
+	UpdatedAt      string            `json:"updatedAt"`
+	HasRecoveryKey bool              `json:"hasRecoveryKey"`
+	AppTokens      int64             `json:"appTokens"`
+	Likes          int64             `json:"likes"`
+	RecentLikes    []InspectionCount `json:"recentLikes"`
+}
+
+type PendingTargetedMessage struct {
+	ID        int64  `json:"id"`
+	Title     string `json:"title"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// SessionInspection summarises what a session or client IP did. Activity is
+// aggregated so long histories stay readable; only the most recent media
+// events are listed one by one. For an IP, Identities lists the sessions
+// seen from it; for a session, the IPs it used.
+type SessionInspection struct {
+	SessionID       string                  `json:"sessionId,omitempty"`
+	ClientIP        string                  `json:"clientIp,omitempty"`
+	Since           string                  `json:"since"`
+	Identities      []InspectionCount       `json:"identities"`
+	Downloads       InspectionDownloads     `json:"downloads"`
+	Plays           InspectionPlays         `json:"plays"`
+	Profile         *InspectedProfile       `json:"profile"`
+	TargetedMessage *PendingTargetedMessage `json:"pendingTargetedMessage"`
+	AccessKeys      []KeyIssuanceUsage      `json:"accessKeys"`
+	// ScraperAssessment is the subject's current scraper detection score,
+	// when detection is on and has seen it.
+	ScraperAssessment *ScraperAssessment `json:"scraperAssessment"`
+}
+
+// InspectSession gathers the activity of a session since the given time.
+func InspectSession(db *sql.DB, sessionID string, since time.Time) (*SessionInspection, error) {
+	inspection := &SessionInspection{SessionID: sessionID}
+	err := inspect(db, inspection, sessionID, since,
+		`de.session_id = $1`,
+		`pe.session_id = $1`,
+		`COALESCE(de.client_ip, '')`)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &InspectedProfile{}
+	var createdAt, updatedAt time.Time
+	err = db.QueryRow(`
+		SELECT created_at, updated_at, recovery_key_hash IS NOT NULL,
+		       (SELECT COUNT(*) FROM app_tokens WHERE profile_id = $1),
+		       (SELECT COUNT(*) FROM likes WHERE profile_id = $1)
+		FROM anonymous_profiles
+		WHERE session_id = $1
+	`, sessionID).Scan(&createdAt, &updatedAt, &profile.HasRecoveryKey, &profile.AppTokens, &profile.Likes)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return nil, err
+	default:
+		profile.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+		profile.UpdatedAt = updatedAt.UTC().Format(time.RFC3339)
+		profile.RecentLikes, err = inspectionCounts(db, `
+			SELECT af.share_key, COALESCE(NULLIF(af.title, ''), af.filename), 1, 0
+			FROM likes l
+			JOIN audio_files af ON af.id = l.audio_file_id
+			WHERE l.profile_id = $1
+			ORDER BY l.created_at DESC
+			LIMIT $2
+		`, sessionID, inspectionTopLimit)
+		if err != nil {
+			return nil, err
+		}
+		inspection.Profile = profile
+	}
+
+	var message PendingTargetedMessage
+	var messageCreatedAt time.Time
+	err = db.QueryRow(`
+		SELECT id, title, created_at FROM targeted_messages WHERE session_id = $1
+	`, sessionID).Scan(&message.ID, &message.Title, &messageCreatedAt)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return nil, err
+	default:
+		message.CreatedAt = messageCreatedAt.UTC().Format(time.RFC3339)
+		inspection.TargetedMessage = &message
+	}
+	return inspection, nil
+}
+
+// InspectClientIP gathers the activity of a client IP since the given time.
+// Plays carry no IP, so they are counted for the sessions that downloaded
+// or streamed from it.
+func InspectClientIP(db *sql.DB, clientIP string, since time.Time) (*SessionInspection, error) {
+	inspection := &SessionInspection{ClientIP: clientIP}
+	err := inspect(db, inspection, clientIP, since,
+		`de.client_ip = $1`,
+		`pe.session_id IN (
+			SELECT session_id FROM download_events
+			WHERE client_ip = $1 AND session_id IS NOT NULL AND downloaded_at >= $2
+		)`,
+		`COALESCE(de.session_id, '')`)
+	if err != nil {
+		return nil, err
+	}
+	return inspection, nil
+}
+
+func inspect(db *sql.DB, inspection *SessionInspection, subject string, since time.Time, downloadWhere, playWhere, identityColumn string) error {
+	since = since.UTC()
+	inspection.Since = since.Format(time.RFC3339)
+	downloads := ` FROM download_events de WHERE ` + downloadWhere + ` AND de.downloaded_at >= $2`
+	plays := ` FROM play_events pe WHERE ` + playWhere + ` AND pe.played_at >= $2`
+
+	var err error
+	queries := []struct {
+		target *[]InspectionCount
+		query  string
+	}{
+		{&inspection.Identities, `SELECT ` + identityColumn + `, '', COUNT(*), COALESCE(SUM(de.requested_bytes), 0)` +
+			downloads + ` GROUP BY 1 ORDER BY 3 DESC LIMIT $3`},
+		{&inspection.Downloads.ByType, `SELECT de.event_type, '', COUNT(*), COALESCE(SUM(de.requested_bytes), 0)` +
+			downloads + ` GROUP BY 1 ORDER BY 3 DESC LIMIT $3`},
+		{&inspection.Downloads.Daily, `SELECT to_char(de.downloaded_at, 'YYYY-MM-DD'), '', COUNT(*), COALESCE(SUM(de.requested_bytes), 0)` +
+			downloads + ` GROUP BY 1 ORDER BY 1 DESC LIMIT $3`},
+		{&inspection.Downloads.TopTracks, `SELECT af.share_key, COALESCE(NULLIF(af.title, ''), af.filename), COUNT(*), COALESCE(SUM(de.requested_bytes), 0)
+			FROM download_events de JOIN audio_files af ON af.id = de.audio_file_id
+			WHERE ` + downloadWhere + ` AND de.downloaded_at >= $2
+			GROUP BY af.id ORDER BY 3 DESC LIMIT $3`},
+		{&inspection.Downloads.UserAgents, `SELECT COALESCE(de.user_agent, ''), '', COUNT(*), 0` +
+			downloads + ` GROUP BY 1 ORDER BY 3 DESC LIMIT $3`},
+		{&inspection.Plays.ByOrigin, `SELECT pe.origin, '', COUNT(*), 0` +
+			plays + ` GROUP BY 1 ORDER BY 3 DESC LIMIT $3`},
+		{&inspection.Plays.Daily, `SELECT to_char(pe.played_at, 'YYYY-MM-DD'), '', COUNT(*), 0` +
+			plays + ` GROUP BY 1 ORDER BY 1 DESC LIMIT $3`},
+		{&inspection.Plays.TopTracks, `SELECT af.share_key, COALESCE(NULLIF(af.title, ''), af.filename), COUNT(*), 0
+			FROM play_events pe JOIN audio_files af ON af.id = pe.audio_file_id
+			WHERE ` + playWhere + ` AND pe.played_at >= $2
+			GROUP BY af.id ORDER BY 3 DESC LIMIT $3`},
+	}
+	for _, q := range queries {
+		if *q.target, err = inspectionCounts(db, q.query, subject, since, inspectionTopLimit); err != nil {
+			return err
+		}
+	}
+
+	rows, err := db.Query(`
+		SELECT de.downloaded_at, de.event_type, COALESCE(de.share_key, af.share_key),
+		       COALESCE(NULLIF(af.title, ''), af.filename), COALESCE(de.session_id, ''),
+		       COALESCE(de.client_ip, ''), COALESCE(de.range_header, ''), COALESCE(de.requested_bytes, 0)
+		FROM download_events de
+		JOIN audio_files af ON af.id = de.audio_file_id
+		WHERE `+downloadWhere+` AND de.downloaded_at >= $2
+		ORDER BY de.downloaded_at DESC, de.id DESC
+		LIMIT $3
+	`, subject, since, inspectionRecentLimit)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	inspection.Downloads.Recent = []InspectionDownload{}
+	for rows.Next() {
+		var event InspectionDownload
+		var at time.Time
+		if err := rows.Scan(&at, &event.EventType, &event.ShareKey, &event.Title, &event.SessionID,
+			&event.ClientIP, &event.Range, &event.RequestedBytes); err != nil {
+			return err
+		}
+		event.At = at.UTC().Format(time.RFC3339)
+		inspection.Downloads.Recent = append(inspection.Downloads.Recent, event)
+	}
+	return rows.Err()
+}
+
+func inspectionCounts(db *sql.DB, query string, args ...any) ([]InspectionCount, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []InspectionCount{}
+	for rows.Next() {
+		var count InspectionCount
+		if err := rows.Scan(&count.Key, &count.Label, &count.Count, &count.Bytes); err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+	return counts, rows.Err()
+}
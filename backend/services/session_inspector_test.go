@@ -0,0 +1,103 @@
+package services
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var inspectionCountColumns = []string{"key", "label", "count", "bytes"}
+
+func TestInspectSessionAggregatesActivity(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	since := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	at := time.Date(2026, 3, 2, 9, 30, 0, 0, time.UTC)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COALESCE(de.client_ip, ''), '', COUNT(*)`)).
+		WithArgs("session-one", since, inspectionTopLimit).
+		WillReturnRows(sqlmock.NewRows(inspectionCountColumns).AddRow("192.0.2.1", "", 40, 4096))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT de.event_type`)).
+		WillReturnRows(sqlmock.NewRows(inspectionCountColumns).AddRow("download", "", 38, 4000).AddRow("stream", "", 2, 96))
+	for range 6 {
+		mock.ExpectQuery(`SELECT`).WithArgs("session-one", since, inspectionTopLimit).
+			WillReturnRows(sqlmock.NewRows(inspectionCountColumns))
+	}
+	mock.ExpectQuery(regexp.QuoteMeta(`ORDER BY de.downloaded_at DESC, de.id DESC`)).
+		WithArgs("session-one", since, inspectionRecentLimit).
+		WillReturnRows(sqlmock.NewRows([]string{"at", "event_type", "share_key", "title", "session_id", "client_ip", "range", "bytes"}).
+			AddRow(at, "download", "track-key", "Track", "session-one", "192.0.2.1", "", 100))
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM anonymous_profiles`)).WithArgs("session-one").
+		WillReturnRows(sqlmock.NewRows([]string{"created_at", "updated_at", "recovery", "tokens", "likes"}).
+			AddRow(since, at, true, 1, 3))
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM likes l`)).WithArgs("session-one", inspectionTopLimit).
+		WillReturnRows(sqlmock.NewRows(inspectionCountColumns).AddRow("track-key", "Track", 1, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM targeted_messages WHERE session_id = $1`)).WithArgs("session-one").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "created_at"}).AddRow(7, "Please slow down", at))
+
+	inspection, err := InspectSession(db, "session-one", since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inspection.Identities) != 1 || inspection.Identities[0].Key != "192.0.2.1" {
+		t.Fatalf("identities = %#v", inspection.Identities)
+	}
+	if len(inspection.Downloads.ByType) != 2 || inspection.Downloads.ByType[0].Bytes != 4000 {
+		t.Fatalf("downloads by type = %#v", inspection.Downloads.ByType)
+	}
+	if len(inspection.Downloads.Recent) != 1 || inspection.Downloads.Recent[0].At != "2026-03-02T09:30:00Z" {
+		t.Fatalf("recent downloads = %#v", inspection.Downloads.Recent)
+	}
+	if inspection.Profile == nil || inspection.Profile.Likes != 3 || !inspection.Profile.HasRecoveryKey || len(inspection.Profile.RecentLikes) != 1 {
+		t.Fatalf("profile = %#v", inspection.Profile)
+	}
+	if inspection.TargetedMessage == nil || inspection.TargetedMessage.ID != 7 {
+		t.Fatalf("targeted message = %#v", inspection.TargetedMessage)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInspectClientIPCountsPlaysOfSessionsSeenOnIP(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	since := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COALESCE(de.session_id, ''), '', COUNT(*)`)).
+		WithArgs("192.0.2.1", since, inspectionTopLimit).
+		WillReturnRows(sqlmock.NewRows(inspectionCountColumns).AddRow("session-one", "", 5, 0).AddRow("session-two", "", 4, 0))
+	for range 4 {
+		mock.ExpectQuery(regexp.QuoteMeta(`de.client_ip = $1`)).WillReturnRows(sqlmock.NewRows(inspectionCountColumns))
+	}
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT pe.origin`)+`(?s).*`+regexp.QuoteMeta(`pe.session_id IN (`)).
+		WithArgs("192.0.2.1", since, inspectionTopLimit).
+		WillReturnRows(sqlmock.NewRows(inspectionCountColumns).AddRow("player", "", 12, 0))
+	for range 2 {
+		mock.ExpectQuery(regexp.QuoteMeta(`pe.session_id IN (`)).WillReturnRows(sqlmock.NewRows(inspectionCountColumns))
+	}
+	mock.ExpectQuery(regexp.QuoteMeta(`ORDER BY de.downloaded_at DESC`)).
+		WillReturnRows(sqlmock.NewRows([]string{"at", "event_type", "share_key", "title", "session_id", "client_ip", "range", "bytes"}))
+
+	inspection, err := InspectClientIP(db, "192.0.2.1", since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inspection.Identities) != 2 || len(inspection.Plays.ByOrigin) != 1 || inspection.Plays.ByOrigin[0].Count != 12 {
+		t.Fatalf("inspection = %#v", inspection)
+	}
+	if inspection.Profile != nil || inspection.TargetedMessage != nil {
+		t.Fatal("IP inspection included session-only sections")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	RateLimitStoreMemory   = "memory"
+	RateLimitStorePostgres = "postgres"
+
+	sharedLimitCleanupInterval = 10 * time.Minute
+	bandwidthLeaseDuration     = time.Second
+)
+
+// PostgresRateLimitBackend shares request counters between every instance
+// using the same database. It satisfies middleware.RateLimitBackend. The
+// counters live in an unlogged table, so they are cheap to update and are
+// emptied if Postgres crashes.
+type PostgresRateLimitBackend struct {
+	db *Database
+}
+
+func NewPostgresRateLimitBackend(db *Database) *PostgresRateLimitBackend {
+	return &PostgresRateLimitBackend{db: db}
+}
+
+// Add counts a request in one statement. Concurrent upserts of the same key
+// serialise on its row, so no request is lost between instances.
+func (b *PostgresRateLimitBackend) Add(key string, delta int, window time.Duration, now time.Time) (int, time.Time, error) {
+	var count int
+	var start time.Time
+	err := b.db.DB().QueryRow(`
+		INSERT INTO rate_limit_windows (key, count, started_at, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (key) DO UPDATE SET
+			count = CASE WHEN rate_limit_windows.expires_at < $3
+				THEN EXCLUDED.count ELSE rate_limit_windows.count + EXCLUDED.count END,
+			started_at = CASE WHEN rate_limit_windows.expires_at < $3
+				THEN EXCLUDED.started_at ELSE rate_limit_windows.started_at END,
+			expires_at = CASE WHEN rate_limit_windows.expires_at < $3
+				THEN EXCLUDED.expires_at ELSE rate_limit_windows.expires_at END
+		RETURNING count, started_at
+	`, key, delta, now, now.Add(window)).Scan(&count, &start)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return count, start, nil
+}
+
+func (b *PostgresRateLimitBackend) prune(now time.Time) (int64, error) {
+	result, err := b.db.DB().Exec(`
+		DELETE FROM rate_limit_windows WHERE expires_at < $1
+	`, now)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (b *PostgresRateLimitBackend) StartCleanup() {
+	startSharedLimitCleanup("rate-limit windows", b.prune)
+}
+
+// PostgresBandwidthBackend shares the token buckets of one IP bandwidth
+// limiter between every instance. Limiters with different rates must use
+// different namespaces.
+//
+// Tokens are withdrawn from the shared bucket in leases of up to
+// bandwidthLeaseDuration worth of bytes and spent locally, so a throttled
+// response costs about one database round trip per second rather than one
+// per chunk. An instance may briefly hold tokens another instance is
+// waiting for, but never more than the shared bucket handed out.
+type PostgresBandwidthBackend struct {
+	db        *Database
+	namespace string
+
+	mu                sync.Mutex
+	leases            map[string]*bandwidthLease
+	takesSinceCleanup int
+}
+
+// bandwidthLease holds tokens already withdrawn from the shared bucket.
+// mu is held across the withdrawal so concurrent readers from one IP wait
+// for a single round trip.
+type bandwidthLease struct {
+	mu       sync.Mutex
+	tokens   int64
+	lastUsed time.Time
+}
+
+func NewPostgresBandwidthBackend(db *Database, namespace string) *PostgresBandwidthBackend {
+	return &PostgresBandwidthBackend{
+		db:        db,
+		namespace: namespace,
+		leases:    make(map[string]*bandwidthLease),
+	}
+}
+
+// Take spends leased tokens and withdraws a new lease when they run short.
+func (b *PostgresBandwidthBackend) Take(
+	key string,
+	byteCount int64,
+	bytesPerSecond int64,
+	burstBytes int64,
+	now time.Time,
+) (time.Duration, error) {
+	lease := b.lease(key, now)
+	lease.mu.Lock()
+	defer lease.mu.Unlock()
+
+	if lease.tokens >= byteCount {
+		lease.tokens -= byteCount
+		return 0, nil
+	}
+	need := byteCount - lease.tokens
+	want := max(need, min(burstBytes, int64(bandwidthLeaseDuration.Seconds()*float64(bytesPerSecond)))-lease.tokens)
+	granted, wait, err := b.withdraw(key, need, want, bytesPerSecond, burstBytes, now)
+	if err != nil || wait > 0 {
+		return wait, err
+	}
+	lease.tokens += granted - byteCount
+	return 0, nil
+}
+
+func (b *PostgresBandwidthBackend) lease(key string, now time.Time) *bandwidthLease {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.takesSinceCleanup++
+	if b.takesSinceCleanup >= 256 {
+		b.takesSinceCleanup = 0
+		for key, lease := range b.leases {
+			if now.Sub(lease.lastUsed) > bandwidthBucketIdleTimeout {
+				delete(b.leases, key)
+			}
+		}
+	}
+	lease, ok := b.leases[key]
+	if !ok {
+		lease = &bandwidthLease{}
+		b.leases[key] = lease
+	}
+	lease.lastUsed = now
+	return lease
+}
+
+// withdraw locks the bucket row for the length of one transaction, so
+// concurrent withdrawals from any instance see each other. It takes at
+// least need and at most want tokens, or takes none and returns how long
+// until need is available.
+func (b *PostgresBandwidthBackend) withdraw(
+	key string,
+	need, want int64,
+	bytesPerSecond int64,
+	burstBytes int64,
+	now time.Time,
+) (int64, time.Duration, error) {
+	tx, err := b.db.DB().Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	var tokens float64
+	var last time.Time
+	if err := tx.QueryRow(`
+		INSERT INTO bandwidth_buckets (namespace, client_ip, tokens, refilled_at, used_at)
+		VALUES ($1, $2, $3, $4, $4)
+		ON CONFLICT (namespace, client_ip)
+		DO UPDATE SET tokens = bandwidth_buckets.tokens
+		RETURNING tokens, refilled_at
+	`, b.namespace, key, float64(burstBytes), now).Scan(&tokens, &last); err != nil {
+		return 0, 0, err
+	}
+
+	tokens, last, wait := takeBandwidthTokens(tokens, last, need, bytesPerSecond, burstBytes, now)
+	granted := int64(0)
+	if wait <= 0 {
+		extra := min(int64(tokens), want-need)
+		tokens -= float64(extra)
+		granted = need + extra
+	}
+	if _, err := tx.Exec(`
+		UPDATE bandwidth_buckets
+		SET tokens = $3, refilled_at = $4, used_at = $5
+		WHERE namespace = $1 AND client_ip = $2
+	`, b.namespace, key, tokens, last, now); err != nil {
+		return 0, 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+	return granted, wait, nil
+}
+
+func (b *PostgresBandwidthBackend) prune(now time.Time) (int64, error) {
+	result, err := b.db.DB().Exec(`
+		DELETE FROM bandwidth_buckets WHERE namespace = $1 AND used_at < $2
+	`, b.namespace, now.Add(-bandwidthBucketIdleTimeout))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (b *PostgresBandwidthBackend) StartCleanup() {
+	startSharedLimitCleanup(b.namespace+" bandwidth buckets", b.prune)
+}
+
+func startSharedLimitCleanup(name string, prune func(time.Time) (int64, error)) {
+	cleanup := func() {
+		deleted, err := prune(time.Now())
+		if err != nil {
+			log.Printf("Error pruning %s: %v", name, err)
+			return
+		}
+		if deleted > 0 {
+			log.Printf("Removed %d expired %s", deleted, name)
+		}
+	}
+
+	cleanup()
+	go func() {
+		ticker := time.NewTicker(sharedLimitCleanupInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			cleanup()
+		}
+	}()
+}
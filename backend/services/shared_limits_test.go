@@ -0,0 +1,292 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/onion/audio-share-backend/config"
+	"github.com/onion/audio-share-backend/middleware"
+)
+
+var _ middleware.RateLimitBackend = (*PostgresRateLimitBackend)(nil)
+
+// The sqlmock tests below check how the limiters act on what the database
+// returns. They cannot show that instances share state; the tests using
+// testPostgresDatabase do that against a real Postgres.
+
+func TestPostgresRateLimitBackendLimitsOnTheCountFromTheDatabase(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	windowStart := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	for _, count := range []int{1, 2} {
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO rate_limit_windows`)).
+			WithArgs("share:192.0.2.1", 1, sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"count", "started_at"}).AddRow(count, windowStart))
+	}
+
+	cfg := &config.Config{ShareRequestLimit: 1, ShareLimitWindow: int(24 * time.Hour / time.Millisecond)}
+	backend := NewPostgresRateLimitBackend(&Database{db: db})
+	var statuses []int
+	for range 2 {
+		limiter := middleware.NewRateLimiter(cfg)
+		limiter.SetBackend(backend)
+		handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		req := httptest.NewRequest(http.MethodPost, "/api/share", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		statuses = append(statuses, recorder.Code)
+	}
+	if statuses[0] != http.StatusNoContent || statuses[1] != http.StatusTooManyRequests {
+		t.Fatalf("statuses = %v, want the second share refused once the database counts two", statuses)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPostgresRateLimitBackendStartsWindowsAtNow(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO rate_limit_windows`)).
+		WithArgs("access:192.0.2.1", 0, now, now.Add(time.Minute)).
+		WillReturnRows(sqlmock.NewRows([]string{"count", "started_at"}).AddRow(3, now.Add(-10*time.Second)))
+
+	count, start, err := NewPostgresRateLimitBackend(&Database{db: db}).Add("access:192.0.2.1", 0, time.Minute, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 || !start.Equal(now.Add(-10*time.Second)) {
+		t.Fatalf("Add = %d, %v", count, start)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPostgresBandwidthBackendWaitsOnAnEmptyBucketFromTheDatabase(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	start := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	later := start.Add(500 * time.Millisecond)
+	bucketRows := func(tokens float64, refilledAt time.Time) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"tokens", "refilled_at"}).AddRow(tokens, refilledAt)
+	}
+	expectTake := func(rows *sqlmock.Rows, tokens float64, refilledAt, usedAt time.Time) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO bandwidth_buckets`)).
+			WithArgs("stream", "192.0.2.1", float64(100), usedAt).
+			WillReturnRows(rows)
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE bandwidth_buckets`)).
+			WithArgs("stream", "192.0.2.1", tokens, refilledAt, usedAt).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+	}
+	// The first limiter spends the whole burst of a new bucket.
+	expectTake(bucketRows(100, start), 0, start, start)
+	// The second is handed an empty bucket, waits, then takes what refilled.
+	expectTake(bucketRows(0, start), 0, start, start)
+	expectTake(bucketRows(0, start), 0, later, later)
+
+	clock := &fakeBandwidthClock{now: start}
+	first := newIPBandwidthLimiter(100, 100, clock)
+	second := newIPBandwidthLimiter(100, 100, clock)
+	first.SetBackend(NewPostgresBandwidthBackend(&Database{db: db}, "stream"))
+	second.SetBackend(NewPostgresBandwidthBackend(&Database{db: db}, "stream"))
+
+	first.Wait("192.0.2.1", 100)
+	second.Wait("192.0.2.1", 50)
+	if clock.TotalSleep() != 500*time.Millisecond {
+		t.Fatalf("second limiter slept for %v, want 500ms", clock.TotalSleep())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPostgresBandwidthBackendLeasesASecondOfTokensPerRoundTrip(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	start := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	later := start.Add(time.Second)
+	// The first chunk leases a second's worth of a full bucket, which
+	// covers the next nine chunks; the eleventh leases again.
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO bandwidth_buckets`)).
+		WithArgs("stream", "192.0.2.1", float64(4000), start).
+		WillReturnRows(sqlmock.NewRows([]string{"tokens", "refilled_at"}).AddRow(float64(4000), start))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE bandwidth_buckets`)).
+		WithArgs("stream", "192.0.2.1", float64(3000), start, start).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO bandwidth_buckets`)).
+		WithArgs("stream", "192.0.2.1", float64(4000), later).
+		WillReturnRows(sqlmock.NewRows([]string{"tokens", "refilled_at"}).AddRow(float64(3000), start))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE bandwidth_buckets`)).
+		WithArgs("stream", "192.0.2.1", float64(3000), later, later).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	clock := &fakeBandwidthClock{now: start}
+	limiter := newIPBandwidthLimiter(1000, 4000, clock)
+	limiter.SetBackend(NewPostgresBandwidthBackend(&Database{db: db}, "stream"))
+	for range 10 {
+		limiter.Wait("192.0.2.1", 100)
+	}
+	clock.now = later
+	limiter.Wait("192.0.2.1", 100)
+	if clock.TotalSleep() != 0 {
+		t.Fatalf("slept for %v, want no wait inside the burst", clock.TotalSleep())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPostgresBandwidthBackendPrunesIdleBucketsInItsNamespace(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM bandwidth_buckets WHERE namespace = $1 AND used_at < $2`)).
+		WithArgs("download", now.Add(-bandwidthBucketIdleTimeout)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	deleted, err := NewPostgresBandwidthBackend(&Database{db: db}, "download").prune(now)
+	if err != nil || deleted != 2 {
+		t.Fatalf("prune = %d, %v", deleted, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testPostgresDatabase connects to the database named by TEST_DATABASE_URL,
+// which the shared-limit SQL is exercised against. Each call opens its own
+// connection pool, standing in for a separate server instance. Tests using
+// it are skipped when it is not set; CI runs them against a Postgres service.
+func testPostgresDatabase(t *testing.T) *Database {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db := NewDatabase(dsn)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestPostgresRateLimitWindowsResetAfterExpiry(t *testing.T) {
+	db := testPostgresDatabase(t)
+	key := fmt.Sprintf("test:%s:%d", t.Name(), time.Now().UnixNano())
+	t.Cleanup(func() { db.DB().Exec(`DELETE FROM rate_limit_windows WHERE key = $1`, key) })
+
+	backend := NewPostgresRateLimitBackend(db)
+	start := time.Now().UTC().Truncate(time.Second)
+	steps := []struct {
+		at        time.Time
+		delta     int
+		wantCount int
+		wantStart time.Time
+	}{
+		{at: start, delta: 1, wantCount: 1, wantStart: start},
+		{at: start.Add(30 * time.Second), delta: 1, wantCount: 2, wantStart: start},
+		{at: start.Add(45 * time.Second), delta: 0, wantCount: 2, wantStart: start},
+		{at: start.Add(61 * time.Second), delta: 1, wantCount: 1, wantStart: start.Add(61 * time.Second)},
+	}
+	for i, step := range steps {
+		count, windowStart, err := backend.Add(key, step.delta, time.Minute, step.at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != step.wantCount || !windowStart.Equal(step.wantStart) {
+			t.Fatalf("step %d: Add = %d, %v; want %d, %v", i, count, windowStart, step.wantCount, step.wantStart)
+		}
+	}
+}
+
+func TestPostgresShareBudgetIsSharedThroughPostgres(t *testing.T) {
+	databases := []*Database{testPostgresDatabase(t), testPostgresDatabase(t)}
+	now := time.Now().UnixNano()
+	ip := fmt.Sprintf("198.51.%d.%d", now/256%256, now%256)
+	forget := func() { databases[0].DB().Exec(`DELETE FROM rate_limit_windows WHERE key = $1`, "share:"+ip) }
+	forget()
+	t.Cleanup(forget)
+
+	cfg := &config.Config{ShareRequestLimit: 1, ShareLimitWindow: int(24 * time.Hour / time.Millisecond)}
+	var statuses []int
+	for _, db := range databases {
+		limiter := middleware.NewRateLimiter(cfg)
+		limiter.SetBackend(NewPostgresRateLimitBackend(db))
+		handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		req := httptest.NewRequest(http.MethodPost, "/api/share", nil)
+		req.RemoteAddr = ip + ":1234"
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		statuses = append(statuses, recorder.Code)
+	}
+	if statuses[0] != http.StatusNoContent || statuses[1] != http.StatusTooManyRequests {
+		t.Fatalf("statuses = %v, want the second instance to see the first's share", statuses)
+	}
+}
+
+func TestPostgresBandwidthBucketIsSharedThroughPostgres(t *testing.T) {
+	db := testPostgresDatabase(t)
+	namespace := fmt.Sprintf("test:%s:%d", t.Name(), time.Now().UnixNano())
+	t.Cleanup(func() { db.DB().Exec(`DELETE FROM bandwidth_buckets WHERE namespace = $1`, namespace) })
+
+	first := NewPostgresBandwidthBackend(db, namespace)
+	second := NewPostgresBandwidthBackend(testPostgresDatabase(t), namespace)
+	start := time.Now().UTC().Truncate(time.Second)
+	takes := []struct {
+		backend  *PostgresBandwidthBackend
+		at       time.Time
+		bytes    int64
+		wantWait time.Duration
+	}{
+		{backend: first, at: start, bytes: 100, wantWait: 0},
+		{backend: second, at: start, bytes: 50, wantWait: 500 * time.Millisecond},
+		{backend: second, at: start.Add(500 * time.Millisecond), bytes: 50, wantWait: 0},
+		{backend: first, at: start.Add(500 * time.Millisecond), bytes: 50, wantWait: 500 * time.Millisecond},
+	}
+	for i, take := range takes {
+		wait, err := take.backend.Take("192.0.2.1", take.bytes, 100, 100, take.at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if wait != take.wantWait {
+			t.Fatalf("take %d waited %v, want %v", i, wait, take.wantWait)
+		}
+	}
+}
@@ -68,32 +68,59 @@ func (w *limitedBuffer) String() string {
 	return w.buffer.String()
 }
 
-func (n *ScriptSourceNormalizer) Normalize(ctx context.Context, rawURL string) (*NormalizedSource, error) {
-	if !n.IsConfigured() {
-		return nil, errors.New("source normalizer is not configured")
-	}
+// scriptOutput is the result of running a Python helper script that reads a
+// JSON object on stdin and writes one to stdout.
+type scriptOutput struct {
+	stdout   []byte
+	stderr   string
+	runErr   error
+	timedOut bool
+}
 
-	timeout := n.timeout
+// scriptError is the "error" object a helper script reports failures with.
+type scriptError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func runJSONScript(ctx context.Context, scriptPath string, timeout time.Duration, input any) (*scriptOutput, error) {
 	if timeout <= 0 {
 		timeout = 15 * time.Second
 	}
 	commandContext, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	input, err := json.Marshal(map[string]string{"url": rawURL})
+	encoded, err := json.Marshal(input)
 	if err != nil {
 		return nil, err
 	}
 
-	command := exec.CommandContext(commandContext, "python3", n.scriptPath)
-	command.Stdin = bytes.NewReader(input)
+	command := exec.CommandContext(commandContext, "python3", scriptPath)
+	command.Stdin = bytes.NewReader(encoded)
 	stdout := &limitedBuffer{limit: normalizerOutputLimit}
 	stderr := &limitedBuffer{limit: normalizerOutputLimit}
 	command.Stdout = stdout
 	command.Stderr = stderr
 	runErr := command.Run()
 
-	if errors.Is(commandContext.Err(), context.DeadlineExceeded) {
+	return &scriptOutput{
+		stdout:   stdout.buffer.Bytes(),
+		stderr:   strings.TrimSpace(stderr.String()),
+		runErr:   runErr,
+		timedOut: errors.Is(commandContext.Err(), context.DeadlineExceeded),
+	}, nil
+}
+
+func (n *ScriptSourceNormalizer) Normalize(ctx context.Context, rawURL string) (*NormalizedSource, error) {
+	if !n.IsConfigured() {
+		return nil, errors.New("source normalizer is not configured")
+	}
+
+	output, err := runJSONScript(ctx, n.scriptPath, n.timeout, map[string]string{"url": rawURL})
+	if err != nil {
+		return nil, err
+	}
+	if output.timedOut {
 		return nil, &SourceNormalizationError{
 			Code:    "timeout",
 			Message: "Source resolution timed out. Please try again.",
@@ -102,14 +129,11 @@ func (n *ScriptSourceNormalizer) Normalize(ctx context.Context, rawURL string) (
 
 	var response struct {
 		NormalizedSource
-		Error *struct {
-			Code    string `json:"code"`
-			Message string `json:"message"`
-		} `json:"error"`
+		Error *scriptError `json:"error"`
 	}
-	if err := json.Unmarshal(stdout.buffer.Bytes(), &response); err != nil {
-		if runErr != nil {
-			return nil, fmt.Errorf("source normalizer failed: %w: %s", runErr, strings.TrimSpace(stderr.String()))
+	if err := json.Unmarshal(output.stdout, &response); err != nil {
+		if output.runErr != nil {
+			return nil, fmt.Errorf("source normalizer failed: %w: %s", output.runErr, output.stderr)
 		}
 		return nil, fmt.Errorf("source normalizer returned invalid JSON: %w", err)
 	}
@@ -119,8 +143,8 @@ func (n *ScriptSourceNormalizer) Normalize(ctx context.Context, rawURL string) (
 			Message: response.Error.Message,
 		}
 	}
-	if runErr != nil {
-		return nil, fmt.Errorf("source normalizer failed: %w: %s", runErr, strings.TrimSpace(stderr.String()))
+	if output.runErr != nil {
+		return nil, fmt.Errorf("source normalizer failed: %w: %s", output.runErr, output.stderr)
 	}
 	if response.SourceKey == "" || response.CanonicalURL == "" || response.Platform == "" {
 		return nil, errors.New("source normalizer returned an incomplete result")
@@ -15,4 +15,7 @@ type TrackSummary struct {
 	PosterImage        *string    `json:"posterImage"`
 	AgeLimit           *int       `json:"ageLimit,omitempty"`
 	RemovalRequestedAt *time.Time `json:"removalRequestedAt,omitempty"`
+
+	AudioImagePlaceholder  *ArtworkPlaceholder `json:"audioImagePlaceholder,omitempty"`
+	PosterImagePlaceholder *ArtworkPlaceholder `json:"posterImagePlaceholder,omitempty"`
 }
@@ -0,0 +1,335 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"html"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxTranscriptBytes skips sidecars too large to be subtitles or lyrics.
+const maxTranscriptBytes = 16 << 20
+
+// transcriptFormats maps sidecar extensions to their stored format.
+var transcriptFormats = map[string]string{
+	".vtt": "vtt",
+	".srt": "srt",
+	".lrc": "lrc",
+}
+
+var (
+	transcriptLanguagePattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+	transcriptTagPattern      = regexp.MustCompile(`<[^>]*>`)
+)
+
+// TranscriptCue is one timed line of a transcript, in seconds from the start.
+type TranscriptCue struct {
+	StartTime float64 `json:"startTime"`
+	EndTime   float64 `json:"endTime,omitempty"`
+	Text      string  `json:"text"`
+}
+
+// Transcript is the parsed content of one subtitle or lyrics sidecar.
+type Transcript struct {
+	Language string          `json:"language,omitempty"`
+	Format   string          `json:"format"`
+	Cues     []TranscriptCue `json:"cues"`
+}
+
+// TranscriptMatch is the first cue matching a search query, so results can
+// link straight to the moment it is spoken.
+type TranscriptMatch struct {
+	StartTime float64 `json:"startTime"`
+	Text      string  `json:"text"`
+}
+
+type transcriptSidecar struct {
+	name     string
+	format   string
+	language string
+}
+
+// transcriptSidecars groups the subtitle and lyrics files of a directory by
+// the audio base name they belong to. yt-dlp names subtitles
+// "<name>.<lang>.vtt", so those are listed under "<name>" with a language;
+// every sidecar is also listed under its full base name.
+func transcriptSidecars(entries []os.DirEntry) map[string][]transcriptSidecar {
+	sidecars := make(map[string][]transcriptSidecar)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		ext := filepath.Ext(name)
+		format, ok := transcriptFormats[strings.ToLower(ext)]
+		if !ok {
+			continue
+		}
+		base := strings.TrimSuffix(name, ext)
+		sidecar := transcriptSidecar{name: name, format: format}
+		sidecars[base] = append(sidecars[base], sidecar)
+		if language := strings.TrimPrefix(filepath.Ext(base), "."); transcriptLanguagePattern.MatchString(language) {
+			sidecar.language = language
+			audioBase := strings.TrimSuffix(base, "."+language)
+			sidecars[audioBase] = append(sidecars[audioBase], sidecar)
+		}
+	}
+	return sidecars
+}
+
+func parseTranscript(format string, data []byte) []TranscriptCue {
+	text := strings.TrimPrefix(string(data), "\ufeff")
+	text = strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\r", "\n")
+	if format == "lrc" {
+		return parseLRC(text)
+	}
+	return parseSubtitles(text)
+}
+
+// parseSubtitles reads WebVTT and SRT cues. Blocks without a timing line,
+// such as the WEBVTT header, NOTE and STYLE blocks, are skipped. Lines
+// repeated from the previous cue are dropped, which collapses the rolling
+// captions of auto-generated subtitles.
+func parseSubtitles(text string) []TranscriptCue {
+	var cues []TranscriptCue
+	var previous []string
+	for _, block := range strings.Split(text, "\n\n") {
+		lines := strings.Split(strings.Trim(block, "\n"), "\n")
+		timing := -1
+		for i, line := range lines {
+			if strings.Contains(line, "-->") {
+				timing = i
+				break
+			}
+		}
+		if timing < 0 {
+			continue
+		}
+		bounds := strings.SplitN(lines[timing], "-->", 2)
+		startFields, endFields := strings.Fields(bounds[0]), strings.Fields(bounds[1])
+		if len(startFields) == 0 || len(endFields) == 0 {
+			continue
+		}
+		start, ok := parseCueTimestamp(startFields[0])
+		if !ok {
+			continue
+		}
+		end, _ := parseCueTimestamp(endFields[0])
+
+		var current, fresh []string
+		for _, line := range lines[timing+1:] {
+			line = strings.TrimSpace(html.UnescapeString(transcriptTagPattern.ReplaceAllString(line, "")))
+			if line == "" {
+				continue
+			}
+			current = append(current, line)
+			if !slices.Contains(previous, line) {
+				fresh = append(fresh, line)
+			}
+		}
+		previous = current
+		if len(fresh) == 0 {
+			continue
+		}
+		cues = append(cues, TranscriptCue{StartTime: start, EndTime: end, Text: strings.Join(fresh, " ")})
+	}
+	return normalizeCues(cues)
+}
+
+// parseLRC reads LRC lyrics, including lines with several timestamps and the
+// [offset:] tag. Lines end where the next one starts.
+func parseLRC(text string) []TranscriptCue {
+	var cues []TranscriptCue
+	offset := 0.0
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		var times []float64
+		for strings.HasPrefix(line, "[") {
+			end := strings.Index(line, "]")
+			if end < 0 {
+				break
+			}
+			tag := line[1:end]
+			if t, ok := parseCueTimestamp(tag); ok {
+				times = append(times, t)
+			} else if value, found := strings.CutPrefix(tag, "offset:"); found {
+				if ms, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+					offset = float64(ms) / 1000
+				}
+			}
+			line = strings.TrimSpace(line[end+1:])
+		}
+		line = strings.TrimSpace(html.UnescapeString(transcriptTagPattern.ReplaceAllString(line, "")))
+		if line == "" {
+			continue
+		}
+		for _, t := range times {
+			cues = append(cues, TranscriptCue{StartTime: max(0, t-offset), Text: line})
+		}
+	}
+	cues = normalizeCues(cues)
+	for i := range cues {
+		if i+1 < len(cues) {
+			cues[i].EndTime = cues[i+1].StartTime
+		}
+	}
+	return cues
+}
+
+// parseCueTimestamp accepts "hh:mm:ss.fff", "mm:ss.fff" and SRT's comma
+// separated fractions.
+func parseCueTimestamp(value string) (float64, bool) {
+	parts := strings.Split(strings.Replace(value, ",", ".", 1), ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, false
+	}
+	seconds, err := strconv.ParseFloat(parts[len(parts)-1], 64)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	multiplier := 60.0
+	for i := len(parts) - 2; i >= 0; i-- {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil || n < 0 {
+			return 0, false
+		}
+		seconds += float64(n) * multiplier
+		multiplier *= 60
+	}
+	return seconds, true
+}
+
+func normalizeCues(cues []TranscriptCue) []TranscriptCue {
+	sort.SliceStable(cues, func(i, j int) bool {
+		return cues[i].StartTime < cues[j].StartTime
+	})
+	for i := range cues {
+		if cues[i].EndTime <= cues[i].StartTime {
+			cues[i].EndTime = 0
+		}
+	}
+	return cues
+}
+
+// indexTranscripts stores the sidecars of an indexed audio file. Sidecars
+// whose size and modification time are unchanged are only marked as seen;
+// transcripts not seen during a rebuild are removed afterwards.
+func (s *SearchService) indexTranscripts(audioPath, dir string, sidecars []transcriptSidecar) {
+	for _, sidecar := range sidecars {
+		fullPath := filepath.Join(dir, sidecar.name)
+		info, err := os.Stat(fullPath)
+		if err != nil || info.Size() > maxTranscriptBytes {
+			continue
+		}
+		modifiedAt := info.ModTime().UTC().Truncate(time.Microsecond)
+		result, err := s.db.DB().Exec(`
+			UPDATE transcripts SET indexed_at = CURRENT_TIMESTAMP
+			WHERE audio_file_id = (SELECT id FROM audio_files WHERE path = $1)
+			  AND filename = $2 AND size = $3 AND modified_at = $4
+		`, audioPath, sidecar.name, info.Size(), modifiedAt)
+		if err != nil {
+			log.Printf("Error checking transcript %s: %v", fullPath, err)
+			continue
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			continue
+		}
+
+		data, err := os.ReadFile(fullPath)
+		if err != nil {
+			log.Printf("Error reading transcript %s: %v", fullPath, err)
+			continue
+		}
+		if err := s.storeTranscript(audioPath, sidecar, info.Size(), modifiedAt, parseTranscript(sidecar.format, data)); err != nil {
+			log.Printf("Error indexing transcript %s: %v", fullPath, err)
+		}
+	}
+}
+
+// storeTranscript replaces the stored cues of a sidecar. A sidecar without
+// cues is still recorded so it is not parsed again until it changes.
+func (s *SearchService) storeTranscript(audioPath string, sidecar transcriptSidecar, size int64, modifiedAt time.Time, cues []TranscriptCue) error {
+	encoded, err := json.Marshal(cues)
+	if err != nil {
+		return err
+	}
+	tx, err := s.db.DB().Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var transcriptID int64
+	if err := tx.QueryRow(`
+		INSERT INTO transcripts (audio_file_id, filename, format, language, size, modified_at, indexed_at)
+		SELECT id, $2, $3, $4, $5, $6, CURRENT_TIMESTAMP FROM audio_files WHERE path = $1
+		ON CONFLICT (audio_file_id, filename) DO UPDATE SET
+			format = excluded.format,
+			language = excluded.language,
+			size = excluded.size,
+			modified_at = excluded.modified_at,
+			indexed_at = CURRENT_TIMESTAMP
+		RETURNING id
+	`, audioPath, sidecar.name, sidecar.format, nullIfEmpty(sidecar.language), size, modifiedAt).Scan(&transcriptID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM transcript_cues WHERE transcript_id = $1`, transcriptID); err != nil {
+		return err
+	}
+	if len(cues) == 0 {
+		return tx.Commit()
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO transcript_cues (transcript_id, position, start_time, end_time, text)
+		SELECT $1, e.ord, (e.cue->>'startTime')::double precision, (e.cue->>'endTime')::double precision, e.cue->>'text'
+		FROM json_array_elements($2::json) WITH ORDINALITY AS e(cue, ord)
+	`, transcriptID, string(encoded)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// AudioTranscripts loads the transcripts of an audio file, optionally only
+// those in one language.
+func AudioTranscripts(db *sql.DB, audioFileID int64, language string) ([]Transcript, error) {
+	rows, err := db.Query(`
+		SELECT t.id, t.format, COALESCE(t.language, ''), tc.start_time, tc.end_time, tc.text
+		FROM transcripts t
+		LEFT JOIN transcript_cues tc ON tc.transcript_id = t.id
+		WHERE t.audio_file_id = $1 AND ($2 = '' OR t.language = $2)
+		ORDER BY t.filename, tc.position
+	`, audioFileID, language)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transcripts := []Transcript{}
+	lastID := int64(-1)
+	for rows.Next() {
+		var id int64
+		var format, lang string
+		var start, end sql.NullFloat64
+		var text sql.NullString
+		if err := rows.Scan(&id, &format, &lang, &start, &end, &text); err != nil {
+			return nil, err
+		}
+		if id != lastID {
+			transcripts = append(transcripts, Transcript{Language: lang, Format: format, Cues: []TranscriptCue{}})
+			lastID = id
+		}
+		if text.Valid {
+			current := &transcripts[len(transcripts)-1]
+			current.Cues = append(current.Cues, TranscriptCue{StartTime: start.Float64, EndTime: end.Float64, Text: text.String})
+		}
+	}
+	return transcripts, rows.Err()
+}
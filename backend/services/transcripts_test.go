@@ -0,0 +1,145 @@
+package services
+
+import (
+	"os"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestParseVTTCollapsesRollingCaptions(t *testing.T) {
+	vtt := "\ufeffWEBVTT\r\nKind: captions\r\nLanguage: en\r\n\r\n" +
+		"NOTE generated by yt-dlp\r\n\r\n" +
+		"00:00:01.000 --> 00:00:03.500 align:start position:0%\r\n" +
+		"welcome<00:00:01.500><c> to</c><c> the</c><c> show</c>\r\n\r\n" +
+		"00:00:03.500 --> 00:00:06.000 align:start position:0%\r\n" +
+		"welcome to the show\r\n" +
+		"tonight&#39;s guest\r\n\r\n" +
+		"00:00:06.000 --> 00:00:06.010\r\n" +
+		"tonight&#39;s guest\r\n\r\n" +
+		"01:02:03.250 --> 01:02:05.000\r\n" +
+		"<i>goodbye</i>\r\n"
+	want := []TranscriptCue{
+		{StartTime: 1, EndTime: 3.5, Text: "welcome to the show"},
+		{StartTime: 3.5, EndTime: 6, Text: "tonight's guest"},
+		{StartTime: 3723.25, EndTime: 3725, Text: "goodbye"},
+	}
+	if cues := parseTranscript("vtt", []byte(vtt)); !reflect.DeepEqual(cues, want) {
+		t.Fatalf("cues = %#v, want %#v", cues, want)
+	}
+}
+
+func TestParseSRT(t *testing.T) {
+	srt := "1\n00:00:00,500 --> 00:00:02,000\nFirst line\nsecond line\n\n2\n00:00:02,000 --> 00:00:04,000\n<font color=\"#fff\">Next</font>\n"
+	want := []TranscriptCue{
+		{StartTime: 0.5, EndTime: 2, Text: "First line second line"},
+		{StartTime: 2, EndTime: 4, Text: "Next"},
+	}
+	if cues := parseTranscript("srt", []byte(srt)); !reflect.DeepEqual(cues, want) {
+		t.Fatalf("cues = %#v, want %#v", cues, want)
+	}
+}
+
+func TestParseLRC(t *testing.T) {
+	lrc := "[ar:Artist]\n[offset:+500]\n[00:10.50]Verse\n[00:20.00][00:40.00]Chorus\n[00:30.00]\n[00:35.00]<00:35.00>Bridge\n"
+	want := []TranscriptCue{
+		{StartTime: 10, EndTime: 19.5, Text: "Verse"},
+		{StartTime: 19.5, EndTime: 34.5, Text: "Chorus"},
+		{StartTime: 34.5, EndTime: 39.5, Text: "Bridge"},
+		{StartTime: 39.5, Text: "Chorus"},
+	}
+	if cues := parseTranscript("lrc", []byte(lrc)); !reflect.DeepEqual(cues, want) {
+		t.Fatalf("cues = %#v, want %#v", cues, want)
+	}
+}
+
+func TestTranscriptSidecarsGroupByAudioName(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"episode.mp3", "episode.en.vtt", "episode.pt-BR.srt", "song.lrc", "song.v2.lrc", "notes.txt"} {
+		if err := os.WriteFile(dir+"/"+name, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sidecars := transcriptSidecars(entries)
+	want := map[string][]transcriptSidecar{
+		"episode":       {{name: "episode.en.vtt", format: "vtt", language: "en"}, {name: "episode.pt-BR.srt", format: "srt", language: "pt-BR"}},
+		"episode.en":    {{name: "episode.en.vtt", format: "vtt"}},
+		"episode.pt-BR": {{name: "episode.pt-BR.srt", format: "srt"}},
+		"song":          {{name: "song.lrc", format: "lrc"}},
+		"song.v2":       {{name: "song.v2.lrc", format: "lrc"}},
+	}
+	if !reflect.DeepEqual(sidecars, want) {
+		t.Fatalf("sidecars = %#v, want %#v", sidecars, want)
+	}
+}
+
+func TestStoreTranscriptRecordsSidecarWithoutCues(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	modifiedAt := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO transcripts`)).
+		WithArgs("audio/episode.mp3", "episode.en.vtt", "vtt", "en", int64(7), modifiedAt).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM transcript_cues WHERE transcript_id = $1`)).
+		WithArgs(int64(3)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	cues := parseTranscript("vtt", []byte("WEBVTT\n\nNOTE nothing was said\n"))
+	if len(cues) != 0 {
+		t.Fatalf("cues = %#v, want none", cues)
+	}
+	service := &SearchService{db: &Database{db: db}}
+	sidecar := transcriptSidecar{name: "episode.en.vtt", format: "vtt", language: "en"}
+	if err := service.storeTranscript("audio/episode.mp3", sidecar, 7, modifiedAt, cues); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSearchReturnsTranscriptMatch(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	columns := []string{
+		"id", "name", "path", "type", "parent_path", "size", "mime_type", "title", "artist", "description",
+		"webpage_url", "age_limit", "original_url", "item_count", "directory_size", "poster_image", "modified_at",
+		"share_key", "unavailable_at", "removal_requested_at", "placeholder", "transcript_time", "transcript_text", "total_count",
+	}
+	mock.ExpectQuery(`(?s)LEFT JOIN LATERAL .* tc\.text ILIKE \$1 .*EXISTS \(SELECT 1 FROM transcripts t .* tc\.text ILIKE \$2\)`).
+		WithArgs("%kenobi%", "%kenobi%", 50, 0).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(
+			7, "Episode", "audio/episode.mp3", "audio", "audio", 100, "audio/mpeg", "Episode", nil, nil,
+			nil, nil, nil, nil, nil, nil, "2026-01-01", "episode-key", nil, nil, nil, 83.5, "General Kenobi", 1,
+		))
+
+	service := &SearchService{db: &Database{db: db}}
+	results, _, err := service.Search("kenobi", 50, 0, SearchOptions{Type: "audio", Fields: []string{"transcript"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].TranscriptMatch == nil ||
+		*results[0].TranscriptMatch != (TranscriptMatch{StartTime: 83.5, Text: "General Kenobi"}) {
+		t.Fatalf("results = %#v", results)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -60,22 +60,9 @@ func (s *WaveformService) StartScheduledJob(cronExpr, maxDurationStr string) {
 
 	c := cron.New()
 	_, err = c.AddFunc(cronExpr, func() {
-		s.mu.Lock()
-		if s.running {
-			s.mu.Unlock()
+		if s.RunJobExclusive(maxDuration) == ErrJobRunning {
 			log.Println("Waveform: job already running, skipping")
-			return
 		}
-		s.running = true
-		s.mu.Unlock()
-
-		defer func() {
-			s.mu.Lock()
-			s.running = false
-			s.mu.Unlock()
-		}()
-
-		s.RunJob(maxDuration)
 	})
 	if err != nil {
 		log.Printf("Waveform: error setting up schedule: %v", err)
@@ -84,6 +71,27 @@ func (s *WaveformService) StartScheduledJob(cronExpr, maxDurationStr string) {
 	c.Start()
 }
 
+// RunJobExclusive runs the job unless a scheduled or manual run is already
+// in progress, in which case it returns ErrJobRunning.
+func (s *WaveformService) RunJobExclusive(maxDuration time.Duration) error {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return ErrJobRunning
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+	}()
+
+	s.RunJob(maxDuration)
+	return nil
+}
+
 func (s *WaveformService) RunJob(maxDuration time.Duration) {
 	start := time.Now()
 	log.Println("Waveform: starting generation job")
@@ -53,6 +53,30 @@ func (w *WebhookService) SendIndexComplete(duration time.Duration, newFolders []
 		NewFolders: newFolders,
 	}
 
+	return w.post(payload)
+}
+
+// AvailabilityCompletePayload reports an availability job run that changed
+// at least one track.
+type AvailabilityCompletePayload struct {
+	Event     string               `json:"event"`
+	Timestamp string               `json:"timestamp"`
+	Summary   *AvailabilitySummary `json:"summary"`
+}
+
+func (w *WebhookService) SendAvailabilityComplete(summary *AvailabilitySummary) error {
+	if !w.IsConfigured() {
+		return nil
+	}
+
+	return w.post(AvailabilityCompletePayload{
+		Event:     "availability_check_complete",
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Summary:   summary,
+	})
+}
+
+func (w *WebhookService) post(payload any) error {
 	body, err := json.Marshal(payload)
 	if err != nil {
 		return fmt.Errorf("failed to marshal payload: %w", err)
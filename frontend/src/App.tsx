@@ -1,7 +1,8 @@
-import { lazy } from 'react'
+import { lazy, Suspense } from 'react'
 import { BrowserRouter, Routes, Route } from 'react-router'
 import Layout from './components/Layout'
 import TargetedMessageModal from './components/TargetedMessageModal'
+import { isEmbeddedPlayer } from './lib/mediaAccess'
 
 const Home = lazy(() => import('./pages/Home'))
 const About = lazy(() => import('./pages/About'))
@@ -14,12 +15,14 @@ const Requests = lazy(() => import('./pages/Requests'))
 const NotFound = lazy(() => import('./pages/NotFound'))
 const Likes = lazy(() => import('./pages/Likes'))
 const Recover = lazy(() => import('./pages/Recover'))
+const Embed = lazy(() => import('./pages/Embed'))
 
 export default function App() {
   return (
     <BrowserRouter>
-      <TargetedMessageModal />
+      {!isEmbeddedPlayer() && <TargetedMessageModal />}
       <Routes>
+        <Route path="/embed/:key" element={<Suspense fallback={null}><Embed /></Suspense>} />
         <Route element={<Layout />}>
           <Route path="/" element={<Home />} />
           <Route path="/about" element={<About />} />
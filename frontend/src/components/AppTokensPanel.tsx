@@ -0,0 +1,160 @@
+import {type FormEvent, useEffect, useState} from 'react';
+import {Check, Clipboard, Smartphone, Trash2} from 'lucide-react';
+import {AppToken, CreatedAppToken, createAppToken, getAppTokens, revokeAppToken} from '@/lib/api';
+import {formatDate} from '@/lib/utils';
+
+export default function AppTokensPanel() {
+    const [tokens, setTokens] = useState<AppToken[]>([]);
+    const [isLoading, setIsLoading] = useState(true);
+    const [name, setName] = useState('');
+    const [created, setCreated] = useState<CreatedAppToken | null>(null);
+    const [isWorking, setIsWorking] = useState(false);
+    const [copied, setCopied] = useState<'username' | 'password' | null>(null);
+    const [error, setError] = useState<string | null>(null);
+
+    useEffect(() => {
+        const controller = new AbortController();
+        getAppTokens(controller.signal)
+            .then(setTokens)
+            .catch(() => {
+                if (!controller.signal.aborted) setError('Could not load app tokens.');
+            })
+            .finally(() => {
+                if (!controller.signal.aborted) setIsLoading(false);
+            });
+        return () => controller.abort();
+    }, []);
+
+    useEffect(() => {
+        if (!copied) return;
+        const timeout = window.setTimeout(() => setCopied(null), 1800);
+        return () => window.clearTimeout(timeout);
+    }, [copied]);
+
+    const create = async (event: FormEvent) => {
+        event.preventDefault();
+        setIsWorking(true);
+        setError(null);
+        try {
+            const token = await createAppToken(name.trim());
+            setCreated(token);
+            setTokens(previous => [...previous, token]);
+            setName('');
+        } catch (err) {
+            setError(err instanceof Error ? err.message : 'Could not create an app token.');
+        } finally {
+            setIsWorking(false);
+        }
+    };
+
+    const revoke = async (token: AppToken) => {
+        setIsWorking(true);
+        setError(null);
+        try {
+            await revokeAppToken(token.id);
+            setTokens(previous => previous.filter(item => item.id !== token.id));
+            if (created?.id === token.id) setCreated(null);
+        } catch {
+            setError(`Could not revoke ${token.name}. Please try again.`);
+        } finally {
+            setIsWorking(false);
+        }
+    };
+
+    const copy = async (value: string, type: 'username' | 'password') => {
+        try {
+            await navigator.clipboard.writeText(value);
+            setCopied(type);
+        } catch {
+            setError('Could not copy automatically. Select and copy the value instead.');
+        }
+    };
+
+    return (
+        <section className="mt-6 rounded-lg border border-[var(--border)] bg-[var(--card)] overflow-hidden">
+            <div className="p-5 sm:p-6 border-b border-[var(--border)]">
+                <div className="flex items-start gap-3">
+                    <div className="p-2 rounded-md bg-[var(--secondary)] text-[var(--primary)]"><Smartphone className="h-5 w-5" /></div>
+                    <div>
+                        <h2 className="text-xl font-semibold">Player apps</h2>
+                        <p className="mt-1 text-sm text-[var(--muted-foreground)] max-w-2xl">
+                            Create an app token to sign in to a Subsonic player with this profile and its likes.
+                        </p>
+                    </div>
+                </div>
+            </div>
+
+            <div className="p-5 sm:p-6">
+                {error && <p role="alert" className="mb-4 text-sm text-[var(--error-text)]">{error}</p>}
+
+                {created && (
+                    <div role="status" className="mb-5 rounded-md border border-[var(--primary-soft-hover)] bg-[var(--primary-wash)] p-4 animate-fadeIn">
+                        <div className="flex items-center gap-2 text-[var(--success-text)]"><Check className="h-4 w-4" /> App token created</div>
+                        <p className="mt-1 text-sm text-[var(--muted-foreground)]">
+                            Enter these in the player with this site’s address. The password won’t be shown again.
+                        </p>
+                        {([['username', created.username], ['password', created.password]] as const).map(([type, value]) => (
+                            <div key={type} className="mt-3 flex items-center gap-2">
+                                <span className="w-20 text-sm text-[var(--muted-foreground)] capitalize">{type}</span>
+                                <div className="min-w-0 flex-1 rounded-md bg-[var(--background)] border border-[var(--border)] p-2 font-mono text-xs break-all select-all">{value}</div>
+                                <button
+                                    type="button"
+                                    onClick={() => void copy(value, type)}
+                                    aria-label={`Copy ${type}`}
+                                    className="p-2 rounded-md border border-[var(--border)] hover:border-[var(--primary)]"
+                                >
+                                    {copied === type ? <Check className="h-4 w-4" /> : <Clipboard className="h-4 w-4" />}
+                                </button>
+                            </div>
+                        ))}
+                    </div>
+                )}
+
+                {isLoading ? (
+                    <div className="h-10 skeleton rounded w-full" />
+                ) : tokens.length > 0 && (
+                    <ul className="mb-5 divide-y divide-[var(--border)] rounded-md border border-[var(--border)]">
+                        {tokens.map(token => (
+                            <li key={token.id} className="flex items-center gap-3 p-3">
+                                <div className="min-w-0 flex-1">
+                                    <div className="font-medium truncate">{token.name}</div>
+                                    <div className="text-xs text-[var(--muted-foreground)] truncate">
+                                        {token.username} · created {formatDate(token.createdAt)}
+                                        {token.lastUsedAt ? ` · last used ${formatDate(token.lastUsedAt)}` : ' · never used'}
+                                    </div>
+                                </div>
+                                <button
+                                    type="button"
+                                    disabled={isWorking}
+                                    onClick={() => void revoke(token)}
+                                    className="inline-flex items-center gap-2 px-3 py-2 rounded-md border border-[var(--border)] hover:border-[var(--error-text)] hover:text-[var(--error-text)] disabled:opacity-50 text-sm"
+                                >
+                                    <Trash2 className="h-4 w-4" /> Revoke
+                                </button>
+                            </li>
+                        ))}
+                    </ul>
+                )}
+
+                <form onSubmit={event => void create(event)} className="flex flex-col sm:flex-row gap-2">
+                    <label htmlFor="app-token-name" className="sr-only">Player name</label>
+                    <input
+                        id="app-token-name"
+                        value={name}
+                        onChange={event => setName(event.target.value)}
+                        maxLength={64}
+                        placeholder="Player name, e.g. Phone"
+                        className="min-w-0 flex-1 px-3 py-2 rounded-md border border-[var(--border)] bg-[var(--background)] text-sm"
+                    />
+                    <button
+                        type="submit"
+                        disabled={isWorking || !name.trim()}
+                        className="inline-flex items-center justify-center gap-2 px-4 py-2 rounded-md bg-[var(--primary)] text-white hover:bg-[var(--primary-hover)] disabled:opacity-50 transition-colors whitespace-nowrap"
+                    >
+                        {isWorking ? 'Working…' : 'Create app token'}
+                    </button>
+                </form>
+            </div>
+        </section>
+    );
+}
@@ -36,6 +36,7 @@ function formatTime(time: number): string {
 export default function AudioPlayer() {
     const [isMinimized, setIsMinimized] = useState(false);
     const [isDescriptionExpanded, setIsDescriptionExpanded] = useState(false);
+    const [isChaptersExpanded, setIsChaptersExpanded] = useState(false);
     const [showQueue, setShowQueue] = useState(false);
     const progressRef = useRef<HTMLDivElement>(null);
 
@@ -87,6 +88,7 @@ export default function AudioPlayer() {
             setIsMinimized(true);
         }
         setIsDescriptionExpanded(false);
+        setIsChaptersExpanded(false);
     }, [currentTrack?.source, currentTrack?.src]);
 
     useEffect(() => {
@@ -108,6 +110,9 @@ export default function AudioPlayer() {
         setIsDescriptionExpanded(!isDescriptionExpanded);
     };
 
+    const chapters = metadata?.chapters ?? [];
+    const currentChapterIndex = chapters.reduce((current, chapter, index) => chapter.startTime <= currentTime ? index : current, -1);
+
     const handleClosePlayer = () => {
         setShowQueue(false);
         closePlayer();
@@ -396,6 +401,39 @@ export default function AudioPlayer() {
                                 )}
                             </div>
                         )}
+                        {chapters.length > 0 && canShowMatureDetails && (
+                            <div className="mt-3 text-xs text-[var(--muted-foreground)]">
+                                <button
+                                    onClick={() => setIsChaptersExpanded(!isChaptersExpanded)}
+                                    className="flex w-full items-center justify-between rounded px-1 py-1.5 text-left font-medium hover:bg-[var(--card-hover-subtle)] hover:text-[var(--foreground)] transition-colors"
+                                    aria-expanded={isChaptersExpanded}
+                                >
+                                    <span>Chapters</span>
+                                    <span className="p-0.5" aria-hidden="true">
+                                        {isChaptersExpanded ? <ChevronsUp className="h-3 w-3"/> :
+                                            <ChevronsDown className="h-3 w-3"/>}
+                                    </span>
+                                </button>
+                                {isChaptersExpanded && (
+                                    <ol className="mt-1 max-h-40 overflow-y-auto rounded bg-[var(--card-hover-subtle)] p-1 custom-scrollbar animate-fadeIn">
+                                        {chapters.map((chapter, index) => (
+                                            <li key={`${chapter.startTime}-${index}`}>
+                                                <button
+                                                    onClick={() => seekTo(chapter.startTime)}
+                                                    className={`flex w-full items-baseline gap-2 rounded px-1 py-1 text-left hover:text-[var(--foreground)] transition-colors ${
+                                                        index === currentChapterIndex ? 'text-[var(--primary)]' : ''
+                                                    }`}
+                                                    aria-current={index === currentChapterIndex ? 'true' : undefined}
+                                                >
+                                                    <span className="tabular-nums">{formatTime(chapter.startTime)}</span>
+                                                    <span className="min-w-0 truncate">{chapter.title}</span>
+                                                </button>
+                                            </li>
+                                        ))}
+                                    </ol>
+                                )}
+                            </div>
+                        )}
                         {metadata?.description && !canShowMatureDetails && (
                             <div className="mt-3 text-xs text-[var(--muted-foreground)] rounded bg-[var(--card-hover-subtle)] p-2">
                                 Description hidden for mature content.
@@ -1,6 +1,6 @@
 import React, {useCallback, useEffect, useLayoutEffect, useMemo, useRef, useState} from 'react';
 import {createPortal} from 'react-dom';
-import {Calendar, Clock, Loader2, SortAsc} from 'lucide-react';
+import {Calendar, Clock, ListOrdered, Loader2, SortAsc} from 'lucide-react';
 import {FileSystemItem} from '@/types';
 import AlphaScrollbar from './AlphaScrollbar';
 import MobileItemName from "@/components/MobileItemName";
@@ -26,7 +26,7 @@ interface FolderViewProps {
     currentPath?: string;
 }
 
-type SortMethod = 'alpha' | 'modified' | 'size' | 'duration';
+type SortMethod = 'alpha' | 'modified' | 'size' | 'duration' | 'manual';
 
 type SearchableItem = {
     item: FileSystemItem;
@@ -206,11 +206,16 @@ export default function FolderView({items, currentPath = ''}: FolderViewProps) {
         return () => mediaQuery.removeEventListener('change', updateLayoutMode);
     }, []);
 
+    // folder.json can give folders a sort_order and tracks a track_order
+    const hasManualOrder = useMemo(() => items.some(item => item.position !== undefined), [items]);
+
     useEffect(() => {
         const urlSort = searchParams.get("sort") as SortMethod | null;
 
         if (urlSort && ['alpha', 'modified', 'size', 'duration'].includes(urlSort)) {
             setSortMethod(urlSort);
+        } else if (hasManualOrder && (!urlSort || urlSort === 'manual')) {
+            setSortMethod('manual');
         } else {
             const folderCount = items.filter(item => item.type === 'folder').length;
             const fileCount = items.filter(item => item.type === 'audio').length;
@@ -226,7 +231,7 @@ export default function FolderView({items, currentPath = ''}: FolderViewProps) {
         if (urlOrder === 'desc' || urlOrder === 'asc') {
             setSortOrder(urlOrder);
         }
-    }, [items, searchParams]);
+    }, [items, searchParams, hasManualOrder]);
 
     const handleOrderToggle = useCallback((method: SortMethod) => {
         if (method === sortMethod) {
@@ -254,6 +259,7 @@ export default function FolderView({items, currentPath = ''}: FolderViewProps) {
             if (item.type === 'folder' && item.metadata) {
                 if (item.metadata.name) parts.push(item.metadata.name);
                 if (item.metadata.description) parts.push(item.metadata.description);
+                parts.push(...(item.metadata.tags ?? []), ...(item.metadata.aliases ?? []));
             }
 
             return {
@@ -311,6 +317,20 @@ export default function FolderView({items, currentPath = ''}: FolderViewProps) {
                     return durationDiff || a.name.localeCompare(b.name);
                 });
             }
+            case 'manual': {
+                // Items without a position keep the server's order after the positioned ones
+                const presortedItems = filteredItems
+                    .map((item, index) => ({item, index}))
+                    .sort((a, b) => {
+                        if (a.item.type === 'folder' && b.item.type !== 'folder') return -1;
+                        if (a.item.type !== 'folder' && b.item.type === 'folder') return 1;
+                        const positionA = a.item.position ?? Number.MAX_SAFE_INTEGER;
+                        const positionB = b.item.position ?? Number.MAX_SAFE_INTEGER;
+                        return positionA - positionB || a.index - b.index;
+                    })
+                    .map(({item}) => item);
+                return reverseIf(presortedItems, sortOrder === 'desc');
+            }
             default:
                 return reverseIf(filteredItems, sortOrder === 'desc');
         }
@@ -512,6 +532,15 @@ export default function FolderView({items, currentPath = ''}: FolderViewProps) {
                             <div className="flex items-center justify-end md:justify-start space-x-2 flex-shrink-0">
                                 <div className="text-[0.7rem] uppercase tracking-[0.1em] text-[var(--muted-foreground)]">Sort by:</div>
                                 <div className="flex border border-[var(--border)] rounded-md overflow-hidden">
+                                    {hasManualOrder && (
+                                        <button
+                                            onClick={() => handleOrderToggle('manual')}
+                                            className={`px-3 py-1.5 text-[0.7rem] uppercase tracking-[0.1em] flex items-center ${sortMethod === 'manual' ? 'bg-[var(--primary)] text-white' : 'bg-[var(--card)] hover:bg-[var(--card-hover)]'}`}
+                                            title="Sort in the folder's own order"
+                                        >
+                                            <ListOrdered className="h-3.5 w-3.5 mr-1 hidden md:block"/> Order
+                                        </button>
+                                    )}
                                     <button
                                         onClick={() => handleOrderToggle('alpha')}
                                         className={`px-3 py-1.5 text-[0.7rem] uppercase tracking-[0.1em] flex items-center ${sortMethod === 'alpha' ? 'bg-[var(--primary)] text-white' : 'bg-[var(--card)] hover:bg-[var(--card-hover)]'}`}
@@ -18,6 +18,7 @@ function MobileItemName({ item }: ItemNameProps) {
                     item.posterImage && item.type === 'folder' && item.shareKey ? (
                         <PosterImage
                             shareKey={item.shareKey}
+                            placeholder={item.placeholder}
                             className="h-8 w-8 rounded object-cover shadow-sm"
                         />
                     ) : (
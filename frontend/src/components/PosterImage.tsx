@@ -1,12 +1,14 @@
 import {useState} from 'react';
 import {API_BASE} from '@/lib/api';
+import type {ArtworkPlaceholder} from '@/types';
 
 interface PosterImageProps {
     shareKey: string;
     className?: string;
+    placeholder?: ArtworkPlaceholder;
 }
 
-export default function PosterImage({ shareKey, className }: PosterImageProps) {
+export default function PosterImage({ shareKey, className, placeholder }: PosterImageProps) {
     const [imageError, setImageError] = useState(false);
 
     if (imageError) {
@@ -15,12 +17,13 @@ export default function PosterImage({ shareKey, className }: PosterImageProps) {
 
     return (
         <img
-            src={`${API_BASE}/api/folder/key/${shareKey}/poster`}
+            src={`${API_BASE}/api/folder/key/${shareKey}/poster?size=icon`}
             alt=""
             width={32}
             height={32}
             loading="lazy"
             className={className}
+            style={{backgroundColor: placeholder?.dominantColor}}
             onError={() => setImageError(true)}
         />
     );
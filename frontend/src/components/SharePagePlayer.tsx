@@ -1,21 +1,33 @@
+import {useEffect, useRef} from 'react';
 import {AlertCircle, Loader2, Pause, Play, Radio} from 'lucide-react';
 import {useGlobalAudioPlayer} from '@/contexts/AudioPlayerContext';
+import {formatDuration} from '@/lib/utils';
 
 interface SharePagePlayerProps {
     src: string;
     name: string;
     artist?: string;
     ageLimit?: number;
+    // Seconds to seek to once the track has loaded, from a ?t= share link.
+    startAt?: number;
 }
 
-export default function SharePagePlayer({src, name, artist, ageLimit}: SharePagePlayerProps) {
-    const {playTrack, currentTrack, isPlaying, isLoading, error} = useGlobalAudioPlayer();
+export default function SharePagePlayer({src, name, artist, ageLimit, startAt}: SharePagePlayerProps) {
+    const {playTrack, currentTrack, isPlaying, isLoading, audioLoaded, error, seekTo} = useGlobalAudioPlayer();
     const isActiveTrack = currentTrack?.src === src;
+    const pendingStartRef = useRef<number | undefined>(undefined);
 
     const handlePlay = () => {
+        pendingStartRef.current = startAt;
         playTrack({src, name, artist, ageLimit, source: 'share'});
     };
 
+    useEffect(() => {
+        if (!isActiveTrack || !audioLoaded || pendingStartRef.current === undefined) return;
+        seekTo(pendingStartRef.current);
+        pendingStartRef.current = undefined;
+    }, [audioLoaded, isActiveTrack, seekTo]);
+
     let status = null;
     if (isActiveTrack) {
         if (error) {
@@ -54,7 +66,7 @@ export default function SharePagePlayer({src, name, artist, ageLimit}: SharePage
                         onClick={handlePlay}
                         className="flex min-w-52 items-center justify-center gap-3 rounded-full bg-[var(--primary)] px-7 py-3 font-medium text-white transition-[background-color,transform] duration-200 hover:scale-[1.02] hover:bg-[var(--primary-hover)]"
                     >
-                        <Play className="h-5 w-5 fill-current" /> Play this track
+                        <Play className="h-5 w-5 fill-current" /> {startAt ? `Play from ${formatDuration(startAt)}` : 'Play this track'}
                     </button>
                     {currentTrack && (
                         <p className="max-w-sm text-xs text-[var(--muted-foreground)]">
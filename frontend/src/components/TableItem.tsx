@@ -45,6 +45,7 @@ function TableItem({ item, showDurationColumn, handleAudioSelect, copiedShareKey
                             {item.posterImage && item.type === 'folder' && item.shareKey ? (
                                 <PosterImage
                                     shareKey={item.shareKey}
+                                    placeholder={item.placeholder}
                                     className="h-8 w-8 rounded object-cover shadow-sm"
                                 />
                             ) : (
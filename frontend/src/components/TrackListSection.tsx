@@ -4,7 +4,7 @@ import {ChevronLeft, ChevronRight, Music, Play, ShieldAlert} from 'lucide-react'
 import type {TrackSummary} from '@/lib/api';
 import {useRybbit} from '@/hooks/useRybbit';
 import {useAudioPlayerCommands} from '@/contexts/AudioPlayerContext';
-import {playbackToPlayerTrack, trackArtworkUrl} from '@/lib/tracks';
+import {playbackToPlayerTrack, trackArtworkPlaceholder, trackArtworkUrl} from '@/lib/tracks';
 import TrackQuickActions from '@/components/TrackQuickActions';
 
 interface TrackListSectionProps {
@@ -15,7 +15,7 @@ interface TrackListSectionProps {
 
 function TrackPoster({track}: {track: TrackSummary}) {
     const [imageError, setImageError] = useState(false);
-    const imageUrl = imageError ? null : trackArtworkUrl(track);
+    const imageUrl = imageError ? null : trackArtworkUrl(track, 'medium');
 
     if (!imageUrl) {
         const bars = [14, 22, 18, 28, 20, 32, 24, 16, 26, 20, 12, 28, 22, 18, 30];
@@ -47,6 +47,7 @@ function TrackPoster({track}: {track: TrackSummary}) {
             src={imageUrl}
             alt=""
             className="w-full h-24 md:h-28 object-cover"
+            style={{backgroundColor: trackArtworkPlaceholder(track)?.dominantColor}}
             loading="lazy"
             onError={() => setImageError(true)}
         />
@@ -2,16 +2,19 @@ import {useEffect, useState} from 'react';
 import {API_BASE} from '@/lib/api';
 import {MATURE_PREFERENCE_EVENT} from '@/lib/matureContentPreference';
 import type {PlayerTrack} from '@/lib/playerQueue';
+import type {ArtworkPlaceholder, Chapter} from '@/types';
 import {appFetch} from '@/lib/cloudflareChallenge';
 
 export interface PlayerMetadata {
     title: string;
     artist: string;
     thumbnail?: boolean;
+    placeholder?: ArtworkPlaceholder;
     uploadDate?: string;
     webpageUrl?: string;
     duration?: number;
     description?: string;
+    chapters?: Chapter[];
     ageLimit?: number;
     isMature?: boolean;
     showMature?: boolean;
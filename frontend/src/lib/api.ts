@@ -2,13 +2,18 @@ import {syncRybbitIdentity} from '@/lib/rybbitIdentity';
 import {appFetch} from '@/lib/cloudflareChallenge';
 
 export type { FileSystemItem, FolderMetadata, AudioFile, Folder, Tag, RequestStatus, SourceRequest, RequestsByStatus } from '@/types';
-import type { FileSystemItem, RequestsByStatus } from '@/types';
+import type { ArtworkPlaceholder, FileSystemItem, RequestsByStatus } from '@/types';
 
 export const API_BASE = import.meta.env.VITE_API_URL || '';
 
 export interface DirectoryContents {
     items: FileSystemItem[];
     currentPath: string;
+    // Paging of the listing. The SPA fetches whole directories, so total
+    // equals items.length and limit is 0.
+    total: number;
+    offset: number;
+    limit: number;
 }
 
 export async function fetchDirectoryContents(path: string = ''): Promise<DirectoryContents> {
@@ -52,6 +57,9 @@ export interface SearchResult {
     posterImage?: string;
 
     modifiedAt?: string;
+    placeholder?: ArtworkPlaceholder;
+    // First matching transcript cue, when the query matched a transcript.
+    transcriptMatch?: {startTime: number; text: string};
 }
 
 export interface SearchResponse {
@@ -76,6 +84,8 @@ export interface TrackSummary {
     posterImage: string | null;
     ageLimit?: number;
     removalRequestedAt?: string;
+    audioImagePlaceholder?: ArtworkPlaceholder;
+    posterImagePlaceholder?: ArtworkPlaceholder;
 }
 
 export interface PlaybackStatsTrack extends TrackSummary {
@@ -183,6 +193,47 @@ export async function recoverBrowserProfile(recoveryKey: string): Promise<string
     return data.profileId;
 }
 
+export interface AppToken {
+    id: number;
+    name: string;
+    username: string;
+    createdAt: string;
+    lastUsedAt: string | null;
+}
+
+// The password is only returned when the token is created. It doubles as
+// the OpenSubsonic API key.
+export interface CreatedAppToken extends AppToken {
+    password: string;
+}
+
+export async function getAppTokens(signal?: AbortSignal): Promise<AppToken[]> {
+    const response = await appFetch(`${API_BASE}/api/profile/app-tokens`, {credentials: 'include', signal});
+    if (!response.ok) throw new Error('Failed to load app tokens');
+    const data = await response.json() as {tokens: AppToken[]};
+    return data.tokens;
+}
+
+export async function createAppToken(name: string): Promise<CreatedAppToken> {
+    const response = await appFetch(`${API_BASE}/api/profile/app-tokens`, {
+        method: 'POST',
+        headers: {'Content-Type': 'application/json'},
+        credentials: 'include',
+        body: JSON.stringify({name}),
+    });
+    const data = await response.json().catch(() => ({}));
+    if (!response.ok) throw new Error(data.error || 'Failed to create app token');
+    return data as CreatedAppToken;
+}
+
+export async function revokeAppToken(id: number): Promise<void> {
+    const response = await appFetch(`${API_BASE}/api/profile/app-tokens/${id}`, {
+        method: 'DELETE',
+        credentials: 'include',
+    });
+    if (!response.ok && response.status !== 404) throw new Error('Failed to revoke app token');
+}
+
 export async function getLikes(signal?: AbortSignal): Promise<LikesResponse> {
     const response = await appFetch(`${API_BASE}/api/likes`, {credentials: 'include', signal});
     if (!response.ok) throw new Error('Failed to load likes');
@@ -247,7 +298,7 @@ export async function getRandomAudio(): Promise<string> {
     return data.shareKey;
 }
 
-export type SearchField = 'filename' | 'title' | 'artist' | 'description';
+export type SearchField = 'filename' | 'title' | 'artist' | 'description' | 'chapters' | 'transcript';
 
 export interface SearchFilters {
     type?: 'audio' | 'folder';
@@ -260,6 +311,8 @@ export interface SearchFilters {
     /** Which audio fields to search in. Empty/undefined = all fields. */
     fields?: SearchField[];
     root?: string;
+    /** Folder path to search beneath. */
+    path?: string;
     includeMature?: boolean;
 }
 
@@ -281,6 +334,7 @@ export async function searchAudio(query: string, limit?: number, offset?: number
         if (filters.durationMax != null && filters.durationMax > 0) params.set('durationMax', filters.durationMax.toString());
         if (filters.fields && filters.fields.length > 0) params.set('fields', filters.fields.join(','));
         if (filters.root) params.set('root', filters.root);
+        if (filters.path) params.set('path', filters.path);
         if (filters.includeMature) params.set('includeMature', 'true');
     }
 
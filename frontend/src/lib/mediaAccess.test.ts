@@ -183,4 +183,13 @@ describe('mediaAccessErrorMessage', () => {
             'download',
         )).toBe('Downloads are available in 2 minutes.');
     });
+
+    it('explains a temporary block from scraper detection', async () => {
+        const {MediaAccessError, mediaAccessErrorMessage} = await import('./mediaAccess');
+
+        expect(mediaAccessErrorMessage(
+            new MediaAccessError(403, 'temporarily_banned', null),
+            'play',
+        )).toBe('Access is temporarily blocked because of unusual activity. Please try again later.');
+    });
 });
@@ -43,10 +43,17 @@ interface SessionBootstrap {
 let sessionBootstrap: SessionBootstrap | null = null;
 let nextSessionGeneration = 1;
 
+// Embedded players run inside third-party iframes, where only partitioned
+// SameSite=None cookies survive, so they ask the server for one.
+export function isEmbeddedPlayer(): boolean {
+    return typeof window !== 'undefined' && window.location.pathname.startsWith('/embed/');
+}
+
 function startSessionBootstrap(): SessionBootstrap {
+    const sessionPath = isEmbeddedPlayer() ? '/api/session?embed=1' : '/api/session';
     const bootstrap: SessionBootstrap = {
         generation: nextSessionGeneration++,
-        promise: appFetch(`${API_BASE}/api/session`, {
+        promise: appFetch(`${API_BASE}${sessionPath}`, {
             method: 'POST',
             credentials: 'include',
         }).then(response => {
@@ -203,6 +210,9 @@ export function mediaAccessErrorMessage(error: unknown, action: 'play' | 'downlo
     if (error.code === 'captcha_invalid') {
         return 'Verification expired or was rejected. Please try again.';
     }
+    if (error.code === 'temporarily_banned') {
+        return 'Access is temporarily blocked because of unusual activity. Please try again later.';
+    }
     if (error.code === 'captcha_unavailable') {
         return 'Verification is temporarily unavailable. Please try again shortly.';
     }
@@ -1,5 +1,5 @@
 import {describe, expect, it} from 'vitest';
-import {audioShareUrl} from './share';
+import {audioShareUrl, parseStartTime, shareStartPath} from './share';
 
 describe('audioShareUrl', () => {
     it('builds an absolute canonical URL and encodes the share key', () => {
@@ -8,3 +8,27 @@ describe('audioShareUrl', () => {
         );
     });
 });
+
+describe('shareStartPath', () => {
+    it('adds whole seconds only for a positive start time', () => {
+        expect(shareStartPath('track/key', 83.7)).toBe('/share/track%2Fkey?t=83');
+        expect(shareStartPath('track-key', 0)).toBe('/share/track-key');
+        expect(shareStartPath('track-key')).toBe('/share/track-key');
+    });
+});
+
+describe('parseStartTime', () => {
+    it('accepts seconds and clock positions', () => {
+        expect(parseStartTime('83')).toBe(83);
+        expect(parseStartTime('1:23')).toBe(83);
+        expect(parseStartTime('1:02:05')).toBe(3725);
+    });
+
+    it('ignores missing, zero and malformed values', () => {
+        expect(parseStartTime(null)).toBeUndefined();
+        expect(parseStartTime('0')).toBeUndefined();
+        expect(parseStartTime('-5')).toBeUndefined();
+        expect(parseStartTime('1:2:3:4')).toBeUndefined();
+        expect(parseStartTime('soon')).toBeUndefined();
+    });
+});
@@ -1,3 +1,19 @@
 export function audioShareUrl(shareKey: string, origin = window.location.origin): string {
     return `${origin}/share/${encodeURIComponent(shareKey)}`;
 }
+
+// Share links can start playback at a moment, e.g. from a transcript search
+// hit. The position is given in seconds, as in `?t=83`, or as `1:23`.
+export function shareStartPath(shareKey: string, startTime?: number): string {
+    const path = `/share/${encodeURIComponent(shareKey)}`;
+    return startTime && startTime > 0 ? `${path}?t=${Math.floor(startTime)}` : path;
+}
+
+export function parseStartTime(value: string | null): number | undefined {
+    if (!value) return undefined;
+    if (/^\d+(\.\d+)?$/.test(value)) return Number(value) || undefined;
+    const match = /^(?:(\d+):)?(\d{1,2}):(\d{2})$/.exec(value);
+    if (!match) return undefined;
+    const [, hours = '0', minutes, seconds] = match;
+    return Number(hours) * 3600 + Number(minutes) * 60 + Number(seconds) || undefined;
+}
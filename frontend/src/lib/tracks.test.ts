@@ -1,6 +1,6 @@
 import {describe, expect, it} from 'vitest';
 import type {TrackSummary} from '@/lib/api';
-import {playbackToPlayerTrack, trackArtworkUrl, trackArtworkUrls} from './tracks';
+import {playbackToPlayerTrack, trackArtworkPlaceholder, trackArtworkUrl, trackArtworkUrls} from './tracks';
 
 type TestTrack = TrackSummary & {
     unavailableAt?: string;
@@ -38,12 +38,17 @@ describe('playback track availability', () => {
 describe('track artwork URLs', () => {
     it('prefers track artwork and falls back to folder artwork', () => {
         expect(trackArtworkUrl(playbackTrack({audioImage: 'thumbnail.jpg'})))
-            .toBe('/api/audio/key/track-key/thumbnail');
+            .toBe('/api/audio/key/track-key/thumbnail?size=icon');
         expect(trackArtworkUrl(playbackTrack({
             audioImage: null,
             parentShareKey: 'folder-key',
             posterImage: 'poster.jpg',
-        }))).toBe('/api/folder/key/folder-key/poster');
+        }))).toBe('/api/folder/key/folder-key/poster?size=icon');
+    });
+
+    it('requests larger variants for cards', () => {
+        expect(trackArtworkUrl(playbackTrack({audioImage: 'thumbnail.jpg'}), 'medium'))
+            .toBe('/api/audio/key/track-key/thumbnail?size=medium');
     });
 
     it('returns null without indexed artwork', () => {
@@ -56,8 +61,32 @@ describe('track artwork URLs', () => {
             parentShareKey: 'folder-key',
             posterImage: 'poster.jpg',
         }))).toEqual([
-            '/api/audio/key/track-key/thumbnail',
-            '/api/folder/key/folder-key/poster',
+            '/api/audio/key/track-key/thumbnail?size=icon',
+            '/api/folder/key/folder-key/poster?size=icon',
         ]);
     });
 });
+
+describe('track artwork placeholders', () => {
+    const thumbnail = {blurhash: 'LKO2?U%2Tw=w]~RBVZRi};RPxuwH', dominantColor: '#336699'};
+    const poster = {blurhash: 'L6PZfSi_.AyE_3t7t7R**0o#DgR4', dominantColor: '#cc3322'};
+
+    it('follows the artwork that is shown', () => {
+        expect(trackArtworkPlaceholder(playbackTrack({
+            audioImage: 'thumbnail.jpg',
+            audioImagePlaceholder: thumbnail,
+            parentShareKey: 'folder-key',
+            posterImage: 'poster.jpg',
+            posterImagePlaceholder: poster,
+        }))).toBe(thumbnail);
+        expect(trackArtworkPlaceholder(playbackTrack({
+            parentShareKey: 'folder-key',
+            posterImage: 'poster.jpg',
+            posterImagePlaceholder: poster,
+        }))).toBe(poster);
+    });
+
+    it('is absent without artwork', () => {
+        expect(trackArtworkPlaceholder(playbackTrack({posterImagePlaceholder: poster}))).toBeUndefined();
+    });
+});
@@ -1,29 +1,44 @@
 import type {AudioPlayerTrack} from '@/contexts/AudioPlayerContext';
 import {API_BASE, type TrackSummary} from '@/lib/api';
-import type {AudioFile} from '@/types';
+import type {ArtworkPlaceholder, AudioFile} from '@/types';
 
 interface TrackArtwork {
     shareKey: string;
     audioImage?: string | null;
     parentShareKey?: string | null;
     posterImage?: string | null;
+    audioImagePlaceholder?: ArtworkPlaceholder;
+    posterImagePlaceholder?: ArtworkPlaceholder;
 }
 
-export function trackArtworkUrl(track: TrackArtwork): string | null {
-    return trackArtworkUrls(track)[0] ?? null;
+export type ArtworkSize = 'icon' | 'small' | 'medium' | 'large';
+
+export function trackArtworkUrl(track: TrackArtwork, size: ArtworkSize = 'icon'): string | null {
+    return trackArtworkUrls(track, size)[0] ?? null;
 }
 
-export function trackArtworkUrls(track: TrackArtwork): string[] {
+export function trackArtworkUrls(track: TrackArtwork, size: ArtworkSize = 'icon'): string[] {
     const urls: string[] = [];
     if (track.audioImage) {
-        urls.push(`${API_BASE}/api/audio/key/${track.shareKey}/thumbnail`);
+        urls.push(`${API_BASE}/api/audio/key/${track.shareKey}/thumbnail?size=${size}`);
     }
     if (track.parentShareKey && track.posterImage) {
-        urls.push(`${API_BASE}/api/folder/key/${track.parentShareKey}/poster`);
+        urls.push(`${API_BASE}/api/folder/key/${track.parentShareKey}/poster?size=${size}`);
     }
     return urls;
 }
 
+// trackArtworkPlaceholder matches the image trackArtworkUrl picks.
+export function trackArtworkPlaceholder(track: TrackArtwork): ArtworkPlaceholder | undefined {
+    if (track.audioImage) {
+        return track.audioImagePlaceholder;
+    }
+    if (track.parentShareKey && track.posterImage) {
+        return track.posterImagePlaceholder;
+    }
+    return undefined;
+}
+
 export function audioFileToPlayerTrack(item: AudioFile, source: AudioPlayerTrack['source'] = 'browse'): AudioPlayerTrack {
     return {
         src: `/audio/key/${item.shareKey}`,
@@ -0,0 +1,195 @@
+import { useEffect, useRef, useState } from 'react';
+import { useParams } from 'react-router';
+import { Helmet } from 'react-helmet-async';
+import { ExternalLink, Music, Pause, Play } from 'lucide-react';
+import { API_BASE } from '@/lib/api';
+import { DEFAULT_TITLE } from '@/lib/config';
+import { appFetch } from '@/lib/cloudflareChallenge';
+import { useMatureContentPreference } from '@/hooks/useMatureContentPreference';
+import { mediaAccessErrorMessage, mediaAccessURL, requestMediaAccess } from '@/lib/mediaAccess';
+
+interface EmbedTrack {
+    shareKey: string;
+    title: string;
+    artist?: string;
+    durationSeconds?: number;
+    isMature: boolean;
+}
+
+interface EmbedDescriptor {
+    type: 'audio' | 'folder';
+    shareKey: string;
+    title: string;
+    artist?: string;
+    pageUrl: string;
+    imageUrl?: string;
+    isMature: boolean;
+    tracks: EmbedTrack[];
+}
+
+function formatDuration(seconds?: number): string {
+    if (!seconds || seconds <= 0) return '';
+    const total = Math.round(seconds);
+    const minutes = Math.floor(total / 60);
+    return `${minutes}:${String(total % 60).padStart(2, '0')}`;
+}
+
+export default function Embed() {
+    const { key } = useParams<{ key: string }>();
+    const [descriptor, setDescriptor] = useState<EmbedDescriptor | null>(null);
+    const [status, setStatus] = useState<'loading' | 'ready' | 'missing' | 'removed'>('loading');
+    const [current, setCurrent] = useState(0);
+    const [isPlaying, setIsPlaying] = useState(false);
+    const [error, setError] = useState<string | null>(null);
+    const audioRef = useRef<HTMLAudioElement>(null);
+    const maturePreference = useMatureContentPreference();
+
+    useEffect(() => {
+        const controller = new AbortController();
+        setDescriptor(null);
+        setStatus('loading');
+        setCurrent(0);
+        if (!key) {
+            setStatus('missing');
+            return;
+        }
+        appFetch(`${API_BASE}/api/embed/${encodeURIComponent(key)}`, {
+            credentials: 'include',
+            signal: controller.signal,
+        }).then(async response => {
+            if (response.status === 410) {
+                setStatus('removed');
+            } else if (!response.ok) {
+                setStatus('missing');
+            } else {
+                setDescriptor(await response.json());
+                setStatus('ready');
+            }
+        }).catch(fetchError => {
+            if (controller.signal.aborted) return;
+            console.error('Could not load embed:', fetchError);
+            setStatus('missing');
+        });
+        return () => controller.abort();
+    }, [key]);
+
+    const playable = descriptor?.tracks.filter(track => !track.isMature || maturePreference.enabled) ?? [];
+    const active = playable[current];
+
+    const play = async (index: number) => {
+        const track = playable[index];
+        const audio = audioRef.current;
+        if (!track || !audio) return;
+        setCurrent(index);
+        setError(null);
+        try {
+            const grant = await requestMediaAccess(track.shareKey, 'stream');
+            audio.src = mediaAccessURL(track.shareKey, 'stream', grant.accessKey);
+            await audio.play();
+        } catch (playError) {
+            setError(mediaAccessErrorMessage(playError, 'play'));
+        }
+    };
+
+    const toggle = () => {
+        const audio = audioRef.current;
+        if (!audio) return;
+        if (audio.src && !audio.paused) {
+            audio.pause();
+        } else if (audio.src) {
+            void audio.play();
+        } else {
+            void play(current);
+        }
+    };
+
+    const handleEnded = () => {
+        setIsPlaying(false);
+        if (current + 1 < playable.length) void play(current + 1);
+    };
+
+    if (status !== 'ready' || !descriptor) {
+        return (
+            <div className="flex h-screen items-center justify-center bg-[var(--background)] px-4 text-sm text-[var(--muted-foreground)]">
+                {status === 'loading' && 'Loading…'}
+                {status === 'missing' && 'This audio is not available.'}
+                {status === 'removed' && 'This audio has been removed at the owner\'s request.'}
+            </div>
+        );
+    }
+
+    return (
+        <div className="flex h-screen flex-col gap-3 overflow-hidden bg-[var(--background)] p-3 text-[var(--foreground)]">
+            <Helmet>
+                <title>{`${descriptor.title} | ${DEFAULT_TITLE}`}</title>
+            </Helmet>
+            <div className="flex min-w-0 items-center gap-3">
+                {descriptor.imageUrl && !(descriptor.isMature && !maturePreference.enabled) ? (
+                    <img src={descriptor.imageUrl} alt="" className="h-16 w-16 shrink-0 rounded object-cover" />
+                ) : (
+                    <div className="flex h-16 w-16 shrink-0 items-center justify-center rounded bg-[var(--muted)]">
+                        <Music className="h-6 w-6 text-[var(--muted-foreground)]" />
+                    </div>
+                )}
+                <button
+                    type="button"
+                    onClick={toggle}
+                    disabled={!active}
+                    aria-label={isPlaying ? 'Pause' : 'Play'}
+                    className="flex h-10 w-10 shrink-0 items-center justify-center rounded-full bg-[var(--primary)] text-white disabled:opacity-50"
+                >
+                    {isPlaying ? <Pause className="h-5 w-5" /> : <Play className="h-5 w-5" />}
+                </button>
+                <div className="min-w-0 flex-1">
+                    <div className="truncate font-medium">{active?.title || descriptor.title}</div>
+                    <div className="truncate text-sm text-[var(--muted-foreground)]">
+                        {active?.artist || descriptor.artist || (descriptor.type === 'folder' ? descriptor.title : DEFAULT_TITLE)}
+                    </div>
+                </div>
+                <a
+                    href={descriptor.pageUrl}
+                    target="_blank"
+                    rel="noopener noreferrer"
+                    aria-label={`Open on ${DEFAULT_TITLE}`}
+                    className="shrink-0 text-[var(--muted-foreground)] hover:text-[var(--foreground)]"
+                >
+                    <ExternalLink className="h-4 w-4" />
+                </a>
+            </div>
+
+            <audio
+                ref={audioRef}
+                controls
+                preload="none"
+                className="w-full"
+                onPlay={() => setIsPlaying(true)}
+                onPause={() => setIsPlaying(false)}
+                onEnded={handleEnded}
+            />
+
+            {error && <p className="text-sm text-[var(--error-text)]">{error}</p>}
+            {!active && (
+                <p className="text-sm text-[var(--muted-foreground)]">
+                    Mature content is hidden. <a href={descriptor.pageUrl} target="_blank" rel="noopener noreferrer" className="underline">Open the page</a> to change this.
+                </p>
+            )}
+
+            {descriptor.type === 'folder' && playable.length > 0 && (
+                <ol className="min-h-0 flex-1 overflow-y-auto text-sm">
+                    {playable.map((track, index) => (
+                        <li key={track.shareKey}>
+                            <button
+                                type="button"
+                                onClick={() => void play(index)}
+                                className={`flex w-full items-center gap-2 rounded px-2 py-1 text-left hover:bg-[var(--muted)] ${index === current ? 'font-medium text-[var(--primary)]' : ''}`}
+                            >
+                                <span className="min-w-0 flex-1 truncate">{track.title}</span>
+                                <span className="shrink-0 text-xs text-[var(--muted-foreground)]">{formatDuration(track.durationSeconds)}</span>
+                            </button>
+                        </li>
+                    ))}
+                </ol>
+            )}
+        </div>
+    );
+}
@@ -3,10 +3,11 @@ import {Heart, ListMusic, Music, Play, ShieldAlert, Unlink} from 'lucide-react';
 import {Link} from 'react-router';
 import {Helmet} from 'react-helmet-async';
 import RecoveryPanel from '@/components/RecoveryPanel';
+import AppTokensPanel from '@/components/AppTokensPanel';
 import TrackQuickActions from '@/components/TrackQuickActions';
 import {useLikes} from '@/contexts/LikesContext';
 import {useAudioPlayerCommands} from '@/contexts/AudioPlayerContext';
-import {playbackToPlayerTrack, trackArtworkUrl} from '@/lib/tracks';
+import {playbackToPlayerTrack, trackArtworkPlaceholder, trackArtworkUrl} from '@/lib/tracks';
 import {getLikedTracks, type LikedTrack} from '@/lib/api';
 import {DEFAULT_TITLE} from '@/lib/config';
 
@@ -18,7 +19,16 @@ function LikedTrackArtwork({track}: {track: LikedTrack}) {
         return <span className="flex h-full w-full items-center justify-center bg-[var(--secondary)]"><Music className="h-5 w-5 text-[var(--primary)]" /></span>;
     }
 
-    return <img src={thumbnailUrl} alt="" className="h-full w-full object-cover" loading="lazy" onError={() => setImageFailed(true)} />;
+    return (
+        <img
+            src={thumbnailUrl}
+            alt=""
+            className="h-full w-full object-cover"
+            style={{backgroundColor: trackArtworkPlaceholder(track)?.dominantColor}}
+            loading="lazy"
+            onError={() => setImageFailed(true)}
+        />
+    );
 }
 
 export default function Likes() {
@@ -155,6 +165,7 @@ export default function Likes() {
             )}
 
             {isReady && <RecoveryPanel />}
+            {isReady && <AppTokensPanel />}
         </div>
     );
 }
@@ -5,7 +5,8 @@ import { Helmet } from 'react-helmet-async';
 import { Search as SearchIcon, Folder, Music, ShieldAlert, Unlink, ArrowRight, ChevronLeft, ChevronRight, ChevronDown, Calendar, Shuffle, SlidersHorizontal, X, ListPlus } from 'lucide-react';
 import { searchAudio, getRandomAudio, getRandomAudioFromSearch, fetchDirectoryContents, SearchResult, SearchFilters, SearchField, isMatureAge } from '@/lib/api';
 import type { Folder as RootFolder } from '@/types';
-import { formatDate } from '@/lib/utils';
+import { formatDate, formatDuration as formatTimestamp } from '@/lib/utils';
+import { shareStartPath } from '@/lib/share';
 import { DEFAULT_TITLE, DEFAULT_DESCRIPTION } from '@/lib/config';
 import { useRybbit } from '@/hooks/useRybbit';
 import { useMatureContentPreference } from '@/hooks/useMatureContentPreference';
@@ -24,7 +25,7 @@ const SORT_OPTIONS = [
     { value: 'date_asc', label: 'Oldest first' },
 ] as const;
 
-const VALID_FIELDS: SearchField[] = ['filename', 'title', 'artist', 'description'];
+const VALID_FIELDS: SearchField[] = ['filename', 'title', 'artist', 'description', 'chapters', 'transcript'];
 
 function filtersFromParams(params: URLSearchParams): SearchFilters {
     const filters: SearchFilters = {};
@@ -48,6 +49,8 @@ function filtersFromParams(params: URLSearchParams): SearchFilters {
     }
     const root = params.get('root');
     if (root) filters.root = root;
+    const path = params.get('path');
+    if (path) filters.path = path;
     if (params.get('includeMature') === 'true') filters.includeMature = true;
     return filters;
 }
@@ -63,6 +66,7 @@ function filtersToParams(filters: SearchFilters): Record<string, string> {
     if (filters.durationMax && filters.durationMax > 0) p.durationMax = filters.durationMax.toString();
     if (filters.fields && filters.fields.length > 0) p.fields = filters.fields.join(',');
     if (filters.root) p.root = filters.root;
+    if (filters.path) p.path = filters.path;
     if (filters.includeMature) p.includeMature = 'true';
     return p;
 }
@@ -74,6 +78,7 @@ function hasActiveFilters(filters: SearchFilters): boolean {
         (filters.durationMax && filters.durationMax > 0) ||
         (filters.fields && filters.fields.length > 0) ||
         filters.root ||
+        filters.path ||
         filters.includeMature);
 }
 
@@ -204,7 +209,7 @@ export default function Search() {
     };
 
     const getResultPath = (result: SearchResult): string => {
-        if (result.type === 'audio') return `/share/${result.shareKey}`;
+        if (result.type === 'audio') return shareStartPath(result.shareKey || '', result.transcriptMatch?.startTime);
         const encodedPath = result.path.split('/').map(s => encodeURIComponent(s)).join('/');
         return `/browse/${encodedPath}`;
     };
@@ -387,6 +392,13 @@ export default function Search() {
                                                         {result.description}
                                                     </p>
                                                 )}
+
+                                                {result.transcriptMatch && (!isMatureAge(result.ageLimit) || maturePreference.enabled) && (
+                                                    <p className="text-sm text-[var(--muted-foreground)] line-clamp-2 mt-1">
+                                                        <span className="tabular-nums text-[var(--primary)]">{formatTimestamp(result.transcriptMatch.startTime) || '0:00'}</span>
+                                                        {' '}“{result.transcriptMatch.text}”
+                                                    </p>
+                                                )}
                                             </div>
                                         </Link>
 
@@ -636,7 +648,7 @@ function FilterPanel({ filters, onChange, onClear }: FilterPanelProps) {
                 <div className="flex flex-wrap gap-1.5">
                     {VALID_FIELDS.map((f) => {
                         const active = filters.fields ? filters.fields.includes(f) : false;
-                        const label = f === 'filename' ? 'Filename' : f === 'title' ? 'Title' : f === 'artist' ? 'Artist' : 'Description';
+                        const label = f === 'filename' ? 'Filename' : f === 'title' ? 'Title' : f === 'artist' ? 'Artist' : f === 'description' ? 'Description' : f === 'chapters' ? 'Chapters' : 'Transcript';
                         const toggle = () => {
                             const current = filters.fields && filters.fields.length > 0 ? filters.fields : [];
                             const next = current.includes(f)
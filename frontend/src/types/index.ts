@@ -1,4 +1,17 @@
 
+// A titled section of a track, in seconds from the start.
+export interface Chapter {
+    startTime: number;
+    endTime?: number;
+    title: string;
+}
+
+export interface ArtworkPlaceholder {
+    blurhash: string;
+    dominantColor?: string;
+    accentColor?: string;
+}
+
 export interface AudioFile {
     name: string;
     path: string;
@@ -12,6 +25,8 @@ export interface AudioFile {
     shareKey: string;
     unavailableAt?: string;
     removalRequestedAt?: string;
+    placeholder?: ArtworkPlaceholder;
+    position?: number;    // index in the folder's manual track order
 }
 
 export interface FolderMetadata {
@@ -21,6 +36,9 @@ export interface FolderMetadata {
     url_broken?: boolean;
     items?: number;
     description?: string;
+    tags?: string[];
+    aliases?: string[];   // other names the folder is found by
+    age_limit?: number;   // inherited by every track in the folder
 }
 
 export interface Folder {
@@ -32,6 +50,9 @@ export interface Folder {
     metadata?: FolderMetadata;
     posterImage?: string;
     shareKey?: string;
+    placeholder?: ArtworkPlaceholder;
+    ageLimit?: number;
+    position?: number;    // manual sort order among sibling folders
 }
 
 export type FileSystemItem = AudioFile | Folder;